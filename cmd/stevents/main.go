@@ -7,20 +7,24 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type event struct {
-	ID   int                    `json:"id"`
-	Type string                 `json:"type"`
-	Time time.Time              `json:"time"`
-	Data map[string]interface{} `json:"data"`
+	ID       int                    `json:"id"`
+	GlobalID int                    `json:"globalID"`
+	Type     string                 `json:"type"`
+	Time     time.Time              `json:"time"`
+	Data     map[string]interface{} `json:"data"`
 }
 
 func main() {
@@ -35,29 +39,66 @@ func main() {
 		log.Fatal("Must give -apikey argument")
 	}
 
+	// Stream the events, resuming from the last one we saw if the
+	// connection is lost.
 	since := 0
 	for {
-		req, err := http.NewRequest("GET", fmt.Sprintf("http://%s/rest/events?since=%d", *target, since), nil)
+		var err error
+		since, err = stream(*target, *apikey, since)
 		if err != nil {
-			log.Fatal(err)
-		}
-		req.Header.Set("X-API-Key", *apikey)
-		res, err := http.DefaultClient.Do(req)
-		if err != nil {
-			log.Fatal(err)
+			log.Println("Event stream:", err)
+			time.Sleep(time.Second)
 		}
+	}
+}
 
-		var events []event
-		err = json.NewDecoder(res.Body).Decode(&events)
-		if err != nil {
-			log.Fatal(err)
-		}
-		res.Body.Close()
+// stream prints the events from the event stream until the connection is
+// closed, and returns the global ID of the last event seen.
+func stream(target, apikey string, since int) (int, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("http://%s/rest/events/stream?since=%d", target, since), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	req.Header.Set("X-API-Key", apikey)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return since, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		log.Fatal(res.Status)
+	}
 
-		for _, event := range events {
+	var eventType string
+	scanner := bufio.NewScanner(res.Body)
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			eventType = strings.TrimPrefix(line, "event: ")
+
+		case strings.HasPrefix(line, "id: "):
+			if id, err := strconv.Atoi(strings.TrimPrefix(line, "id: ")); err == nil {
+				since = id
+			}
+
+		case strings.HasPrefix(line, "data: "):
+			data := strings.TrimPrefix(line, "data: ")
+			if eventType == "missed" {
+				log.Println("Some events were missed:", data)
+				continue
+			}
+			var event event
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				return since, err
+			}
 			bs, _ := json.MarshalIndent(event, "", "    ")
 			log.Printf("%s", bs)
-			since = event.ID
+
+		case line == "":
+			eventType = ""
 		}
 	}
+	return since, scanner.Err()
 }
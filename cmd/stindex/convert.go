@@ -0,0 +1,42 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/db/backend"
+)
+
+// convert copies the database to a new one of the given type. Syncthing
+// must not be running, and the new database must not exist yet.
+func convert(ldb *db.Lowlevel, to backend.Type, path string) error {
+	if path == "" {
+		return errors.New("no destination given (-to)")
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("destination %s already exists", path)
+	}
+
+	dst, err := backend.Open(to, path)
+	if err != nil {
+		return err
+	}
+	n, err := backend.Copy(dst, ldb)
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Copied %d keys to %s database at %s\n", n, to, path)
+	return nil
+}
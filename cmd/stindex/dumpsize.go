@@ -41,7 +41,7 @@ func dumpsize(ldb *db.Lowlevel) {
 	h := &ElementHeap{}
 	heap.Init(h)
 
-	it := ldb.NewIterator(nil, nil)
+	it := ldb.NewPrefixIterator(nil)
 	var ele SizedElement
 	for it.Next() {
 		key := it.Key()
@@ -42,7 +42,7 @@ func idxck(ldb *db.Lowlevel) (success bool) {
 	var localDeviceKey uint32
 	success = true
 
-	it := ldb.NewIterator(nil, nil)
+	it := ldb.NewPrefixIterator(nil)
 	for it.Next() {
 		key := it.Key()
 		switch key[0] {
@@ -14,23 +14,35 @@ import (
 	"path/filepath"
 
 	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/db/backend"
 )
 
 func main() {
-	var mode string
+	var mode, backendName, toPath, toBackendName string
 	log.SetFlags(0)
 	log.SetOutput(os.Stdout)
 
-	flag.StringVar(&mode, "mode", "dump", "Mode of operation: dump, dumpsize, idxck")
+	flag.StringVar(&mode, "mode", "dump", "Mode of operation: dump, dumpsize, idxck, convert")
+	flag.StringVar(&backendName, "backend", "leveldb", "Database backend: leveldb, badger")
+	flag.StringVar(&toPath, "to", "", "Destination database for convert")
+	flag.StringVar(&toBackendName, "to-backend", "badger", "Destination database backend for convert")
 
 	flag.Parse()
 
+	var bt, toBt backend.Type
+	if err := bt.UnmarshalText([]byte(backendName)); err != nil {
+		log.Fatal(err)
+	}
+	if err := toBt.UnmarshalText([]byte(toBackendName)); err != nil {
+		log.Fatal(err)
+	}
+
 	path := flag.Arg(0)
 	if path == "" {
 		path = filepath.Join(defaultConfigDir(), "index-v0.14.0.db")
 	}
 
-	ldb, err := db.OpenRO(path)
+	ldb, err := db.OpenBackendRO(bt, path)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -43,6 +55,10 @@ func main() {
 		if !idxck(ldb) {
 			os.Exit(1)
 		}
+	} else if mode == "convert" {
+		if err := convert(ldb, toBt, toPath); err != nil {
+			log.Fatal(err)
+		}
 	} else {
 		fmt.Println("Unknown mode")
 	}
@@ -172,6 +172,7 @@ type RuntimeOptions struct {
 	guiAddress       string
 	guiAPIKey        string
 	generateDir      string
+	rotateCertDir    string
 	noRestart        bool
 	cpuProfile       bool
 	stRestarting     bool
@@ -212,6 +213,7 @@ func parseCommandLineOptions() RuntimeOptions {
 	options := defaultRuntimeOptions()
 
 	flag.StringVar(&options.generateDir, "generate", "", "Generate key and config in specified dir, then exit")
+	flag.StringVar(&options.rotateCertDir, "rotate-cert", "", "Replace key and certificate with those in specified dir, keeping the device ID, then exit")
 	flag.StringVar(&options.guiAddress, "gui-address", options.guiAddress, "Override GUI address (e.g. \"http://192.0.2.42:8443\")")
 	flag.StringVar(&options.guiAPIKey, "gui-apikey", options.guiAPIKey, "Override GUI API key")
 	flag.StringVar(&options.confDir, "home", "", "Set configuration directory")
@@ -322,6 +324,13 @@ func main() {
 	}
 
 	if options.showDeviceId {
+		if err := tlsutil.FinishCertificateRotation(
+			locations.Get(locations.CertFile),
+			locations.Get(locations.KeyFile),
+		); err != nil {
+			l.Warnln("Error reading device ID:", err)
+			os.Exit(exitError)
+		}
 		cert, err := tls.LoadX509KeyPair(
 			locations.Get(locations.CertFile),
 			locations.Get(locations.KeyFile),
@@ -331,7 +340,7 @@ func main() {
 			os.Exit(exitError)
 		}
 
-		fmt.Println(protocol.NewDeviceID(cert.Certificate[0]))
+		fmt.Println(tlsutil.DeviceID(cert))
 		return
 	}
 
@@ -351,6 +360,14 @@ func main() {
 		return
 	}
 
+	if options.rotateCertDir != "" {
+		if err := rotateCert(options.rotateCertDir); err != nil {
+			l.Warnln("Failed to replace key and certificate:", err)
+			os.Exit(exitError)
+		}
+		return
+	}
+
 	// Ensure that our home directory exists.
 	if err := ensureDir(locations.GetBaseDir(locations.ConfigBaseDir), 0700); err != nil {
 		l.Warnln("Failure on home directory:", err)
@@ -429,7 +446,7 @@ func generate(generateDir string) error {
 			return errors.Wrap(err, "create certificate")
 		}
 	}
-	myID = protocol.NewDeviceID(cert.Certificate[0])
+	myID = tlsutil.DeviceID(cert)
 	l.Infoln("Device ID:", myID)
 
 	cfgFile := filepath.Join(dir, "config.xml")
@@ -448,6 +465,28 @@ func generate(generateDir string) error {
 	return nil
 }
 
+// rotateCert replaces our key and certificate with cert.pem and key.pem in
+// the given dir. The new certificate must be issued by a CA for our device
+// ID, so that devices trusting the CA keep recognising us.
+func rotateCert(rotateCertDir string) error {
+	dir, err := fs.ExpandTilde(rotateCertDir)
+	if err != nil {
+		return err
+	}
+
+	myID, err := tlsutil.RotateCertificate(
+		locations.Get(locations.CertFile),
+		locations.Get(locations.KeyFile),
+		filepath.Join(dir, "cert.pem"),
+		filepath.Join(dir, "key.pem"),
+	)
+	if err != nil {
+		return err
+	}
+	l.Infoln("Replaced key and certificate, device ID:", myID)
+	return nil
+}
+
 func debugFacilities() string {
 	facilities := l.Facilities()
 
@@ -490,8 +529,13 @@ func checkUpgrade() upgrade.Release {
 }
 
 func performUpgrade(release upgrade.Release) {
-	// Use leveldb database locks to protect against concurrent upgrades
-	_, err := syncthing.OpenGoleveldb(locations.Get(locations.Database))
+	// Use database locks to protect against concurrent upgrades
+	cfg, err := loadOrDefaultConfig(protocol.EmptyDeviceID)
+	if err != nil {
+		l.Warnln("Upgrade:", err)
+		os.Exit(exitError)
+	}
+	_, err = syncthing.OpenDatabase(cfg.Options().DatabaseBackend)
 	if err == nil {
 		err = upgrade.To(release)
 		if err != nil {
@@ -578,8 +622,7 @@ func syncthingMain(runtimeOptions RuntimeOptions) {
 		setPauseState(cfg, true)
 	}
 
-	dbFile := locations.Get(locations.Database)
-	ldb, err := syncthing.OpenGoleveldb(dbFile)
+	ldb, err := syncthing.OpenDatabase(cfg.Options().DatabaseBackend)
 	if err != nil {
 		l.Warnln("Error opening database:", err)
 		os.Exit(1)
@@ -728,6 +771,9 @@ func auditWriter(auditFile string) io.Writer {
 }
 
 func resetDB() error {
+	if err := os.RemoveAll(locations.Get(locations.BadgerDB)); err != nil {
+		return err
+	}
 	return os.RemoveAll(locations.Get(locations.Database))
 }
 
@@ -875,7 +921,7 @@ func cleanConfigDirectory() {
 
 func showPaths(options RuntimeOptions) {
 	fmt.Printf("Configuration file:\n\t%s\n\n", locations.Get(locations.ConfigFile))
-	fmt.Printf("Database directory:\n\t%s\n\n", locations.Get(locations.Database))
+	fmt.Printf("Database directories:\n\t%s\n\t%s\n\n", locations.Get(locations.Database), locations.Get(locations.BadgerDB))
 	fmt.Printf("Device private key & certificate files:\n\t%s\n\t%s\n\n", locations.Get(locations.KeyFile), locations.Get(locations.CertFile))
 	fmt.Printf("HTTPS private key & certificate files:\n\t%s\n\t%s\n\n", locations.Get(locations.HTTPSKeyFile), locations.Get(locations.HTTPSCertFile))
 	fmt.Printf("Log file:\n\t%s\n\n", options.logFile)
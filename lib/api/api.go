@@ -60,6 +60,10 @@ const (
 	DiskEventMask       = events.LocalChangeDetected | events.RemoteChangeDetected
 	EventSubBufferSize  = 1000
 	defaultEventTimeout = time.Minute
+
+	// Event streams are sent a comment at this interval when there are no
+	// events, to keep proxies and clients from timing out the connection.
+	eventStreamKeepalive = 30 * time.Second
 )
 
 type service struct {
@@ -71,10 +75,12 @@ type service struct {
 	model                model.Model
 	eventSubs            map[events.EventType]events.BufferedSubscription
 	eventSubsMut         sync.Mutex
+	eventHistory         *events.History
 	discoverer           discover.CachingMux
 	connectionsService   connections.Service
 	fss                  model.FolderSummaryService
 	urService            *ur.Service
+	metrics              *metricsCollector
 	systemConfigMut      sync.Mutex // serializes posts to /rest/system/config
 	cpu                  Rater
 	contr                Controller
@@ -116,10 +122,12 @@ func New(id protocol.DeviceID, cfg config.Wrapper, assetDir, tlsDefaultCommonNam
 			DiskEventMask:    diskSub,
 		},
 		eventSubsMut:         sync.NewMutex(),
+		eventHistory:         events.NewHistory(EventSubBufferSize),
 		discoverer:           discoverer,
 		connectionsService:   connectionsService,
 		fss:                  fss,
 		urService:            urService,
+		metrics:              newMetricsCollector(cfg, m),
 		systemConfigMut:      sync.NewMutex(),
 		guiErrors:            errors,
 		systemLog:            systemLog,
@@ -225,21 +233,33 @@ func (s *service) serve(stop chan struct{}) {
 	s.cfg.Subscribe(s)
 	defer s.cfg.Unsubscribe(s)
 
+	eventsStop := make(chan struct{})
+	defer close(eventsStop)
+	go s.metrics.processEvents(eventsStop)
+
+	historySub := events.Default.Subscribe(events.AllEvents)
+	defer events.Default.Unsubscribe(historySub)
+	go s.eventHistory.Record(historySub, eventsStop)
+
 	// The GET handlers
 	getRestMux := http.NewServeMux()
 	getRestMux.HandleFunc("/rest/db/completion", s.getDBCompletion)              // device folder
 	getRestMux.HandleFunc("/rest/db/file", s.getDBFile)                          // folder file
 	getRestMux.HandleFunc("/rest/db/ignores", s.getDBIgnores)                    // folder
+	getRestMux.HandleFunc("/rest/db/selection", s.getDBSelection)                // folder
 	getRestMux.HandleFunc("/rest/db/need", s.getDBNeed)                          // folder [perpage] [page]
 	getRestMux.HandleFunc("/rest/db/remoteneed", s.getDBRemoteNeed)              // device folder [perpage] [page]
 	getRestMux.HandleFunc("/rest/db/localchanged", s.getDBLocalChanged)          // folder
 	getRestMux.HandleFunc("/rest/db/status", s.getDBStatus)                      // folder
 	getRestMux.HandleFunc("/rest/db/browse", s.getDBBrowse)                      // folder [prefix] [dirsonly] [levels]
 	getRestMux.HandleFunc("/rest/folder/versions", s.getFolderVersions)          // folder
+	getRestMux.HandleFunc("/rest/folder/conflicts", s.getFolderConflicts)        // folder
+	getRestMux.HandleFunc("/rest/folder/staged", s.getFolderStaged)              // folder
 	getRestMux.HandleFunc("/rest/folder/errors", s.getFolderErrors)              // folder
 	getRestMux.HandleFunc("/rest/folder/pullerrors", s.getFolderErrors)          // folder (deprecated)
 	getRestMux.HandleFunc("/rest/events", s.getIndexEvents)                      // [since] [limit] [timeout] [events]
 	getRestMux.HandleFunc("/rest/events/disk", s.getDiskEvents)                  // [since] [limit] [timeout]
+	getRestMux.HandleFunc("/rest/events/stream", s.getEventStream)               // [since] [events]
 	getRestMux.HandleFunc("/rest/stats/device", s.getDeviceStats)                // -
 	getRestMux.HandleFunc("/rest/stats/folder", s.getFolderStats)                // -
 	getRestMux.HandleFunc("/rest/svc/deviceid", s.getDeviceID)                   // id
@@ -262,23 +282,26 @@ func (s *service) serve(stop chan struct{}) {
 
 	// The POST handlers
 	postRestMux := http.NewServeMux()
-	postRestMux.HandleFunc("/rest/db/prio", s.postDBPrio)                          // folder file [perpage] [page]
-	postRestMux.HandleFunc("/rest/db/ignores", s.postDBIgnores)                    // folder
-	postRestMux.HandleFunc("/rest/db/override", s.postDBOverride)                  // folder
-	postRestMux.HandleFunc("/rest/db/revert", s.postDBRevert)                      // folder
-	postRestMux.HandleFunc("/rest/db/scan", s.postDBScan)                          // folder [sub...] [delay]
-	postRestMux.HandleFunc("/rest/folder/versions", s.postFolderVersionsRestore)   // folder <body>
-	postRestMux.HandleFunc("/rest/system/config", s.postSystemConfig)              // <body>
-	postRestMux.HandleFunc("/rest/system/error", s.postSystemError)                // <body>
-	postRestMux.HandleFunc("/rest/system/error/clear", s.postSystemErrorClear)     // -
-	postRestMux.HandleFunc("/rest/system/ping", s.restPing)                        // -
-	postRestMux.HandleFunc("/rest/system/reset", s.postSystemReset)                // [folder]
-	postRestMux.HandleFunc("/rest/system/restart", s.postSystemRestart)            // -
-	postRestMux.HandleFunc("/rest/system/shutdown", s.postSystemShutdown)          // -
-	postRestMux.HandleFunc("/rest/system/upgrade", s.postSystemUpgrade)            // -
-	postRestMux.HandleFunc("/rest/system/pause", s.makeDevicePauseHandler(true))   // [device]
-	postRestMux.HandleFunc("/rest/system/resume", s.makeDevicePauseHandler(false)) // [device]
-	postRestMux.HandleFunc("/rest/system/debug", s.postSystemDebug)                // [enable] [disable]
+	postRestMux.HandleFunc("/rest/db/prio", s.postDBPrio)                                  // folder file [perpage] [page]
+	postRestMux.HandleFunc("/rest/db/ignores", s.postDBIgnores)                            // folder
+	postRestMux.HandleFunc("/rest/db/selection", s.postDBSelection)                        // folder
+	postRestMux.HandleFunc("/rest/db/override", s.postDBOverride)                          // folder
+	postRestMux.HandleFunc("/rest/db/revert", s.postDBRevert)                              // folder
+	postRestMux.HandleFunc("/rest/db/scan", s.postDBScan)                                  // folder [sub...] [delay]
+	postRestMux.HandleFunc("/rest/folder/versions", s.postFolderVersionsRestore)           // folder <body>
+	postRestMux.HandleFunc("/rest/folder/conflicts/resolve", s.postFolderConflictsResolve) // folder <body>
+	postRestMux.HandleFunc("/rest/folder/staged/approve", s.postFolderStagedApprove)       // folder [path...]
+	postRestMux.HandleFunc("/rest/system/config", s.postSystemConfig)                      // <body>
+	postRestMux.HandleFunc("/rest/system/error", s.postSystemError)                        // <body>
+	postRestMux.HandleFunc("/rest/system/error/clear", s.postSystemErrorClear)             // -
+	postRestMux.HandleFunc("/rest/system/ping", s.restPing)                                // -
+	postRestMux.HandleFunc("/rest/system/reset", s.postSystemReset)                        // [folder]
+	postRestMux.HandleFunc("/rest/system/restart", s.postSystemRestart)                    // -
+	postRestMux.HandleFunc("/rest/system/shutdown", s.postSystemShutdown)                  // -
+	postRestMux.HandleFunc("/rest/system/upgrade", s.postSystemUpgrade)                    // -
+	postRestMux.HandleFunc("/rest/system/pause", s.makeDevicePauseHandler(true))           // [device]
+	postRestMux.HandleFunc("/rest/system/resume", s.makeDevicePauseHandler(false))         // [device]
+	postRestMux.HandleFunc("/rest/system/debug", s.postSystemDebug)                        // [enable] [disable]
 
 	// Debug endpoints, not for general use
 	debugMux := http.NewServeMux()
@@ -298,6 +321,10 @@ func (s *service) serve(stop chan struct{}) {
 	mux.Handle("/rest/", restMux)
 	mux.HandleFunc("/qr/", s.getQR)
 
+	// Prometheus metrics, for monitoring systems. Protected like /rest, so
+	// scrapers need to send the API key.
+	mux.Handle("/metrics", noCacheMiddleware(s.metrics.handler()))
+
 	// Serve compiled in assets unless an asset directory was set (for development)
 	mux.Handle("/", s.statics)
 
@@ -306,9 +333,9 @@ func (s *service) serve(stop chan struct{}) {
 
 	guiCfg := s.cfg.GUI()
 
-	// Wrap everything in CSRF protection. The /rest prefix should be
-	// protected, other requests will grant cookies.
-	handler := csrfMiddleware(s.id.String()[:5], "/rest", guiCfg, mux)
+	// Wrap everything in CSRF protection. The /rest and /metrics prefixes
+	// should be protected, other requests will grant cookies.
+	handler := csrfMiddleware(s.id.String()[:5], []string{"/rest", "/metrics"}, guiCfg, mux)
 
 	// Add our version and ID as a header to responses
 	handler = withDetailsMiddleware(s.id, handler)
@@ -1158,6 +1185,48 @@ func (s *service) postDBIgnores(w http.ResponseWriter, r *http.Request) {
 	s.getDBIgnores(w, r)
 }
 
+func (s *service) getDBSelection(w http.ResponseWriter, r *http.Request) {
+	folder := r.URL.Query().Get("folder")
+
+	cfg, ok := s.cfg.Folder(folder)
+	if !ok {
+		http.Error(w, "Invalid folder ID", 500)
+		return
+	}
+
+	sendJSON(w, map[string][]string{
+		"selectedPaths": cfg.SelectedPaths,
+	})
+}
+
+func (s *service) postDBSelection(w http.ResponseWriter, r *http.Request) {
+	folder := r.URL.Query().Get("folder")
+
+	cfg, ok := s.cfg.Folder(folder)
+	if !ok {
+		http.Error(w, "Invalid folder ID", 500)
+		return
+	}
+
+	var data map[string][]string
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	// Changing the selection restarts the folder, which rescans it and
+	// pulls whatever became selected.
+	cfg.SelectedPaths = data["selectedPaths"]
+	waiter, err := s.cfg.SetFolder(cfg)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	waiter.Wait()
+
+	s.getDBSelection(w, r)
+}
+
 func (s *service) getIndexEvents(w http.ResponseWriter, r *http.Request) {
 	s.fss.OnEventRequest()
 	mask := s.getEventMask(r.URL.Query().Get("events"))
@@ -1199,6 +1268,108 @@ func (s *service) getEvents(w http.ResponseWriter, r *http.Request, eventSub eve
 	sendJSON(w, evs)
 }
 
+// getEventStream streams events to the client as they happen, as
+// server-sent events. Each event is sent with its global ID as the event ID,
+// and the stream can be resumed after the event with the given global ID
+// using either the since parameter or the Last-Event-ID header. An event of
+// type "missed" is sent when some of the events to resume from are no longer
+// available. A client that doesn't keep up with the events is disconnected,
+// and is expected to resume from the last event it received.
+func (s *service) getEventStream(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	mask := s.getEventMask(qs.Get("events"))
+	since, _ := strconv.Atoi(qs.Get("since"))
+	if id, err := strconv.Atoi(r.Header.Get("Last-Event-ID")); err == nil {
+		since = id
+	}
+
+	f, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// Subscribe before looking at the history, so that no events are lost
+	// in between. Events are moved from the subscription to a buffer as
+	// they arrive so that a slow client doesn't hold up the event bus.
+
+	sub := events.Default.Subscribe(mask)
+	defer events.Default.Unsubscribe(sub)
+
+	queue := make(chan events.Event, EventSubBufferSize)
+	overflow := make(chan struct{})
+	go func() {
+		overflowed := false
+		for ev := range sub.C() {
+			select {
+			case queue <- ev:
+			default:
+				if !overflowed {
+					close(overflow)
+					overflowed = true
+				}
+			}
+		}
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("X-Accel-Buffering", "no") // disable buffering in nginx
+	w.WriteHeader(http.StatusOK)
+
+	send := func(ev events.Event) error {
+		bs, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "id: %d\ndata: %s\n\n", ev.GlobalID, bs)
+		return err
+	}
+
+	if since > 0 {
+		evs, complete := s.eventHistory.Since(since, mask)
+		if !complete {
+			fmt.Fprintf(w, "event: missed\ndata: {\"since\":%d}\n\n", since)
+		}
+		for _, ev := range evs {
+			if err := send(ev); err != nil {
+				return
+			}
+			since = ev.GlobalID
+		}
+	}
+	f.Flush()
+
+	keepalive := time.NewTicker(eventStreamKeepalive)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case ev := <-queue:
+			if ev.GlobalID <= since {
+				// Already sent from the history
+				continue
+			}
+			if err := send(ev); err != nil {
+				return
+			}
+			f.Flush()
+
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			f.Flush()
+
+		case <-overflow:
+			l.Debugln("Event stream client too slow, disconnecting", r.RemoteAddr)
+			return
+
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
 func (s *service) getEventMask(evs string) events.EventType {
 	eventMask := DefaultEventMask
 	if evs != "" {
@@ -1432,6 +1603,61 @@ func (s *service) postFolderVersionsRestore(w http.ResponseWriter, r *http.Reque
 	sendJSON(w, ferr)
 }
 
+func (s *service) getFolderConflicts(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	conflicts, err := s.model.FolderConflicts(qs.Get("folder"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	sendJSON(w, conflicts)
+}
+
+func (s *service) postFolderConflictsResolve(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	bs, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	// The version to keep, keyed by the name of the file in conflict
+	var chosen map[string]string
+	err = json.Unmarshal(bs, &chosen)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ferr, err := s.model.ResolveConflicts(qs.Get("folder"), chosen)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	sendJSON(w, ferr)
+}
+
+func (s *service) getFolderStaged(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	changes, err := s.model.StagedChanges(qs.Get("folder"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	sendJSON(w, changes)
+}
+
+func (s *service) postFolderStagedApprove(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	// Everything staged is approved if no paths are given
+	if err := s.model.ApproveStaged(qs.Get("folder"), qs["path"]); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+}
+
 func (s *service) getFolderErrors(w http.ResponseWriter, r *http.Request) {
 	qs := r.URL.Query()
 	folder := qs.Get("folder")
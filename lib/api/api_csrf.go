@@ -30,10 +30,10 @@ var csrfMut = sync.NewMutex()
 
 const maxCsrfTokens = 25
 
-// Check for CSRF token on /rest/ URLs. If a correct one is not given, reject
-// the request with 403. For / and /index.html, set a new CSRF cookie if none
-// is currently set.
-func csrfMiddleware(unique string, prefix string, cfg config.GUIConfiguration, next http.Handler) http.Handler {
+// Check for CSRF token on URLs under the protected prefixes. If a correct
+// one is not given, reject the request with 403. For / and /index.html, set
+// a new CSRF cookie if none is currently set.
+func csrfMiddleware(unique string, prefixes []string, cfg config.GUIConfiguration, next http.Handler) http.Handler {
 	loadCsrfTokens()
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Allow requests carrying a valid API key
@@ -52,9 +52,9 @@ func csrfMiddleware(unique string, prefix string, cfg config.GUIConfiguration, n
 			return
 		}
 
-		// Allow requests for anything not under the protected path prefix,
+		// Allow requests for anything not under the protected path prefixes,
 		// and set a CSRF cookie if there isn't already a valid one.
-		if !strings.HasPrefix(r.URL.Path, prefix) {
+		if !hasAnyPrefix(r.URL.Path, prefixes) {
 			cookie, err := r.Cookie("CSRF-Token-" + unique)
 			if err != nil || !validCsrfToken(cookie.Value) {
 				l.Debugln("new CSRF cookie in response to request for", r.URL)
@@ -79,6 +79,15 @@ func csrfMiddleware(unique string, prefix string, cfg config.GUIConfiguration, n
 	})
 }
 
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 func validCsrfToken(token string) bool {
 	csrfMut.Lock()
 	defer csrfMut.Unlock()
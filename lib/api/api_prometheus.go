@@ -0,0 +1,263 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/model"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+const metricsNamespace = "syncthing"
+
+// The folder states reported by syncthing_folder_state. All of them are
+// exported for each folder, with the current one set to 1, so that a state
+// change doesn't leave stale series behind.
+var folderStates = []string{
+	model.FolderIdle.String(),
+	model.FolderScanning.String(),
+	model.FolderScanWaiting.String(),
+	model.FolderSyncing.String(),
+	model.FolderError.String(),
+}
+
+var (
+	folderStateDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "folder", "state"),
+		"The current state of the folder.",
+		[]string{"folder", "state"}, nil)
+	folderStateChangedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "folder", "state_changed_timestamp_seconds"),
+		"The time of the last folder state change, in seconds since the epoch.",
+		[]string{"folder"}, nil)
+	folderFilesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "folder", "files"),
+		"The number of files in the global, local and needed state of the folder.",
+		[]string{"folder", "scope"}, nil)
+	folderDirectoriesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "folder", "directories"),
+		"The number of directories in the global, local and needed state of the folder.",
+		[]string{"folder", "scope"}, nil)
+	folderBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "folder", "bytes"),
+		"The size in bytes of the global, local and needed state of the folder.",
+		[]string{"folder", "scope"}, nil)
+	folderDeletesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "folder", "need_deletes"),
+		"The number of deletes needed in the folder.",
+		[]string{"folder"}, nil)
+	folderPullQueueDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "folder", "pull_queue_files"),
+		"The number of files being pulled and queued to be pulled.",
+		[]string{"folder", "status"}, nil)
+	deviceConnectedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "device", "connected"),
+		"Whether the device is currently connected.",
+		[]string{"device"}, nil)
+	deviceInBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "device", "in_bytes_total"),
+		"The number of bytes received from the device on the current connection.",
+		[]string{"device"}, nil)
+	deviceOutBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "device", "out_bytes_total"),
+		"The number of bytes sent to the device on the current connection.",
+		[]string{"device"}, nil)
+	totalInBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "connections", "in_bytes_total"),
+		"The number of bytes received on all connections.",
+		nil, nil)
+	totalOutBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "connections", "out_bytes_total"),
+		"The number of bytes sent on all connections.",
+		nil, nil)
+)
+
+// metricsCollector exports the state of the model as Prometheus metrics.
+// Sizes, states and connection statistics are read from the model when
+// scraped. Scan and pull durations and event counts are accumulated from
+// the event stream by processEvents.
+type metricsCollector struct {
+	cfg   config.Wrapper
+	model model.Model
+
+	scanSeconds *prometheus.HistogramVec
+	pullSeconds *prometheus.HistogramVec
+	eventsTotal *prometheus.CounterVec
+}
+
+func newMetricsCollector(cfg config.Wrapper, m model.Model) *metricsCollector {
+	buckets := prometheus.ExponentialBuckets(0.01, 4, 12) // 10 ms to about 12 hours
+	return &metricsCollector{
+		cfg:   cfg,
+		model: m,
+		scanSeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "folder",
+			Name:      "scan_duration_seconds",
+			Help:      "The time spent scanning the folder.",
+			Buckets:   buckets,
+		}, []string{"folder"}),
+		pullSeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "folder",
+			Name:      "pull_duration_seconds",
+			Help:      "The time spent syncing the folder.",
+			Buckets:   buckets,
+		}, []string{"folder"}),
+		eventsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "events",
+			Name:      "total",
+			Help:      "The number of events emitted on the event bus, by type.",
+		}, []string{"type"}),
+	}
+}
+
+// handler returns the HTTP handler for the /metrics endpoint, including the
+// standard Go runtime and process metrics.
+func (c *metricsCollector) handler() http.Handler {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(c)
+	reg.MustRegister(prometheus.NewGoCollector())
+	reg.MustRegister(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+}
+
+// processEvents updates the event based metrics until stop is closed.
+func (c *metricsCollector) processEvents(stop chan struct{}) {
+	sub := events.Default.Subscribe(events.AllEvents)
+	defer events.Default.Unsubscribe(sub)
+
+	for {
+		select {
+		case ev, ok := <-sub.C():
+			if !ok {
+				return
+			}
+			c.handleEvent(ev)
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (c *metricsCollector) handleEvent(ev events.Event) {
+	c.eventsTotal.WithLabelValues(ev.Type.String()).Inc()
+
+	if ev.Type != events.StateChanged {
+		return
+	}
+	data, ok := ev.Data.(map[string]interface{})
+	if !ok {
+		return
+	}
+	folder, _ := data["folder"].(string)
+	from, _ := data["from"].(string)
+	duration, ok := data["duration"].(float64)
+	if !ok {
+		return
+	}
+	switch from {
+	case model.FolderScanning.String():
+		c.scanSeconds.WithLabelValues(folder).Observe(duration)
+	case model.FolderSyncing.String():
+		c.pullSeconds.WithLabelValues(folder).Observe(duration)
+	}
+}
+
+func (c *metricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- folderStateDesc
+	ch <- folderStateChangedDesc
+	ch <- folderFilesDesc
+	ch <- folderDirectoriesDesc
+	ch <- folderBytesDesc
+	ch <- folderDeletesDesc
+	ch <- folderPullQueueDesc
+	ch <- deviceConnectedDesc
+	ch <- deviceInBytesDesc
+	ch <- deviceOutBytesDesc
+	ch <- totalInBytesDesc
+	ch <- totalOutBytesDesc
+	c.scanSeconds.Describe(ch)
+	c.pullSeconds.Describe(ch)
+	c.eventsTotal.Describe(ch)
+}
+
+func (c *metricsCollector) Collect(ch chan<- prometheus.Metric) {
+	for id, folder := range c.cfg.Folders() {
+		if folder.Paused {
+			continue
+		}
+		c.collectFolder(ch, id)
+	}
+	c.collectConnections(ch)
+
+	c.scanSeconds.Collect(ch)
+	c.pullSeconds.Collect(ch)
+	c.eventsTotal.Collect(ch)
+}
+
+func (c *metricsCollector) collectFolder(ch chan<- prometheus.Metric, folder string) {
+	state, changed, err := c.model.State(folder)
+	if err != nil && state == "" {
+		state = model.FolderError.String()
+	}
+	for _, s := range folderStates {
+		val := 0.0
+		if s == state {
+			val = 1
+		}
+		ch <- prometheus.MustNewConstMetric(folderStateDesc, prometheus.GaugeValue, val, folder, s)
+	}
+	if !changed.IsZero() {
+		ch <- prometheus.MustNewConstMetric(folderStateChangedDesc, prometheus.GaugeValue, float64(changed.UnixNano())/1e9, folder)
+	}
+
+	need := c.model.NeedSize(folder)
+	for scope, counts := range map[string]db.Counts{
+		"global": c.model.GlobalSize(folder),
+		"local":  c.model.LocalSize(folder),
+		"need":   need,
+	} {
+		ch <- prometheus.MustNewConstMetric(folderFilesDesc, prometheus.GaugeValue, float64(counts.Files), folder, scope)
+		ch <- prometheus.MustNewConstMetric(folderDirectoriesDesc, prometheus.GaugeValue, float64(counts.Directories), folder, scope)
+		ch <- prometheus.MustNewConstMetric(folderBytesDesc, prometheus.GaugeValue, float64(counts.Bytes), folder, scope)
+	}
+	ch <- prometheus.MustNewConstMetric(folderDeletesDesc, prometheus.GaugeValue, float64(need.Deleted), folder)
+
+	inProgress, queued := c.model.PullerQueueSize(folder)
+	ch <- prometheus.MustNewConstMetric(folderPullQueueDesc, prometheus.GaugeValue, float64(inProgress), folder, "in_progress")
+	ch <- prometheus.MustNewConstMetric(folderPullQueueDesc, prometheus.GaugeValue, float64(queued), folder, "queued")
+}
+
+func (c *metricsCollector) collectConnections(ch chan<- prometheus.Metric) {
+	stats := c.model.ConnectionStats()
+
+	conns, _ := stats["connections"].(map[string]model.ConnectionInfo)
+	for device, info := range conns {
+		connected := 0.0
+		if info.Connected {
+			connected = 1
+		}
+		ch <- prometheus.MustNewConstMetric(deviceConnectedDesc, prometheus.GaugeValue, connected, device)
+		if info.Connected {
+			ch <- prometheus.MustNewConstMetric(deviceInBytesDesc, prometheus.CounterValue, float64(info.InBytesTotal), device)
+			ch <- prometheus.MustNewConstMetric(deviceOutBytesDesc, prometheus.CounterValue, float64(info.OutBytesTotal), device)
+		}
+	}
+
+	in, out := protocol.TotalInOut()
+	ch <- prometheus.MustNewConstMetric(totalInBytesDesc, prometheus.CounterValue, float64(in))
+	ch <- prometheus.MustNewConstMetric(totalOutBytesDesc, prometheus.CounterValue, float64(out))
+}
@@ -0,0 +1,90 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/syncthing/syncthing/lib/events"
+)
+
+func TestMetricsEvents(t *testing.T) {
+	c := newMetricsCollector(new(mockedConfig), new(mockedModel))
+
+	c.handleEvent(events.Event{Type: events.StateChanged, Data: map[string]interface{}{
+		"folder":   "default",
+		"from":     "scanning",
+		"to":       "idle",
+		"duration": 1.5,
+	}})
+	c.handleEvent(events.Event{Type: events.StateChanged, Data: map[string]interface{}{
+		"folder":   "default",
+		"from":     "syncing",
+		"to":       "idle",
+		"duration": 20.0,
+	}})
+	c.handleEvent(events.Event{Type: events.StateChanged, Data: map[string]interface{}{
+		"folder": "default",
+		"from":   "idle",
+		"to":     "scanning",
+	}})
+	c.handleEvent(events.Event{Type: events.LocalIndexUpdated})
+
+	if v := testutil.ToFloat64(c.eventsTotal.WithLabelValues("StateChanged")); v != 3 {
+		t.Errorf("expected three state changes, got %v", v)
+	}
+	if v := testutil.ToFloat64(c.eventsTotal.WithLabelValues("LocalIndexUpdated")); v != 1 {
+		t.Errorf("expected one index update, got %v", v)
+	}
+
+	expected := `
+# HELP syncthing_folder_scan_duration_seconds The time spent scanning the folder.
+# TYPE syncthing_folder_scan_duration_seconds histogram
+syncthing_folder_scan_duration_seconds_bucket{folder="default",le="0.01"} 0
+syncthing_folder_scan_duration_seconds_bucket{folder="default",le="0.04"} 0
+syncthing_folder_scan_duration_seconds_bucket{folder="default",le="0.16"} 0
+syncthing_folder_scan_duration_seconds_bucket{folder="default",le="0.64"} 0
+syncthing_folder_scan_duration_seconds_bucket{folder="default",le="2.56"} 1
+syncthing_folder_scan_duration_seconds_bucket{folder="default",le="10.24"} 1
+syncthing_folder_scan_duration_seconds_bucket{folder="default",le="40.96"} 1
+syncthing_folder_scan_duration_seconds_bucket{folder="default",le="163.84"} 1
+syncthing_folder_scan_duration_seconds_bucket{folder="default",le="655.36"} 1
+syncthing_folder_scan_duration_seconds_bucket{folder="default",le="2621.44"} 1
+syncthing_folder_scan_duration_seconds_bucket{folder="default",le="10485.76"} 1
+syncthing_folder_scan_duration_seconds_bucket{folder="default",le="41943.04"} 1
+syncthing_folder_scan_duration_seconds_bucket{folder="default",le="+Inf"} 1
+syncthing_folder_scan_duration_seconds_sum{folder="default"} 1.5
+syncthing_folder_scan_duration_seconds_count{folder="default"} 1
+`
+	if err := testutil.CollectAndCompare(c.scanSeconds, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+	if err := testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP syncthing_folder_pull_duration_seconds The time spent syncing the folder.
+# TYPE syncthing_folder_pull_duration_seconds histogram
+syncthing_folder_pull_duration_seconds_bucket{folder="default",le="0.01"} 0
+syncthing_folder_pull_duration_seconds_bucket{folder="default",le="0.04"} 0
+syncthing_folder_pull_duration_seconds_bucket{folder="default",le="0.16"} 0
+syncthing_folder_pull_duration_seconds_bucket{folder="default",le="0.64"} 0
+syncthing_folder_pull_duration_seconds_bucket{folder="default",le="2.56"} 0
+syncthing_folder_pull_duration_seconds_bucket{folder="default",le="10.24"} 0
+syncthing_folder_pull_duration_seconds_bucket{folder="default",le="40.96"} 1
+syncthing_folder_pull_duration_seconds_bucket{folder="default",le="163.84"} 1
+syncthing_folder_pull_duration_seconds_bucket{folder="default",le="655.36"} 1
+syncthing_folder_pull_duration_seconds_bucket{folder="default",le="2621.44"} 1
+syncthing_folder_pull_duration_seconds_bucket{folder="default",le="10485.76"} 1
+syncthing_folder_pull_duration_seconds_bucket{folder="default",le="41943.04"} 1
+syncthing_folder_pull_duration_seconds_bucket{folder="default",le="+Inf"} 1
+syncthing_folder_pull_duration_seconds_sum{folder="default"} 20
+syncthing_folder_pull_duration_seconds_count{folder="default"} 1
+`), "syncthing_folder_pull_duration_seconds"); err != nil {
+		t.Error(err)
+	}
+}
@@ -7,6 +7,7 @@
 package api
 
 import (
+	"bufio"
 	"bytes"
 	"compress/gzip"
 	"encoding/json"
@@ -377,6 +378,14 @@ func TestAPIServiceRequests(t *testing.T) {
 			Type:   "text/plain",
 			Prefix: "",
 		},
+
+		// /metrics
+		{
+			URL:    "/metrics",
+			Code:   200,
+			Type:   "text/plain",
+			Prefix: "# HELP",
+		},
 	}
 
 	for _, tc := range cases {
@@ -586,6 +595,17 @@ func TestCSRFRequired(t *testing.T) {
 		t.Fatal("Getting /rest/system/config without CSRF token should fail, not", resp.Status)
 	}
 
+	// Neither should getting the metrics
+
+	resp, err = cli.Get(baseURL + "/metrics")
+	if err != nil {
+		t.Fatal("Unexpected error from getting /metrics:", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatal("Getting /metrics without CSRF token should fail, not", resp.Status)
+	}
+
 	// Calling on /rest with a token should succeed
 
 	req, _ := http.NewRequest("GET", baseURL+"/rest/system/config", nil)
@@ -1007,6 +1027,85 @@ func TestEventMasks(t *testing.T) {
 	}
 }
 
+func TestEventStream(t *testing.T) {
+	const testAPIKey = "foobarbaz"
+	cfg := new(mockedConfig)
+	cfg.gui.APIKey = testAPIKey
+	baseURL, err := startHTTP(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// readEvents connects to the event stream and returns the first n
+	// messages as id and data pairs.
+	readEvents := func(lastID string, n int, trigger func()) [][2]string {
+		req, _ := http.NewRequest("GET", baseURL+"/rest/events/stream?events=FolderSummary", nil)
+		req.Header.Set("X-API-Key", testAPIKey)
+		if lastID != "" {
+			req.Header.Set("Last-Event-ID", lastID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatal("unexpected content type", ct)
+		}
+
+		if trigger != nil {
+			trigger()
+		}
+
+		var msgs [][2]string
+		var cur [2]string
+		scanner := bufio.NewScanner(resp.Body)
+		for len(msgs) < n && scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				cur[0] = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				cur[0] = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				cur[1] = strings.TrimPrefix(line, "data: ")
+			case line == "":
+				msgs = append(msgs, cur)
+				cur = [2]string{}
+			}
+		}
+		return msgs
+	}
+
+	// Live events matching the mask are streamed as they happen
+
+	msgs := readEvents("", 2, func() {
+		events.Default.Log(events.FolderSummary, "first")
+		events.Default.Log(events.FolderErrors, "ignored")
+		events.Default.Log(events.FolderSummary, "second")
+	})
+	if len(msgs) != 2 {
+		t.Fatal("expected two events, got", msgs)
+	}
+	var ev events.Event
+	if err := json.Unmarshal([]byte(msgs[0][1]), &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Data != "first" || strconv.Itoa(ev.GlobalID) != msgs[0][0] {
+		t.Errorf("unexpected first event %v", msgs[0])
+	}
+	if !strings.Contains(msgs[1][1], `"second"`) {
+		t.Errorf("unexpected second event %v", msgs[1])
+	}
+
+	// Resuming replays the events after the last seen one
+
+	msgs = readEvents(msgs[0][0], 1, nil)
+	if len(msgs) != 1 || !strings.Contains(msgs[0][1], `"second"`) {
+		t.Errorf("unexpected resumed events %v", msgs)
+	}
+}
+
 func TestBrowse(t *testing.T) {
 	pathSep := string(os.PathSeparator)
 
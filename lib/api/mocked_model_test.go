@@ -37,6 +37,10 @@ func (m *mockedModel) NeedFolderFiles(folder string, page, perpage int) ([]db.Fi
 	return nil, nil, nil
 }
 
+func (m *mockedModel) PullerQueueSize(folder string) (int, int) {
+	return 0, 0
+}
+
 func (m *mockedModel) RemoteNeedFolderFiles(device protocol.DeviceID, folder string, page, perpage int) ([]db.FileInfoTruncated, error) {
 	return nil, nil
 }
@@ -84,6 +88,22 @@ func (m *mockedModel) GetFolderVersions(folder string) (map[string][]versioner.F
 	return nil, nil
 }
 
+func (m *mockedModel) FolderConflicts(folder string) ([]model.FolderConflict, error) {
+	return nil, nil
+}
+
+func (m *mockedModel) ResolveConflicts(folder string, chosen map[string]string) (map[string]string, error) {
+	return nil, nil
+}
+
+func (m *mockedModel) StagedChanges(folder string) ([]model.StagedChange, error) {
+	return nil, nil
+}
+
+func (m *mockedModel) ApproveStaged(folder string, paths []string) error {
+	return nil
+}
+
 func (m *mockedModel) RestoreFolderVersions(folder string, versions map[string]time.Time) (map[string]string, error) {
 	return nil, nil
 }
@@ -0,0 +1,159 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A BandwidthScheduleEntry overrides the static rate limits during a
+// recurring period of the week. Days is a comma separated list of day names
+// or ranges of day names ("mon-fri,sun"), empty meaning every day. Start and
+// End are in "15:04" format, in local time; an empty Start means the start
+// of the day and an empty End the end of the day. A period where End is not
+// after Start extends past midnight into the following day. As with the
+// static limits, the rates are in KiB/s and zero means unlimited.
+type BandwidthScheduleEntry struct {
+	Days        string `xml:"days,attr" json:"days"`
+	Start       string `xml:"start,attr" json:"start"`
+	End         string `xml:"end,attr" json:"end"`
+	MaxSendKbps int    `xml:"maxSendKbps,attr" json:"maxSendKbps"`
+	MaxRecvKbps int    `xml:"maxRecvKbps,attr" json:"maxRecvKbps"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+const minutesPerDay = 24 * 60
+
+// Validate returns an error if the entry can not be parsed.
+func (e BandwidthScheduleEntry) Validate() error {
+	_, _, _, err := e.parse()
+	return err
+}
+
+// Matches returns true if t falls within the scheduled period.
+func (e BandwidthScheduleEntry) Matches(t time.Time) bool {
+	days, start, end, err := e.parse()
+	if err != nil {
+		return false
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	if start < end {
+		return days[day] && minute >= start && minute < end
+	}
+	// The period wraps past midnight, so the early part of the day belongs
+	// to the period started the day before.
+	yesterday := (day + 6) % 7
+	return days[day] && minute >= start || days[yesterday] && minute < end
+}
+
+func (e BandwidthScheduleEntry) parse() (days [7]bool, start, end int, err error) {
+	days, err = parseWeekdays(e.Days)
+	if err != nil {
+		return
+	}
+	start, err = parseTimeOfDay(e.Start, 0)
+	if err != nil {
+		return
+	}
+	end, err = parseTimeOfDay(e.End, minutesPerDay)
+	return
+}
+
+func parseWeekdays(s string) (days [7]bool, err error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	if s == "" {
+		for i := range days {
+			days[i] = true
+		}
+		return days, nil
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		from, to := part, part
+		if i := strings.IndexByte(part, '-'); i >= 0 {
+			from, to = part[:i], part[i+1:]
+		}
+		first, ok := weekdays[strings.TrimSpace(from)]
+		if !ok {
+			return days, fmt.Errorf("unknown day %q", from)
+		}
+		last, ok := weekdays[strings.TrimSpace(to)]
+		if !ok {
+			return days, fmt.Errorf("unknown day %q", to)
+		}
+		// Ranges may wrap around the end of the week ("sat-mon").
+		for d := first; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// parseTimeOfDay returns the number of minutes since midnight of a "15:04"
+// formatted time, or def for the empty string.
+func parseTimeOfDay(s string, def int) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return def, nil
+	}
+	fields := strings.Split(s, ":")
+	if len(fields) != 2 {
+		return 0, fmt.Errorf("invalid time of day %q", s)
+	}
+	hour, err := strconv.Atoi(fields[0])
+	if err != nil || hour < 0 || hour > 24 {
+		return 0, fmt.Errorf("invalid time of day %q", s)
+	}
+	minute, err := strconv.Atoi(fields[1])
+	if err != nil || minute < 0 || minute > 59 || hour == 24 && minute != 0 {
+		return 0, fmt.Errorf("invalid time of day %q", s)
+	}
+	return hour*60 + minute, nil
+}
+
+// ScheduledLimits returns the send and receive rate limits in effect at time
+// t. The first matching entry of the schedule wins; when none matches, the
+// given static limits apply.
+func ScheduledLimits(schedule []BandwidthScheduleEntry, t time.Time, maxSendKbps, maxRecvKbps int) (int, int) {
+	for _, e := range schedule {
+		if e.Matches(t) {
+			return e.MaxSendKbps, e.MaxRecvKbps
+		}
+	}
+	return maxSendKbps, maxRecvKbps
+}
+
+// cleanBandwidthSchedule removes and warns about entries that can't be
+// parsed.
+func cleanBandwidthSchedule(schedule []BandwidthScheduleEntry, context string) []BandwidthScheduleEntry {
+	cleaned := make([]BandwidthScheduleEntry, 0, len(schedule))
+	for _, e := range schedule {
+		if err := e.Validate(); err != nil {
+			l.Warnf("Ignoring invalid bandwidth schedule entry for %s: %v", context, err)
+			continue
+		}
+		cleaned = append(cleaned, e)
+	}
+	return cleaned
+}
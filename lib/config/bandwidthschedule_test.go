@@ -0,0 +1,113 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBandwidthScheduleMatches(t *testing.T) {
+	// 2019-07-01 is a Monday
+	at := func(day int, hhmm string) time.Time {
+		tod, err := time.Parse("15:04", hhmm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return time.Date(2019, 7, day, tod.Hour(), tod.Minute(), 30, 0, time.Local)
+	}
+
+	cases := []struct {
+		entry BandwidthScheduleEntry
+		when  time.Time
+		match bool
+	}{
+		// Every day, all day
+		{BandwidthScheduleEntry{}, at(1, "00:00"), true},
+		{BandwidthScheduleEntry{}, at(7, "23:59"), true},
+
+		// Weekdays during office hours
+		{BandwidthScheduleEntry{Days: "mon-fri", Start: "08:00", End: "18:00"}, at(1, "07:59"), false},
+		{BandwidthScheduleEntry{Days: "mon-fri", Start: "08:00", End: "18:00"}, at(1, "08:00"), true},
+		{BandwidthScheduleEntry{Days: "mon-fri", Start: "08:00", End: "18:00"}, at(5, "17:59"), true},
+		{BandwidthScheduleEntry{Days: "mon-fri", Start: "08:00", End: "18:00"}, at(5, "18:00"), false},
+		{BandwidthScheduleEntry{Days: "mon-fri", Start: "08:00", End: "18:00"}, at(6, "12:00"), false},
+
+		// Lists, wrapping ranges and case
+		{BandwidthScheduleEntry{Days: "Sat, sun"}, at(6, "12:00"), true},
+		{BandwidthScheduleEntry{Days: "tue,thu"}, at(3, "12:00"), false},
+		{BandwidthScheduleEntry{Days: "sat-mon"}, at(1, "12:00"), true},
+		{BandwidthScheduleEntry{Days: "sat-mon"}, at(2, "12:00"), false},
+
+		// Nights, extending into the following day
+		{BandwidthScheduleEntry{Days: "fri", Start: "22:00", End: "06:00"}, at(5, "21:59"), false},
+		{BandwidthScheduleEntry{Days: "fri", Start: "22:00", End: "06:00"}, at(5, "23:00"), true},
+		{BandwidthScheduleEntry{Days: "fri", Start: "22:00", End: "06:00"}, at(6, "05:59"), true},
+		{BandwidthScheduleEntry{Days: "fri", Start: "22:00", End: "06:00"}, at(6, "06:00"), false},
+		{BandwidthScheduleEntry{Days: "fri", Start: "22:00", End: "06:00"}, at(5, "05:00"), false},
+
+		// Invalid entries never match
+		{BandwidthScheduleEntry{Days: "someday"}, at(1, "12:00"), false},
+		{BandwidthScheduleEntry{Start: "8"}, at(1, "12:00"), false},
+		{BandwidthScheduleEntry{End: "24:30"}, at(1, "12:00"), false},
+	}
+
+	for _, tc := range cases {
+		if res := tc.entry.Matches(tc.when); res != tc.match {
+			t.Errorf("%+v matches %v: %v, expected %v", tc.entry, tc.when, res, tc.match)
+		}
+	}
+}
+
+func TestScheduledLimits(t *testing.T) {
+	schedule := []BandwidthScheduleEntry{
+		{Days: "mon-fri", Start: "08:00", End: "18:00", MaxSendKbps: 250, MaxRecvKbps: 500},
+		{Days: "mon-fri", MaxSendKbps: 1000},
+	}
+
+	cases := []struct {
+		when       time.Time
+		send, recv int
+	}{
+		{time.Date(2019, 7, 1, 9, 0, 0, 0, time.Local), 250, 500},
+		{time.Date(2019, 7, 1, 20, 0, 0, 0, time.Local), 1000, 0},
+		{time.Date(2019, 7, 6, 9, 0, 0, 0, time.Local), 10, 20},
+	}
+
+	for _, tc := range cases {
+		send, recv := ScheduledLimits(schedule, tc.when, 10, 20)
+		if send != tc.send || recv != tc.recv {
+			t.Errorf("limits at %v are %d/%d, expected %d/%d", tc.when, send, recv, tc.send, tc.recv)
+		}
+	}
+}
+
+func TestLoadBandwidthSchedule(t *testing.T) {
+	cfg, err := Load("testdata/bandwidthschedule.xml", device2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := cfg.Options()
+	if opts.MaxSendKbps != 500 {
+		t.Error("unexpected static send limit", opts.MaxSendKbps)
+	}
+	// The entry with invalid days is dropped
+	expected := BandwidthScheduleEntry{Days: "mon-fri", Start: "08:00", End: "18:00", MaxSendKbps: 250, MaxRecvKbps: 250}
+	if len(opts.BandwidthSchedule) != 1 || opts.BandwidthSchedule[0] != expected {
+		t.Errorf("unexpected global schedule %+v", opts.BandwidthSchedule)
+	}
+
+	dev, ok := cfg.Device(device1)
+	if !ok {
+		t.Fatal("device1 missing")
+	}
+	expected = BandwidthScheduleEntry{Days: "sat,sun", MaxSendKbps: 1000}
+	if len(dev.BandwidthSchedule) != 1 || dev.BandwidthSchedule[0] != expected {
+		t.Errorf("unexpected device schedule %+v", dev.BandwidthSchedule)
+	}
+}
@@ -307,6 +307,7 @@ func (cfg *Configuration) clean() error {
 
 	cfg.Options.ListenAddresses = util.UniqueTrimmedStrings(cfg.Options.ListenAddresses)
 	cfg.Options.GlobalAnnServers = util.UniqueTrimmedStrings(cfg.Options.GlobalAnnServers)
+	cfg.Options.BandwidthSchedule = cleanBandwidthSchedule(cfg.Options.BandwidthSchedule, "all devices")
 
 	if cfg.Version > 0 && cfg.Version < OldestHandledVersion {
 		l.Warnf("Configuration version %d is deprecated. Attempting best effort conversion, but please verify manually.", cfg.Version)
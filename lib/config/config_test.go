@@ -64,6 +64,7 @@ func TestDefaultValues(t *testing.T) {
 		URPostInsecurely:        false,
 		ReleasesURL:             "https://upgrades.syncthing.net/meta.json",
 		AlwaysLocalNets:         []string{},
+		BandwidthSchedule:       []BandwidthScheduleEntry{},
 		OverwriteRemoteDevNames: false,
 		TempIndexMinBlocks:      10,
 		UnackedNotificationIDs:  []string{},
@@ -74,6 +75,7 @@ func TestDefaultValues(t *testing.T) {
 		StunKeepaliveStartS:     180,
 		StunKeepaliveMinS:       20,
 		StunServers:             []string{"default"},
+		DatabaseBackend:         "leveldb",
 	}
 
 	cfg := New(device1)
@@ -132,22 +134,28 @@ func TestDeviceConfig(t *testing.T) {
 
 		expectedDevices := []DeviceConfiguration{
 			{
-				DeviceID:        device1,
-				Name:            "node one",
-				Addresses:       []string{"tcp://a"},
-				Compression:     protocol.CompressMetadata,
-				AllowedNetworks: []string{},
-				IgnoredFolders:  []ObservedFolder{},
-				PendingFolders:  []ObservedFolder{},
+				DeviceID:             device1,
+				Name:                 "node one",
+				Addresses:            []string{"tcp://a"},
+				Compression:          protocol.CompressMetadata,
+				AllowedNetworks:      []string{},
+				CompressionAlgorithm: protocol.MessageCompressionLZ4,
+				BandwidthSchedule:    []BandwidthScheduleEntry{},
+				IgnoredFolders:       []ObservedFolder{},
+				PendingFolders:       []ObservedFolder{},
+				OwnershipMappings:    []OwnershipMapping{},
 			},
 			{
-				DeviceID:        device4,
-				Name:            "node two",
-				Addresses:       []string{"tcp://b"},
-				Compression:     protocol.CompressMetadata,
-				AllowedNetworks: []string{},
-				IgnoredFolders:  []ObservedFolder{},
-				PendingFolders:  []ObservedFolder{},
+				DeviceID:             device4,
+				Name:                 "node two",
+				Addresses:            []string{"tcp://b"},
+				Compression:          protocol.CompressMetadata,
+				AllowedNetworks:      []string{},
+				CompressionAlgorithm: protocol.MessageCompressionLZ4,
+				BandwidthSchedule:    []BandwidthScheduleEntry{},
+				IgnoredFolders:       []ObservedFolder{},
+				PendingFolders:       []ObservedFolder{},
+				OwnershipMappings:    []OwnershipMapping{},
 			},
 		}
 		expectedDeviceIDs := []protocol.DeviceID{device1, device4}
@@ -212,6 +220,7 @@ func TestOverriddenValues(t *testing.T) {
 		URPostInsecurely:        true,
 		ReleasesURL:             "https://localhost/releases",
 		AlwaysLocalNets:         []string{},
+		BandwidthSchedule:       []BandwidthScheduleEntry{},
 		OverwriteRemoteDevNames: true,
 		TempIndexMinBlocks:      100,
 		UnackedNotificationIDs:  []string{"asdfasdf"},
@@ -222,6 +231,7 @@ func TestOverriddenValues(t *testing.T) {
 		StunKeepaliveStartS:     9000,
 		StunKeepaliveMinS:       900,
 		StunServers:             []string{"foo"},
+		DatabaseBackend:         "badger",
 	}
 
 	os.Unsetenv("STNOUPGRADE")
@@ -239,34 +249,46 @@ func TestDeviceAddressesDynamic(t *testing.T) {
 	name, _ := os.Hostname()
 	expected := map[protocol.DeviceID]DeviceConfiguration{
 		device1: {
-			DeviceID:        device1,
-			Addresses:       []string{"dynamic"},
-			AllowedNetworks: []string{},
-			IgnoredFolders:  []ObservedFolder{},
-			PendingFolders:  []ObservedFolder{},
+			DeviceID:             device1,
+			Addresses:            []string{"dynamic"},
+			AllowedNetworks:      []string{},
+			CompressionAlgorithm: protocol.MessageCompressionLZ4,
+			BandwidthSchedule:    []BandwidthScheduleEntry{},
+			IgnoredFolders:       []ObservedFolder{},
+			PendingFolders:       []ObservedFolder{},
+			OwnershipMappings:    []OwnershipMapping{},
 		},
 		device2: {
-			DeviceID:        device2,
-			Addresses:       []string{"dynamic"},
-			AllowedNetworks: []string{},
-			IgnoredFolders:  []ObservedFolder{},
-			PendingFolders:  []ObservedFolder{},
+			DeviceID:             device2,
+			Addresses:            []string{"dynamic"},
+			AllowedNetworks:      []string{},
+			CompressionAlgorithm: protocol.MessageCompressionLZ4,
+			BandwidthSchedule:    []BandwidthScheduleEntry{},
+			IgnoredFolders:       []ObservedFolder{},
+			PendingFolders:       []ObservedFolder{},
+			OwnershipMappings:    []OwnershipMapping{},
 		},
 		device3: {
-			DeviceID:        device3,
-			Addresses:       []string{"dynamic"},
-			AllowedNetworks: []string{},
-			IgnoredFolders:  []ObservedFolder{},
-			PendingFolders:  []ObservedFolder{},
+			DeviceID:             device3,
+			Addresses:            []string{"dynamic"},
+			AllowedNetworks:      []string{},
+			CompressionAlgorithm: protocol.MessageCompressionLZ4,
+			BandwidthSchedule:    []BandwidthScheduleEntry{},
+			IgnoredFolders:       []ObservedFolder{},
+			PendingFolders:       []ObservedFolder{},
+			OwnershipMappings:    []OwnershipMapping{},
 		},
 		device4: {
-			DeviceID:        device4,
-			Name:            name, // Set when auto created
-			Addresses:       []string{"dynamic"},
-			Compression:     protocol.CompressMetadata,
-			AllowedNetworks: []string{},
-			IgnoredFolders:  []ObservedFolder{},
-			PendingFolders:  []ObservedFolder{},
+			DeviceID:             device4,
+			Name:                 name, // Set when auto created
+			Addresses:            []string{"dynamic"},
+			Compression:          protocol.CompressMetadata,
+			AllowedNetworks:      []string{},
+			CompressionAlgorithm: protocol.MessageCompressionLZ4,
+			BandwidthSchedule:    []BandwidthScheduleEntry{},
+			IgnoredFolders:       []ObservedFolder{},
+			PendingFolders:       []ObservedFolder{},
+			OwnershipMappings:    []OwnershipMapping{},
 		},
 	}
 
@@ -285,37 +307,49 @@ func TestDeviceCompression(t *testing.T) {
 	name, _ := os.Hostname()
 	expected := map[protocol.DeviceID]DeviceConfiguration{
 		device1: {
-			DeviceID:        device1,
-			Addresses:       []string{"dynamic"},
-			Compression:     protocol.CompressMetadata,
-			AllowedNetworks: []string{},
-			IgnoredFolders:  []ObservedFolder{},
-			PendingFolders:  []ObservedFolder{},
+			DeviceID:             device1,
+			Addresses:            []string{"dynamic"},
+			Compression:          protocol.CompressMetadata,
+			AllowedNetworks:      []string{},
+			CompressionAlgorithm: protocol.MessageCompressionLZ4,
+			BandwidthSchedule:    []BandwidthScheduleEntry{},
+			IgnoredFolders:       []ObservedFolder{},
+			PendingFolders:       []ObservedFolder{},
+			OwnershipMappings:    []OwnershipMapping{},
 		},
 		device2: {
-			DeviceID:        device2,
-			Addresses:       []string{"dynamic"},
-			Compression:     protocol.CompressMetadata,
-			AllowedNetworks: []string{},
-			IgnoredFolders:  []ObservedFolder{},
-			PendingFolders:  []ObservedFolder{},
+			DeviceID:             device2,
+			Addresses:            []string{"dynamic"},
+			Compression:          protocol.CompressMetadata,
+			AllowedNetworks:      []string{},
+			CompressionAlgorithm: protocol.MessageCompressionZstd,
+			BandwidthSchedule:    []BandwidthScheduleEntry{},
+			IgnoredFolders:       []ObservedFolder{},
+			PendingFolders:       []ObservedFolder{},
+			OwnershipMappings:    []OwnershipMapping{},
 		},
 		device3: {
-			DeviceID:        device3,
-			Addresses:       []string{"dynamic"},
-			Compression:     protocol.CompressNever,
-			AllowedNetworks: []string{},
-			IgnoredFolders:  []ObservedFolder{},
-			PendingFolders:  []ObservedFolder{},
+			DeviceID:             device3,
+			Addresses:            []string{"dynamic"},
+			Compression:          protocol.CompressNever,
+			AllowedNetworks:      []string{},
+			CompressionAlgorithm: protocol.MessageCompressionLZ4,
+			BandwidthSchedule:    []BandwidthScheduleEntry{},
+			IgnoredFolders:       []ObservedFolder{},
+			PendingFolders:       []ObservedFolder{},
+			OwnershipMappings:    []OwnershipMapping{},
 		},
 		device4: {
-			DeviceID:        device4,
-			Name:            name, // Set when auto created
-			Addresses:       []string{"dynamic"},
-			Compression:     protocol.CompressMetadata,
-			AllowedNetworks: []string{},
-			IgnoredFolders:  []ObservedFolder{},
-			PendingFolders:  []ObservedFolder{},
+			DeviceID:             device4,
+			Name:                 name, // Set when auto created
+			Addresses:            []string{"dynamic"},
+			Compression:          protocol.CompressMetadata,
+			AllowedNetworks:      []string{},
+			CompressionAlgorithm: protocol.MessageCompressionLZ4,
+			BandwidthSchedule:    []BandwidthScheduleEntry{},
+			IgnoredFolders:       []ObservedFolder{},
+			PendingFolders:       []ObservedFolder{},
+			OwnershipMappings:    []OwnershipMapping{},
 		},
 	}
 
@@ -334,34 +368,46 @@ func TestDeviceAddressesStatic(t *testing.T) {
 	name, _ := os.Hostname()
 	expected := map[protocol.DeviceID]DeviceConfiguration{
 		device1: {
-			DeviceID:        device1,
-			Addresses:       []string{"tcp://192.0.2.1", "tcp://192.0.2.2"},
-			AllowedNetworks: []string{},
-			IgnoredFolders:  []ObservedFolder{},
-			PendingFolders:  []ObservedFolder{},
+			DeviceID:             device1,
+			Addresses:            []string{"tcp://192.0.2.1", "tcp://192.0.2.2"},
+			AllowedNetworks:      []string{},
+			CompressionAlgorithm: protocol.MessageCompressionLZ4,
+			BandwidthSchedule:    []BandwidthScheduleEntry{},
+			IgnoredFolders:       []ObservedFolder{},
+			PendingFolders:       []ObservedFolder{},
+			OwnershipMappings:    []OwnershipMapping{},
 		},
 		device2: {
-			DeviceID:        device2,
-			Addresses:       []string{"tcp://192.0.2.3:6070", "tcp://[2001:db8::42]:4242"},
-			AllowedNetworks: []string{},
-			IgnoredFolders:  []ObservedFolder{},
-			PendingFolders:  []ObservedFolder{},
+			DeviceID:             device2,
+			Addresses:            []string{"tcp://192.0.2.3:6070", "tcp://[2001:db8::42]:4242"},
+			AllowedNetworks:      []string{},
+			CompressionAlgorithm: protocol.MessageCompressionLZ4,
+			BandwidthSchedule:    []BandwidthScheduleEntry{},
+			IgnoredFolders:       []ObservedFolder{},
+			PendingFolders:       []ObservedFolder{},
+			OwnershipMappings:    []OwnershipMapping{},
 		},
 		device3: {
-			DeviceID:        device3,
-			Addresses:       []string{"tcp://[2001:db8::44]:4444", "tcp://192.0.2.4:6090"},
-			AllowedNetworks: []string{},
-			IgnoredFolders:  []ObservedFolder{},
-			PendingFolders:  []ObservedFolder{},
+			DeviceID:             device3,
+			Addresses:            []string{"tcp://[2001:db8::44]:4444", "tcp://192.0.2.4:6090"},
+			AllowedNetworks:      []string{},
+			CompressionAlgorithm: protocol.MessageCompressionLZ4,
+			BandwidthSchedule:    []BandwidthScheduleEntry{},
+			IgnoredFolders:       []ObservedFolder{},
+			PendingFolders:       []ObservedFolder{},
+			OwnershipMappings:    []OwnershipMapping{},
 		},
 		device4: {
-			DeviceID:        device4,
-			Name:            name, // Set when auto created
-			Addresses:       []string{"dynamic"},
-			Compression:     protocol.CompressMetadata,
-			AllowedNetworks: []string{},
-			IgnoredFolders:  []ObservedFolder{},
-			PendingFolders:  []ObservedFolder{},
+			DeviceID:             device4,
+			Name:                 name, // Set when auto created
+			Addresses:            []string{"dynamic"},
+			Compression:          protocol.CompressMetadata,
+			AllowedNetworks:      []string{},
+			CompressionAlgorithm: protocol.MessageCompressionLZ4,
+			BandwidthSchedule:    []BandwidthScheduleEntry{},
+			IgnoredFolders:       []ObservedFolder{},
+			PendingFolders:       []ObservedFolder{},
+			OwnershipMappings:    []OwnershipMapping{},
 		},
 	}
 
@@ -653,6 +699,86 @@ func TestPullOrder(t *testing.T) {
 	}
 }
 
+func TestConflictPolicy(t *testing.T) {
+	wrapper, err := Load("testdata/conflictpolicy.xml", device1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	folders := wrapper.Folders()
+
+	expected := []struct {
+		name   string
+		policy ConflictPolicy
+	}{
+		{"f1", ConflictRename},     // empty value, default
+		{"f2", ConflictRename},     // explicit
+		{"f3", ConflictNewestWins}, // explicit
+		{"f4", ConflictRename},     // empty value, default
+		{"f5", ConflictKeepLocal},  // explicit
+		{"f6", ConflictKeepRemote}, // explicit
+		{"f7", ConflictExternal},   // explicit
+	}
+
+	// Serialize and deserialize again to verify it survives the transformation
+
+	buf := new(bytes.Buffer)
+	cfg := wrapper.RawCopy()
+	cfg.WriteXML(buf)
+
+	cfg, err = ReadXML(buf, device1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reread := Wrap("testdata/conflictpolicy.xml", cfg).Folders()
+
+	for _, tc := range expected {
+		if actual := folders[tc.name].ConflictPolicy; actual != tc.policy {
+			t.Errorf("Incorrect conflict policy for %q: %v != %v", tc.name, actual, tc.policy)
+		}
+		if actual := reread[tc.name].ConflictPolicy; actual != tc.policy {
+			t.Errorf("Incorrect conflict policy for %q after rewrite: %v != %v", tc.name, actual, tc.policy)
+		}
+	}
+
+	if cmd := reread["f7"].ConflictCommand; cmd != "merge %LOCAL_PATH% %REMOTE_PATH%" {
+		t.Errorf("Incorrect conflict command %q", cmd)
+	}
+
+	if _, err := Load("testdata/conflictpolicy-unknown.xml", device1); err == nil {
+		t.Error("Expected an error loading an unknown conflict policy")
+	}
+}
+
+func TestFolderHooks(t *testing.T) {
+	wrapper, err := Load("testdata/folderhooks.xml", device1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []FolderHook{
+		{Event: HookItemFinished, Command: "systemctl reload foo"},
+		{Event: HookPostScan, Command: "make -C %FOLDER_PATH%", TimeoutS: 600},
+		{Event: HookNone, Command: "true"}, // unknown event, never run
+	}
+	if hooks := wrapper.Folders()["f1"].Hooks; !reflect.DeepEqual(hooks, expected) {
+		t.Errorf("Incorrect hooks %+v, expected %+v", hooks, expected)
+	}
+
+	// Serialize and deserialize again to verify it survives the transformation
+
+	buf := new(bytes.Buffer)
+	cfg := wrapper.RawCopy()
+	cfg.WriteXML(buf)
+
+	cfg, err = ReadXML(buf, device1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hooks := cfg.Folders[0].Hooks; !reflect.DeepEqual(hooks, expected) {
+		t.Errorf("Incorrect hooks after rewrite %+v, expected %+v", hooks, expected)
+	}
+}
+
 func TestLargeRescanInterval(t *testing.T) {
 	wrapper, err := Load("testdata/largeinterval.xml", device1)
 	if err != nil {
@@ -1145,3 +1271,23 @@ func defaultConfigAsMap() map[string]interface{} {
 	}
 	return tmp
 }
+
+func TestCleanSelectedPaths(t *testing.T) {
+	cases := []struct {
+		in, out []string
+	}{
+		{nil, nil},
+		{[]string{"a/b", "/a/b/", "a//b/c", "a/b c", "d\\e"}, []string{"a/b", "a/b c", "d\\e"}},
+		{[]string{"a", "."}, nil},
+		{[]string{"x/../y", "../z"}, []string{"y", "z"}},
+	}
+	if runtime.GOOS == "windows" {
+		cases[1].out[2] = "d/e"
+	}
+
+	for _, tc := range cases {
+		if res := cleanSelectedPaths(tc.in); !reflect.DeepEqual(res, tc.out) {
+			t.Errorf("cleanSelectedPaths(%q) == %q, expected %q", tc.in, res, tc.out)
+		}
+	}
+}
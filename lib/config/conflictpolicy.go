@@ -0,0 +1,60 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package config
+
+import "fmt"
+
+// ConflictPolicy is what to do with a local item when a change to it by
+// another device is in conflict with the local one.
+type ConflictPolicy int
+
+const (
+	ConflictRename     ConflictPolicy = iota // default, the local item is moved to a conflict copy
+	ConflictNewestWins                       // the most recently modified item is kept, without conflict copy
+	ConflictKeepLocal                        // the local item is kept and the remote change ignored
+	ConflictKeepRemote                       // the remote change replaces the local item, without conflict copy
+	ConflictExternal                         // an external command merges the remote version into the local file
+)
+
+func (p ConflictPolicy) String() string {
+	switch p {
+	case ConflictRename:
+		return "rename"
+	case ConflictNewestWins:
+		return "newest-wins"
+	case ConflictKeepLocal:
+		return "keep-local"
+	case ConflictKeepRemote:
+		return "keep-remote"
+	case ConflictExternal:
+		return "external"
+	default:
+		return "unknown"
+	}
+}
+
+func (p ConflictPolicy) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p *ConflictPolicy) UnmarshalText(bs []byte) error {
+	switch string(bs) {
+	case "", "rename":
+		*p = ConflictRename
+	case "newest-wins":
+		*p = ConflictNewestWins
+	case "keep-local":
+		*p = ConflictKeepLocal
+	case "keep-remote":
+		*p = ConflictKeepRemote
+	case "external":
+		*p = ConflictExternal
+	default:
+		return fmt.Errorf("unknown conflict policy %q", bs)
+	}
+	return nil
+}
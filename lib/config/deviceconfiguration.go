@@ -14,22 +14,26 @@ import (
 )
 
 type DeviceConfiguration struct {
-	DeviceID                 protocol.DeviceID    `xml:"id,attr" json:"deviceID"`
-	Name                     string               `xml:"name,attr,omitempty" json:"name"`
-	Addresses                []string             `xml:"address,omitempty" json:"addresses" default:"dynamic"`
-	Compression              protocol.Compression `xml:"compression,attr" json:"compression"`
-	CertName                 string               `xml:"certName,attr,omitempty" json:"certName"`
-	Introducer               bool                 `xml:"introducer,attr" json:"introducer"`
-	SkipIntroductionRemovals bool                 `xml:"skipIntroductionRemovals,attr" json:"skipIntroductionRemovals"`
-	IntroducedBy             protocol.DeviceID    `xml:"introducedBy,attr" json:"introducedBy"`
-	Paused                   bool                 `xml:"paused" json:"paused"`
-	AllowedNetworks          []string             `xml:"allowedNetwork,omitempty" json:"allowedNetworks"`
-	AutoAcceptFolders        bool                 `xml:"autoAcceptFolders" json:"autoAcceptFolders"`
-	MaxSendKbps              int                  `xml:"maxSendKbps" json:"maxSendKbps"`
-	MaxRecvKbps              int                  `xml:"maxRecvKbps" json:"maxRecvKbps"`
-	IgnoredFolders           []ObservedFolder     `xml:"ignoredFolder" json:"ignoredFolders"`
-	PendingFolders           []ObservedFolder     `xml:"pendingFolder" json:"pendingFolders"`
-	MaxRequestKiB            int                  `xml:"maxRequestKiB" json:"maxRequestKiB"`
+	DeviceID                 protocol.DeviceID           `xml:"id,attr" json:"deviceID"`
+	Name                     string                      `xml:"name,attr,omitempty" json:"name"`
+	Addresses                []string                    `xml:"address,omitempty" json:"addresses" default:"dynamic"`
+	Compression              protocol.Compression        `xml:"compression,attr" json:"compression"`
+	CompressionAlgorithm     protocol.MessageCompression `xml:"compressionAlgorithm,attr" json:"compressionAlgorithm"`
+	CertName                 string                      `xml:"certName,attr,omitempty" json:"certName"`
+	Introducer               bool                        `xml:"introducer,attr" json:"introducer"`
+	SkipIntroductionRemovals bool                        `xml:"skipIntroductionRemovals,attr" json:"skipIntroductionRemovals"`
+	IntroducedBy             protocol.DeviceID           `xml:"introducedBy,attr" json:"introducedBy"`
+	Paused                   bool                        `xml:"paused" json:"paused"`
+	AllowedNetworks          []string                    `xml:"allowedNetwork,omitempty" json:"allowedNetworks"`
+	AutoAcceptFolders        bool                        `xml:"autoAcceptFolders" json:"autoAcceptFolders"`
+	MaxSendKbps              int                         `xml:"maxSendKbps" json:"maxSendKbps"`
+	MaxRecvKbps              int                         `xml:"maxRecvKbps" json:"maxRecvKbps"`
+	BandwidthSchedule        []BandwidthScheduleEntry    `xml:"bandwidthSchedule" json:"bandwidthSchedule"`
+	IgnoredFolders           []ObservedFolder            `xml:"ignoredFolder" json:"ignoredFolders"`
+	PendingFolders           []ObservedFolder            `xml:"pendingFolder" json:"pendingFolders"`
+	MaxRequestKiB            int                         `xml:"maxRequestKiB" json:"maxRequestKiB"`
+	NumConnections           int                         `xml:"numConnections" json:"numConnections"` // zero means one
+	OwnershipMappings        []OwnershipMapping          `xml:"ownershipMapping" json:"ownershipMappings"`
 }
 
 func NewDeviceConfiguration(id protocol.DeviceID, name string) DeviceConfiguration {
@@ -54,6 +58,10 @@ func (cfg DeviceConfiguration) Copy() DeviceConfiguration {
 	copy(c.IgnoredFolders, cfg.IgnoredFolders)
 	c.PendingFolders = make([]ObservedFolder, len(cfg.PendingFolders))
 	copy(c.PendingFolders, cfg.PendingFolders)
+	c.BandwidthSchedule = make([]BandwidthScheduleEntry, len(cfg.BandwidthSchedule))
+	copy(c.BandwidthSchedule, cfg.BandwidthSchedule)
+	c.OwnershipMappings = make([]OwnershipMapping, len(cfg.OwnershipMappings))
+	copy(c.OwnershipMappings, cfg.OwnershipMappings)
 	return c
 }
 
@@ -64,6 +72,13 @@ func (cfg *DeviceConfiguration) prepare(sharedFolders []string) {
 	if len(cfg.AllowedNetworks) == 0 {
 		cfg.AllowedNetworks = []string{}
 	}
+	if cfg.CompressionAlgorithm == protocol.MessageCompressionNone {
+		// Which messages are compressed is up to Compression; when they
+		// are, it's with LZ4 unless something else is asked for.
+		cfg.CompressionAlgorithm = protocol.MessageCompressionLZ4
+	}
+	cfg.BandwidthSchedule = cleanBandwidthSchedule(cfg.BandwidthSchedule, "device "+cfg.DeviceID.String())
+	cfg.OwnershipMappings = cleanOwnershipMappings(cfg.OwnershipMappings, "device "+cfg.DeviceID.String())
 
 	ignoredFolders := deduplicateObservedFoldersToMap(cfg.IgnoredFolders)
 	pendingFolders := deduplicateObservedFoldersToMap(cfg.PendingFolders)
@@ -9,7 +9,10 @@ package config
 import (
 	"errors"
 	"fmt"
+	"path"
+	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"time"
 
@@ -51,13 +54,27 @@ type FolderConfiguration struct {
 	ScanProgressIntervalS   int                         `xml:"scanProgressIntervalS" json:"scanProgressIntervalS"` // Set to a negative value to disable. Value of 0 will get replaced with value of 2 (default value)
 	PullerPauseS            int                         `xml:"pullerPauseS" json:"pullerPauseS"`
 	MaxConflicts            int                         `xml:"maxConflicts" json:"maxConflicts" default:"-1"`
+	ConflictPolicy          ConflictPolicy              `xml:"conflictPolicy" json:"conflictPolicy"`
+	ConflictCommand         string                      `xml:"conflictCommand" json:"conflictCommand"` // Run with the local and remote versions of a file for the "external" conflict policy.
+	MergePatterns           []string                    `xml:"mergePattern" json:"mergePatterns"`      // Glob patterns of text files to merge on conflict, using an archived version as common ancestor.
+	Hooks                   []FolderHook                `xml:"hook" json:"hooks"`
+	StageChanges            bool                        `xml:"stageChanges" json:"stageChanges"`     // Receive only folders hold incoming changes until approved.
+	ScrubIntervalS          int                         `xml:"scrubIntervalS" json:"scrubIntervalS"` // How often to verify the data against the stored hashes. Zero disables scrubbing.
+	ScrubRateKiBs           int                         `xml:"scrubRateKiBs" json:"scrubRateKiBs"`   // How fast to read data when scrubbing. Zero sets the default.
+	ScrubRepair             bool                        `xml:"scrubRepair" json:"scrubRepair"`       // Pull corrupted data again from devices with the same version.
 	DisableSparseFiles      bool                        `xml:"disableSparseFiles" json:"disableSparseFiles"`
 	DisableTempIndexes      bool                        `xml:"disableTempIndexes" json:"disableTempIndexes"`
 	Paused                  bool                        `xml:"paused" json:"paused"`
-	WeakHashThresholdPct    int                         `xml:"weakHashThresholdPct" json:"weakHashThresholdPct"` // Use weak hash if more than X percent of the file has changed. Set to -1 to always use weak hash.
+	WeakHashThresholdPct    int                         `xml:"weakHashThresholdPct" json:"weakHashThresholdPct"`     // Use weak hash if more than X percent of the file has changed. Set to -1 to always use weak hash.
+	ContentDefinedChunking  bool                        `xml:"contentDefinedChunking" json:"contentDefinedChunking"` // Cut files into blocks by content, if all devices sharing the folder agree.
 	MarkerName              string                      `xml:"markerName" json:"markerName"`
 	CopyOwnershipFromParent bool                        `xml:"copyOwnershipFromParent" json:"copyOwnershipFromParent"`
+	SyncXattrs              bool                        `xml:"syncXattrs" json:"syncXattrs"`       // Scan and apply user extended attributes and POSIX ACLs.
+	SyncOwnership           bool                        `xml:"syncOwnership" json:"syncOwnership"` // Scan and apply the owner and group, mapped per device.
 	RawModTimeWindowS       int                         `xml:"modTimeWindowS" json:"modTimeWindowS"`
+	SelectedPaths           []string                    `xml:"selectedPath" json:"selectedPaths"`  // Subtrees to sync, empty for the whole folder
+	MountPath               string                      `xml:"mountPath" json:"mountPath"`         // Where to present the global state, fetching file data on demand. Empty for off.
+	MountCacheMiB           int                         `xml:"mountCacheMiB" json:"mountCacheMiB"` // Size of the block cache of the mount. Zero for the default.
 
 	cachedFilesystem    fs.Filesystem
 	cachedModTimeWindow time.Duration
@@ -68,8 +85,9 @@ type FolderConfiguration struct {
 }
 
 type FolderDeviceConfiguration struct {
-	DeviceID     protocol.DeviceID `xml:"id,attr" json:"deviceID"`
-	IntroducedBy protocol.DeviceID `xml:"introducedBy,attr" json:"introducedBy"`
+	DeviceID           protocol.DeviceID `xml:"id,attr" json:"deviceID"`
+	IntroducedBy       protocol.DeviceID `xml:"introducedBy,attr" json:"introducedBy"`
+	EncryptionPassword string            `xml:"encryptionPassword" json:"encryptionPassword"`
 }
 
 func NewFolderConfiguration(myID protocol.DeviceID, id, label string, fsType fs.FilesystemType, path string) FolderConfiguration {
@@ -92,6 +110,12 @@ func (f FolderConfiguration) Copy() FolderConfiguration {
 	c.Devices = make([]FolderDeviceConfiguration, len(f.Devices))
 	copy(c.Devices, f.Devices)
 	c.Versioning = f.Versioning.Copy()
+	c.SelectedPaths = make([]string, len(f.SelectedPaths))
+	copy(c.SelectedPaths, f.SelectedPaths)
+	c.MergePatterns = make([]string, len(f.MergePatterns))
+	copy(c.MergePatterns, f.MergePatterns)
+	c.Hooks = make([]FolderHook, len(f.Hooks))
+	copy(c.Hooks, f.Hooks)
 	return c
 }
 
@@ -244,6 +268,8 @@ func (f *FolderConfiguration) prepare() {
 		f.MarkerName = DefaultMarkerName
 	}
 
+	f.SelectedPaths = cleanSelectedPaths(f.SelectedPaths)
+
 	switch {
 	case f.RawModTimeWindowS > 0:
 		f.cachedModTimeWindow = time.Duration(f.RawModTimeWindowS) * time.Second
@@ -285,6 +311,29 @@ func (f *FolderConfiguration) SharedWith(device protocol.DeviceID) bool {
 	return false
 }
 
+// EncryptionPassword returns the password that the folder is encrypted with
+// when sent to the device, or the empty string if it isn't encrypted.
+func (f *FolderConfiguration) EncryptionPassword(device protocol.DeviceID) string {
+	for _, dev := range f.Devices {
+		if dev.DeviceID == device {
+			return dev.EncryptionPassword
+		}
+	}
+	return ""
+}
+
+// EncryptionPasswords returns the password of each of the folders that is
+// encrypted when sent to the device.
+func EncryptionPasswords(folders []FolderConfiguration, device protocol.DeviceID) map[string]string {
+	passwords := make(map[string]string)
+	for _, folder := range folders {
+		if password := folder.EncryptionPassword(device); password != "" {
+			passwords[folder.ID] = password
+		}
+	}
+	return passwords
+}
+
 func (f *FolderConfiguration) CheckAvailableSpace(req int64) error {
 	val := f.MinDiskFree.BaseValue()
 	if val <= 0 {
@@ -303,3 +352,32 @@ func (f *FolderConfiguration) CheckAvailableSpace(req int64) error {
 	}
 	return fmt.Errorf("insufficient space in %v %v", fs.Type(), fs.URI())
 }
+
+// cleanSelectedPaths returns the selected subtrees in canonical, slash
+// separated form without duplicates or overlaps. Selecting the folder root
+// is the same as selecting nothing at all, i.e. the whole folder.
+func cleanSelectedPaths(paths []string) []string {
+	var cleaned []string
+	for _, p := range paths {
+		p = path.Clean("/" + filepath.ToSlash(strings.TrimSpace(p)))[1:]
+		if p == "" {
+			return nil
+		}
+		cleaned = append(cleaned, p)
+	}
+	sort.Strings(cleaned)
+
+	// Parents sort before their children, so checking against the paths
+	// kept so far suffices.
+	var unique []string
+nextPath:
+	for _, p := range cleaned {
+		for _, u := range unique {
+			if p == u || strings.HasPrefix(p, u+"/") {
+				continue nextPath
+			}
+		}
+		unique = append(unique, p)
+	}
+	return unique
+}
@@ -0,0 +1,62 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package config
+
+// A FolderHook is a command run when something happens in a folder.
+type FolderHook struct {
+	Event    HookEvent `xml:"event,attr" json:"event"`
+	Command  string    `xml:"command" json:"command"`
+	TimeoutS int       `xml:"timeoutS" json:"timeoutS"` // Zero for the default.
+}
+
+// HookEvent is what happens in a folder for a hook to run.
+type HookEvent int
+
+const (
+	HookNone         HookEvent = iota // default, never run
+	HookItemFinished                  // items were synced from other devices
+	HookSyncFinished                  // the folder is in sync after pulling
+	HookPreScan                       // a scan is about to start
+	HookPostScan                      // a scan has completed
+)
+
+func (e HookEvent) String() string {
+	switch e {
+	case HookNone:
+		return "none"
+	case HookItemFinished:
+		return "itemFinished"
+	case HookSyncFinished:
+		return "syncFinished"
+	case HookPreScan:
+		return "preScan"
+	case HookPostScan:
+		return "postScan"
+	default:
+		return "unknown"
+	}
+}
+
+func (e HookEvent) MarshalText() ([]byte, error) {
+	return []byte(e.String()), nil
+}
+
+func (e *HookEvent) UnmarshalText(bs []byte) error {
+	switch string(bs) {
+	case "itemFinished":
+		*e = HookItemFinished
+	case "syncFinished":
+		*e = HookSyncFinished
+	case "preScan":
+		*e = HookPreScan
+	case "postScan":
+		*e = HookPostScan
+	default:
+		*e = HookNone
+	}
+	return nil
+}
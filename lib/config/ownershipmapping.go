@@ -0,0 +1,56 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package config
+
+import (
+	"errors"
+	"strconv"
+)
+
+// An OwnershipMapping translates the owner, or the group if Group is set,
+// of files changed by a device into a local one, for devices where users
+// and groups have different names or ids. Remote and Local are each a name
+// or a numeric id; Remote matches either the name or the id the file has
+// on the other device.
+type OwnershipMapping struct {
+	Group  bool   `xml:"group,attr" json:"group"`
+	Remote string `xml:"remote,attr" json:"remote"`
+	Local  string `xml:"local,attr" json:"local"`
+}
+
+func (m OwnershipMapping) Validate() error {
+	if m.Remote == "" || m.Local == "" {
+		return errors.New("remote and local owner must be given")
+	}
+	return nil
+}
+
+// MapOwner returns the local owner, or group, for the one with the given
+// name and id on the device, and whether there is a mapping for it.
+func (cfg DeviceConfiguration) MapOwner(group bool, name string, id int) (string, bool) {
+	for _, m := range cfg.OwnershipMappings {
+		if m.Group != group {
+			continue
+		}
+		if name != "" && m.Remote == name || m.Remote == strconv.Itoa(id) {
+			return m.Local, true
+		}
+	}
+	return "", false
+}
+
+func cleanOwnershipMappings(mappings []OwnershipMapping, context string) []OwnershipMapping {
+	cleaned := make([]OwnershipMapping, 0, len(mappings))
+	for _, m := range mappings {
+		if err := m.Validate(); err != nil {
+			l.Warnf("Ignoring invalid ownership mapping for %s: %v", context, err)
+			continue
+		}
+		cleaned = append(cleaned, m)
+	}
+	return cleaned
+}
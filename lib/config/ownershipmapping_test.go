@@ -0,0 +1,40 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package config
+
+import "testing"
+
+func TestMapOwner(t *testing.T) {
+	cfg := DeviceConfiguration{
+		OwnershipMappings: []OwnershipMapping{
+			{Remote: "alice", Local: "alice2"},
+			{Remote: "1001", Local: "1501"},
+			{Group: true, Remote: "staff", Local: "users"},
+		},
+	}
+
+	cases := []struct {
+		group    bool
+		name     string
+		id       int
+		expected string
+		ok       bool
+	}{
+		{false, "alice", 1000, "alice2", true},
+		{false, "bob", 1001, "1501", true},
+		{false, "", 1001, "1501", true},
+		{false, "carol", 1002, "", false},
+		{false, "staff", 50, "", false},
+		{true, "staff", 50, "users", true},
+		{true, "alice", 1000, "", false},
+	}
+	for _, tc := range cases {
+		if local, ok := cfg.MapOwner(tc.group, tc.name, tc.id); local != tc.expected || ok != tc.ok {
+			t.Errorf("MapOwner(%v, %q, %d) => %q, %v, expected %q, %v", tc.group, tc.name, tc.id, local, ok, tc.expected, tc.ok)
+		}
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"io"
 	"sync/atomic"
+	"time"
 
 	"github.com/syncthing/syncthing/lib/config"
 	"github.com/syncthing/syncthing/lib/protocol"
@@ -19,9 +20,10 @@ import (
 )
 
 // limiter manages a read and write rate limit, reacting to config changes
-// as appropriate.
+// and bandwidth schedules as appropriate.
 type limiter struct {
 	mu                  sync.Mutex
+	cfg                 config.Configuration
 	write               *rate.Limiter
 	read                *rate.Limiter
 	limitsLAN           atomicBool
@@ -53,21 +55,17 @@ func newLimiter(cfg config.Wrapper) *limiter {
 }
 
 // This function sets limiters according to corresponding DeviceConfiguration
-func (lim *limiter) setLimitsLocked(device config.DeviceConfiguration) bool {
+// and its bandwidth schedule at the given time.
+func (lim *limiter) setLimitsLocked(device config.DeviceConfiguration, now time.Time) bool {
 	readLimiter := lim.getReadLimiterLocked(device.DeviceID)
 	writeLimiter := lim.getWriteLimiterLocked(device.DeviceID)
 
 	// limiters for this device are created so we can store previous rates for logging
 	previousReadLimit := readLimiter.Limit()
 	previousWriteLimit := writeLimiter.Limit()
-	currentReadLimit := rate.Limit(device.MaxRecvKbps) * 1024
-	currentWriteLimit := rate.Limit(device.MaxSendKbps) * 1024
-	if device.MaxSendKbps <= 0 {
-		currentWriteLimit = rate.Inf
-	}
-	if device.MaxRecvKbps <= 0 {
-		currentReadLimit = rate.Inf
-	}
+	sendKbps, recvKbps := config.ScheduledLimits(device.BandwidthSchedule, now, device.MaxSendKbps, device.MaxRecvKbps)
+	currentReadLimit := limitFromKbps(recvKbps)
+	currentWriteLimit := limitFromKbps(sendKbps)
 	// Nothing about this device has changed. Start processing next device
 	if previousWriteLimit == currentWriteLimit && previousReadLimit == currentReadLimit {
 		return false
@@ -76,11 +74,13 @@ func (lim *limiter) setLimitsLocked(device config.DeviceConfiguration) bool {
 	readLimiter.SetLimit(currentReadLimit)
 	writeLimiter.SetLimit(currentWriteLimit)
 
+	l.Infof("Device %s send rate %s, receive rate %s", device.DeviceID, limitString(sendKbps), limitString(recvKbps))
+
 	return true
 }
 
 // This function handles removing, adding and updating of device limiters.
-func (lim *limiter) processDevicesConfigurationLocked(from, to config.Configuration) {
+func (lim *limiter) processDevicesConfigurationLocked(from, to config.Configuration, now time.Time) {
 	seen := make(map[protocol.DeviceID]struct{})
 
 	// Mark devices which should not be removed, create new limiters if needed and assign new limiter rate
@@ -91,18 +91,7 @@ func (lim *limiter) processDevicesConfigurationLocked(from, to config.Configurat
 		}
 		seen[dev.DeviceID] = struct{}{}
 
-		if lim.setLimitsLocked(dev) {
-			readLimitStr := "is unlimited"
-			if dev.MaxRecvKbps > 0 {
-				readLimitStr = fmt.Sprintf("limit is %d KiB/s", dev.MaxRecvKbps)
-			}
-			writeLimitStr := "is unlimited"
-			if dev.MaxSendKbps > 0 {
-				writeLimitStr = fmt.Sprintf("limit is %d KiB/s", dev.MaxSendKbps)
-			}
-
-			l.Infof("Device %s send rate %s, receive rate %s", dev.DeviceID, writeLimitStr, readLimitStr)
-		}
+		lim.setLimitsLocked(dev, now)
 	}
 
 	// Delete remote devices which were removed in new configuration
@@ -116,6 +105,34 @@ func (lim *limiter) processDevicesConfigurationLocked(from, to config.Configurat
 	}
 }
 
+// This function sets the overall limiters according to the options and the
+// bandwidth schedule at the given time.
+func (lim *limiter) setGlobalLimitsLocked(from, to config.OptionsConfiguration, now time.Time) {
+	sendKbps, recvKbps := config.ScheduledLimits(to.BandwidthSchedule, now, to.MaxSendKbps, to.MaxRecvKbps)
+
+	if from.MaxRecvKbps == to.MaxRecvKbps &&
+		from.MaxSendKbps == to.MaxSendKbps &&
+		from.LimitBandwidthInLan == to.LimitBandwidthInLan &&
+		lim.read.Limit() == limitFromKbps(recvKbps) &&
+		lim.write.Limit() == limitFromKbps(sendKbps) {
+		return
+	}
+
+	lim.read.SetLimit(limitFromKbps(recvKbps))
+	lim.write.SetLimit(limitFromKbps(sendKbps))
+	lim.limitsLAN.set(to.LimitBandwidthInLan)
+
+	l.Infof("Overall send rate %s, receive rate %s", limitString(sendKbps), limitString(recvKbps))
+
+	if sendKbps > 0 || recvKbps > 0 {
+		if to.LimitBandwidthInLan {
+			l.Infoln("Rate limits apply to LAN connections")
+		} else {
+			l.Infoln("Rate limits do not apply to LAN connections")
+		}
+	}
+}
+
 func (lim *limiter) VerifyConfiguration(from, to config.Configuration) error {
 	return nil
 }
@@ -125,50 +142,61 @@ func (lim *limiter) CommitConfiguration(from, to config.Configuration) bool {
 	lim.mu.Lock()
 	defer lim.mu.Unlock()
 
+	lim.cfg = to
+	now := time.Now()
+
 	// Delete, add or update limiters for devices
-	lim.processDevicesConfigurationLocked(from, to)
+	lim.processDevicesConfigurationLocked(from, to, now)
 
-	if from.Options.MaxRecvKbps == to.Options.MaxRecvKbps &&
-		from.Options.MaxSendKbps == to.Options.MaxSendKbps &&
-		from.Options.LimitBandwidthInLan == to.Options.LimitBandwidthInLan {
-		return true
-	}
+	lim.setGlobalLimitsLocked(from.Options, to.Options, now)
 
-	limited := false
-	sendLimitStr := "is unlimited"
-	recvLimitStr := "is unlimited"
+	return true
+}
 
-	// The rate variables are in KiB/s in the config (despite the camel casing
-	// of the name). We multiply by 1024 to get bytes/s.
-	if to.Options.MaxRecvKbps <= 0 {
-		lim.read.SetLimit(rate.Inf)
-	} else {
-		lim.read.SetLimit(1024 * rate.Limit(to.Options.MaxRecvKbps))
-		recvLimitStr = fmt.Sprintf("limit is %d KiB/s", to.Options.MaxRecvKbps)
-		limited = true
-	}
+// updateSchedule applies the bandwidth schedules for the given time, to the
+// existing limiters so that connections need not be restarted.
+func (lim *limiter) updateSchedule(now time.Time) {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
 
-	if to.Options.MaxSendKbps <= 0 {
-		lim.write.SetLimit(rate.Inf)
-	} else {
-		lim.write.SetLimit(1024 * rate.Limit(to.Options.MaxSendKbps))
-		sendLimitStr = fmt.Sprintf("limit is %d KiB/s", to.Options.MaxSendKbps)
-		limited = true
-	}
+	lim.processDevicesConfigurationLocked(lim.cfg, lim.cfg, now)
+	lim.setGlobalLimitsLocked(lim.cfg.Options, lim.cfg.Options, now)
+}
 
-	lim.limitsLAN.set(to.Options.LimitBandwidthInLan)
+// serve re-evaluates the bandwidth schedules at the start of every minute,
+// as that is their resolution.
+func (lim *limiter) serve(stop chan struct{}) {
+	timer := time.NewTimer(untilNextMinute(time.Now()))
+	defer timer.Stop()
+	for {
+		select {
+		case now := <-timer.C:
+			lim.updateSchedule(now)
+			timer.Reset(untilNextMinute(time.Now()))
+		case <-stop:
+			return
+		}
+	}
+}
 
-	l.Infof("Overall send rate %s, receive rate %s", sendLimitStr, recvLimitStr)
+func untilNextMinute(now time.Time) time.Duration {
+	return now.Truncate(time.Minute).Add(time.Minute).Sub(now)
+}
 
-	if limited {
-		if to.Options.LimitBandwidthInLan {
-			l.Infoln("Rate limits apply to LAN connections")
-		} else {
-			l.Infoln("Rate limits do not apply to LAN connections")
-		}
+// limitFromKbps converts a rate in KiB/s from the config (despite the camel
+// casing of the name) to bytes/s. Zero or less means unlimited.
+func limitFromKbps(kbps int) rate.Limit {
+	if kbps <= 0 {
+		return rate.Inf
 	}
+	return 1024 * rate.Limit(kbps)
+}
 
-	return true
+func limitString(kbps int) string {
+	if kbps <= 0 {
+		return "is unlimited"
+	}
+	return fmt.Sprintf("limit is %d KiB/s", kbps)
 }
 
 func (lim *limiter) String() string {
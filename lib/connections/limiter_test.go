@@ -12,6 +12,7 @@ import (
 	"golang.org/x/time/rate"
 	"math/rand"
 	"testing"
+	"time"
 )
 
 var device1, device2, device3, device4 protocol.DeviceID
@@ -203,3 +204,48 @@ func checkActualAndExpected(t *testing.T, actualR, actualW, expectedR, expectedW
 		}
 	}
 }
+
+func TestLimiterSchedule(t *testing.T) {
+	cfg := initConfig()
+
+	// Weekdays during office hours the overall send rate is limited, and
+	// device3 is limited more on weekends.
+	opts := cfg.Options()
+	opts.BandwidthSchedule = []config.BandwidthScheduleEntry{
+		{Days: "mon-fri", Start: "08:00", End: "18:00", MaxSendKbps: 256},
+	}
+	waiter, _ := cfg.SetOptions(opts)
+	waiter.Wait()
+	dev3Conf.BandwidthSchedule = []config.BandwidthScheduleEntry{
+		{Days: "sat,sun", MaxSendKbps: 10, MaxRecvKbps: 20},
+	}
+	waiter, _ = cfg.SetDevice(dev3Conf)
+	waiter.Wait()
+
+	lim := newLimiter(cfg)
+
+	// 2019-07-01 is a Monday
+	lim.updateSchedule(time.Date(2019, 7, 1, 9, 0, 0, 0, time.Local))
+	if lim.write.Limit() != 256*1024 || lim.read.Limit() != rate.Inf {
+		t.Errorf("unexpected overall limits during office hours: %v/%v", lim.write.Limit(), lim.read.Limit())
+	}
+	if lim.deviceWriteLimiters[device3].Limit() != rate.Inf {
+		t.Error("device3 should be unlimited on weekdays")
+	}
+
+	lim.updateSchedule(time.Date(2019, 7, 1, 19, 0, 0, 0, time.Local))
+	if lim.write.Limit() != rate.Inf || lim.read.Limit() != rate.Inf {
+		t.Errorf("unexpected overall limits after office hours: %v/%v", lim.write.Limit(), lim.read.Limit())
+	}
+
+	lim.updateSchedule(time.Date(2019, 7, 6, 9, 0, 0, 0, time.Local))
+	if lim.write.Limit() != rate.Inf {
+		t.Error("overall send rate should be unlimited on weekends")
+	}
+	if w, r := lim.deviceWriteLimiters[device3].Limit(), lim.deviceReadLimiters[device3].Limit(); w != 10*1024 || r != 20*1024 {
+		t.Errorf("unexpected device3 limits on weekends: %v/%v", w, r)
+	}
+	if lim.deviceWriteLimiters[device2].Limit() != limitFromKbps(dev2Conf.MaxSendKbps) {
+		t.Error("device2 limit should be unaffected by the schedule")
+	}
+}
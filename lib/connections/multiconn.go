@@ -0,0 +1,164 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+// The most connections we open to a single device, whatever is configured.
+const maxNumConnections = 16
+
+// multiConn is the connection to a device when both sides want more than
+// one. The primary connection carries everything a single connection
+// would, while requests for blocks are spread over it and the secondary
+// connections. To the model it's just one connection.
+//
+// Only the device with the lower device ID dials secondary connections,
+// marking them as such in the hello message, so that both sides agree on
+// which connection is the primary one.
+type multiConn struct {
+	completeConn
+	want int // including the primary connection
+
+	mut         sync.Mutex
+	secondaries []completeConn
+	next        int
+}
+
+func newMultiConn(want int) *multiConn {
+	return &multiConn{
+		want: want,
+		mut:  sync.NewMutex(),
+	}
+}
+
+// wantConnections returns how many connections there should be to a
+// device, given how many each side wants.
+func wantConnections(ours, theirs int) int {
+	if ours <= 1 || theirs <= 1 {
+		return 1
+	}
+	if theirs < ours {
+		ours = theirs
+	}
+	if ours > maxNumConnections {
+		return maxNumConnections
+	}
+	return ours
+}
+
+func (c *multiConn) Request(folder string, name string, blockNo int, offset int64, size int, hash []byte, weakHash uint32, fromTemporary bool) ([]byte, error) {
+	return c.pick().Request(folder, name, blockNo, offset, size, hash, weakHash, fromTemporary)
+}
+
+// pick returns the connection to send the next request on, taking turns
+// and skipping those that are closed but not yet removed.
+func (c *multiConn) pick() protocol.Connection {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	for range c.secondaries {
+		c.next = (c.next + 1) % (len(c.secondaries) + 1)
+		if c.next == 0 {
+			break
+		}
+		if sc := c.secondaries[c.next-1]; !sc.Closed() {
+			return sc
+		}
+	}
+	return c.completeConn
+}
+
+func (c *multiConn) Statistics() protocol.Statistics {
+	stats := c.completeConn.Statistics()
+	c.mut.Lock()
+	for _, sc := range c.secondaries {
+		s := sc.Statistics()
+		stats.InBytesTotal += s.InBytesTotal
+		stats.OutBytesTotal += s.OutBytesTotal
+	}
+	c.mut.Unlock()
+	return stats
+}
+
+func (c *multiConn) Close(err error) {
+	c.closeSecondaries(err)
+	c.completeConn.Close(err)
+}
+
+// count returns the number of connections, including the primary one.
+func (c *multiConn) count() int {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return len(c.secondaries) + 1
+}
+
+// addSecondary adds the connection unless there are enough already, and
+// returns whether it did.
+func (c *multiConn) addSecondary(sc completeConn) bool {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if c.completeConn.Closed() || len(c.secondaries)+1 >= c.want {
+		return false
+	}
+	c.secondaries = append(c.secondaries, sc)
+	return true
+}
+
+// removeSecondary removes the secondary connection running the given
+// protocol connection and returns it, if it's still there.
+func (c *multiConn) removeSecondary(conn protocol.Connection) (completeConn, bool) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	for i, sc := range c.secondaries {
+		if sc.Connection == conn {
+			c.secondaries = append(c.secondaries[:i], c.secondaries[i+1:]...)
+			return sc, true
+		}
+	}
+	return completeConn{}, false
+}
+
+func (c *multiConn) closeSecondaries(err error) {
+	c.mut.Lock()
+	secondaries := c.secondaries
+	c.secondaries = nil
+	c.mut.Unlock()
+	for _, sc := range secondaries {
+		sc.Close(err)
+	}
+}
+
+// primaryReceiver takes the secondary connections down with the primary
+// one.
+type primaryReceiver struct {
+	Model
+	conn *multiConn
+}
+
+func (r primaryReceiver) Closed(conn protocol.Connection, err error) {
+	r.conn.closeSecondaries(err)
+	r.Model.Closed(conn, err)
+}
+
+// secondaryReceiver passes on what is received on a secondary connection,
+// except for its cluster config, which is empty, and it being closed,
+// which is none of the model's business.
+type secondaryReceiver struct {
+	Model
+	conn *multiConn
+}
+
+func (r secondaryReceiver) ClusterConfig(protocol.DeviceID, protocol.ClusterConfig) {}
+
+func (r secondaryReceiver) Closed(conn protocol.Connection, err error) {
+	l.Debugf("Secondary connection to %s at %s closed: %v", conn.ID(), conn.Name(), err)
+	if sc, ok := r.conn.removeSecondary(conn); ok {
+		sc.internalConn.Close()
+	}
+}
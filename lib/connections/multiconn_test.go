@@ -0,0 +1,134 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestWantConnections(t *testing.T) {
+	cases := []struct {
+		ours, theirs, want int
+	}{
+		{0, 0, 1},
+		{1, 4, 1},
+		{4, 0, 1}, // older versions don't say
+		{4, 1, 1},
+		{4, 2, 2},
+		{2, 4, 2},
+		{4, 4, 4},
+		{100, 100, maxNumConnections},
+	}
+	for _, tc := range cases {
+		if res := wantConnections(tc.ours, tc.theirs); res != tc.want {
+			t.Errorf("wantConnections(%d, %d) == %d, expected %d", tc.ours, tc.theirs, res, tc.want)
+		}
+	}
+}
+
+func TestMultiConn(t *testing.T) {
+	mc := newMultiConn(3)
+	primary := &fakeProtoConn{name: "primary"}
+	mc.completeConn = newFakeConn(primary)
+	first := &fakeProtoConn{name: "first"}
+	second := &fakeProtoConn{name: "second"}
+	third := &fakeProtoConn{name: "third"}
+
+	if !mc.addSecondary(newFakeConn(first)) || !mc.addSecondary(newFakeConn(second)) {
+		t.Fatal("Expected secondary connections to be added")
+	}
+	if mc.addSecondary(newFakeConn(third)) {
+		t.Error("Expected no more than three connections")
+	}
+
+	// Requests take turns
+
+	var got []string
+	for i := 0; i < 6; i++ {
+		data, _ := mc.Request("default", "file", 0, 0, 1, nil, 0, false)
+		got = append(got, string(data))
+	}
+	if exp := "first second primary first second primary"; strings.Join(got, " ") != exp {
+		t.Errorf("Requests went to %v, expected %v", strings.Join(got, " "), exp)
+	}
+
+	// Skipping closed connections until they are removed
+
+	second.closed = true
+	got = got[:0]
+	for i := 0; i < 3; i++ {
+		data, _ := mc.Request("default", "file", 0, 0, 1, nil, 0, false)
+		got = append(got, string(data))
+	}
+	if exp := "first primary first"; strings.Join(got, " ") != exp {
+		t.Errorf("Requests went to %v, expected %v", strings.Join(got, " "), exp)
+	}
+	if _, ok := mc.removeSecondary(second); !ok || mc.count() != 2 {
+		t.Error("Expected the closed connection to be removed")
+	}
+
+	primary.stats = protocol.Statistics{InBytesTotal: 1, OutBytesTotal: 2}
+	first.stats = protocol.Statistics{InBytesTotal: 10, OutBytesTotal: 20}
+	if stats := mc.Statistics(); stats.InBytesTotal != 11 || stats.OutBytesTotal != 22 {
+		t.Errorf("Unexpected statistics %+v", stats)
+	}
+
+	// Closing the primary connection takes the rest with it
+
+	primaryReceiver{conn: mc, Model: nopModel{}}.Closed(primary, errors.New("test"))
+	if !first.closed || mc.count() != 1 {
+		t.Error("Expected the secondary connections to be closed")
+	}
+}
+
+func newFakeConn(pc protocol.Connection) completeConn {
+	c, _ := net.Pipe()
+	return completeConn{internalConn{fakeTLSConn{c}, connTypeTCPClient, tcpPriority}, pc}
+}
+
+type fakeTLSConn struct {
+	net.Conn
+}
+
+func (fakeTLSConn) ConnectionState() tls.ConnectionState {
+	return tls.ConnectionState{}
+}
+
+type fakeProtoConn struct {
+	protocol.Connection
+	name   string
+	closed bool
+	stats  protocol.Statistics
+}
+
+func (c *fakeProtoConn) Request(string, string, int, int64, int, []byte, uint32, bool) ([]byte, error) {
+	return []byte(c.name), nil
+}
+
+func (c *fakeProtoConn) Close(error) {
+	c.closed = true
+}
+
+func (c *fakeProtoConn) Closed() bool {
+	return c.closed
+}
+
+func (c *fakeProtoConn) Statistics() protocol.Statistics {
+	return c.stats
+}
+
+type nopModel struct {
+	Model
+}
+
+func (nopModel) Closed(protocol.Connection, error) {}
@@ -46,6 +46,7 @@ type quicListener struct {
 	factory listenerFactory
 
 	address *url.URL
+	conn    net.PacketConn // what quic reads from, for punching
 	mut     sync.Mutex
 }
 
@@ -96,6 +97,15 @@ func (t *quicListener) serve(stop chan struct{}) error {
 	registry.Register(t.uri.Scheme, conn)
 	defer registry.Unregister(t.uri.Scheme, conn)
 
+	t.mut.Lock()
+	t.conn = conn
+	t.mut.Unlock()
+	defer func() {
+		t.mut.Lock()
+		t.conn = nil
+		t.mut.Unlock()
+	}()
+
 	listener, err := quic.Listen(conn, t.tlsCfg, quicConfig)
 	if err != nil {
 		l.Infoln("Listen (BEP/quic):", err)
@@ -178,6 +188,38 @@ func (t *quicListener) LANAddresses() []*url.URL {
 	return []*url.URL{t.uri}
 }
 
+// PunchAddresses returns the external address found by STUN, if the NAT
+// in front of us lets peers connect to it after we Punch towards them.
+func (t *quicListener) PunchAddresses() []*url.URL {
+	if !stun.IsPunchable(t.nat.Load().(stun.NATType)) {
+		return nil
+	}
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	if t.address == nil {
+		return nil
+	}
+	return []*url.URL{t.address}
+}
+
+// Punch sends a packet to the address from the port we listen on, so that
+// our NAT lets in the peer's attempts to connect from there.
+func (t *quicListener) Punch(addr *url.URL) error {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr.Host)
+	if err != nil {
+		return err
+	}
+	t.mut.Lock()
+	conn := t.conn
+	t.mut.Unlock()
+	if conn == nil {
+		return errNotListening
+	}
+	// The contents don't matter, quic drops what it can't parse.
+	_, err = conn.WriteTo([]byte{0}, udpAddr)
+	return err
+}
+
 func (t *quicListener) String() string {
 	return t.uri.String()
 }
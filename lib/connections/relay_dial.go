@@ -75,7 +75,7 @@ type relayDialerFactory struct{}
 func (relayDialerFactory) New(cfg config.Wrapper, tlsCfg *tls.Config) genericDialer {
 	return &relayDialer{
 		cfg:    cfg,
-		tlsCfg: tlsCfg,
+		tlsCfg: relayTLSConfig(tlsCfg),
 	}
 }
 
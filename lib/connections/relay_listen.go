@@ -174,7 +174,7 @@ func (f *relayListenerFactory) New(uri *url.URL, cfg config.Wrapper, tlsCfg *tls
 	t := &relayListener{
 		uri:     uri,
 		cfg:     cfg,
-		tlsCfg:  tlsCfg,
+		tlsCfg:  relayTLSConfig(tlsCfg),
 		conns:   conns,
 		factory: f,
 	}
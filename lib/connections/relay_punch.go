@@ -0,0 +1,213 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/relay/client"
+)
+
+// Relay connections are slow, so when both devices support it they use
+// the relay connection to exchange the external addresses that STUN found
+// for their QUIC listeners, and then try to connect directly. The device
+// that dialed the relay connection dials the other's addresses, while the
+// other sends packets towards its addresses so that its NAT lets the
+// attempts through. A direct connection replaces the relay connection the
+// usual way, by having better priority.
+
+const (
+	// Offered during the TLS handshake of relay connections; when it's
+	// agreed on, both sides send their addresses before the hello.
+	relayPunchProtocolName = "bep-relay-punch/1.0"
+
+	punchAttempts = 3
+	punchDuration = 30 * time.Second // how long to open pinholes for
+	punchInterval = time.Second
+)
+
+var errNotListening = errors.New("not listening")
+
+// A punchingListener can be connected to through NAT, by peers that it
+// Punches towards while they try.
+type punchingListener interface {
+	PunchAddresses() []*url.URL
+	Punch(addr *url.URL) error
+}
+
+// relayTLSConfig returns the TLS config for relay connections, which is
+// the one for other connections except for also offering to punch.
+func relayTLSConfig(tlsCfg *tls.Config) *tls.Config {
+	cfg := tlsCfg.Clone()
+	cfg.NextProtos = append([]string{relayPunchProtocolName}, tlsCfg.NextProtos...)
+	return cfg
+}
+
+// punchAddresses returns the addresses that peers may be able to connect
+// to directly, if we punch towards them.
+func (s *service) punchAddresses() []string {
+	var addrs []string
+	s.listenersMut.RLock()
+	for _, lst := range s.listeners {
+		if pl, ok := lst.(punchingListener); ok {
+			for _, uri := range pl.PunchAddresses() {
+				addrs = append(addrs, uri.String())
+			}
+		}
+	}
+	s.listenersMut.RUnlock()
+	return addrs
+}
+
+// punchingListeners returns the listeners that we can punch towards the
+// address from.
+func (s *service) punchingListeners(uri *url.URL) []punchingListener {
+	var pls []punchingListener
+	s.listenersMut.RLock()
+	for _, lst := range s.listeners {
+		// quic:// listens for both quic4:// and quic6://
+		if pl, ok := lst.(punchingListener); ok && strings.HasPrefix(uri.Scheme, lst.URI().Scheme) {
+			pls = append(pls, pl)
+		}
+	}
+	s.listenersMut.RUnlock()
+	return pls
+}
+
+// exchangePunchAddresses sends our addresses over the relay connection and
+// returns those of the peer that we could punch towards.
+// Devices that the hello would be rejected from get an empty list, as the
+// exchange happens before it.
+func (s *service) exchangePunchAddresses(remoteID protocol.DeviceID, c internalConn) ([]*url.URL, error) {
+	deviceCfg, accepted := s.acceptsPunching(remoteID, c)
+	var ours []string
+	if accepted {
+		ours = s.punchAddresses()
+	}
+	addrs, err := client.ExchangePunchAddresses(c, ours)
+	if err != nil || !accepted {
+		return nil, err
+	}
+
+	var uris []*url.URL
+	for _, addr := range addrs {
+		uri, err := url.Parse(addr)
+		if err != nil {
+			l.Debugf("Ignoring punch address %q from %s: %v", addr, remoteID, err)
+			continue
+		}
+		if len(deviceCfg.AllowedNetworks) > 0 && !IsAllowedNetwork(uri.Host, deviceCfg.AllowedNetworks) {
+			l.Debugln("Network for punch address", uri, "is disallowed")
+			continue
+		}
+		if len(s.punchingListeners(uri)) == 0 {
+			l.Debugln("Ignoring punch address", uri, "as we don't listen for it")
+			continue
+		}
+		uris = append(uris, uri)
+	}
+	return uris, nil
+}
+
+// acceptsPunching returns the device's configuration, and whether it's a
+// device that we'd accept the connection from, the same way as the model
+// does when it gets the hello.
+func (s *service) acceptsPunching(remoteID protocol.DeviceID, c internalConn) (config.DeviceConfiguration, bool) {
+	if s.cfg.IgnoredDevice(remoteID) {
+		return config.DeviceConfiguration{}, false
+	}
+	deviceCfg, ok := s.cfg.Device(remoteID)
+	if !ok || deviceCfg.Paused {
+		return deviceCfg, false
+	}
+	if len(deviceCfg.AllowedNetworks) > 0 && !IsAllowedNetwork(c.RemoteAddr().String(), deviceCfg.AllowedNetworks) {
+		return deviceCfg, false
+	}
+	return deviceCfg, true
+}
+
+// punch tries to connect directly to the device, which is connected over
+// the relay connection c, at the addresses it sent us.
+func (s *service) punch(remoteID protocol.DeviceID, c internalConn, addrs []*url.URL, stop chan struct{}) {
+	l.Debugf("Punching towards %s at %v", remoteID, addrs)
+
+	if !c.isOutgoing() {
+		s.openPinholes(remoteID, c, addrs, stop)
+		return
+	}
+
+	for i := 0; i < punchAttempts; i++ {
+		for _, uri := range addrs {
+			if !s.isRelayed(remoteID, c) {
+				return
+			}
+
+			dialerFactory, err := getDialerFactory(s.cfg.RawCopy(), uri)
+			if err != nil {
+				l.Debugf("Dialer for punch address %v: %v", uri, err)
+				continue
+			}
+			if dialerFactory.Priority() >= c.priority {
+				continue
+			}
+
+			conn, err := dialerFactory.New(s.cfg, s.tlsCfg).Dial(remoteID, uri)
+			if err != nil {
+				l.Debugf("Punching towards %s at %v: %v", remoteID, uri, err)
+				continue
+			}
+
+			l.Infof("Punched through to %s at %v", remoteID, uri)
+			select {
+			case s.conns <- conn:
+			case <-stop:
+				conn.Close()
+			}
+			return
+		}
+
+		select {
+		case <-time.After(punchInterval):
+		case <-stop:
+			return
+		}
+	}
+}
+
+// openPinholes sends packets towards the device's addresses, from the
+// ports that it's trying to connect to.
+func (s *service) openPinholes(remoteID protocol.DeviceID, c internalConn, addrs []*url.URL, stop chan struct{}) {
+	deadline := time.Now().Add(punchDuration)
+	for time.Now().Before(deadline) && s.isRelayed(remoteID, c) {
+		for _, uri := range addrs {
+			for _, pl := range s.punchingListeners(uri) {
+				if err := pl.Punch(uri); err != nil {
+					l.Debugf("Punching towards %s at %v: %v", remoteID, uri, err)
+				}
+			}
+		}
+
+		select {
+		case <-time.After(punchInterval):
+		case <-stop:
+			return
+		}
+	}
+}
+
+// isRelayed returns whether the device is still connected, and not over a
+// connection better than c.
+func (s *service) isRelayed(remoteID protocol.DeviceID, c internalConn) bool {
+	ct, connected := s.model.Connection(remoteID)
+	return connected && ct.Priority() >= c.priority
+}
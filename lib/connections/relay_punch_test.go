@@ -0,0 +1,312 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/relay/client"
+	"github.com/syncthing/syncthing/lib/sync"
+	"github.com/syncthing/syncthing/lib/tlsutil"
+)
+
+func TestRelayPunchNegotiation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syncthing-punch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cert, err := tlsutil.NewCertificate(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), "syncthing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tlsCfg := &tls.Config{
+		Certificates:       []tls.Certificate{cert},
+		ClientAuth:         tls.RequestClientCert,
+		InsecureSkipVerify: true,
+		NextProtos:         []string{"bep/1.0"},
+	}
+
+	// Devices that don't know about punching just speak BEP.
+	c, s, closePipe := tlsPipe(t, relayTLSConfig(tlsCfg), tlsCfg)
+	if proto := c.ConnectionState().NegotiatedProtocol; proto != "bep/1.0" {
+		t.Errorf("Expected bep/1.0 with an older peer, got %q", proto)
+	}
+	closePipe()
+
+	c, s, closePipe = tlsPipe(t, relayTLSConfig(tlsCfg), relayTLSConfig(tlsCfg))
+	defer closePipe()
+	if proto := c.ConnectionState().NegotiatedProtocol; proto != relayPunchProtocolName {
+		t.Fatalf("Expected %s, got %q", relayPunchProtocolName, proto)
+	}
+
+	ours := []string{"quic://192.0.2.1:22000"}
+	theirs := []string{"quic://198.51.100.1:22000", "quic://[2001:db8::1]:22000"}
+	res := make(chan []string, 1)
+	go func() {
+		addrs, err := client.ExchangePunchAddresses(s, theirs)
+		if err != nil {
+			t.Error(err)
+		}
+		res <- addrs
+	}()
+	got, err := client.ExchangePunchAddresses(c, ours)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, theirs) {
+		t.Errorf("Expected to receive %v, got %v", theirs, got)
+	}
+	if got := <-res; !reflect.DeepEqual(got, ours) {
+		t.Errorf("Expected peer to receive %v, got %v", ours, got)
+	}
+}
+
+func TestExchangePunchAddresses(t *testing.T) {
+	cfg := config.New(device1)
+	dev2Conf := config.NewDeviceConfiguration(device2, "device2")
+	dev2Conf.AllowedNetworks = []string{"192.0.2.0/24", "198.51.100.0/24"}
+	dev3Conf := config.NewDeviceConfiguration(device3, "device3")
+	dev3Conf.Paused = true
+	cfg.Devices = append(cfg.Devices, dev2Conf, dev3Conf)
+	cfg.IgnoredDevices = []config.ObservedDevice{{ID: device4}}
+
+	lst := &fakePunchingListener{
+		uri:   mustParseURL(t, "quic://0.0.0.0:22000"),
+		addrs: []*url.URL{mustParseURL(t, "quic4://203.0.113.1:22000")},
+		mut:   sync.NewMutex(),
+	}
+	s := &service{
+		cfg:          config.Wrap("/dev/null", cfg),
+		listeners:    map[string]genericListener{"quic://0.0.0.0:22000": lst},
+		listenersMut: sync.NewRWMutex(),
+	}
+
+	theirs := []string{
+		"quic4://198.51.100.1:22000",
+		"quic6://[2001:db8::1]:22000", // disallowed network
+		"tcp://198.51.100.1:22000",    // we don't listen for it
+		"quic4://%zz",                 // doesn't parse
+	}
+
+	var unknown protocol.DeviceID
+	unknown[0] = 42
+
+	cases := []struct {
+		name     string
+		id       protocol.DeviceID
+		relay    string
+		sent     []string
+		received []string
+	}{
+		{"allowed", device2, "192.0.2.10:443", []string{"quic4://203.0.113.1:22000"}, []string{"quic4://198.51.100.1:22000"}},
+		{"relay network disallowed", device2, "203.0.113.10:443", nil, nil},
+		{"paused", device3, "192.0.2.10:443", nil, nil},
+		{"ignored", device4, "192.0.2.10:443", nil, nil},
+		{"unknown", unknown, "192.0.2.10:443", nil, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ours, peer := net.Pipe()
+			defer ours.Close()
+			defer peer.Close()
+			relayAddr, err := net.ResolveTCPAddr("tcp", tc.relay)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := internalConn{fakeTLSConn{addrConn{ours, relayAddr}}, connTypeRelayServer, relayPriority}
+
+			sent := make(chan []string, 1)
+			go func() {
+				addrs, err := client.ExchangePunchAddresses(peer, theirs)
+				if err != nil {
+					t.Error(err)
+				}
+				sent <- addrs
+			}()
+			uris, err := s.exchangePunchAddresses(tc.id, c)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var received []string
+			for _, uri := range uris {
+				received = append(received, uri.String())
+			}
+			if !reflect.DeepEqual(received, tc.received) {
+				t.Errorf("Expected to punch towards %v, got %v", tc.received, received)
+			}
+			if got := <-sent; len(got) != len(tc.sent) || len(got) > 0 && !reflect.DeepEqual(got, tc.sent) {
+				t.Errorf("Expected to send %v, sent %v", tc.sent, got)
+			}
+		})
+	}
+}
+
+func TestPunchStops(t *testing.T) {
+	lst := &fakePunchingListener{uri: mustParseURL(t, "quic://0.0.0.0:22000"), mut: sync.NewMutex()}
+	m := &fakePunchModel{}
+	s := &service{
+		cfg:          config.Wrap("/dev/null", config.New(device1)),
+		model:        m,
+		listeners:    map[string]genericListener{"quic://0.0.0.0:22000": lst},
+		listenersMut: sync.NewRWMutex(),
+	}
+	// Nothing dials unknown schemes, so attempts only wait for the next,
+	// and nothing listens for them either.
+	addrs := map[connType][]*url.URL{
+		connTypeRelayClient: {mustParseURL(t, "unknown://198.51.100.1:22000")},
+		connTypeRelayServer: {mustParseURL(t, "quic4://198.51.100.1:22000"), mustParseURL(t, "unknown://198.51.100.1:22000")},
+	}
+
+	relayed := func(connType connType) internalConn {
+		c, _ := net.Pipe()
+		return internalConn{fakeTLSConn{c}, connType, relayPriority}
+	}
+	direct := completeConn{internalConn: internalConn{priority: tcpPriority}}
+
+	run := func(c internalConn, stop chan struct{}) {
+		t.Helper()
+		done := make(chan struct{})
+		go func() {
+			s.punch(device2, c, addrs[c.connType], stop)
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("Punching didn't stop")
+		}
+	}
+
+	// The punches are counted across both, so the side that only punches
+	// when stopped goes last.
+	for _, connType := range []connType{connTypeRelayClient, connTypeRelayServer} {
+		// Once there's a better connection, there's nothing to do.
+		c := relayed(connType)
+		m.set(direct, true)
+		run(c, make(chan struct{}))
+		if n := lst.punches(); n != 0 {
+			t.Errorf("%v: Expected no punches with a direct connection, got %d", connType, n)
+		}
+
+		// Neither is there once the relay connection is gone.
+		m.set(nil, false)
+		run(c, make(chan struct{}))
+		if n := lst.punches(); n != 0 {
+			t.Errorf("%v: Expected no punches when disconnected, got %d", connType, n)
+		}
+
+		// Otherwise it goes on until stopped.
+		m.set(completeConn{internalConn: c}, true)
+		stop := make(chan struct{})
+		close(stop)
+		run(c, stop)
+	}
+
+	// The side that didn't dial punches once per address that it listens
+	// for before being stopped.
+	if n := lst.punches(); n != 1 {
+		t.Errorf("Expected one punch, got %d", n)
+	}
+}
+
+type fakePunchingListener struct {
+	genericListener
+	uri   *url.URL
+	addrs []*url.URL
+
+	mut     sync.Mutex
+	punched []*url.URL
+}
+
+func (l *fakePunchingListener) URI() *url.URL {
+	return l.uri
+}
+
+func (l *fakePunchingListener) PunchAddresses() []*url.URL {
+	return l.addrs
+}
+
+func (l *fakePunchingListener) Punch(addr *url.URL) error {
+	l.mut.Lock()
+	l.punched = append(l.punched, addr)
+	l.mut.Unlock()
+	return nil
+}
+
+func (l *fakePunchingListener) punches() int {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	return len(l.punched)
+}
+
+type fakePunchModel struct {
+	Model
+	conn      Connection
+	connected bool
+}
+
+func (m *fakePunchModel) set(conn Connection, connected bool) {
+	m.conn, m.connected = conn, connected
+}
+
+func (m *fakePunchModel) Connection(protocol.DeviceID) (Connection, bool) {
+	return m.conn, m.connected
+}
+
+// addrConn is a net.Conn with the remote address of a relay connection.
+type addrConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+func (c addrConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func mustParseURL(t *testing.T, s string) *url.URL {
+	t.Helper()
+	uri, err := url.Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return uri
+}
+
+// tlsPipe returns the client and server ends of a TLS connection that has
+// done the handshake, and a function to close it without waiting for the
+// TLS close alerts to be read.
+func tlsPipe(t *testing.T, clientCfg, serverCfg *tls.Config) (*tls.Conn, *tls.Conn, func()) {
+	t.Helper()
+	cc, sc := net.Pipe()
+	c, s := tls.Client(cc, clientCfg), tls.Server(sc, serverCfg)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- s.Handshake()
+	}()
+	if err := c.Handshake(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	return c, s, func() {
+		cc.Close()
+		sc.Close()
+	}
+}
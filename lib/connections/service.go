@@ -24,9 +24,11 @@ import (
 	"github.com/syncthing/syncthing/lib/osutil"
 	"github.com/syncthing/syncthing/lib/protocol"
 	"github.com/syncthing/syncthing/lib/sync"
+	"github.com/syncthing/syncthing/lib/tlsutil"
 	"github.com/syncthing/syncthing/lib/util"
 
 	// Registers NAT service providers
+	_ "github.com/syncthing/syncthing/lib/pcp"
 	_ "github.com/syncthing/syncthing/lib/pmp"
 	_ "github.com/syncthing/syncthing/lib/upnp"
 
@@ -182,10 +184,11 @@ func NewService(cfg config.Wrapper, myID protocol.DeviceID, mdl Model, tlsCfg *t
 	// (handled in configuration changing) to handle incoming connections,
 	// one routine to periodically attempt outgoing connections, one routine to
 	// the common handling regardless of whether the connection was
-	// incoming or outgoing.
+	// incoming or outgoing, and one routine to apply bandwidth schedules.
 
 	service.Add(util.AsService(service.connect))
 	service.Add(util.AsService(service.handle))
+	service.Add(util.AsService(service.limiter.serve))
 	service.Add(service.listenerSupervisor)
 
 	return service
@@ -206,15 +209,17 @@ func (s *service) handle(stop chan struct{}) {
 		// of the TLS handshake. Unfortunately this can't be a hard error,
 		// because there are implementations out there that don't support
 		// protocol negotiation (iOS for one...).
-		if !cs.NegotiatedProtocolIsMutual || cs.NegotiatedProtocol != s.bepProtocolName {
+		if !cs.NegotiatedProtocolIsMutual || cs.NegotiatedProtocol != s.bepProtocolName && cs.NegotiatedProtocol != relayPunchProtocolName {
 			l.Infof("Peer at %s did not negotiate bep/1.0", c)
 		}
 
 		// We should have received exactly one certificate from the other
-		// side. If we didn't, they don't have a device ID and we drop the
-		// connection.
+		// side, or, if we trust CAs, one followed by those it's issued
+		// through. If we didn't, they don't have a device ID and we drop
+		// the connection.
 		certs := cs.PeerCertificates
-		if cl := len(certs); cl != 1 {
+		caTrust := s.caTrust()
+		if cl := len(certs); cl == 0 || cl > 1 && caTrust == nil {
 			l.Infof("Got peer certificate list of length %d != 1 from peer at %s; protocol error", cl, c)
 			c.Close()
 			continue
@@ -222,6 +227,19 @@ func (s *service) handle(stop chan struct{}) {
 		remoteCert := certs[0]
 		remoteID := protocol.NewDeviceID(remoteCert.Raw)
 
+		// A certificate issued by a CA that we trust may carry a device ID
+		// that stays the same when the certificate is replaced.
+		caIssued := false
+		if claimedID, ok := tlsutil.ClaimedDeviceID(remoteCert); ok && caTrust != nil {
+			if err := caTrust.Verify(remoteCert, certs[1:]); err != nil {
+				l.Infof("Untrusted certificate for %s from peer at %s: %v", claimedID, c, err)
+				c.Close()
+				continue
+			}
+			remoteID = claimedID
+			caIssued = true
+		}
+
 		// The device ID should not be that of ourselves. It can happen
 		// though, especially in the presence of NAT hairpinning, multiple
 		// clients between the same NAT gateway, and global discovery.
@@ -231,8 +249,32 @@ func (s *service) handle(stop chan struct{}) {
 			continue
 		}
 
+		// Secondary connections are dialed by us, and marked as such, if we
+		// have the lower device ID.
+		ourHello := s.model.GetHello(remoteID)
+		sentSecondary := false
+		if h, ok := ourHello.(*protocol.Hello); ok && s.wantsSecondary(remoteID, c) {
+			h.Secondary = true
+			sentSecondary = true
+		}
+
 		c.SetDeadline(time.Now().Add(20 * time.Second))
-		hello, err := protocol.ExchangeHello(c, s.model.GetHello(remoteID))
+
+		// Relay connections start with an exchange of addresses, when both
+		// sides want to try for a direct connection. Only devices that we
+		// would accept below are sent ours.
+		var punchAddrs []*url.URL
+		if cs.NegotiatedProtocol == relayPunchProtocolName {
+			var err error
+			punchAddrs, err = s.exchangePunchAddresses(remoteID, c)
+			if err != nil {
+				l.Infof("Failed to exchange punch addresses with %s at %s: %s", remoteID, c, err)
+				c.Close()
+				continue
+			}
+		}
+
+		hello, err := protocol.ExchangeHello(c, ourHello)
 		if err != nil {
 			if protocol.IsVersionMismatch(err) {
 				// The error will be a relatively user friendly description
@@ -268,9 +310,17 @@ func (s *service) handle(stop chan struct{}) {
 		// If we have a relay connection, and the new incoming connection is
 		// not a relay connection, we should drop that, and prefer this one.
 		ct, connected := s.model.Connection(remoteID)
+		mc, _ := ct.(*multiConn)
+		secondary := sentSecondary || hello.Secondary
 
-		// Lower priority is better, just like nice etc.
-		if connected && ct.Priority() > c.priority {
+		if secondary {
+			if mc == nil || ct.Priority() != c.priority {
+				l.Debugf("Dropping secondary connection to %s without a matching primary (%s)", remoteID, c)
+				c.Close()
+				continue
+			}
+		} else if connected && ct.Priority() > c.priority {
+			// Lower priority is better, just like nice etc.
 			l.Debugf("Switching connections %s (existing: %s new: %s)", remoteID, ct, c)
 		} else if connected {
 			// We should not already be connected to the other party. TODO: This
@@ -293,18 +343,21 @@ func (s *service) handle(stop chan struct{}) {
 
 		// Verify the name on the certificate. By default we set it to
 		// "syncthing" when generating, but the user may have replaced
-		// the certificate and used another name.
+		// the certificate and used another name. Certificates issued by a
+		// CA only have to have a name if one is configured.
 		certName := deviceCfg.CertName
-		if certName == "" {
+		if certName == "" && !caIssued {
 			certName = s.tlsDefaultCommonName
 		}
-		if err := remoteCert.VerifyHostname(certName); err != nil {
-			// Incorrect certificate name is something the user most
-			// likely wants to know about, since it's an advanced
-			// config. Warn instead of Info.
-			l.Warnf("Bad certificate from %s at %s: %v", remoteID, c, err)
-			c.Close()
-			continue
+		if certName != "" {
+			if err := remoteCert.VerifyHostname(certName); err != nil {
+				// Incorrect certificate name is something the user most
+				// likely wants to know about, since it's an advanced
+				// config. Warn instead of Info.
+				l.Warnf("Bad certificate from %s at %s: %v", remoteID, c, err)
+				c.Close()
+				continue
+			}
 		}
 
 		// Wrap the connection in rate limiters. The limiter itself will
@@ -313,16 +366,79 @@ func (s *service) handle(stop chan struct{}) {
 		isLAN := s.isLAN(c.RemoteAddr())
 		rd, wr := s.limiter.getLimiters(remoteID, c, isLAN)
 
-		protoConn := protocol.NewConnection(remoteID, rd, wr, s.model, c.String(), deviceCfg.Compression)
-		modelConn := completeConn{c, protoConn}
+		algorithm := protocol.NegotiateCompression(deviceCfg.CompressionAlgorithm, hello.CompressionAlgorithms)
+		l.Debugf("Compressing messages to %s with %v", remoteID, algorithm)
+		passwords := config.EncryptionPasswords(s.cfg.FolderList(), remoteID)
+
+		if secondary {
+			protoConn := protocol.NewConnection(remoteID, rd, wr, secondaryReceiver{s.model, mc}, c.String(), deviceCfg.Compression, algorithm, passwords)
+			secondaryConn := completeConn{c, protoConn}
+			if !mc.addSecondary(secondaryConn) {
+				l.Debugf("Dropping secondary connection to %s, already have %d (%s)", remoteID, mc.want, c)
+				c.Close()
+				continue
+			}
+			l.Infof("Established secondary connection to %s at %s", remoteID, c)
+			protoConn.Start()
+			// The cluster config is sent on the primary connection, but
+			// every connection has to start with one.
+			protoConn.ClusterConfig(protocol.ClusterConfig{})
+			continue
+		}
+
+		var modelConn Connection
+		if want := wantConnections(deviceCfg.NumConnections, int(hello.NumConnections)); want > 1 {
+			multi := newMultiConn(want)
+			protoConn := protocol.NewConnection(remoteID, rd, wr, primaryReceiver{s.model, multi}, c.String(), deviceCfg.Compression, algorithm, passwords)
+			multi.completeConn = completeConn{c, protoConn}
+			modelConn = multi
+		} else {
+			protoConn := protocol.NewConnection(remoteID, rd, wr, s.model, c.String(), deviceCfg.Compression, algorithm, passwords)
+			modelConn = completeConn{c, protoConn}
+		}
 
 		l.Infof("Established secure connection to %s at %s", remoteID, c)
 
 		s.model.AddConnection(modelConn, hello)
+
+		if len(punchAddrs) > 0 {
+			go s.punch(remoteID, c, punchAddrs, stop)
+		}
 		continue
 	}
 }
 
+// caTrust returns the CAs that we trust to issue certificates to devices,
+// or nil if there are none. The files are read again each time, so that
+// changes to the list of revoked certificates take effect for the next
+// connection.
+func (s *service) caTrust() *tlsutil.CATrust {
+	opts := s.cfg.Options()
+	if opts.TrustedCAFile == "" {
+		return nil
+	}
+	trust, err := tlsutil.LoadCATrust(opts.TrustedCAFile, opts.RevokedCertsFile)
+	if err != nil {
+		l.Warnln("Trusted CAs:", err)
+		return nil
+	}
+	return trust
+}
+
+// wantsSecondary returns whether the connection, which we dialed, should
+// become a secondary connection to the device.
+func (s *service) wantsSecondary(remoteID protocol.DeviceID, c internalConn) bool {
+	if !c.isOutgoing() || s.myID.Compare(remoteID) >= 0 {
+		return false
+	}
+	ct, connected := s.model.Connection(remoteID)
+	if !connected {
+		return false
+	}
+	mc, ok := ct.(*multiConn)
+	return ok && ct.Priority() == c.priority && mc.count() < mc.want
+}
+
 func (s *service) connect(stop chan struct{}) {
 	nextDial := make(map[string]time.Time)
 
@@ -363,7 +479,16 @@ func (s *service) connect(stop chan struct{}) {
 
 			ct, connected := s.model.Connection(deviceID)
 
-			if connected && ct.Priority() == bestDialerPrio {
+			// Secondary connections are dialed by the device with the
+			// lower device ID, when both want more than one.
+			missing := 0
+			if connected && s.myID.Compare(deviceID) < 0 {
+				if mc, ok := ct.(*multiConn); ok {
+					missing = mc.want - mc.count()
+				}
+			}
+
+			if connected && ct.Priority() == bestDialerPrio && missing == 0 {
 				// Things are already as good as they can get.
 				continue
 			}
@@ -435,7 +560,7 @@ func (s *service) connect(stop chan struct{}) {
 
 				priority := dialerFactory.Priority()
 
-				if connected && priority >= ct.Priority() {
+				if connected && priority >= ct.Priority() && !(missing > 0 && priority == ct.Priority()) {
 					l.Debugf("Not dialing using %s as priority is less than current connection (%d >= %d)", dialerFactory, dialerFactory.Priority(), ct.Priority())
 					continue
 				}
@@ -465,6 +590,14 @@ func (s *service) connect(stop chan struct{}) {
 			if ok {
 				s.conns <- conn
 			}
+			for i := 1; ok && i < missing; i++ {
+				// Dial the rest of the missing secondary connections
+				// right away.
+				conn, ok = s.dialParallel(deviceCfg.DeviceID, dialTargets)
+				if ok {
+					s.conns <- conn
+				}
+			}
 		}
 
 		nextDial, sleep = filterAndFindSleepDuration(nextDial, seen, now)
@@ -561,6 +694,21 @@ func (s *service) logListenAddressesChangedEvent(l genericListener) {
 }
 
 func (s *service) VerifyConfiguration(from, to config.Configuration) error {
+	return VerifyClaimedDeviceID(s.tlsCfg.Certificates[0], to.Options)
+}
+
+// ErrClaimedDeviceID is returned for a configuration that uses global
+// discovery or relays, when our device ID is claimed by a certificate
+// issued by a CA. Global discovery servers and relays know devices by the
+// hash of their certificate, so no one could find us through them.
+var ErrClaimedDeviceID = errors.New("global discovery and relays can't be used with a device ID claimed by a certificate issued by a CA")
+
+// VerifyClaimedDeviceID returns ErrClaimedDeviceID if the options enable
+// global discovery or relays, while our certificate claims a device ID.
+func VerifyClaimedDeviceID(cert tls.Certificate, opts config.OptionsConfiguration) error {
+	if tlsutil.HasClaimedDeviceID(cert) && (opts.GlobalAnnEnabled || opts.RelaysEnabled) {
+		return ErrClaimedDeviceID
+	}
 	return nil
 }
 
@@ -70,6 +70,8 @@ const (
 	connTypeTCPServer
 	connTypeQUICClient
 	connTypeQUICServer
+	connTypeWSClient
+	connTypeWSServer
 )
 
 func (t connType) String() string {
@@ -86,6 +88,10 @@ func (t connType) String() string {
 		return "quic-client"
 	case connTypeQUICServer:
 		return "quic-server"
+	case connTypeWSClient:
+		return "ws-client"
+	case connTypeWSServer:
+		return "ws-server"
 	default:
 		return "unknown-type"
 	}
@@ -99,11 +105,23 @@ func (t connType) Transport() string {
 		return "tcp"
 	case connTypeQUICClient, connTypeQUICServer:
 		return "quic"
+	case connTypeWSClient, connTypeWSServer:
+		return "ws"
 	default:
 		return "unknown"
 	}
 }
 
+// isOutgoing returns whether we dialed the connection.
+func (c internalConn) isOutgoing() bool {
+	switch c.connType {
+	case connTypeRelayClient, connTypeTCPClient, connTypeQUICClient, connTypeWSClient:
+		return true
+	default:
+		return false
+	}
+}
+
 func (c internalConn) Close() {
 	// *tls.Conn.Close() does more than it says on the tin. Specifically, it
 	// sends a TLS alert message, which might block forever if the
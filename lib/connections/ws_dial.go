@@ -0,0 +1,115 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"crypto/tls"
+	"net"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/dialer"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func init() {
+	factory := &wsDialerFactory{}
+	for _, scheme := range []string{"ws", "wss"} {
+		dialers[scheme] = factory
+	}
+}
+
+type wsDialer struct {
+	cfg    config.Wrapper
+	tlsCfg *tls.Config
+}
+
+func (d *wsDialer) Dial(id protocol.DeviceID, uri *url.URL) (internalConn, error) {
+	uri = fixupPort(uri, wsDefaultPortFor(uri.Scheme))
+
+	// Proxies are picked by the scheme of the URL as if it were HTTP.
+	httpScheme := "http"
+	if uri.Scheme == "wss" {
+		httpScheme = "https"
+	}
+	conn, err := dialer.DialHTTPProxied(httpScheme, uri.Host, 10*time.Second)
+	if err != nil {
+		return internalConn{}, err
+	}
+
+	err = dialer.SetTCPOptions(conn)
+	if err != nil {
+		l.Debugln("Dial (BEP/ws): setting tcp options:", err)
+	}
+
+	localAddr, remoteAddr := conn.LocalAddr(), conn.RemoteAddr()
+	if uri.Scheme == "wss" {
+		// The certificate of whatever terminates the outer TLS isn't
+		// checked, the device is authenticated by the BEP TLS inside.
+		host, _, _ := net.SplitHostPort(uri.Host)
+		conn = tls.Client(conn, &tls.Config{
+			ServerName:         host,
+			InsecureSkipVerify: true,
+			NextProtos:         []string{"http/1.1"},
+		})
+	}
+
+	wsCfg, err := websocket.NewConfig(uri.String(), httpScheme+"://"+uri.Host+"/")
+	if err != nil {
+		conn.Close()
+		return internalConn{}, err
+	}
+	conn.SetDeadline(time.Now().Add(tlsHandshakeTimeout))
+	ws, err := websocket.NewClient(wsCfg, conn)
+	if err != nil {
+		conn.Close()
+		return internalConn{}, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	tc := tls.Client(newWSConn(ws, localAddr, remoteAddr), d.tlsCfg)
+	err = tlsTimedHandshake(tc)
+	if err != nil {
+		tc.Close()
+		return internalConn{}, err
+	}
+
+	return internalConn{tc, connTypeWSClient, wsPriority}, nil
+}
+
+func (d *wsDialer) RedialFrequency() time.Duration {
+	return time.Duration(d.cfg.Options().ReconnectIntervalS) * time.Second
+}
+
+type wsDialerFactory struct{}
+
+func (wsDialerFactory) New(cfg config.Wrapper, tlsCfg *tls.Config) genericDialer {
+	return &wsDialer{
+		cfg:    cfg,
+		tlsCfg: tlsCfg,
+	}
+}
+
+func (wsDialerFactory) Priority() int {
+	return wsPriority
+}
+
+func (wsDialerFactory) AlwaysWAN() bool {
+	return false
+}
+
+func (wsDialerFactory) Valid(_ config.Configuration) error {
+	// Always valid
+	return nil
+}
+
+func (wsDialerFactory) String() string {
+	return "WebSocket Dialer"
+}
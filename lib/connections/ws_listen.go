@@ -0,0 +1,225 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/dialer"
+	"github.com/syncthing/syncthing/lib/nat"
+	"github.com/syncthing/syncthing/lib/util"
+)
+
+func init() {
+	factory := &wsListenerFactory{}
+	for _, scheme := range []string{"ws", "wss"} {
+		listeners[scheme] = factory
+	}
+}
+
+// wsListener accepts BEP connections tunnelled over WebSocket, either
+// directly (wss) or behind something else that terminates the TLS of the
+// HTTPS connection (ws).
+type wsListener struct {
+	util.ServiceWithError
+	onAddressesChangedNotifier
+
+	uri     *url.URL
+	cfg     config.Wrapper
+	tlsCfg  *tls.Config
+	conns   chan internalConn
+	factory listenerFactory
+
+	natService *nat.Service
+	mapping    *nat.Mapping
+
+	mut sync.RWMutex
+}
+
+func (t *wsListener) serve(stop chan struct{}) error {
+	tcaddr, err := net.ResolveTCPAddr("tcp", t.uri.Host)
+	if err != nil {
+		l.Infoln("Listen (BEP/ws):", err)
+		return err
+	}
+
+	tcpListener, err := net.ListenTCP("tcp", tcaddr)
+	if err != nil {
+		l.Infoln("Listen (BEP/ws):", err)
+		return err
+	}
+	var listener net.Listener = tcpKeepAliveListener{tcpListener}
+	if t.uri.Scheme == "wss" {
+		// The outer TLS only has to look like HTTPS, the device
+		// certificates are checked by the BEP TLS inside.
+		outerCfg := t.tlsCfg.Clone()
+		outerCfg.ClientAuth = tls.NoClientCert
+		outerCfg.NextProtos = []string{"http/1.1"}
+		listener = tls.NewListener(listener, outerCfg)
+	}
+	defer listener.Close()
+
+	l.Infof("WebSocket listener (%v) starting", tcpListener.Addr())
+	defer l.Infof("WebSocket listener (%v) shutting down", tcpListener.Addr())
+
+	mapping := t.natService.NewMapping(nat.TCP, tcaddr.IP, tcaddr.Port)
+	mapping.OnChanged(func(_ *nat.Mapping, _, _ []nat.Address) {
+		t.notifyAddressesChanged(t)
+	})
+	defer t.natService.RemoveMapping(mapping)
+
+	t.mut.Lock()
+	t.mapping = mapping
+	t.mut.Unlock()
+
+	path := t.uri.Path
+	if path == "" {
+		path = "/"
+	}
+	mux := http.NewServeMux()
+	mux.Handle(path, websocket.Server{
+		Handler: t.handle,
+		// Not a browser, so there is no origin to check.
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+	})
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: tlsHandshakeTimeout,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(listener)
+	}()
+
+	select {
+	case <-stop:
+		srv.Close()
+		<-serveErr
+		err = nil
+	case err = <-serveErr:
+		l.Warnln("Listen (BEP/ws):", err)
+	}
+
+	t.mut.Lock()
+	t.mapping = nil
+	t.mut.Unlock()
+	return err
+}
+
+// handle does the BEP TLS handshake over the WebSocket connection and
+// hands it over. The connection is closed when this returns, so it waits
+// until whoever got it is done with it.
+func (t *wsListener) handle(ws *websocket.Conn) {
+	req := ws.Request()
+	remoteAddr, err := net.ResolveTCPAddr("tcp", req.RemoteAddr)
+	if err != nil {
+		l.Debugln("Listen (BEP/ws): remote address:", err)
+		return
+	}
+	localAddr, _ := req.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	l.Debugln("Listen (BEP/ws): connect from", remoteAddr)
+
+	conn := newWSConn(ws, localAddr, remoteAddr)
+	tc := tls.Server(conn, t.tlsCfg)
+	if err := tlsTimedHandshake(tc); err != nil {
+		l.Infoln("Listen (BEP/ws): TLS handshake:", err)
+		tc.Close()
+		return
+	}
+
+	t.conns <- internalConn{tc, connTypeWSServer, wsPriority}
+	<-conn.closed
+}
+
+func (t *wsListener) URI() *url.URL {
+	return t.uri
+}
+
+func (t *wsListener) WANAddresses() []*url.URL {
+	uris := t.LANAddresses()
+	t.mut.RLock()
+	if t.mapping != nil {
+		addrs := t.mapping.ExternalAddresses()
+		for _, addr := range addrs {
+			uri := *t.uri
+			// Does net.JoinHostPort internally
+			uri.Host = addr.String()
+			uris = append(uris, &uri)
+
+			// For every address with a specified IP, add one without an IP,
+			// just in case the specified IP is still internal (router behind DMZ).
+			if len(addr.IP) != 0 && !addr.IP.IsUnspecified() {
+				uri = *t.uri
+				addr.IP = nil
+				uri.Host = addr.String()
+				uris = append(uris, &uri)
+			}
+		}
+	}
+	t.mut.RUnlock()
+	return uris
+}
+
+func (t *wsListener) LANAddresses() []*url.URL {
+	return []*url.URL{t.uri}
+}
+
+func (t *wsListener) String() string {
+	return t.uri.String()
+}
+
+func (t *wsListener) Factory() listenerFactory {
+	return t.factory
+}
+
+func (t *wsListener) NATType() string {
+	return "unknown"
+}
+
+// tcpKeepAliveListener sets our TCP options on accepted connections.
+type tcpKeepAliveListener struct {
+	*net.TCPListener
+}
+
+func (ln tcpKeepAliveListener) Accept() (net.Conn, error) {
+	conn, err := ln.AcceptTCP()
+	if err != nil {
+		return nil, err
+	}
+	if err := dialer.SetTCPOptions(conn); err != nil {
+		l.Debugln("Listen (BEP/ws): setting tcp options:", err)
+	}
+	return conn, nil
+}
+
+type wsListenerFactory struct{}
+
+func (f *wsListenerFactory) New(uri *url.URL, cfg config.Wrapper, tlsCfg *tls.Config, conns chan internalConn, natService *nat.Service) genericListener {
+	l := &wsListener{
+		uri:        fixupPort(uri, wsDefaultPortFor(uri.Scheme)),
+		cfg:        cfg,
+		tlsCfg:     tlsCfg,
+		conns:      conns,
+		natService: natService,
+		factory:    f,
+	}
+	l.ServiceWithError = util.AsServiceWithError(l.serve)
+	return l
+}
+
+func (wsListenerFactory) Valid(_ config.Configuration) error {
+	// Always valid
+	return nil
+}
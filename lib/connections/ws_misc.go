@@ -0,0 +1,69 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"net"
+	"sync"
+
+	"golang.org/x/net/websocket"
+)
+
+const wsPriority = 150
+
+// Ports used if the URI does not specify one, the same as for HTTP and
+// HTTPS as that's what proxies and firewalls are most likely to let
+// through.
+const (
+	wsDefaultPort  = 80
+	wssDefaultPort = 443
+)
+
+func wsDefaultPortFor(scheme string) int {
+	if scheme == "wss" {
+		return wssDefaultPort
+	}
+	return wsDefaultPort
+}
+
+// wsConn is a WebSocket connection carrying binary frames, used as the
+// stream underneath the BEP TLS connection. The addresses of a
+// websocket.Conn are URLs, so the ones of the TCP connection are kept
+// instead.
+type wsConn struct {
+	*websocket.Conn
+	localAddr  net.Addr
+	remoteAddr net.Addr
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newWSConn(ws *websocket.Conn, localAddr, remoteAddr net.Addr) *wsConn {
+	ws.PayloadType = websocket.BinaryFrame
+	return &wsConn{
+		Conn:       ws,
+		localAddr:  localAddr,
+		remoteAddr: remoteAddr,
+		closed:     make(chan struct{}),
+	}
+}
+
+func (c *wsConn) LocalAddr() net.Addr {
+	return c.localAddr
+}
+
+func (c *wsConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+func (c *wsConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+	return c.Conn.Close()
+}
@@ -0,0 +1,104 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/nat"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/tlsutil"
+)
+
+func TestWebSocketTransport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syncthing-ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cert, err := tlsutil.NewCertificate(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), "syncthing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tlsCfg := &tls.Config{
+		Certificates:       []tls.Certificate{cert},
+		ClientAuth:         tls.RequestClientCert,
+		InsecureSkipVerify: true,
+		NextProtos:         []string{"bep/1.0"},
+	}
+	cfg := config.Wrap(filepath.Join(dir, "config.xml"), config.New(protocol.LocalDeviceID))
+
+	for _, scheme := range []string{"ws", "wss"} {
+		t.Run(scheme, func(t *testing.T) {
+			uri := &url.URL{Scheme: scheme, Host: freeTCPAddr(t), Path: "/bep"}
+			conns := make(chan internalConn, 1)
+			lst := listeners[scheme].New(uri, cfg, tlsCfg, conns, nat.NewService(protocol.LocalDeviceID, cfg))
+			go lst.Serve()
+			defer lst.Stop()
+
+			// The listener may take a moment to start
+			var client internalConn
+			for i := 0; ; i++ {
+				client, err = dialers[scheme].New(cfg, tlsCfg).Dial(protocol.LocalDeviceID, uri)
+				if err == nil {
+					break
+				}
+				if i == 20 {
+					t.Fatal(err)
+				}
+				time.Sleep(50 * time.Millisecond)
+			}
+			defer client.Close()
+
+			var server internalConn
+			select {
+			case server = <-conns:
+			case <-time.After(5 * time.Second):
+				t.Fatal("Timed out waiting for the connection")
+			}
+			defer server.Close()
+
+			if client.Type() != "ws-client" || server.Type() != "ws-server" {
+				t.Errorf("Unexpected connection types %v and %v", client.Type(), server.Type())
+			}
+			if client.RemoteAddr().String() != uri.Host {
+				t.Errorf("Client connected to %v, expected %v", client.RemoteAddr(), uri.Host)
+			}
+			if server.RemoteAddr().String() != client.LocalAddr().String() {
+				t.Errorf("Server connected to %v, expected %v", server.RemoteAddr(), client.LocalAddr())
+			}
+
+			go client.Write([]byte("hello"))
+			buf := make([]byte, 5)
+			if _, err := io.ReadFull(server, buf); err != nil {
+				t.Fatal(err)
+			}
+			if string(buf) != "hello" {
+				t.Errorf("Read %q, expected %q", buf, "hello")
+			}
+		})
+	}
+}
+
+func freeTCPAddr(t *testing.T) string {
+	t.Helper()
+	lst, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lst.Close()
+	return lst.Addr().String()
+}
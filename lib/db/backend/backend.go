@@ -0,0 +1,147 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// Package backend provides the key/value stores the database can be kept
+// in. All of them offer the same ordered keyspace, point in time snapshots
+// and batched writes, so the layers above don't need to know which one is
+// in use.
+package backend
+
+import (
+	"errors"
+	"fmt"
+)
+
+// These errors are returned by all backends, in place of their own.
+var (
+	ErrNotFound = errors.New("key not found")
+	ErrClosed   = errors.New("database is closed")
+)
+
+// The Reader interface specifies the read only operations available on the
+// database and its snapshots. Iteration is in key order; errors during
+// iteration are returned by the iterator's Error method.
+type Reader interface {
+	Get(key []byte) ([]byte, error)
+	NewPrefixIterator(prefix []byte) Iterator
+	// NewRangeIterator iterates over the keys from start (inclusive) to
+	// limit (exclusive).
+	NewRangeIterator(start, limit []byte) Iterator
+}
+
+// The Writer interface specifies the operations that change the database.
+type Writer interface {
+	Put(key, val []byte) error
+	Delete(key []byte) error
+}
+
+// Backend is a key/value store.
+type Backend interface {
+	Reader
+	Writer
+	// NewSnapshot returns a consistent view of the database, unaffected by
+	// later writes, until it is released.
+	NewSnapshot() (Snapshot, error)
+	// NewBatch returns an empty batch of writes, to be applied atomically
+	// by Write.
+	NewBatch() Batch
+	Write(Batch) error
+	Close() error
+}
+
+type Snapshot interface {
+	Reader
+	Release()
+}
+
+// A Batch collects writes. The keys and values are copied, so the caller
+// may reuse its buffers.
+type Batch interface {
+	Put(key, val []byte)
+	Delete(key []byte)
+	// Size returns the approximate number of bytes in the batch.
+	Size() int
+	Reset()
+}
+
+type Iterator interface {
+	Next() bool
+	Key() []byte
+	Value() []byte
+	Error() error
+	Release()
+}
+
+// Type selects the key/value store to use.
+type Type int
+
+const (
+	TypeLevelDB Type = iota // The default
+	TypeBadger
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeLevelDB:
+		return "leveldb"
+	case TypeBadger:
+		return "badger"
+	default:
+		return "unknown"
+	}
+}
+
+func (t Type) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText takes an empty name to mean leveldb, the default.
+func (t *Type) UnmarshalText(bs []byte) error {
+	switch string(bs) {
+	case "", "leveldb":
+		*t = TypeLevelDB
+	case "badger":
+		*t = TypeBadger
+	default:
+		return fmt.Errorf("unknown database backend %q", bs)
+	}
+	return nil
+}
+
+// Open opens the database of the given type at location, creating it if it
+// does not exist.
+func Open(t Type, location string) (Backend, error) {
+	switch t {
+	case TypeLevelDB:
+		return OpenLevelDB(location)
+	case TypeBadger:
+		return OpenBadger(location)
+	default:
+		return nil, fmt.Errorf("unknown database backend %v", t)
+	}
+}
+
+// OpenRO opens the existing database of the given type at location, read
+// only.
+func OpenRO(t Type, location string) (Backend, error) {
+	switch t {
+	case TypeLevelDB:
+		return OpenLevelDBRO(location)
+	case TypeBadger:
+		return OpenBadgerRO(location)
+	default:
+		return nil, fmt.Errorf("unknown database backend %v", t)
+	}
+}
+
+type errorSuggestion struct {
+	inner      error
+	suggestion string
+}
+
+func (e errorSuggestion) Error() string {
+	return fmt.Sprintf("%s (%s)", e.inner.Error(), e.suggestion)
+}
@@ -0,0 +1,268 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package backend
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dgraph-io/badger"
+)
+
+// backends returns a fresh database of each type, and a function to clean
+// them up.
+func backends(t *testing.T) (map[string]Backend, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "syncthing-backend-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bdb, err := OpenBadger(filepath.Join(dir, "badger"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ldb, err := OpenLevelDB(filepath.Join(dir, "leveldb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bks := map[string]Backend{
+		"leveldb": ldb,
+		"memory":  OpenLevelDBMemory(),
+		"badger":  bdb,
+	}
+	return bks, func() {
+		for _, bk := range bks {
+			bk.Close()
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func TestGetPutDelete(t *testing.T) {
+	bks, cleanup := backends(t)
+	defer cleanup()
+
+	for name, bk := range bks {
+		if _, err := bk.Get([]byte("foo")); err != ErrNotFound {
+			t.Errorf("%s: expected not found, got %v", name, err)
+		}
+		if err := bk.Put([]byte("foo"), []byte("bar")); err != nil {
+			t.Fatal(name, err)
+		}
+		if val, err := bk.Get([]byte("foo")); err != nil || string(val) != "bar" {
+			t.Errorf("%s: got %q, %v", name, val, err)
+		}
+		// Values may be empty
+		if err := bk.Put([]byte("empty"), nil); err != nil {
+			t.Fatal(name, err)
+		}
+		if val, err := bk.Get([]byte("empty")); err != nil || len(val) != 0 {
+			t.Errorf("%s: got %q, %v for empty value", name, val, err)
+		}
+		if err := bk.Delete([]byte("foo")); err != nil {
+			t.Fatal(name, err)
+		}
+		if _, err := bk.Get([]byte("foo")); err != ErrNotFound {
+			t.Errorf("%s: expected not found after delete, got %v", name, err)
+		}
+	}
+}
+
+func TestIterators(t *testing.T) {
+	bks, cleanup := backends(t)
+	defer cleanup()
+
+	for name, bk := range bks {
+		batch := bk.NewBatch()
+		key := make([]byte, 2)
+		for _, prefix := range []byte{1, 2, 3} {
+			for i := 9; i >= 0; i-- {
+				// The buffer is reused, as by the callers in lib/db
+				key[0], key[1] = prefix, byte(i)
+				batch.Put(key, []byte(fmt.Sprint(i)))
+			}
+		}
+		if err := bk.Write(batch); err != nil {
+			t.Fatal(name, err)
+		}
+
+		it := bk.NewPrefixIterator([]byte{2})
+		n := 0
+		for it.Next() {
+			if it.Key()[0] != 2 || int(it.Key()[1]) != n || string(it.Value()) != fmt.Sprint(n) {
+				t.Errorf("%s: unexpected key %x, value %q at %d", name, it.Key(), it.Value(), n)
+			}
+			n++
+		}
+		if err := it.Error(); err != nil {
+			t.Error(name, err)
+		}
+		it.Release()
+		if n != 10 {
+			t.Errorf("%s: prefix iterator returned %d keys, expected 10", name, n)
+		}
+
+		it = bk.NewRangeIterator([]byte{1, 5}, []byte{2, 3})
+		n = 0
+		for it.Next() {
+			n++
+		}
+		it.Release()
+		if n != 8 {
+			t.Errorf("%s: range iterator returned %d keys, expected 8", name, n)
+		}
+
+		it = bk.NewPrefixIterator(nil)
+		n = 0
+		for it.Next() {
+			n++
+		}
+		it.Release()
+		if n != 30 {
+			t.Errorf("%s: full iteration returned %d keys, expected 30", name, n)
+		}
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	bks, cleanup := backends(t)
+	defer cleanup()
+
+	for name, bk := range bks {
+		bk.Put([]byte("a"), []byte("1"))
+		snap, err := bk.NewSnapshot()
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		bk.Put([]byte("a"), []byte("2"))
+		bk.Put([]byte("b"), []byte("2"))
+
+		if val, err := snap.Get([]byte("a")); err != nil || string(val) != "1" {
+			t.Errorf("%s: snapshot sees %q, %v", name, val, err)
+		}
+		if _, err := snap.Get([]byte("b")); err != ErrNotFound {
+			t.Errorf("%s: snapshot sees later key", name)
+		}
+		it := snap.NewPrefixIterator(nil)
+		n := 0
+		for it.Next() {
+			n++
+		}
+		it.Release()
+		if n != 1 {
+			t.Errorf("%s: snapshot iterator returned %d keys, expected 1", name, n)
+		}
+		snap.Release()
+	}
+}
+
+func TestClosed(t *testing.T) {
+	bks, cleanup := backends(t)
+	defer cleanup()
+
+	for name, bk := range bks {
+		bk.Close()
+		if _, err := bk.Get([]byte("a")); err != ErrClosed {
+			t.Errorf("%s: get after close returned %v", name, err)
+		}
+		if err := bk.Put([]byte("a"), nil); err != ErrClosed {
+			t.Errorf("%s: put after close returned %v", name, err)
+		}
+		if _, err := bk.NewSnapshot(); err != ErrClosed {
+			t.Errorf("%s: snapshot after close returned %v", name, err)
+		}
+	}
+}
+
+func TestCopy(t *testing.T) {
+	bks, cleanup := backends(t)
+	defer cleanup()
+
+	src := bks["leveldb"]
+	for i := 0; i < 1000; i++ {
+		src.Put([]byte(fmt.Sprintf("key%04d", i)), []byte(fmt.Sprint(i)))
+	}
+
+	dst := bks["badger"]
+	n, err := Copy(dst, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1000 {
+		t.Errorf("copied %d keys, expected 1000", n)
+	}
+	for _, i := range []int{0, 500, 999} {
+		if val, err := dst.Get([]byte(fmt.Sprintf("key%04d", i))); err != nil || string(val) != fmt.Sprint(i) {
+			t.Errorf("key %d: got %q, %v", i, val, err)
+		}
+	}
+}
+
+func TestTypeText(t *testing.T) {
+	for _, tc := range []struct {
+		text string
+		typ  Type
+	}{
+		{"leveldb", TypeLevelDB},
+		{"badger", TypeBadger},
+		{"", TypeLevelDB},
+	} {
+		var typ Type
+		if err := typ.UnmarshalText([]byte(tc.text)); err != nil {
+			t.Fatal(err)
+		}
+		if typ != tc.typ {
+			t.Errorf("%q unmarshals to %v, expected %v", tc.text, typ, tc.typ)
+		}
+	}
+
+	var typ Type
+	if err := typ.UnmarshalText([]byte("badgr")); err == nil {
+		t.Error("expected an error for an unknown backend")
+	}
+}
+
+func TestBadgerBatchLimit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syncthing-backend-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// Small tables for small transaction limits
+	bk, err := openBadger(badger.DefaultOptions(dir).WithLogger(badgerLogger{}).WithMaxTableSize(1 << 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bk.Close()
+	bdb := bk.(*badgerBackend).bdb
+
+	// Many small writes run into the limit on the number of entries
+	// first. A batch that stays below the size limit fits in one
+	// transaction.
+	batch := bk.NewBatch()
+	for i := 0; int64(batch.Size()) < bdb.MaxBatchSize()*9/10; i++ {
+		batch.Put([]byte(fmt.Sprint(i)), nil)
+	}
+	if err := bk.Write(batch); err != nil {
+		t.Fatal(err)
+	}
+
+	// A batch that doesn't fit isn't applied at all.
+	batch = bk.NewBatch()
+	for i := 0; int64(batch.Size()) < bdb.MaxBatchSize()*2; i++ {
+		batch.Put([]byte(fmt.Sprintf("big%d", i)), []byte("value"))
+	}
+	if err := bk.Write(batch); err == nil {
+		t.Fatal("expected an error writing a batch that doesn't fit")
+	}
+	if _, err := bk.Get([]byte("big0")); err != ErrNotFound {
+		t.Error("expected nothing to be written, got", err)
+	}
+}
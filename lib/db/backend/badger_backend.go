@@ -0,0 +1,376 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package backend
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger"
+)
+
+const (
+	badgerGCInterval     = 10 * time.Minute
+	badgerGCDiscardRatio = 0.5
+)
+
+// OpenBadger opens the badger database at the given location, creating it
+// if it does not exist.
+func OpenBadger(location string) (Backend, error) {
+	opts := badger.DefaultOptions(location).
+		WithLogger(badgerLogger{}).
+		// Recover from writes interrupted by a crash, rather than refusing
+		// to open.
+		WithTruncate(true)
+	return openBadger(opts)
+}
+
+// OpenBadgerRO opens the existing badger database at the given location,
+// read only.
+func OpenBadgerRO(location string) (Backend, error) {
+	opts := badger.DefaultOptions(location).
+		WithLogger(badgerLogger{}).
+		WithReadOnly(true)
+	return openBadger(opts)
+}
+
+func openBadger(opts badger.Options) (Backend, error) {
+	bdb, err := badger.Open(opts)
+	if err != nil {
+		return nil, errorSuggestion{err, "is another instance of Syncthing running?"}
+	}
+
+	b := &badgerBackend{
+		bdb:  bdb,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go b.gcLoop()
+	return b, nil
+}
+
+// badgerBackend implements Backend on top of a badger database. Badger
+// transactions are used for snapshots, batches and single writes alike.
+type badgerBackend struct {
+	bdb *badger.DB
+
+	// Badger panics on use after close, while the other backends return an
+	// error, so we keep track.
+	closeMut sync.RWMutex
+	closed   bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func (b *badgerBackend) Get(key []byte) ([]byte, error) {
+	snap, err := b.NewSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	defer snap.Release()
+	return snap.Get(key)
+}
+
+func (b *badgerBackend) NewPrefixIterator(prefix []byte) Iterator {
+	snap, err := b.NewSnapshot()
+	if err != nil {
+		return &errorIterator{err}
+	}
+	it := snap.(*badgerSnapshot).newIterator(prefix, nil, nil)
+	it.releaseSnap = true
+	return it
+}
+
+func (b *badgerBackend) NewRangeIterator(start, limit []byte) Iterator {
+	snap, err := b.NewSnapshot()
+	if err != nil {
+		return &errorIterator{err}
+	}
+	it := snap.(*badgerSnapshot).newIterator(nil, start, limit)
+	it.releaseSnap = true
+	return it
+}
+
+func (b *badgerBackend) Put(key, val []byte) error {
+	batch := b.NewBatch()
+	batch.Put(key, val)
+	return b.Write(batch)
+}
+
+func (b *badgerBackend) Delete(key []byte) error {
+	batch := b.NewBatch()
+	batch.Delete(key)
+	return b.Write(batch)
+}
+
+func (b *badgerBackend) NewSnapshot() (Snapshot, error) {
+	b.closeMut.RLock()
+	defer b.closeMut.RUnlock()
+	if b.closed {
+		return nil, ErrClosed
+	}
+	return &badgerSnapshot{
+		txn:     b.bdb.NewTransaction(false),
+		backend: b,
+	}, nil
+}
+
+func (b *badgerBackend) NewBatch() Batch {
+	return &badgerBatch{
+		// Badger limits both the size and the number of entries in a
+		// transaction. Counting every entry as at least the size limit
+		// divided by the count limit makes the size cover both.
+		minOpSize: int(b.bdb.MaxBatchSize() / b.bdb.MaxBatchCount()),
+	}
+}
+
+// Write applies the batch in a single transaction. Batches are flushed by
+// their size well before they reach badger's transaction limits, so a batch
+// that doesn't fit is an error rather than something to split.
+func (b *badgerBackend) Write(batch Batch) error {
+	b.closeMut.RLock()
+	defer b.closeMut.RUnlock()
+	if b.closed {
+		return ErrClosed
+	}
+
+	bb := batch.(*badgerBatch)
+	txn := b.bdb.NewTransaction(true)
+	defer txn.Discard()
+	for _, op := range bb.ops {
+		if err := op.apply(txn); err == badger.ErrTxnTooBig {
+			return fmt.Errorf("batch of %d writes (%d bytes) too large for a transaction", len(bb.ops), bb.size)
+		} else if err != nil {
+			return err
+		}
+	}
+	return txn.Commit()
+}
+
+func (b *badgerBackend) Close() error {
+	b.closeMut.Lock()
+	if b.closed {
+		b.closeMut.Unlock()
+		return ErrClosed
+	}
+	b.closed = true
+	b.closeMut.Unlock()
+
+	close(b.stop)
+	<-b.done
+	return b.bdb.Close()
+}
+
+// gcLoop periodically reclaims the space taken by overwritten and deleted
+// values, which badger does not do on its own.
+func (b *badgerBackend) gcLoop() {
+	defer close(b.done)
+	t := time.NewTicker(badgerGCInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-t.C:
+		}
+		// Each successful run rewrites one value log file; keep going
+		// until there is nothing more worth rewriting.
+		for {
+			if err := b.bdb.RunValueLogGC(badgerGCDiscardRatio); err != nil {
+				if err != badger.ErrNoRewrite {
+					l.Debugln("badger gc:", err)
+				}
+				break
+			}
+			select {
+			case <-b.stop:
+				return
+			default:
+			}
+		}
+	}
+}
+
+type badgerSnapshot struct {
+	txn     *badger.Txn
+	backend *badgerBackend
+}
+
+func (s *badgerSnapshot) Get(key []byte) ([]byte, error) {
+	s.backend.closeMut.RLock()
+	defer s.backend.closeMut.RUnlock()
+	if s.backend.closed {
+		return nil, ErrClosed
+	}
+
+	item, err := s.txn.Get(key)
+	if err == badger.ErrKeyNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return item.ValueCopy(nil)
+}
+
+func (s *badgerSnapshot) NewPrefixIterator(prefix []byte) Iterator {
+	return s.newIterator(prefix, nil, nil)
+}
+
+func (s *badgerSnapshot) NewRangeIterator(start, limit []byte) Iterator {
+	return s.newIterator(nil, start, limit)
+}
+
+func (s *badgerSnapshot) newIterator(prefix, start, limit []byte) *badgerIterator {
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = prefix
+	if start == nil {
+		start = prefix
+	}
+	return &badgerIterator{
+		it:    s.txn.NewIterator(opts),
+		snap:  s,
+		start: start,
+		limit: limit,
+	}
+}
+
+func (s *badgerSnapshot) Release() {
+	s.backend.closeMut.RLock()
+	defer s.backend.closeMut.RUnlock()
+	if s.backend.closed {
+		return
+	}
+	s.txn.Discard()
+}
+
+type badgerIterator struct {
+	it           *badger.Iterator
+	snap         *badgerSnapshot
+	releaseSnap  bool // the snapshot was made for this iterator only
+	start, limit []byte
+	started      bool
+	key, val     []byte
+	err          error
+}
+
+func (it *badgerIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if !it.started {
+		it.it.Seek(it.start)
+		it.started = true
+	} else {
+		it.it.Next()
+	}
+
+	if !it.it.Valid() {
+		return false
+	}
+	item := it.it.Item()
+	if it.limit != nil && bytes.Compare(item.Key(), it.limit) >= 0 {
+		return false
+	}
+	it.key = item.KeyCopy(it.key[:0])
+	it.val, it.err = item.ValueCopy(it.val[:0])
+	return it.err == nil
+}
+
+// The returned key and value are only valid until the next call to Next,
+// like with leveldb.
+func (it *badgerIterator) Key() []byte {
+	return it.key
+}
+
+func (it *badgerIterator) Value() []byte {
+	return it.val
+}
+
+func (it *badgerIterator) Error() error {
+	return it.err
+}
+
+func (it *badgerIterator) Release() {
+	it.it.Close()
+	if it.releaseSnap {
+		it.snap.Release()
+	}
+}
+
+type badgerBatch struct {
+	ops       []badgerOp
+	size      int
+	minOpSize int
+}
+
+type badgerOp struct {
+	key, val []byte
+	delete   bool
+}
+
+func (op badgerOp) apply(txn *badger.Txn) error {
+	if op.delete {
+		return txn.Delete(op.key)
+	}
+	return txn.Set(op.key, op.val)
+}
+
+func (b *badgerBatch) Put(key, val []byte) {
+	op := badgerOp{
+		key: append([]byte(nil), key...),
+		val: append([]byte{}, val...), // badger treats nil values like empty ones, but be explicit
+	}
+	b.ops = append(b.ops, op)
+	b.addSize(len(key) + len(val))
+}
+
+func (b *badgerBatch) Delete(key []byte) {
+	b.ops = append(b.ops, badgerOp{key: append([]byte(nil), key...), delete: true})
+	b.addSize(len(key))
+}
+
+// addSize accounts for an entry like badger does, with room for the
+// version and metadata.
+func (b *badgerBatch) addSize(n int) {
+	n += 12
+	if n < b.minOpSize {
+		n = b.minOpSize
+	}
+	b.size += n
+}
+
+func (b *badgerBatch) Size() int {
+	return b.size
+}
+
+func (b *badgerBatch) Reset() {
+	b.ops = b.ops[:0]
+	b.size = 0
+}
+
+// badgerLogger sends badger's log output to our logger, at a lower level as
+// it is rather chatty.
+type badgerLogger struct{}
+
+func (badgerLogger) Errorf(format string, args ...interface{})   { l.Warnf(format, args...) }
+func (badgerLogger) Warningf(format string, args ...interface{}) { l.Infof(format, args...) }
+func (badgerLogger) Infof(format string, args ...interface{})    { l.Debugf(format, args...) }
+func (badgerLogger) Debugf(format string, args ...interface{})   { l.Debugf(format, args...) }
+
+type errorIterator struct {
+	err error
+}
+
+func (it *errorIterator) Next() bool    { return false }
+func (it *errorIterator) Key() []byte   { return nil }
+func (it *errorIterator) Value() []byte { return nil }
+func (it *errorIterator) Error() error  { return it.err }
+func (it *errorIterator) Release()      {}
@@ -0,0 +1,37 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package backend
+
+const copyBatchSize = 4 << 20
+
+// Copy writes all keys of src to dst, returning the number of keys copied.
+// It is used to convert a database from one backend to another, so dst is
+// expected to be empty.
+func Copy(dst Backend, src Reader) (int, error) {
+	it := src.NewPrefixIterator(nil)
+	defer it.Release()
+
+	batch := dst.NewBatch()
+	n := 0
+	for it.Next() {
+		batch.Put(it.Key(), it.Value())
+		n++
+		if batch.Size() > copyBatchSize {
+			if err := dst.Write(batch); err != nil {
+				return n, err
+			}
+			batch.Reset()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return n, err
+	}
+	if err := dst.Write(batch); err != nil {
+		return n, err
+	}
+	return n, nil
+}
@@ -0,0 +1,220 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package backend
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	dbMaxOpenFiles = 100
+	dbWriteBuffer  = 16 << 20
+)
+
+// OpenLevelDB attempts to open the database at the given location, and runs
+// recovery on it if opening fails. Worst case, if recovery is not possible,
+// the database is erased and created from scratch.
+func OpenLevelDB(location string) (Backend, error) {
+	opts := &opt.Options{
+		BlockCacheCapacity:            debugEnvValue("BlockCacheCapacity", 0),
+		BlockCacheEvictRemoved:        debugEnvValue("BlockCacheEvictRemoved", 0) != 0,
+		BlockRestartInterval:          debugEnvValue("BlockRestartInterval", 0),
+		BlockSize:                     debugEnvValue("BlockSize", 0),
+		CompactionExpandLimitFactor:   debugEnvValue("CompactionExpandLimitFactor", 0),
+		CompactionGPOverlapsFactor:    debugEnvValue("CompactionGPOverlapsFactor", 0),
+		CompactionL0Trigger:           debugEnvValue("CompactionL0Trigger", 0),
+		CompactionSourceLimitFactor:   debugEnvValue("CompactionSourceLimitFactor", 0),
+		CompactionTableSize:           debugEnvValue("CompactionTableSize", 0),
+		CompactionTableSizeMultiplier: float64(debugEnvValue("CompactionTableSizeMultiplier", 0)) / 10.0,
+		CompactionTotalSize:           debugEnvValue("CompactionTotalSize", 0),
+		CompactionTotalSizeMultiplier: float64(debugEnvValue("CompactionTotalSizeMultiplier", 0)) / 10.0,
+		DisableBufferPool:             debugEnvValue("DisableBufferPool", 0) != 0,
+		DisableBlockCache:             debugEnvValue("DisableBlockCache", 0) != 0,
+		DisableCompactionBackoff:      debugEnvValue("DisableCompactionBackoff", 0) != 0,
+		DisableLargeBatchTransaction:  debugEnvValue("DisableLargeBatchTransaction", 0) != 0,
+		NoSync:                        debugEnvValue("NoSync", 0) != 0,
+		NoWriteMerge:                  debugEnvValue("NoWriteMerge", 0) != 0,
+		OpenFilesCacheCapacity:        debugEnvValue("OpenFilesCacheCapacity", dbMaxOpenFiles),
+		WriteBuffer:                   debugEnvValue("WriteBuffer", dbWriteBuffer),
+		// The write slowdown and pause can be overridden, but even if they
+		// are not and the compaction trigger is overridden we need to
+		// adjust so that we don't pause writes for L0 compaction before we
+		// even *start* L0 compaction...
+		WriteL0SlowdownTrigger: debugEnvValue("WriteL0SlowdownTrigger", 2*debugEnvValue("CompactionL0Trigger", opt.DefaultCompactionL0Trigger)),
+		WriteL0PauseTrigger:    debugEnvValue("WriteL0SlowdownTrigger", 3*debugEnvValue("CompactionL0Trigger", opt.DefaultCompactionL0Trigger)),
+	}
+	return openLevelDB(location, opts)
+}
+
+// OpenLevelDBRO attempts to open the database at the given location, read
+// only.
+func OpenLevelDBRO(location string) (Backend, error) {
+	opts := &opt.Options{
+		OpenFilesCacheCapacity: dbMaxOpenFiles,
+		ReadOnly:               true,
+	}
+	return openLevelDB(location, opts)
+}
+
+// OpenLevelDBMemory returns a new in-memory leveldb database.
+func OpenLevelDBMemory() Backend {
+	ldb, _ := leveldb.Open(storage.NewMemStorage(), nil)
+	return &leveldbBackend{ldb}
+}
+
+func openLevelDB(location string, opts *opt.Options) (Backend, error) {
+	ldb, err := leveldb.OpenFile(location, opts)
+	if leveldbIsCorrupted(err) {
+		ldb, err = leveldb.RecoverFile(location, opts)
+	}
+	if leveldbIsCorrupted(err) {
+		// The database is corrupted, and we've tried to recover it but it
+		// didn't work. At this point there isn't much to do beyond dropping
+		// the database and reindexing...
+		l.Infoln("Database corruption detected, unable to recover. Reinitializing...")
+		if err := os.RemoveAll(location); err != nil {
+			return nil, errorSuggestion{err, "failed to delete corrupted database"}
+		}
+		ldb, err = leveldb.OpenFile(location, opts)
+	}
+	if err != nil {
+		return nil, errorSuggestion{err, "is another instance of Syncthing running?"}
+	}
+
+	if debugEnvValue("CompactEverything", 0) != 0 {
+		if err := ldb.CompactRange(util.Range{}); err != nil {
+			l.Warnln("Compacting database:", err)
+		}
+	}
+	return &leveldbBackend{ldb}, nil
+}
+
+// leveldbBackend implements Backend on top of a goleveldb database.
+type leveldbBackend struct {
+	ldb *leveldb.DB
+}
+
+func (b *leveldbBackend) Get(key []byte) ([]byte, error) {
+	val, err := b.ldb.Get(key, nil)
+	return val, wrapLevelDBError(err)
+}
+
+func (b *leveldbBackend) NewPrefixIterator(prefix []byte) Iterator {
+	return &leveldbIterator{b.ldb.NewIterator(util.BytesPrefix(prefix), nil)}
+}
+
+func (b *leveldbBackend) NewRangeIterator(start, limit []byte) Iterator {
+	return &leveldbIterator{b.ldb.NewIterator(&util.Range{Start: start, Limit: limit}, nil)}
+}
+
+func (b *leveldbBackend) Put(key, val []byte) error {
+	return wrapLevelDBError(b.ldb.Put(key, val, nil))
+}
+
+func (b *leveldbBackend) Delete(key []byte) error {
+	return wrapLevelDBError(b.ldb.Delete(key, nil))
+}
+
+func (b *leveldbBackend) NewSnapshot() (Snapshot, error) {
+	snap, err := b.ldb.GetSnapshot()
+	if err != nil {
+		return nil, wrapLevelDBError(err)
+	}
+	return &leveldbSnapshot{snap}, nil
+}
+
+func (b *leveldbBackend) NewBatch() Batch {
+	return &leveldbBatch{new(leveldb.Batch)}
+}
+
+func (b *leveldbBackend) Write(batch Batch) error {
+	return wrapLevelDBError(b.ldb.Write(batch.(*leveldbBatch).Batch, nil))
+}
+
+func (b *leveldbBackend) Close() error {
+	return wrapLevelDBError(b.ldb.Close())
+}
+
+type leveldbSnapshot struct {
+	snap *leveldb.Snapshot
+}
+
+func (s *leveldbSnapshot) Get(key []byte) ([]byte, error) {
+	val, err := s.snap.Get(key, nil)
+	return val, wrapLevelDBError(err)
+}
+
+func (s *leveldbSnapshot) NewPrefixIterator(prefix []byte) Iterator {
+	return &leveldbIterator{s.snap.NewIterator(util.BytesPrefix(prefix), nil)}
+}
+
+func (s *leveldbSnapshot) NewRangeIterator(start, limit []byte) Iterator {
+	return &leveldbIterator{s.snap.NewIterator(&util.Range{Start: start, Limit: limit}, nil)}
+}
+
+func (s *leveldbSnapshot) Release() {
+	s.snap.Release()
+}
+
+type leveldbBatch struct {
+	*leveldb.Batch
+}
+
+func (b *leveldbBatch) Size() int {
+	return len(b.Dump())
+}
+
+type leveldbIterator struct {
+	iterator.Iterator
+}
+
+func (it *leveldbIterator) Error() error {
+	return wrapLevelDBError(it.Iterator.Error())
+}
+
+func wrapLevelDBError(err error) error {
+	switch err {
+	case leveldb.ErrNotFound:
+		return ErrNotFound
+	case leveldb.ErrClosed, leveldb.ErrSnapshotReleased, leveldb.ErrIterReleased:
+		return ErrClosed
+	}
+	return err
+}
+
+// A "better" version of leveldb's errors.IsCorrupted.
+func leveldbIsCorrupted(err error) bool {
+	switch {
+	case err == nil:
+		return false
+
+	case errors.IsCorrupted(err):
+		return true
+
+	case strings.Contains(err.Error(), "corrupted"):
+		return true
+	}
+
+	return false
+}
+
+func debugEnvValue(key string, def int) int {
+	v, err := strconv.ParseInt(os.Getenv("STDEBUG_"+key), 10, 63)
+	if err != nil {
+		return def
+	}
+	return int(v)
+}
@@ -11,8 +11,6 @@ import (
 	"fmt"
 
 	"github.com/syncthing/syncthing/lib/osutil"
-
-	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 var blockFinder *BlockFinder
@@ -47,7 +45,7 @@ func (f *BlockFinder) Iterate(folders []string, hash []byte, iterFn func(string,
 	var key []byte
 	for _, folder := range folders {
 		key = f.db.keyer.GenerateBlockMapKey(key, []byte(folder), hash, nil)
-		iter := t.NewIterator(util.BytesPrefix(key), nil)
+		iter := t.NewPrefixIterator(key)
 
 		for iter.Next() && iter.Error() == nil {
 			file := string(f.db.keyer.NameFromBlockMapKey(iter.Key()))
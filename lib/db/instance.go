@@ -9,11 +9,9 @@ package db
 import (
 	"bytes"
 	"encoding/binary"
-	"fmt"
 
+	"github.com/syncthing/syncthing/lib/db/backend"
 	"github.com/syncthing/syncthing/lib/protocol"
-	"github.com/syndtr/goleveldb/leveldb"
-	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 type instance struct {
@@ -136,7 +134,7 @@ func (db *instance) withHave(folder, device, prefix []byte, truncate bool, fn It
 		}
 	}
 
-	dbi := t.NewIterator(util.BytesPrefix(db.keyer.GenerateDeviceFileKey(nil, folder, device, prefix)), nil)
+	dbi := t.NewPrefixIterator(db.keyer.GenerateDeviceFileKey(nil, folder, device, prefix))
 	defer dbi.Release()
 
 	for dbi.Next() {
@@ -160,7 +158,7 @@ func (db *instance) withHaveSequence(folder []byte, startSeq int64, fn Iterator)
 	t := db.newReadOnlyTransaction()
 	defer t.close()
 
-	dbi := t.NewIterator(&util.Range{Start: db.keyer.GenerateSequenceKey(nil, folder, startSeq), Limit: db.keyer.GenerateSequenceKey(nil, folder, maxInt64)}, nil)
+	dbi := t.NewRangeIterator(db.keyer.GenerateSequenceKey(nil, folder, startSeq), db.keyer.GenerateSequenceKey(nil, folder, maxInt64))
 	defer dbi.Release()
 
 	for dbi.Next() {
@@ -186,7 +184,7 @@ func (db *instance) withAllFolderTruncated(folder []byte, fn func(device []byte,
 	t := db.newReadWriteTransaction()
 	defer t.close()
 
-	dbi := t.NewIterator(util.BytesPrefix(db.keyer.GenerateDeviceFileKey(nil, folder, nil, nil).WithoutNameAndDevice()), nil)
+	dbi := t.NewPrefixIterator(db.keyer.GenerateDeviceFileKey(nil, folder, nil, nil).WithoutNameAndDevice())
 	defer dbi.Release()
 
 	var gk, keyBuf []byte
@@ -256,7 +254,7 @@ func (db *instance) withGlobal(folder, prefix []byte, truncate bool, fn Iterator
 		}
 	}
 
-	dbi := t.NewIterator(util.BytesPrefix(db.keyer.GenerateGlobalVersionKey(nil, folder, prefix)), nil)
+	dbi := t.NewPrefixIterator(db.keyer.GenerateGlobalVersionKey(nil, folder, prefix))
 	defer dbi.Release()
 
 	var dk []byte
@@ -284,10 +282,74 @@ func (db *instance) withGlobal(folder, prefix []byte, truncate bool, fn Iterator
 	}
 }
 
+func (db *instance) withGlobalChildren(folder, dir []byte, truncate bool, fn Iterator) {
+	t := db.newReadOnlyTransaction()
+	defer t.close()
+
+	var prefix []byte
+	if len(dir) > 0 && !bytes.HasSuffix(dir, []byte{'/'}) {
+		prefix = append(dir, '/')
+	} else {
+		prefix = dir
+	}
+
+	start := db.keyer.GenerateGlobalVersionKey(nil, folder, prefix)
+	limit := prefixLimit(start)
+	var dk []byte
+	for start != nil {
+		dbi := t.NewRangeIterator(start, limit)
+		start = nil
+		for dbi.Next() {
+			name := db.keyer.NameFromGlobalVersionKey(dbi.Key())
+			if i := bytes.IndexByte(name[len(prefix):], '/'); i >= 0 {
+				// This is in a subdirectory, which we skip by continuing
+				// with the keys after all those starting with "subdir/".
+				skip := make([]byte, len(prefix)+i+1)
+				copy(skip, name)
+				skip[len(skip)-1] = '/' + 1
+				start = db.keyer.GenerateGlobalVersionKey(nil, folder, skip)
+				break
+			}
+
+			vl, ok := unmarshalVersionList(dbi.Value())
+			if !ok {
+				continue
+			}
+
+			dk = db.keyer.GenerateDeviceFileKey(dk, folder, vl.Versions[0].Device, name)
+
+			f, ok := t.getFileTrunc(dk, truncate)
+			if !ok {
+				continue
+			}
+
+			if !fn(f) {
+				dbi.Release()
+				return
+			}
+		}
+		dbi.Release()
+	}
+}
+
+// prefixLimit returns the smallest key that is larger than all keys with
+// the given prefix, or nil if there is none.
+func prefixLimit(prefix []byte) []byte {
+	limit := make([]byte, len(prefix))
+	copy(limit, prefix)
+	for i := len(limit) - 1; i >= 0; i-- {
+		if limit[i] < 0xff {
+			limit[i]++
+			return limit[:i+1]
+		}
+	}
+	return nil
+}
+
 func (db *instance) availability(folder, file []byte) []protocol.DeviceID {
 	k := db.keyer.GenerateGlobalVersionKey(nil, folder, file)
-	bs, err := db.Get(k, nil)
-	if err == leveldb.ErrNotFound {
+	bs, err := db.Get(k)
+	if err == backend.ErrNotFound {
 		return nil
 	}
 	if err != nil {
@@ -324,7 +386,7 @@ func (db *instance) withNeed(folder, device []byte, truncate bool, fn Iterator)
 	t := db.newReadOnlyTransaction()
 	defer t.close()
 
-	dbi := t.NewIterator(util.BytesPrefix(db.keyer.GenerateGlobalVersionKey(nil, folder, nil).WithoutName()), nil)
+	dbi := t.NewPrefixIterator(db.keyer.GenerateGlobalVersionKey(nil, folder, nil).WithoutName())
 	defer dbi.Release()
 
 	var dk []byte
@@ -385,7 +447,7 @@ func (db *instance) withNeedLocal(folder []byte, truncate bool, fn Iterator) {
 	t := db.newReadOnlyTransaction()
 	defer t.close()
 
-	dbi := t.NewIterator(util.BytesPrefix(db.keyer.GenerateNeedFileKey(nil, folder, nil).WithoutName()), nil)
+	dbi := t.NewPrefixIterator(db.keyer.GenerateNeedFileKey(nil, folder, nil).WithoutName())
 	defer dbi.Release()
 
 	var keyBuf []byte
@@ -426,7 +488,7 @@ func (db *instance) dropDeviceFolder(device, folder []byte, meta *metadataTracke
 	t := db.newReadWriteTransaction()
 	defer t.close()
 
-	dbi := t.NewIterator(util.BytesPrefix(db.keyer.GenerateDeviceFileKey(nil, folder, device, nil)), nil)
+	dbi := t.NewPrefixIterator(db.keyer.GenerateDeviceFileKey(nil, folder, device, nil))
 	defer dbi.Release()
 
 	var gk, keyBuf []byte
@@ -446,7 +508,7 @@ func (db *instance) checkGlobals(folder []byte, meta *metadataTracker) {
 	t := db.newReadWriteTransaction()
 	defer t.close()
 
-	dbi := t.NewIterator(util.BytesPrefix(db.keyer.GenerateGlobalVersionKey(nil, folder, nil).WithoutName()), nil)
+	dbi := t.NewPrefixIterator(db.keyer.GenerateGlobalVersionKey(nil, folder, nil).WithoutName())
 	defer dbi.Release()
 
 	var dk []byte
@@ -465,8 +527,8 @@ func (db *instance) checkGlobals(folder []byte, meta *metadataTracker) {
 		var newVL VersionList
 		for i, version := range vl.Versions {
 			dk = db.keyer.GenerateDeviceFileKey(dk, folder, version.Device, name)
-			_, err := t.Get(dk, nil)
-			if err == leveldb.ErrNotFound {
+			_, err := t.Get(dk)
+			if err == backend.ErrNotFound {
 				continue
 			}
 			if err != nil {
@@ -491,7 +553,7 @@ func (db *instance) checkGlobals(folder []byte, meta *metadataTracker) {
 }
 
 func (db *instance) getIndexID(device, folder []byte) protocol.IndexID {
-	cur, err := db.Get(db.keyer.GenerateIndexIDKey(nil, device, folder), nil)
+	cur, err := db.Get(db.keyer.GenerateIndexIDKey(nil, device, folder))
 	if err != nil {
 		return 0
 	}
@@ -506,7 +568,7 @@ func (db *instance) getIndexID(device, folder []byte) protocol.IndexID {
 
 func (db *instance) setIndexID(device, folder []byte, id protocol.IndexID) {
 	bs, _ := id.Marshal() // marshalling can't fail
-	if err := db.Put(db.keyer.GenerateIndexIDKey(nil, device, folder), bs, nil); err != nil && err != leveldb.ErrClosed {
+	if err := db.Put(db.keyer.GenerateIndexIDKey(nil, device, folder), bs); err != nil && err != backend.ErrClosed {
 		panic("storing index ID: " + err.Error())
 	}
 }
@@ -519,6 +581,10 @@ func (db *instance) dropFolderMeta(folder []byte) {
 	db.dropPrefix(db.keyer.GenerateFolderMetaKey(nil, folder))
 }
 
+func (db *instance) dropFolderData(folder []byte) {
+	db.dropPrefix(db.keyer.GenerateFolderDataKey(nil, folder))
+}
+
 func (db *instance) dropPrefix(prefix []byte) {
 	t := db.newReadWriteTransaction()
 	defer t.close()
@@ -551,15 +617,6 @@ func unmarshalVersionList(data []byte) (VersionList, bool) {
 	return vl, true
 }
 
-type errorSuggestion struct {
-	inner      error
-	suggestion string
-}
-
-func (e errorSuggestion) Error() string {
-	return fmt.Sprintf("%s (%s)", e.inner.Error(), e.suggestion)
-}
-
 // unchanged checks if two files are the same and thus don't need to be updated.
 // Local flags or the invalid bit might change without the version
 // being bumped. The IsInvalid() method handles both.
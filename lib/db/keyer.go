@@ -59,6 +59,9 @@ const (
 
 	// KeyTypeNeed <int32 folder ID> <file name> = <nothing>
 	KeyTypeNeed = 12
+
+	// KeyTypeFolderData <int32 folder ID> <some string> = some value
+	KeyTypeFolderData = 13
 )
 
 type keyer interface {
@@ -93,6 +96,9 @@ type keyer interface {
 
 	// Folder metadata
 	GenerateFolderMetaKey(key, folder []byte) folderMetaKey
+
+	// Miscellaneous folder data
+	GenerateFolderDataKey(key, folder []byte) folderDataKey
 }
 
 // defaultKeyer implements our key scheme. It needs folder and device
@@ -241,6 +247,15 @@ func (k defaultKeyer) GenerateFolderMetaKey(key, folder []byte) folderMetaKey {
 	return key
 }
 
+type folderDataKey []byte
+
+func (k defaultKeyer) GenerateFolderDataKey(key, folder []byte) folderDataKey {
+	key = resize(key, keyPrefixLen+keyFolderLen)
+	key[0] = KeyTypeFolderData
+	binary.BigEndian.PutUint32(key[keyPrefixLen:], k.folderIdx.ID(folder))
+	return key
+}
+
 // resize returns a byte slice of the specified size, reusing bs if possible
 func resize(bs []byte, size int) []byte {
 	if cap(bs) < size {
@@ -9,35 +9,24 @@ package db
 import (
 	"os"
 	"strconv"
-	"strings"
 	"sync"
 	"sync/atomic"
 
-	"github.com/syndtr/goleveldb/leveldb"
-	"github.com/syndtr/goleveldb/leveldb/errors"
-	"github.com/syndtr/goleveldb/leveldb/iterator"
-	"github.com/syndtr/goleveldb/leveldb/opt"
-	"github.com/syndtr/goleveldb/leveldb/storage"
-	"github.com/syndtr/goleveldb/leveldb/util"
-)
-
-const (
-	dbMaxOpenFiles = 100
-	dbWriteBuffer  = 16 << 20
+	"github.com/syncthing/syncthing/lib/db/backend"
 )
 
 var (
-	dbFlushBatch = debugEnvValue("WriteBuffer", dbWriteBuffer) / 4 // Some leeway for any leveldb in-memory optimizations
+	dbFlushBatch = debugEnvValue("WriteBuffer", 16<<20) / 4 // Some leeway for any leveldb in-memory optimizations
 )
 
 // Lowlevel is the lowest level database interface. It has a very simple
-// purpose: hold the actual *leveldb.DB database, and the in-memory state
+// purpose: hold the actual backend database, and the in-memory state
 // that belong to that database. In the same way that a single on disk
 // database can only be opened once, there should be only one Lowlevel for
-// any given *leveldb.DB.
+// any given backend.
 type Lowlevel struct {
 	committed int64 // atomic, must come first
-	*leveldb.DB
+	backend.Backend
 	location  string
 	folderIdx *smallIndex
 	deviceIdx *smallIndex
@@ -46,81 +35,41 @@ type Lowlevel struct {
 	iterWG    sync.WaitGroup
 }
 
-// Open attempts to open the database at the given location, and runs
-// recovery on it if opening fails. Worst case, if recovery is not possible,
-// the database is erased and created from scratch.
+// Open attempts to open the leveldb database at the given location, and
+// runs recovery on it if opening fails. Worst case, if recovery is not
+// possible, the database is erased and created from scratch.
 func Open(location string) (*Lowlevel, error) {
-	opts := &opt.Options{
-		BlockCacheCapacity:            debugEnvValue("BlockCacheCapacity", 0),
-		BlockCacheEvictRemoved:        debugEnvValue("BlockCacheEvictRemoved", 0) != 0,
-		BlockRestartInterval:          debugEnvValue("BlockRestartInterval", 0),
-		BlockSize:                     debugEnvValue("BlockSize", 0),
-		CompactionExpandLimitFactor:   debugEnvValue("CompactionExpandLimitFactor", 0),
-		CompactionGPOverlapsFactor:    debugEnvValue("CompactionGPOverlapsFactor", 0),
-		CompactionL0Trigger:           debugEnvValue("CompactionL0Trigger", 0),
-		CompactionSourceLimitFactor:   debugEnvValue("CompactionSourceLimitFactor", 0),
-		CompactionTableSize:           debugEnvValue("CompactionTableSize", 0),
-		CompactionTableSizeMultiplier: float64(debugEnvValue("CompactionTableSizeMultiplier", 0)) / 10.0,
-		CompactionTotalSize:           debugEnvValue("CompactionTotalSize", 0),
-		CompactionTotalSizeMultiplier: float64(debugEnvValue("CompactionTotalSizeMultiplier", 0)) / 10.0,
-		DisableBufferPool:             debugEnvValue("DisableBufferPool", 0) != 0,
-		DisableBlockCache:             debugEnvValue("DisableBlockCache", 0) != 0,
-		DisableCompactionBackoff:      debugEnvValue("DisableCompactionBackoff", 0) != 0,
-		DisableLargeBatchTransaction:  debugEnvValue("DisableLargeBatchTransaction", 0) != 0,
-		NoSync:                        debugEnvValue("NoSync", 0) != 0,
-		NoWriteMerge:                  debugEnvValue("NoWriteMerge", 0) != 0,
-		OpenFilesCacheCapacity:        debugEnvValue("OpenFilesCacheCapacity", dbMaxOpenFiles),
-		WriteBuffer:                   debugEnvValue("WriteBuffer", dbWriteBuffer),
-		// The write slowdown and pause can be overridden, but even if they
-		// are not and the compaction trigger is overridden we need to
-		// adjust so that we don't pause writes for L0 compaction before we
-		// even *start* L0 compaction...
-		WriteL0SlowdownTrigger: debugEnvValue("WriteL0SlowdownTrigger", 2*debugEnvValue("CompactionL0Trigger", opt.DefaultCompactionL0Trigger)),
-		WriteL0PauseTrigger:    debugEnvValue("WriteL0SlowdownTrigger", 3*debugEnvValue("CompactionL0Trigger", opt.DefaultCompactionL0Trigger)),
+	return OpenBackend(backend.TypeLevelDB, location)
+}
+
+// OpenBackend opens the database of the given type at the given location.
+func OpenBackend(t backend.Type, location string) (*Lowlevel, error) {
+	bk, err := backend.Open(t, location)
+	if err != nil {
+		return nil, err
 	}
-	return open(location, opts)
+	return NewLowlevel(bk, location), nil
 }
 
-// OpenRO attempts to open the database at the given location, read only.
+// OpenRO attempts to open the leveldb database at the given location, read
+// only.
 func OpenRO(location string) (*Lowlevel, error) {
-	opts := &opt.Options{
-		OpenFilesCacheCapacity: dbMaxOpenFiles,
-		ReadOnly:               true,
-	}
-	return open(location, opts)
+	return OpenBackendRO(backend.TypeLevelDB, location)
 }
 
-func open(location string, opts *opt.Options) (*Lowlevel, error) {
-	db, err := leveldb.OpenFile(location, opts)
-	if leveldbIsCorrupted(err) {
-		db, err = leveldb.RecoverFile(location, opts)
-	}
-	if leveldbIsCorrupted(err) {
-		// The database is corrupted, and we've tried to recover it but it
-		// didn't work. At this point there isn't much to do beyond dropping
-		// the database and reindexing...
-		l.Infoln("Database corruption detected, unable to recover. Reinitializing...")
-		if err := os.RemoveAll(location); err != nil {
-			return nil, errorSuggestion{err, "failed to delete corrupted database"}
-		}
-		db, err = leveldb.OpenFile(location, opts)
-	}
+// OpenBackendRO opens the database of the given type at the given location,
+// read only.
+func OpenBackendRO(t backend.Type, location string) (*Lowlevel, error) {
+	bk, err := backend.OpenRO(t, location)
 	if err != nil {
-		return nil, errorSuggestion{err, "is another instance of Syncthing running?"}
-	}
-
-	if debugEnvValue("CompactEverything", 0) != 0 {
-		if err := db.CompactRange(util.Range{}); err != nil {
-			l.Warnln("Compacting database:", err)
-		}
+		return nil, err
 	}
-	return NewLowlevel(db, location), nil
+	return NewLowlevel(bk, location), nil
 }
 
 // OpenMemory returns a new Lowlevel referencing an in-memory database.
 func OpenMemory() *Lowlevel {
-	db, _ := leveldb.Open(storage.NewMemStorage(), nil)
-	return NewLowlevel(db, "<memory>")
+	return NewLowlevel(backend.OpenLevelDBMemory(), "<memory>")
 }
 
 // ListFolders returns the list of folders currently in the database
@@ -133,42 +82,46 @@ func (db *Lowlevel) Committed() int64 {
 	return atomic.LoadInt64(&db.committed)
 }
 
-func (db *Lowlevel) Put(key, val []byte, wo *opt.WriteOptions) error {
+func (db *Lowlevel) Put(key, val []byte) error {
 	db.closeMut.RLock()
 	defer db.closeMut.RUnlock()
 	if db.closed {
-		return leveldb.ErrClosed
+		return backend.ErrClosed
 	}
 	atomic.AddInt64(&db.committed, 1)
-	return db.DB.Put(key, val, wo)
+	return db.Backend.Put(key, val)
 }
 
-func (db *Lowlevel) Write(batch *leveldb.Batch, wo *opt.WriteOptions) error {
+func (db *Lowlevel) Write(batch backend.Batch) error {
 	db.closeMut.RLock()
 	defer db.closeMut.RUnlock()
 	if db.closed {
-		return leveldb.ErrClosed
+		return backend.ErrClosed
 	}
-	return db.DB.Write(batch, wo)
+	return db.Backend.Write(batch)
 }
 
-func (db *Lowlevel) Delete(key []byte, wo *opt.WriteOptions) error {
+func (db *Lowlevel) Delete(key []byte) error {
 	db.closeMut.RLock()
 	defer db.closeMut.RUnlock()
 	if db.closed {
-		return leveldb.ErrClosed
+		return backend.ErrClosed
 	}
 	atomic.AddInt64(&db.committed, 1)
-	return db.DB.Delete(key, wo)
+	return db.Backend.Delete(key)
 }
 
-func (db *Lowlevel) NewIterator(slice *util.Range, ro *opt.ReadOptions) iterator.Iterator {
-	return db.newIterator(func() iterator.Iterator { return db.DB.NewIterator(slice, ro) })
+func (db *Lowlevel) NewPrefixIterator(prefix []byte) backend.Iterator {
+	return db.newIterator(func() backend.Iterator { return db.Backend.NewPrefixIterator(prefix) })
+}
+
+func (db *Lowlevel) NewRangeIterator(start, limit []byte) backend.Iterator {
+	return db.newIterator(func() backend.Iterator { return db.Backend.NewRangeIterator(start, limit) })
 }
 
 // newIterator returns an iterator created with the given constructor only if db
 // is not yet closed. If it is closed, a closedIter is returned instead.
-func (db *Lowlevel) newIterator(constr func() iterator.Iterator) iterator.Iterator {
+func (db *Lowlevel) newIterator(constr func() backend.Iterator) backend.Iterator {
 	db.closeMut.RLock()
 	defer db.closeMut.RUnlock()
 	if db.closed {
@@ -182,9 +135,9 @@ func (db *Lowlevel) newIterator(constr func() iterator.Iterator) iterator.Iterat
 }
 
 func (db *Lowlevel) GetSnapshot() snapshot {
-	s, err := db.DB.GetSnapshot()
+	s, err := db.Backend.NewSnapshot()
 	if err != nil {
-		if err == leveldb.ErrClosed {
+		if err == backend.ErrClosed {
 			return &closedSnap{}
 		}
 		panic(err)
@@ -204,106 +157,86 @@ func (db *Lowlevel) Close() {
 	db.closed = true
 	db.closeMut.Unlock()
 	db.iterWG.Wait()
-	db.DB.Close()
+	db.Backend.Close()
 }
 
-// NewLowlevel wraps the given *leveldb.DB into a *lowlevel
-func NewLowlevel(db *leveldb.DB, location string) *Lowlevel {
+// NewLowlevel wraps the given backend into a *Lowlevel
+func NewLowlevel(bk backend.Backend, location string) *Lowlevel {
 	return &Lowlevel{
-		DB:        db,
+		Backend:   bk,
 		location:  location,
-		folderIdx: newSmallIndex(db, []byte{KeyTypeFolderIdx}),
-		deviceIdx: newSmallIndex(db, []byte{KeyTypeDeviceIdx}),
+		folderIdx: newSmallIndex(bk, []byte{KeyTypeFolderIdx}),
+		deviceIdx: newSmallIndex(bk, []byte{KeyTypeDeviceIdx}),
 		closeMut:  &sync.RWMutex{},
 		iterWG:    sync.WaitGroup{},
 	}
 }
 
-// A "better" version of leveldb's errors.IsCorrupted.
-func leveldbIsCorrupted(err error) bool {
-	switch {
-	case err == nil:
-		return false
-
-	case errors.IsCorrupted(err):
-		return true
-
-	case strings.Contains(err.Error(), "corrupted"):
-		return true
-	}
-
-	return false
-}
-
 type batch struct {
-	*leveldb.Batch
+	backend.Batch
 	db *Lowlevel
 }
 
 func (db *Lowlevel) newBatch() *batch {
 	return &batch{
-		Batch: new(leveldb.Batch),
+		Batch: db.Backend.NewBatch(),
 		db:    db,
 	}
 }
 
 // checkFlush flushes and resets the batch if its size exceeds dbFlushBatch.
 func (b *batch) checkFlush() {
-	if len(b.Dump()) > dbFlushBatch {
+	if b.Size() > dbFlushBatch {
 		b.flush()
 		b.Reset()
 	}
 }
 
 func (b *batch) flush() {
-	if err := b.db.Write(b.Batch, nil); err != nil && err != leveldb.ErrClosed {
+	if err := b.db.Write(b.Batch); err != nil && err != backend.ErrClosed {
 		panic(err)
 	}
 }
 
 type closedIter struct{}
 
-func (it *closedIter) Release()                           {}
-func (it *closedIter) Key() []byte                        { return nil }
-func (it *closedIter) Value() []byte                      { return nil }
-func (it *closedIter) Next() bool                         { return false }
-func (it *closedIter) Prev() bool                         { return false }
-func (it *closedIter) First() bool                        { return false }
-func (it *closedIter) Last() bool                         { return false }
-func (it *closedIter) Seek(key []byte) bool               { return false }
-func (it *closedIter) Valid() bool                        { return false }
-func (it *closedIter) Error() error                       { return leveldb.ErrClosed }
-func (it *closedIter) SetReleaser(releaser util.Releaser) {}
+func (it *closedIter) Release()      {}
+func (it *closedIter) Key() []byte   { return nil }
+func (it *closedIter) Value() []byte { return nil }
+func (it *closedIter) Next() bool    { return false }
+func (it *closedIter) Error() error  { return backend.ErrClosed }
 
 type snapshot interface {
-	Get([]byte, *opt.ReadOptions) ([]byte, error)
-	Has([]byte, *opt.ReadOptions) (bool, error)
-	NewIterator(*util.Range, *opt.ReadOptions) iterator.Iterator
+	Get(key []byte) ([]byte, error)
+	NewPrefixIterator(prefix []byte) backend.Iterator
+	NewRangeIterator(start, limit []byte) backend.Iterator
 	Release()
 }
 
 type closedSnap struct{}
 
-func (s *closedSnap) Get([]byte, *opt.ReadOptions) ([]byte, error) { return nil, leveldb.ErrClosed }
-func (s *closedSnap) Has([]byte, *opt.ReadOptions) (bool, error)   { return false, leveldb.ErrClosed }
-func (s *closedSnap) NewIterator(*util.Range, *opt.ReadOptions) iterator.Iterator {
-	return &closedIter{}
-}
-func (s *closedSnap) Release() {}
+func (s *closedSnap) Get([]byte) ([]byte, error)                    { return nil, backend.ErrClosed }
+func (s *closedSnap) NewPrefixIterator([]byte) backend.Iterator     { return &closedIter{} }
+func (s *closedSnap) NewRangeIterator(_, _ []byte) backend.Iterator { return &closedIter{} }
+func (s *closedSnap) Release()                                      {}
 
 type snap struct {
-	*leveldb.Snapshot
+	backend.Snapshot
 	db *Lowlevel
 }
 
-func (s *snap) NewIterator(slice *util.Range, ro *opt.ReadOptions) iterator.Iterator {
-	return s.db.newIterator(func() iterator.Iterator { return s.Snapshot.NewIterator(slice, ro) })
+func (s *snap) NewPrefixIterator(prefix []byte) backend.Iterator {
+	return s.db.newIterator(func() backend.Iterator { return s.Snapshot.NewPrefixIterator(prefix) })
 }
 
-// iter implements iterator.Iterator which allows tracking active iterators
+func (s *snap) NewRangeIterator(start, limit []byte) backend.Iterator {
+	return s.db.newIterator(func() backend.Iterator { return s.Snapshot.NewRangeIterator(start, limit) })
+}
+
+// iter implements backend.Iterator which allows tracking active iterators
 // and aborts if the underlying database is being closed.
 type iter struct {
-	iterator.Iterator
+	backend.Iterator
 	db *Lowlevel
 }
 
@@ -313,30 +246,12 @@ func (it *iter) Release() {
 }
 
 func (it *iter) Next() bool {
-	return it.execIfNotClosed(it.Iterator.Next)
-}
-func (it *iter) Prev() bool {
-	return it.execIfNotClosed(it.Iterator.Prev)
-}
-func (it *iter) First() bool {
-	return it.execIfNotClosed(it.Iterator.First)
-}
-func (it *iter) Last() bool {
-	return it.execIfNotClosed(it.Iterator.Last)
-}
-func (it *iter) Seek(key []byte) bool {
-	return it.execIfNotClosed(func() bool {
-		return it.Iterator.Seek(key)
-	})
-}
-
-func (it *iter) execIfNotClosed(fn func() bool) bool {
 	it.db.closeMut.RLock()
 	defer it.db.closeMut.RUnlock()
 	if it.db.closed {
 		return false
 	}
-	return fn()
+	return it.Iterator.Next()
 }
 
 func debugEnvValue(key string, def int) int {
@@ -70,7 +70,7 @@ func (m *metadataTracker) toDB(db *instance, folder []byte) error {
 	if err != nil {
 		return err
 	}
-	err = db.Put(key, bs, nil)
+	err = db.Put(key, bs)
 	if err == nil {
 		m.dirty = false
 	}
@@ -82,7 +82,7 @@ func (m *metadataTracker) toDB(db *instance, folder []byte) error {
 // the database under the key corresponding to the given folder
 func (m *metadataTracker) fromDB(db *instance, folder []byte) error {
 	key := db.keyer.GenerateFolderMetaKey(nil, folder)
-	bs, err := db.Get(key, nil)
+	bs, err := db.Get(key)
 	if err != nil {
 		return err
 	}
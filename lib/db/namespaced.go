@@ -9,12 +9,10 @@ package db
 import (
 	"encoding/binary"
 	"time"
-
-	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 // NamespacedKV is a simple key-value store using a specific namespace within
-// a leveldb.
+// the database.
 type NamespacedKV struct {
 	db     *Lowlevel
 	prefix []byte
@@ -36,7 +34,7 @@ func NewNamespacedKV(db *Lowlevel, prefix string) *NamespacedKV {
 
 // Reset removes all entries in this namespace.
 func (n *NamespacedKV) Reset() {
-	it := n.db.NewIterator(util.BytesPrefix(n.prefix), nil)
+	it := n.db.NewPrefixIterator(n.prefix)
 	defer it.Release()
 	batch := n.db.newBatch()
 	for it.Next() {
@@ -51,13 +49,13 @@ func (n *NamespacedKV) Reset() {
 func (n *NamespacedKV) PutInt64(key string, val int64) {
 	var valBs [8]byte
 	binary.BigEndian.PutUint64(valBs[:], uint64(val))
-	n.db.Put(n.prefixedKey(key), valBs[:], nil)
+	n.db.Put(n.prefixedKey(key), valBs[:])
 }
 
 // Int64 returns the stored value interpreted as an int64 and a boolean that
 // is false if no value was stored at the key.
 func (n *NamespacedKV) Int64(key string) (int64, bool) {
-	valBs, err := n.db.Get(n.prefixedKey(key), nil)
+	valBs, err := n.db.Get(n.prefixedKey(key))
 	if err != nil {
 		return 0, false
 	}
@@ -69,14 +67,14 @@ func (n *NamespacedKV) Int64(key string) (int64, bool) {
 // type) is overwritten.
 func (n *NamespacedKV) PutTime(key string, val time.Time) {
 	valBs, _ := val.MarshalBinary() // never returns an error
-	n.db.Put(n.prefixedKey(key), valBs, nil)
+	n.db.Put(n.prefixedKey(key), valBs)
 }
 
 // Time returns the stored value interpreted as a time.Time and a boolean
 // that is false if no value was stored at the key.
 func (n NamespacedKV) Time(key string) (time.Time, bool) {
 	var t time.Time
-	valBs, err := n.db.Get(n.prefixedKey(key), nil)
+	valBs, err := n.db.Get(n.prefixedKey(key))
 	if err != nil {
 		return t, false
 	}
@@ -87,13 +85,13 @@ func (n NamespacedKV) Time(key string) (time.Time, bool) {
 // PutString stores a new string. Any existing value (even if of another type)
 // is overwritten.
 func (n *NamespacedKV) PutString(key, val string) {
-	n.db.Put(n.prefixedKey(key), []byte(val), nil)
+	n.db.Put(n.prefixedKey(key), []byte(val))
 }
 
 // String returns the stored value interpreted as a string and a boolean that
 // is false if no value was stored at the key.
 func (n NamespacedKV) String(key string) (string, bool) {
-	valBs, err := n.db.Get(n.prefixedKey(key), nil)
+	valBs, err := n.db.Get(n.prefixedKey(key))
 	if err != nil {
 		return "", false
 	}
@@ -103,13 +101,13 @@ func (n NamespacedKV) String(key string) (string, bool) {
 // PutBytes stores a new byte slice. Any existing value (even if of another type)
 // is overwritten.
 func (n *NamespacedKV) PutBytes(key string, val []byte) {
-	n.db.Put(n.prefixedKey(key), val, nil)
+	n.db.Put(n.prefixedKey(key), val)
 }
 
 // Bytes returns the stored value as a raw byte slice and a boolean that
 // is false if no value was stored at the key.
 func (n NamespacedKV) Bytes(key string) ([]byte, bool) {
-	valBs, err := n.db.Get(n.prefixedKey(key), nil)
+	valBs, err := n.db.Get(n.prefixedKey(key))
 	if err != nil {
 		return nil, false
 	}
@@ -120,16 +118,16 @@ func (n NamespacedKV) Bytes(key string) ([]byte, bool) {
 // is overwritten.
 func (n *NamespacedKV) PutBool(key string, val bool) {
 	if val {
-		n.db.Put(n.prefixedKey(key), []byte{0x0}, nil)
+		n.db.Put(n.prefixedKey(key), []byte{0x0})
 	} else {
-		n.db.Put(n.prefixedKey(key), []byte{0x1}, nil)
+		n.db.Put(n.prefixedKey(key), []byte{0x1})
 	}
 }
 
 // Bool returns the stored value as a boolean and a boolean that
 // is false if no value was stored at the key.
 func (n NamespacedKV) Bool(key string) (bool, bool) {
-	valBs, err := n.db.Get(n.prefixedKey(key), nil)
+	valBs, err := n.db.Get(n.prefixedKey(key))
 	if err != nil {
 		return false, false
 	}
@@ -139,7 +137,21 @@ func (n NamespacedKV) Bool(key string) (bool, bool) {
 // Delete deletes the specified key. It is allowed to delete a nonexistent
 // key.
 func (n NamespacedKV) Delete(key string) {
-	n.db.Delete(n.prefixedKey(key), nil)
+	n.db.Delete(n.prefixedKey(key))
+}
+
+// Each calls fn with the key, without the namespace, and the value of each
+// entry with a key starting with the given prefix, until fn returns false.
+func (n NamespacedKV) Each(prefix string, fn func(key string, val []byte) bool) {
+	it := n.db.NewPrefixIterator(n.prefixedKey(prefix))
+	defer it.Release()
+	for it.Next() {
+		key := string(it.Key()[len(n.prefix):])
+		val := append([]byte(nil), it.Value()...)
+		if !fn(key, val) {
+			return
+		}
+	}
 }
 
 func (n NamespacedKV) prefixedKey(key string) []byte {
@@ -110,3 +110,33 @@ func TestNamespacedReset(t *testing.T) {
 		t.Errorf("Incorrect return v %q != \"\" || ok %v != false", v, ok)
 	}
 }
+
+func TestNamespacedEach(t *testing.T) {
+	ldb := OpenMemory()
+
+	n1 := NewNamespacedKV(ldb, "foo")
+	n2 := NewNamespacedKV(ldb, "foobar")
+
+	n1.PutString("a/1", "yo1")
+	n1.PutString("a/2", "yo2")
+	n1.PutString("b/1", "yo3")
+	n2.PutString("a/3", "yo4")
+
+	got := make(map[string]string)
+	n1.Each("a/", func(key string, val []byte) bool {
+		got[key] = string(val)
+		return true
+	})
+	if len(got) != 2 || got["a/1"] != "yo1" || got["a/2"] != "yo2" {
+		t.Errorf("Incorrect entries %v", got)
+	}
+
+	calls := 0
+	n1.Each("", func(string, []byte) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Errorf("Incorrect number of calls %d != 1", calls)
+	}
+}
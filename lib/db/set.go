@@ -20,7 +20,6 @@ import (
 	"github.com/syncthing/syncthing/lib/osutil"
 	"github.com/syncthing/syncthing/lib/protocol"
 	"github.com/syncthing/syncthing/lib/sync"
-	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 type FileSet struct {
@@ -200,6 +199,13 @@ func (s *FileSet) WithPrefixedGlobalTruncated(prefix string, fn Iterator) {
 	s.db.withGlobal([]byte(s.folder), []byte(osutil.NormalizedFilename(prefix)), true, nativeFileIterator(fn))
 }
 
+// Only the items directly in dir are iterated, not dir itself nor anything
+// in its subdirectories. The empty dir is the folder root.
+func (s *FileSet) WithGlobalChildrenTruncated(dir string, fn Iterator) {
+	l.Debugf(`%s WithGlobalChildrenTruncated("%v")`, s.folder, dir)
+	s.db.withGlobalChildren([]byte(s.folder), []byte(osutil.NormalizedFilename(dir)), true, nativeFileIterator(fn))
+}
+
 func (s *FileSet) Get(device protocol.DeviceID, file string) (protocol.FileInfo, bool) {
 	f, ok := s.db.getFileDirty([]byte(s.folder), device[:], []byte(osutil.NormalizedFilename(file)))
 	f.Name = osutil.NativeFilename(f.Name)
@@ -273,6 +279,13 @@ func (s *FileSet) MtimeFS() *fs.MtimeFS {
 	return fs.NewMtimeFS(s.fs, kv)
 }
 
+// Namespace returns a KV store for other data about the folder, which is
+// dropped along with the folder. Each user picks a name of its own.
+func (s *FileSet) Namespace(name string) *NamespacedKV {
+	prefix := s.db.keyer.GenerateFolderDataKey(nil, []byte(s.folder))
+	return NewNamespacedKV(s.db.Lowlevel, string(prefix)+name+"/")
+}
+
 func (s *FileSet) ListDevices() []protocol.DeviceID {
 	return s.meta.devices()
 }
@@ -284,6 +297,7 @@ func DropFolder(ll *Lowlevel, folder string) {
 	db.dropFolder([]byte(folder))
 	db.dropMtimes([]byte(folder))
 	db.dropFolderMeta([]byte(folder))
+	db.dropFolderData([]byte(folder))
 
 	// Also clean out the folder ID mapping.
 	db.folderIdx.Delete([]byte(folder))
@@ -292,10 +306,10 @@ func DropFolder(ll *Lowlevel, folder string) {
 // DropDeltaIndexIDs removes all delta index IDs from the database.
 // This will cause a full index transmission on the next connection.
 func DropDeltaIndexIDs(db *Lowlevel) {
-	dbi := db.NewIterator(util.BytesPrefix([]byte{KeyTypeIndexID}), nil)
+	dbi := db.NewPrefixIterator([]byte{KeyTypeIndexID})
 	defer dbi.Release()
 	for dbi.Next() {
-		db.Delete(dbi.Key(), nil)
+		db.Delete(dbi.Key())
 	}
 }
 
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"sort"
 	"testing"
 	"time"
@@ -587,6 +588,10 @@ func TestListDropFolder(t *testing.T) {
 	}
 	replace(s1, remoteDevice0, local2)
 
+	kv0, kv1 := s0.Namespace("test"), s1.Namespace("test")
+	kv0.PutString("key", "value0")
+	kv1.PutString("key", "value1")
+
 	// Check that we have both folders and their data is in the global list
 
 	expectedFolderList := []string{"test0", "test1"}
@@ -616,6 +621,12 @@ func TestListDropFolder(t *testing.T) {
 	if l := len(globalList(s1)); l != 0 {
 		t.Errorf("Incorrect global length %d != 0 for s1", l)
 	}
+	if v, ok := kv0.String("key"); !ok || v != "value0" {
+		t.Errorf("Incorrect folder data %q for s0", v)
+	}
+	if v, ok := kv1.String("key"); ok {
+		t.Errorf("Folder data %q for s1 should have been dropped", v)
+	}
 }
 
 func TestGlobalNeedWithInvalid(t *testing.T) {
@@ -989,6 +1000,50 @@ func TestIssue4925(t *testing.T) {
 	}
 }
 
+func TestGlobalChildren(t *testing.T) {
+	ldb := db.OpenMemory()
+
+	s := db.NewFileSet("test", fs.NewFilesystem(fs.FilesystemTypeBasic, "."), ldb)
+	other := db.NewFileSet("other", fs.NewFilesystem(fs.FilesystemTypeBasic, "."), ldb)
+
+	replace(s, protocol.LocalDeviceID, fileList{
+		protocol.FileInfo{Name: "a"},
+		protocol.FileInfo{Name: "dir"},
+		protocol.FileInfo{Name: "dir.file"},
+		protocol.FileInfo{Name: "dir/a"},
+		protocol.FileInfo{Name: "dir/a.txt"},
+		protocol.FileInfo{Name: "dir/a/x"},
+		protocol.FileInfo{Name: "dir/a/y/z"},
+		protocol.FileInfo{Name: "dir/b"},
+		protocol.FileInfo{Name: "dir/b/c"},
+		protocol.FileInfo{Name: "dir0"},
+	})
+	replace(other, protocol.LocalDeviceID, fileList{
+		protocol.FileInfo{Name: "b"},
+	})
+
+	children := func(dir string) []string {
+		var names []string
+		s.WithGlobalChildrenTruncated(dir, func(fi db.FileIntf) bool {
+			names = append(names, filepath.ToSlash(fi.FileName()))
+			return true
+		})
+		return names
+	}
+
+	for dir, expected := range map[string][]string{
+		"":      {"a", "dir", "dir.file", "dir0"},
+		"dir":   {"dir/a", "dir/a.txt", "dir/b"},
+		"dir/":  {"dir/a", "dir/a.txt", "dir/b"},
+		"dir/a": {"dir/a/x"},
+		"a":     nil,
+	} {
+		if names := children(dir); !reflect.DeepEqual(names, expected) {
+			t.Errorf("Expected children %v of %q, got %v", expected, dir, names)
+		}
+	}
+}
+
 func TestMoveGlobalBack(t *testing.T) {
 	ldb := db.OpenMemory()
 
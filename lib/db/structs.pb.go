@@ -34,7 +34,7 @@ func (m *FileVersion) Reset()         { *m = FileVersion{} }
 func (m *FileVersion) String() string { return proto.CompactTextString(m) }
 func (*FileVersion) ProtoMessage()    {}
 func (*FileVersion) Descriptor() ([]byte, []int) {
-	return fileDescriptor_structs_6691fa5e0cf0de4d, []int{0}
+	return fileDescriptor_structs_3087e98920c417c3, []int{0}
 }
 func (m *FileVersion) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -70,7 +70,7 @@ type VersionList struct {
 func (m *VersionList) Reset()      { *m = VersionList{} }
 func (*VersionList) ProtoMessage() {}
 func (*VersionList) Descriptor() ([]byte, []int) {
-	return fileDescriptor_structs_6691fa5e0cf0de4d, []int{1}
+	return fileDescriptor_structs_3087e98920c417c3, []int{1}
 }
 func (m *VersionList) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -114,8 +114,12 @@ type FileInfoTruncated struct {
 	Version       protocol.Vector                                     `protobuf:"bytes,9,opt,name=version,proto3" json:"version"`
 	Sequence      int64                                               `protobuf:"varint,10,opt,name=sequence,proto3" json:"sequence,omitempty"`
 	RawBlockSize  int32                                               `protobuf:"varint,13,opt,name=block_size,json=blockSize,proto3" json:"block_size,omitempty"`
-	// repeated BlockInfo Blocks         = 16
-	SymlinkTarget string `protobuf:"bytes,17,opt,name=symlink_target,json=symlinkTarget,proto3" json:"symlink_target,omitempty"`
+	// repeated BlockInfo  Blocks         = 16
+	SymlinkTarget string                 `protobuf:"bytes,17,opt,name=symlink_target,json=symlinkTarget,proto3" json:"symlink_target,omitempty"`
+	Chunking      protocol.BlockChunking `protobuf:"varint,18,opt,name=chunking,proto3,enum=protocol.BlockChunking" json:"chunking,omitempty"`
+	Encrypted     []byte                 `protobuf:"bytes,19,opt,name=encrypted,proto3" json:"encrypted,omitempty"`
+	Xattrs        *protocol.Xattrs       `protobuf:"bytes,20,opt,name=xattrs,proto3" json:"xattrs,omitempty"`
+	Ownership     *protocol.Ownership    `protobuf:"bytes,21,opt,name=ownership,proto3" json:"ownership,omitempty"`
 	// see bep.proto
 	LocalFlags uint32 `protobuf:"varint,1000,opt,name=local_flags,json=localFlags,proto3" json:"local_flags,omitempty"`
 }
@@ -123,7 +127,7 @@ type FileInfoTruncated struct {
 func (m *FileInfoTruncated) Reset()      { *m = FileInfoTruncated{} }
 func (*FileInfoTruncated) ProtoMessage() {}
 func (*FileInfoTruncated) Descriptor() ([]byte, []int) {
-	return fileDescriptor_structs_6691fa5e0cf0de4d, []int{2}
+	return fileDescriptor_structs_3087e98920c417c3, []int{2}
 }
 func (m *FileInfoTruncated) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -169,7 +173,7 @@ func (m *Counts) Reset()         { *m = Counts{} }
 func (m *Counts) String() string { return proto.CompactTextString(m) }
 func (*Counts) ProtoMessage()    {}
 func (*Counts) Descriptor() ([]byte, []int) {
-	return fileDescriptor_structs_6691fa5e0cf0de4d, []int{3}
+	return fileDescriptor_structs_3087e98920c417c3, []int{3}
 }
 func (m *Counts) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -207,7 +211,7 @@ func (m *CountsSet) Reset()         { *m = CountsSet{} }
 func (m *CountsSet) String() string { return proto.CompactTextString(m) }
 func (*CountsSet) ProtoMessage()    {}
 func (*CountsSet) Descriptor() ([]byte, []int) {
-	return fileDescriptor_structs_6691fa5e0cf0de4d, []int{4}
+	return fileDescriptor_structs_3087e98920c417c3, []int{4}
 }
 func (m *CountsSet) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -422,6 +426,45 @@ func (m *FileInfoTruncated) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintStructs(dAtA, i, uint64(len(m.SymlinkTarget)))
 		i += copy(dAtA[i:], m.SymlinkTarget)
 	}
+	if m.Chunking != 0 {
+		dAtA[i] = 0x90
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintStructs(dAtA, i, uint64(m.Chunking))
+	}
+	if len(m.Encrypted) > 0 {
+		dAtA[i] = 0x9a
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintStructs(dAtA, i, uint64(len(m.Encrypted)))
+		i += copy(dAtA[i:], m.Encrypted)
+	}
+	if m.Xattrs != nil {
+		dAtA[i] = 0xa2
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintStructs(dAtA, i, uint64(m.Xattrs.ProtoSize()))
+		n3, err := m.Xattrs.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n3
+	}
+	if m.Ownership != nil {
+		dAtA[i] = 0xaa
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintStructs(dAtA, i, uint64(m.Ownership.ProtoSize()))
+		n4, err := m.Ownership.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n4
+	}
 	if m.LocalFlags != 0 {
 		dAtA[i] = 0xc0
 		i++
@@ -621,6 +664,21 @@ func (m *FileInfoTruncated) ProtoSize() (n int) {
 	if l > 0 {
 		n += 2 + l + sovStructs(uint64(l))
 	}
+	if m.Chunking != 0 {
+		n += 2 + sovStructs(uint64(m.Chunking))
+	}
+	l = len(m.Encrypted)
+	if l > 0 {
+		n += 2 + l + sovStructs(uint64(l))
+	}
+	if m.Xattrs != nil {
+		l = m.Xattrs.ProtoSize()
+		n += 2 + l + sovStructs(uint64(l))
+	}
+	if m.Ownership != nil {
+		l = m.Ownership.ProtoSize()
+		n += 2 + l + sovStructs(uint64(l))
+	}
 	if m.LocalFlags != 0 {
 		n += 2 + sovStructs(uint64(m.LocalFlags))
 	}
@@ -1233,6 +1291,122 @@ func (m *FileInfoTruncated) Unmarshal(dAtA []byte) error {
 			}
 			m.SymlinkTarget = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
+		case 18:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Chunking", wireType)
+			}
+			m.Chunking = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStructs
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Chunking |= (protocol.BlockChunking(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 19:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Encrypted", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStructs
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthStructs
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Encrypted = append(m.Encrypted[:0], dAtA[iNdEx:postIndex]...)
+			if m.Encrypted == nil {
+				m.Encrypted = []byte{}
+			}
+			iNdEx = postIndex
+		case 20:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Xattrs", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStructs
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthStructs
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Xattrs == nil {
+				m.Xattrs = &protocol.Xattrs{}
+			}
+			if err := m.Xattrs.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 21:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ownership", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStructs
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthStructs
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Ownership == nil {
+				m.Ownership = &protocol.Ownership{}
+			}
+			if err := m.Ownership.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		case 1000:
 			if wireType != 0 {
 				return fmt.Errorf("proto: wrong wireType = %d for field LocalFlags", wireType)
@@ -1692,51 +1866,56 @@ var (
 	ErrIntOverflowStructs   = fmt.Errorf("proto: integer overflow")
 )
 
-func init() { proto.RegisterFile("structs.proto", fileDescriptor_structs_6691fa5e0cf0de4d) }
+func init() { proto.RegisterFile("structs.proto", fileDescriptor_structs_3087e98920c417c3) }
 
-var fileDescriptor_structs_6691fa5e0cf0de4d = []byte{
-	// 684 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x53, 0x4f, 0x6b, 0xdb, 0x4e,
-	0x10, 0xb5, 0x62, 0xf9, 0xdf, 0xd8, 0xce, 0x2f, 0x59, 0x42, 0x10, 0x86, 0x9f, 0x2c, 0x5c, 0x0a,
-	0xa2, 0x07, 0xbb, 0x4d, 0x6e, 0xed, 0xcd, 0x0d, 0x01, 0x43, 0x69, 0xcb, 0x3a, 0xe4, 0x54, 0x30,
-	0xfa, 0xb3, 0x76, 0x96, 0xc8, 0x5a, 0x47, 0xbb, 0x4e, 0x70, 0x3e, 0x45, 0x8f, 0x3d, 0xe6, 0xe3,
-	0xe4, 0x98, 0x63, 0xe9, 0xc1, 0xa4, 0x76, 0x0f, 0xfd, 0x18, 0x65, 0x77, 0x25, 0x45, 0xcd, 0xa9,
-	0xb7, 0x79, 0x6f, 0x67, 0x77, 0xde, 0xcc, 0xbc, 0x85, 0x36, 0x17, 0xc9, 0x32, 0x10, 0xbc, 0xbf,
-	0x48, 0x98, 0x60, 0x68, 0x27, 0xf4, 0x3b, 0x2f, 0x12, 0xb2, 0x60, 0x7c, 0xa0, 0x08, 0x7f, 0x39,
-	0x1d, 0xcc, 0xd8, 0x8c, 0x29, 0xa0, 0x22, 0x9d, 0xd8, 0x39, 0x8c, 0xa8, 0xaf, 0x53, 0x02, 0x16,
-	0x0d, 0x7c, 0xb2, 0xd0, 0x7c, 0xef, 0x0a, 0x9a, 0xa7, 0x34, 0x22, 0xe7, 0x24, 0xe1, 0x94, 0xc5,
-	0xe8, 0x35, 0xd4, 0xae, 0x75, 0x68, 0x19, 0x8e, 0xe1, 0x36, 0x8f, 0xf6, 0xfa, 0xd9, 0xa5, 0xfe,
-	0x39, 0x09, 0x04, 0x4b, 0x86, 0xe6, 0xfd, 0xba, 0x5b, 0xc2, 0x59, 0x1a, 0x3a, 0x84, 0x6a, 0x48,
-	0xae, 0x69, 0x40, 0xac, 0x1d, 0xc7, 0x70, 0x5b, 0x38, 0x45, 0xc8, 0x82, 0x1a, 0x8d, 0xaf, 0xbd,
-	0x88, 0x86, 0x56, 0xd9, 0x31, 0xdc, 0x3a, 0xce, 0x60, 0xef, 0x14, 0x9a, 0x69, 0xb9, 0x0f, 0x94,
-	0x0b, 0xf4, 0x06, 0xea, 0xe9, 0x5b, 0xdc, 0x32, 0x9c, 0xb2, 0xdb, 0x3c, 0xfa, 0xaf, 0x1f, 0xfa,
-	0xfd, 0x82, 0xaa, 0xb4, 0x64, 0x9e, 0xf6, 0xd6, 0xfc, 0x76, 0xd7, 0x2d, 0xf5, 0x1e, 0x4d, 0xd8,
-	0x97, 0x59, 0xa3, 0x78, 0xca, 0xce, 0x92, 0x65, 0x1c, 0x78, 0x82, 0x84, 0x08, 0x81, 0x19, 0x7b,
-	0x73, 0xa2, 0xe4, 0x37, 0xb0, 0x8a, 0xd1, 0x2b, 0x30, 0xc5, 0x6a, 0xa1, 0x15, 0xee, 0x1e, 0x1d,
-	0x3e, 0xb5, 0x94, 0x5f, 0x5f, 0x2d, 0x08, 0x56, 0x39, 0xf2, 0x3e, 0xa7, 0xb7, 0x44, 0x89, 0x2e,
-	0x63, 0x15, 0x23, 0x07, 0x9a, 0x0b, 0x92, 0xcc, 0x29, 0xd7, 0x2a, 0x4d, 0xc7, 0x70, 0xdb, 0xb8,
-	0x48, 0xa1, 0xff, 0x01, 0xe6, 0x2c, 0xa4, 0x53, 0x4a, 0xc2, 0x09, 0xb7, 0x2a, 0xea, 0x6e, 0x23,
-	0x63, 0xc6, 0x72, 0x18, 0x21, 0x89, 0x88, 0x20, 0xa1, 0x55, 0xd5, 0xc3, 0x48, 0x21, 0x72, 0x9f,
-	0xc6, 0x54, 0x93, 0x27, 0xc3, 0xdd, 0xcd, 0xba, 0x0b, 0xd8, 0xbb, 0x19, 0x69, 0x36, 0x1f, 0x1b,
-	0x7a, 0x09, 0xbb, 0x31, 0x9b, 0x14, 0x75, 0xd4, 0xd5, 0x53, 0xed, 0x98, 0x7d, 0x2e, 0x28, 0x29,
-	0x6c, 0xb0, 0xf1, 0x6f, 0x1b, 0xec, 0x40, 0x9d, 0x93, 0xab, 0x25, 0x89, 0x03, 0x62, 0x81, 0x52,
-	0x9e, 0x63, 0xd4, 0x85, 0x66, 0xde, 0x57, 0xcc, 0xad, 0xa6, 0x63, 0xb8, 0x15, 0x9c, 0xb7, 0xfa,
-	0x91, 0xa3, 0x2f, 0x85, 0x04, 0x7f, 0x65, 0xb5, 0x1c, 0xc3, 0x35, 0x87, 0xef, 0x64, 0x81, 0x1f,
-	0xeb, 0xee, 0xf1, 0x8c, 0x8a, 0x8b, 0xa5, 0xdf, 0x0f, 0xd8, 0x7c, 0xc0, 0x57, 0x71, 0x20, 0x2e,
-	0x68, 0x3c, 0x2b, 0x44, 0x45, 0x4f, 0xf6, 0xc7, 0x17, 0x2c, 0x11, 0xa3, 0x93, 0xa7, 0xd7, 0x87,
-	0x2b, 0x34, 0x00, 0xf0, 0x23, 0x16, 0x5c, 0x4e, 0xd4, 0x4a, 0xda, 0xb2, 0xfa, 0x70, 0x6f, 0xb3,
-	0xee, 0xb6, 0xb0, 0x77, 0x33, 0x94, 0x07, 0x63, 0x7a, 0x4b, 0x70, 0xc3, 0xcf, 0x42, 0x39, 0x24,
-	0xbe, 0x9a, 0x47, 0x34, 0xbe, 0x9c, 0x08, 0x2f, 0x99, 0x11, 0x61, 0xed, 0x2b, 0x1f, 0xb4, 0x53,
-	0xf6, 0x4c, 0x91, 0x72, 0xa1, 0x11, 0x0b, 0xbc, 0x68, 0x32, 0x8d, 0xbc, 0x19, 0xb7, 0x7e, 0xd7,
-	0xd4, 0x46, 0x41, 0x71, 0xa7, 0x92, 0x4a, 0x2d, 0xf6, 0xcb, 0x80, 0xea, 0x7b, 0xb6, 0x8c, 0x05,
-	0x47, 0x07, 0x50, 0x99, 0xd2, 0x88, 0x70, 0x65, 0xac, 0x0a, 0xd6, 0x40, 0x3e, 0x14, 0xd2, 0x44,
-	0x8d, 0x95, 0x12, 0xae, 0x0c, 0x56, 0xc1, 0x45, 0x4a, 0x4d, 0x57, 0xd7, 0xe6, 0xca, 0x53, 0x15,
-	0x9c, 0xe3, 0xa2, 0x2d, 0x4c, 0x75, 0x94, 0xdb, 0xe2, 0x00, 0x2a, 0xfe, 0x4a, 0x90, 0xcc, 0x4a,
-	0x1a, 0xfc, 0xb5, 0xa9, 0xea, 0xb3, 0x4d, 0x75, 0xa0, 0xae, 0x7f, 0xde, 0xe8, 0x44, 0xf5, 0xdc,
-	0xc2, 0x39, 0x46, 0x36, 0x14, 0x5a, 0xb3, 0xd0, 0xf3, 0x66, 0x7b, 0x9f, 0xa0, 0xa1, 0xbb, 0x1c,
-	0x13, 0x81, 0x5c, 0xa8, 0x06, 0x0a, 0xa4, 0xbf, 0x11, 0xe4, 0x6f, 0xd4, 0xc7, 0xa9, 0x73, 0xd2,
-	0x73, 0x29, 0x3f, 0x48, 0x88, 0xfc, 0x75, 0xaa, 0xf1, 0x32, 0xce, 0xe0, 0xd0, 0xb9, 0xff, 0x69,
-	0x97, 0xee, 0x37, 0xb6, 0xf1, 0xb0, 0xb1, 0x8d, 0xc7, 0x8d, 0x5d, 0xfa, 0xba, 0xb5, 0x4b, 0x77,
-	0x5b, 0xdb, 0x78, 0xd8, 0xda, 0xa5, 0xef, 0x5b, 0xbb, 0xe4, 0x57, 0xd5, 0xde, 0x8f, 0xff, 0x04,
-	0x00, 0x00, 0xff, 0xff, 0x26, 0xe4, 0x54, 0xca, 0xd0, 0x04, 0x00, 0x00,
+var fileDescriptor_structs_3087e98920c417c3 = []byte{
+	// 757 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x54, 0x4b, 0x4f, 0x2b, 0x37,
+	0x18, 0xcd, 0x90, 0xb7, 0x93, 0x50, 0x30, 0x94, 0x5a, 0x51, 0x3b, 0x19, 0xa5, 0xaa, 0x34, 0xea,
+	0x22, 0x29, 0xb0, 0x6b, 0x77, 0x01, 0x21, 0x45, 0xaa, 0x4a, 0xe5, 0x20, 0xd4, 0x45, 0xa5, 0x68,
+	0x1e, 0x4e, 0x62, 0x31, 0xb1, 0x87, 0xb1, 0x03, 0x1d, 0x7e, 0x05, 0xcb, 0x2e, 0xf9, 0x39, 0x2c,
+	0x59, 0x56, 0x5d, 0x44, 0x6d, 0x72, 0x17, 0xf7, 0x67, 0x5c, 0xd9, 0xf3, 0xc8, 0x5c, 0x56, 0x77,
+	0xe7, 0x73, 0xbe, 0x63, 0xfb, 0x7b, 0x1c, 0x1b, 0x74, 0x84, 0x8c, 0x56, 0x9e, 0x14, 0x83, 0x30,
+	0xe2, 0x92, 0xc3, 0x3d, 0xdf, 0xed, 0x7e, 0x1f, 0x91, 0x90, 0x8b, 0xa1, 0x26, 0xdc, 0xd5, 0x6c,
+	0x38, 0xe7, 0x73, 0xae, 0x81, 0x5e, 0x25, 0xc2, 0xee, 0x49, 0x40, 0xdd, 0x44, 0xe2, 0xf1, 0x60,
+	0xe8, 0x92, 0x30, 0xe1, 0xfb, 0xf7, 0xa0, 0x75, 0x45, 0x03, 0x72, 0x4b, 0x22, 0x41, 0x39, 0x83,
+	0x3f, 0x81, 0xfa, 0x43, 0xb2, 0x44, 0x86, 0x65, 0xd8, 0xad, 0xb3, 0x83, 0x41, 0xb6, 0x69, 0x70,
+	0x4b, 0x3c, 0xc9, 0xa3, 0x51, 0xe5, 0x75, 0xdd, 0x2b, 0xe1, 0x4c, 0x06, 0x4f, 0x40, 0xcd, 0x27,
+	0x0f, 0xd4, 0x23, 0x68, 0xcf, 0x32, 0xec, 0x36, 0x4e, 0x11, 0x44, 0xa0, 0x4e, 0xd9, 0x83, 0x13,
+	0x50, 0x1f, 0x95, 0x2d, 0xc3, 0x6e, 0xe0, 0x0c, 0xf6, 0xaf, 0x40, 0x2b, 0xbd, 0xee, 0x57, 0x2a,
+	0x24, 0x3c, 0x05, 0x8d, 0xf4, 0x2c, 0x81, 0x0c, 0xab, 0x6c, 0xb7, 0xce, 0xbe, 0x1a, 0xf8, 0xee,
+	0xa0, 0x90, 0x55, 0x7a, 0x65, 0x2e, 0xfb, 0xb9, 0xf2, 0xf7, 0x4b, 0xaf, 0xd4, 0x7f, 0xae, 0x81,
+	0x43, 0xa5, 0x1a, 0xb3, 0x19, 0xbf, 0x89, 0x56, 0xcc, 0x73, 0x24, 0xf1, 0x21, 0x04, 0x15, 0xe6,
+	0x2c, 0x89, 0x4e, 0xbf, 0x89, 0xf5, 0x1a, 0xfe, 0x08, 0x2a, 0x32, 0x0e, 0x93, 0x0c, 0xf7, 0xcf,
+	0x4e, 0x76, 0x25, 0xe5, 0xdb, 0xe3, 0x90, 0x60, 0xad, 0x51, 0xfb, 0x05, 0x7d, 0x22, 0x3a, 0xe9,
+	0x32, 0xd6, 0x6b, 0x68, 0x81, 0x56, 0x48, 0xa2, 0x25, 0x15, 0x49, 0x96, 0x15, 0xcb, 0xb0, 0x3b,
+	0xb8, 0x48, 0xc1, 0xef, 0x00, 0x58, 0x72, 0x9f, 0xce, 0x28, 0xf1, 0xa7, 0x02, 0x55, 0xf5, 0xde,
+	0x66, 0xc6, 0x4c, 0x54, 0x33, 0x7c, 0x12, 0x10, 0x49, 0x7c, 0x54, 0x4b, 0x9a, 0x91, 0x42, 0x68,
+	0xef, 0xda, 0x54, 0x57, 0x91, 0xd1, 0xfe, 0x66, 0xdd, 0x03, 0xd8, 0x79, 0x1c, 0x27, 0x6c, 0xde,
+	0x36, 0xf8, 0x03, 0xd8, 0x67, 0x7c, 0x5a, 0xcc, 0xa3, 0xa1, 0x8f, 0xea, 0x30, 0xfe, 0x7b, 0x21,
+	0x93, 0xc2, 0x04, 0x9b, 0x5f, 0x36, 0xc1, 0x2e, 0x68, 0x08, 0x72, 0xbf, 0x22, 0xcc, 0x23, 0x08,
+	0xe8, 0xcc, 0x73, 0x0c, 0x7b, 0xa0, 0x95, 0xd7, 0xc5, 0x04, 0x6a, 0x59, 0x86, 0x5d, 0xc5, 0x79,
+	0xa9, 0xbf, 0x09, 0xf8, 0x67, 0x41, 0xe0, 0xc6, 0xa8, 0x6d, 0x19, 0x76, 0x65, 0xf4, 0x8b, 0xba,
+	0xe0, 0xdf, 0x75, 0xef, 0x7c, 0x4e, 0xe5, 0x62, 0xe5, 0x0e, 0x3c, 0xbe, 0x1c, 0x8a, 0x98, 0x79,
+	0x72, 0x41, 0xd9, 0xbc, 0xb0, 0x2a, 0x7a, 0x72, 0x30, 0x59, 0xf0, 0x48, 0x8e, 0x2f, 0x77, 0xa7,
+	0x8f, 0x62, 0x38, 0x04, 0xc0, 0x0d, 0xb8, 0x77, 0x37, 0xd5, 0x23, 0xe9, 0xa8, 0xdb, 0x47, 0x07,
+	0x9b, 0x75, 0xaf, 0x8d, 0x9d, 0xc7, 0x91, 0x0a, 0x4c, 0xe8, 0x13, 0xc1, 0x4d, 0x37, 0x5b, 0xaa,
+	0x26, 0x89, 0x78, 0x19, 0x50, 0x76, 0x37, 0x95, 0x4e, 0x34, 0x27, 0x12, 0x1d, 0x6a, 0x1f, 0x74,
+	0x52, 0xf6, 0x46, 0x93, 0xf0, 0x1c, 0x34, 0xbc, 0xc5, 0x8a, 0xdd, 0x51, 0x36, 0x47, 0x50, 0x9b,
+	0xe2, 0x9b, 0x5d, 0x97, 0xf4, 0xc1, 0x17, 0x69, 0x18, 0xe7, 0x42, 0xf8, 0x2d, 0x68, 0x12, 0xe6,
+	0x45, 0x71, 0xa8, 0xc6, 0x78, 0xa4, 0xcd, 0xbe, 0x23, 0xa0, 0x0d, 0x6a, 0x7f, 0x39, 0x52, 0x46,
+	0x02, 0x1d, 0xbf, 0x6f, 0xfb, 0x1f, 0x9a, 0xc7, 0x69, 0x1c, 0x9e, 0x82, 0x26, 0x7f, 0x64, 0x24,
+	0x12, 0x0b, 0x1a, 0xa2, 0xaf, 0xb5, 0xf8, 0x68, 0x27, 0xbe, 0xce, 0x42, 0x78, 0xa7, 0x52, 0x06,
+	0x0c, 0xb8, 0xe7, 0x04, 0xd3, 0x59, 0xe0, 0xcc, 0x05, 0xfa, 0x58, 0xd7, 0x0e, 0x04, 0x9a, 0xbb,
+	0x52, 0x54, 0xfa, 0x24, 0x3e, 0x18, 0xa0, 0x76, 0xc1, 0x57, 0x4c, 0x0a, 0x78, 0x0c, 0xaa, 0x33,
+	0x1a, 0x10, 0xa1, 0x1f, 0x42, 0x15, 0x27, 0x40, 0x1d, 0xe4, 0xd3, 0x48, 0xdb, 0x80, 0x12, 0xa1,
+	0x1f, 0x44, 0x15, 0x17, 0x29, 0xed, 0x86, 0xa4, 0x57, 0x42, 0xbf, 0x81, 0x2a, 0xce, 0x71, 0xd1,
+	0xc6, 0x15, 0x1d, 0xca, 0xa0, 0xba, 0xcd, 0x8d, 0x25, 0xc9, 0xac, 0x9f, 0x80, 0xcf, 0x9c, 0x55,
+	0x7b, 0xe7, 0xac, 0x2e, 0x68, 0x24, 0x3f, 0xc5, 0xf8, 0x52, 0xcf, 0xa8, 0x8d, 0x73, 0x0c, 0x4d,
+	0x50, 0x28, 0x0d, 0xc1, 0xf7, 0xc5, 0xf6, 0xaf, 0x41, 0x33, 0xa9, 0x72, 0x42, 0xa4, 0x6a, 0xbc,
+	0xa7, 0x41, 0xfa, 0x7b, 0x00, 0xf5, 0x7b, 0x24, 0xe1, 0xd4, 0xe9, 0x69, 0x5c, 0xa5, 0xef, 0x45,
+	0x44, 0xfd, 0x12, 0xba, 0xf0, 0x32, 0xce, 0xe0, 0xc8, 0x7a, 0xfd, 0xdf, 0x2c, 0xbd, 0x6e, 0x4c,
+	0xe3, 0x6d, 0x63, 0x1a, 0xff, 0x6d, 0xcc, 0xd2, 0xf3, 0xd6, 0x2c, 0xbd, 0x6c, 0x4d, 0xe3, 0x6d,
+	0x6b, 0x96, 0xfe, 0xd9, 0x9a, 0x25, 0xb7, 0xa6, 0x07, 0x74, 0xfe, 0x69, 0x00, 0x02, 0x7f, 0x3b,
+	0x50, 0x80, 0x05, 0x00, 0x00,
 }
@@ -0,0 +1,57 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package db
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestFileInfoTruncatedUnmarshal(t *testing.T) {
+	f := protocol.FileInfo{
+		Name:      "file",
+		Size:      42,
+		Version:   protocol.Vector{}.Update(protocol.LocalDeviceID.Short()),
+		Blocks:    []protocol.BlockInfo{{Size: 42, Hash: []byte("hash")}},
+		Encrypted: []byte("encrypted"),
+		Xattrs:    &protocol.Xattrs{Entries: []protocol.Xattr{{Name: "user.tag", Value: []byte("red")}}},
+		Ownership: &protocol.Ownership{OwnerName: "user", GroupName: "group", Uid: 1234, Gid: 5678},
+	}
+	bs, err := f.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tf FileInfoTruncated
+	if err := tf.Unmarshal(bs); err != nil {
+		t.Fatal(err)
+	}
+	if tf.Name != f.Name || tf.Size != f.Size || !tf.Version.Equal(f.Version) {
+		t.Errorf("Truncated %v doesn't match %v", tf, f)
+	}
+	if !bytes.Equal(tf.Encrypted, f.Encrypted) {
+		t.Errorf("Encrypted is %q, expected %q", tf.Encrypted, f.Encrypted)
+	}
+	if tf.Xattrs == nil || !protocol.XattrsEqual(tf.Xattrs.Entries, f.Xattrs.Entries) {
+		t.Errorf("Xattrs are %v, expected %v", tf.Xattrs, f.Xattrs)
+	}
+	if tf.Ownership == nil || *tf.Ownership != *f.Ownership {
+		t.Errorf("Ownership is %v, expected %v", tf.Ownership, f.Ownership)
+	}
+
+	// The truncated file marshals to the file without blocks.
+	f.Blocks = nil
+	tbs, err := tf.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bs, _ = f.Marshal(); !bytes.Equal(tbs, bs) {
+		t.Error("Truncated file marshals differently from the file without blocks")
+	}
+}
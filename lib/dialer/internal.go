@@ -7,6 +7,10 @@
 package dialer
 
 import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
 	"net"
 	"net/http"
 	"net/url"
@@ -123,6 +127,73 @@ func getDialer(forward proxy.Dialer) proxy.Dialer {
 	return perHost
 }
 
+// dialHTTPProxy connects to addr through the HTTP proxy, using CONNECT.
+func dialHTTPProxy(proxyURL *url.URL, addr string, timeout time.Duration) (net.Conn, error) {
+	proxyAddr := proxyURL.Host
+	if proxyURL.Port() == "" {
+		port := "80"
+		if proxyURL.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddr = net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+
+	conn, err := net.DialTimeout("tcp", proxyAddr, timeout)
+	if err != nil {
+		l.Debugf("Dialing %s via HTTP proxy %s - error %s", addr, proxyAddr, err)
+		return nil, err
+	}
+	SetTCPOptions(conn)
+	if proxyURL.Scheme == "https" {
+		conn = tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+	}
+
+	conn.SetDeadline(time.Now().Add(timeout))
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		conn.Close()
+		return nil, fmt.Errorf("HTTP proxy %s: %s", proxyAddr, resp.Status)
+	}
+	conn.SetDeadline(time.Time{})
+
+	l.Debugf("Dialing %s via HTTP proxy %s - success, %s -> %s", addr, proxyAddr, conn.LocalAddr(), conn.RemoteAddr())
+	return dialerConn{
+		bufferedConn{conn, br}, newDialerAddr("tcp", addr),
+	}, nil
+}
+
+// bufferedConn reads through the buffer that was used to read the response
+// of the proxy, in case it already holds some of what follows.
+type bufferedConn struct {
+	net.Conn
+	br *bufio.Reader
+}
+
+func (c bufferedConn) Read(p []byte) (int, error) {
+	return c.br.Read(p)
+}
+
 type timeoutDirectDialer struct {
 	timeout time.Duration
 }
@@ -9,6 +9,8 @@ package dialer
 import (
 	"fmt"
 	"net"
+	"net/http"
+	"net/url"
 	"time"
 
 	"golang.org/x/net/ipv4"
@@ -51,6 +53,21 @@ func DialTimeout(network, addr string, timeout time.Duration) (net.Conn, error)
 	return net.DialTimeout(network, addr, timeout)
 }
 
+// DialHTTPProxied dials addr through the HTTP proxy that the environment
+// (HTTPS_PROXY or HTTP_PROXY, and NO_PROXY) sets for URLs with the given
+// scheme, tunnelling the connection with CONNECT. Without such a proxy it's
+// the same as DialTimeout.
+func DialHTTPProxied(scheme, addr string, timeout time.Duration) (net.Conn, error) {
+	proxyURL, err := http.ProxyFromEnvironment(&http.Request{URL: &url.URL{Scheme: scheme, Host: addr}})
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL == nil {
+		return DialTimeout("tcp", addr, timeout)
+	}
+	return dialHTTPProxy(proxyURL, addr, timeout)
+}
+
 // SetTCPOptions sets our default TCP options on a TCP connection, possibly
 // digging through dialerConn to extract the *net.TCPConn
 func SetTCPOptions(conn net.Conn) error {
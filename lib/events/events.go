@@ -47,6 +47,7 @@ const (
 	FolderWatchStateChanged
 	ListenAddressesChanged
 	LoginAttempt
+	FileCorrupted
 
 	AllEvents = (1 << iota) - 1
 )
@@ -113,6 +114,8 @@ func (t EventType) String() string {
 		return "LoginAttempt"
 	case FolderWatchStateChanged:
 		return "FolderWatchStateChanged"
+	case FileCorrupted:
+		return "FileCorrupted"
 	default:
 		return "Unknown"
 	}
@@ -192,6 +195,8 @@ func UnmarshalEventType(s string) EventType {
 		return LoginAttempt
 	case "FolderWatchStateChanged":
 		return FolderWatchStateChanged
+	case "FileCorrupted":
+		return FileCorrupted
 	default:
 		return 0
 	}
@@ -479,6 +484,95 @@ func (s *bufferedSubscription) Since(id int, into []Event, timeout time.Duration
 	return into
 }
 
+// History keeps the most recent events of all types seen on a subscription,
+// so that a stream of events can be resumed from a given global ID.
+type History struct {
+	buf  []Event
+	next int
+	full bool
+	gap  int // events up to this global ID may be missing, as we weren't recording
+	mut  sync.Mutex
+}
+
+func NewHistory(size int) *History {
+	return &History{
+		buf: make([]Event, size),
+		mut: sync.NewMutex(),
+	}
+}
+
+// Record adds the events from the subscription to the history, until the
+// subscription is closed or stop is.
+func (h *History) Record(s *Subscription, stop chan struct{}) {
+	// Whatever happened since we last recorded is lost.
+	h.mut.Lock()
+	resumed := h.next > 0 || h.full
+	h.mut.Unlock()
+
+	for {
+		select {
+		case ev, ok := <-s.C():
+			if !ok {
+				return
+			}
+			h.add(ev, resumed)
+			resumed = false
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (h *History) add(ev Event, resumed bool) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	switch {
+	case h.next == 0 && !h.full:
+		// Nothing from before the first event was recorded.
+		h.gap = ev.GlobalID - 1
+	case resumed:
+		last := h.buf[(h.next+len(h.buf)-1)%len(h.buf)]
+		if ev.GlobalID > last.GlobalID+1 {
+			h.gap = ev.GlobalID - 1
+		}
+	}
+	h.buf[h.next] = ev
+	h.next = (h.next + 1) % len(h.buf)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// Since returns the events matching the mask with a global ID larger than
+// the given one, oldest first. The returned bool is false if some of the
+// events after the given ID have already been dropped from the history.
+func (h *History) Since(globalID int, mask EventType) ([]Event, bool) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	var evs []Event
+	add := func(ev Event) {
+		if ev.GlobalID > globalID && ev.Type&mask != 0 {
+			evs = append(evs, ev)
+		}
+	}
+	if h.full {
+		for _, ev := range h.buf[h.next:] {
+			add(ev)
+		}
+	}
+	for _, ev := range h.buf[:h.next] {
+		add(ev)
+	}
+
+	complete := globalID >= h.gap
+	if h.full && h.buf[h.next].GlobalID > globalID+1 {
+		complete = false
+	}
+	return evs, complete
+}
+
 // Error returns a string pointer suitable for JSON marshalling errors. It
 // retains the "null on success" semantics, but ensures the error result is a
 // string regardless of the underlying concrete error type.
@@ -350,6 +350,88 @@ func TestSinceUsesSubscriptionId(t *testing.T) {
 	}
 }
 
+func TestHistory(t *testing.T) {
+	l := NewLogger()
+	defer l.Stop()
+	go l.Serve()
+
+	s := l.Subscribe(AllEvents)
+	defer l.Unsubscribe(s)
+	h := NewHistory(4)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		h.Record(s, stop)
+		close(done)
+	}()
+
+	l.Log(DeviceConnected, "a")    // GlobalID = 1
+	l.Log(DeviceDisconnected, "b") // GlobalID = 2
+	l.Log(DeviceConnected, "c")    // GlobalID = 3
+
+	// We need to loop for the events, as they may not all have been
+	// delivered to the history when we get here.
+	t0 := time.Now()
+	for time.Since(t0) < time.Second {
+		if evs, _ := h.Since(0, AllEvents); len(evs) == 3 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	evs, complete := h.Since(1, DeviceConnected)
+	if !complete || len(evs) != 1 || evs[0].GlobalID != 3 {
+		t.Fatalf("Incorrect events: %v, %v", evs, complete)
+	}
+
+	// Roll the history over; the first events are no longer available.
+
+	l.Log(DeviceConnected, "d")
+	l.Log(DeviceConnected, "e")
+	l.Log(DeviceConnected, "f")
+
+	t0 = time.Now()
+	for time.Since(t0) < time.Second {
+		if evs, _ := h.Since(5, AllEvents); len(evs) == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if evs, complete := h.Since(0, AllEvents); complete || len(evs) != 4 || evs[0].GlobalID != 3 {
+		t.Fatalf("Incorrect events: %v, %v", evs, complete)
+	}
+	if evs, complete := h.Since(2, AllEvents); !complete || len(evs) != 4 {
+		t.Fatalf("Incorrect events: %v, %v", evs, complete)
+	}
+
+	// Events while not recording are missing from the history.
+
+	close(stop)
+	<-done
+	l.Log(DeviceConnected, "g") // GlobalID = 7
+
+	s2 := l.Subscribe(AllEvents)
+	defer l.Unsubscribe(s2)
+	go h.Record(s2, make(chan struct{}))
+	l.Log(DeviceConnected, "h") // GlobalID = 8
+
+	t0 = time.Now()
+	for time.Since(t0) < time.Second {
+		if evs, _ := h.Since(7, AllEvents); len(evs) == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if evs, complete := h.Since(6, AllEvents); complete || len(evs) != 1 {
+		t.Fatalf("Incorrect events: %v, %v", evs, complete)
+	}
+	if evs, complete := h.Since(7, AllEvents); !complete || len(evs) != 1 {
+		t.Fatalf("Incorrect events: %v, %v", evs, complete)
+	}
+}
+
 func TestUnmarshalEvent(t *testing.T) {
 	var event Event
 
@@ -456,3 +538,38 @@ func BenchmarkLogEvent(b *testing.B) {
 		l.Log(StateChanged, nil)
 	}
 }
+
+func TestHistoryStartedLate(t *testing.T) {
+	l := NewLogger()
+	defer l.Stop()
+	go l.Serve()
+
+	// An event from before we started recording
+	s0 := l.Subscribe(AllEvents)
+	l.Log(DeviceConnected, "a") // GlobalID = 1
+	if _, err := s0.Poll(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	l.Unsubscribe(s0)
+
+	s := l.Subscribe(AllEvents)
+	defer l.Unsubscribe(s)
+	h := NewHistory(4)
+	go h.Record(s, make(chan struct{}))
+	l.Log(DeviceConnected, "b") // GlobalID = 2
+
+	t0 := time.Now()
+	for time.Since(t0) < time.Second {
+		if evs, _ := h.Since(0, AllEvents); len(evs) == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if evs, complete := h.Since(0, AllEvents); complete || len(evs) != 1 {
+		t.Fatalf("Incorrect events: %v, %v", evs, complete)
+	}
+	if evs, complete := h.Since(1, AllEvents); !complete || len(evs) != 1 {
+		t.Fatalf("Incorrect events: %v, %v", evs, complete)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"io"
+	"os"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// The argument to the FICLONERANGE ioctl, struct file_clone_range.
+type fileCloneRange struct {
+	srcFd      int64
+	srcOffset  uint64
+	srcLength  uint64
+	destOffset uint64
+}
+
+// ficloneRange returns the FICLONERANGE ioctl request, i.e.
+// _IOW(0x94, 13, struct file_clone_range), which is encoded differently on
+// some architectures.
+func ficloneRange() uintptr {
+	switch runtime.GOARCH {
+	case "mips", "mipsle", "mips64", "mips64le", "ppc64", "ppc64le":
+		return 0x8020940d
+	}
+	return 0x4020940d
+}
+
+// CopyRange shares the extents of the source range with the destination
+// where the filesystem supports it (reflinks on Btrfs and XFS), which
+// requires the ranges to be aligned to its blocks. Otherwise the data is
+// copied by the kernel, using copy_file_range.
+func (f *BasicFilesystem) CopyRange(src, dst File, srcOffset, dstOffset, size int64) error {
+	srcFile, ok := src.(basicFile)
+	if !ok {
+		return ErrCopyRangeNotSupported
+	}
+	dstFile, ok := dst.(basicFile)
+	if !ok {
+		return ErrCopyRangeNotSupported
+	}
+	srcFd, dstFd := srcFile.Fd(), dstFile.Fd()
+
+	arg := fileCloneRange{
+		srcFd:      int64(srcFd),
+		srcOffset:  uint64(srcOffset),
+		srcLength:  uint64(size),
+		destOffset: uint64(dstOffset),
+	}
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, dstFd, ficloneRange(), uintptr(unsafe.Pointer(&arg))); errno == 0 {
+		return nil
+	}
+
+	for size > 0 {
+		n, err := unix.CopyFileRange(int(srcFd), &srcOffset, int(dstFd), &dstOffset, int(size), 0)
+		switch err {
+		case nil:
+		case unix.ENOSYS, unix.EXDEV, unix.EINVAL, unix.EOPNOTSUPP, unix.EBADF:
+			// Too old a kernel, different filesystems or special files.
+			return ErrCopyRangeNotSupported
+		default:
+			return &os.SyscallError{Syscall: "copy_file_range", Err: err}
+		}
+		if n == 0 {
+			return io.ErrUnexpectedEOF
+		}
+		size -= int64(n)
+	}
+	return nil
+}
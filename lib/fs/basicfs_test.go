@@ -7,6 +7,7 @@
 package fs
 
 import (
+	"bytes"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -572,3 +573,89 @@ func TestBasicWalkSkipSymlink(t *testing.T) {
 	defer os.RemoveAll(dir)
 	testWalkSkipSymlink(t, FilesystemTypeBasic, dir)
 }
+
+func TestCopyRange(t *testing.T) {
+	fs, dir := setup(t)
+	defer os.RemoveAll(dir)
+
+	// Copy through the wrappers used for folders, which must get out of
+	// the way.
+	mtimefs := NewMtimeFS(&walkFilesystem{fs}, make(mapStore))
+
+	data := []byte(rand.String(3*4096 + 100))
+	if err := ioutil.WriteFile(filepath.Join(dir, "src"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	src, err := mtimefs.Open("src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	dst, err := mtimefs.Create("dst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+
+	// The unaligned end of the file into the start of another
+	err = CopyRange(mtimefs, src, dst, 4096, 0, int64(len(data)-4096))
+	if err == ErrCopyRangeNotSupported {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	copied, err := ioutil.ReadFile(filepath.Join(dir, "dst"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(copied, data[4096:]) {
+		t.Error("Copied data differs")
+	}
+}
+
+func TestXattr(t *testing.T) {
+	fs, dir := setup(t)
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "file"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	set := []Xattr{
+		{Name: "user.b", Value: []byte("second")},
+		{Name: "user.a", Value: []byte("first")},
+		{Name: "user.c", Value: make([]byte, MaxXattrSize)}, // too large to sync
+	}
+	if runtime.GOOS == "darwin" {
+		set[0].Name, set[1].Name, set[2].Name = "b", "a", "c"
+	}
+	err := fs.SetXattr("file", set)
+	if err == ErrXattrsNotSupported || IsPermission(err) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := fs.GetXattr("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Name != set[1].Name || got[1].Name != set[0].Name || string(got[0].Value) != "first" {
+		t.Errorf("Expected attributes %v sorted by name, without the oversized one, got %v", set[:2], got)
+	}
+
+	// Setting fewer removes the others
+	if err := fs.SetXattr("file", set[1:2]); err != nil {
+		t.Fatal(err)
+	}
+	got, err = fs.GetXattr("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Name != set[1].Name {
+		t.Errorf("Expected only attribute %v, got %v", set[1], got)
+	}
+}
@@ -0,0 +1,23 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+const errNoXattr = unix.ENOATTR
+
+// xattrSynced returns whether the extended attribute is one we handle.
+// There are no namespaces, but those of the system are named com.apple.*,
+// including resource forks, Finder info and quarantine flags, which are
+// kept local. Spotlight metadata, such as Finder tags, is synced.
+func xattrSynced(attr string) bool {
+	return !strings.HasPrefix(attr, "com.apple.") || strings.HasPrefix(attr, "com.apple.metadata:")
+}
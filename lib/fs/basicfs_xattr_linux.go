@@ -0,0 +1,21 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+const errNoXattr = unix.ENODATA
+
+// xattrSynced returns whether the extended attribute is one we handle,
+// which are those in the user namespace and POSIX ACLs.
+func xattrSynced(attr string) bool {
+	return strings.HasPrefix(attr, "user.") || attr == "system.posix_acl_access" || attr == "system.posix_acl_default"
+}
@@ -0,0 +1,120 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build linux darwin
+
+package fs
+
+import (
+	"bytes"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+func (f *BasicFilesystem) GetXattr(name string) ([]Xattr, error) {
+	path, err := f.rooted(name)
+	if err != nil {
+		return nil, err
+	}
+	return getXattrs(path)
+}
+
+func (f *BasicFilesystem) SetXattr(name string, xattrs []Xattr) error {
+	path, err := f.rooted(name)
+	if err != nil {
+		return err
+	}
+	cur, err := getXattrs(path)
+	if err != nil {
+		return err
+	}
+
+	want := make(map[string][]byte, len(xattrs))
+	for _, x := range LimitXattrs(xattrs) {
+		if xattrSynced(x.Name) {
+			want[x.Name] = x.Value
+		}
+	}
+	for _, x := range cur {
+		val, ok := want[x.Name]
+		switch {
+		case !ok:
+			if err := unix.Lremovexattr(path, x.Name); err != nil && err != errNoXattr {
+				return &os.PathError{Op: "removexattr", Path: path, Err: err}
+			}
+		case bytes.Equal(val, x.Value):
+			delete(want, x.Name)
+		}
+	}
+	for attr, val := range want {
+		if err := unix.Lsetxattr(path, attr, val, 0); err != nil {
+			return &os.PathError{Op: "setxattr", Path: path, Err: err}
+		}
+	}
+	return nil
+}
+
+func getXattrs(path string) ([]Xattr, error) {
+	buf, err := readXattr(func(dest []byte) (int, error) {
+		return unix.Llistxattr(path, dest)
+	})
+	switch err {
+	case nil:
+	case unix.ENOTSUP:
+		return nil, ErrXattrsNotSupported
+	default:
+		return nil, &os.PathError{Op: "listxattr", Path: path, Err: err}
+	}
+
+	var xattrs []Xattr
+	for _, attr := range strings.Split(string(buf), "\x00") {
+		if attr == "" || !xattrSynced(attr) {
+			continue
+		}
+		// Skip those too large to sync without reading them.
+		if size, err := unix.Lgetxattr(path, attr, nil); err == nil && len(attr)+size > MaxXattrSize {
+			l.Debugf("skipping extended attribute %s of %s of %d bytes", attr, path, size)
+			continue
+		}
+		val, err := readXattr(func(dest []byte) (int, error) {
+			return unix.Lgetxattr(path, attr, dest)
+		})
+		if err == errNoXattr {
+			// Removed since listing them
+			continue
+		} else if err != nil {
+			return nil, &os.PathError{Op: "getxattr", Path: path, Err: err}
+		}
+		xattrs = append(xattrs, Xattr{Name: attr, Value: val})
+	}
+	sort.Slice(xattrs, func(a, b int) bool { return xattrs[a].Name < xattrs[b].Name })
+	return LimitXattrs(xattrs), nil
+}
+
+// readXattr calls fn with a buffer large enough for what it returns, which
+// may change in between asking for the size and getting the data.
+func readXattr(fn func(dest []byte) (int, error)) ([]byte, error) {
+	for {
+		size, err := fn(nil)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size)
+		n, err := fn(buf)
+		if err == unix.ERANGE {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+}
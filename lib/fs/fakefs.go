@@ -17,6 +17,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -144,6 +145,7 @@ type fakeEntry struct {
 	uid       int
 	gid       int
 	mtime     time.Time
+	xattrs    []Xattr
 	children  map[string]*fakeEntry
 }
 
@@ -194,6 +196,28 @@ func (fs *fakefs) Lchown(name string, uid, gid int) error {
 	return nil
 }
 
+func (fs *fakefs) GetXattr(name string) ([]Xattr, error) {
+	fs.mut.Lock()
+	defer fs.mut.Unlock()
+	entry := fs.entryForName(name)
+	if entry == nil {
+		return nil, os.ErrNotExist
+	}
+	return append([]Xattr(nil), entry.xattrs...), nil
+}
+
+func (fs *fakefs) SetXattr(name string, xattrs []Xattr) error {
+	fs.mut.Lock()
+	defer fs.mut.Unlock()
+	entry := fs.entryForName(name)
+	if entry == nil {
+		return os.ErrNotExist
+	}
+	entry.xattrs = append([]Xattr(nil), xattrs...)
+	sort.Slice(entry.xattrs, func(a, b int) bool { return entry.xattrs[a].Name < entry.xattrs[b].Name })
+	return nil
+}
+
 func (fs *fakefs) Chtimes(name string, atime time.Time, mtime time.Time) error {
 	fs.mut.Lock()
 	defer fs.mut.Unlock()
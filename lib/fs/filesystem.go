@@ -48,6 +48,12 @@ type Filesystem interface {
 	Type() FilesystemType
 	URI() string
 	SameFile(fi1, fi2 FileInfo) bool
+	// GetXattr returns the extended attributes of the item, sorted by name,
+	// and SetXattr makes them what's given. Both are limited to user
+	// attributes and access control lists within the size limits of
+	// LimitXattrs, and don't follow symlinks.
+	GetXattr(name string) ([]Xattr, error)
+	SetXattr(name string, xattrs []Xattr) error
 }
 
 // The File interface abstracts access to a regular file, being a somewhat
@@ -89,6 +95,38 @@ func (fm FileMode) String() string {
 	return os.FileMode(fm).String()
 }
 
+// Xattr is an extended attribute of a file or directory
+type Xattr struct {
+	Name  string
+	Value []byte
+}
+
+// The extended attributes of an item are sent along with it in every index
+// update, so only small ones are synced: none larger than MaxXattrSize, name
+// and value together, and no more than MaxXattrsSize of them per item.
+const (
+	MaxXattrSize  = 1 << 10
+	MaxXattrsSize = 4 << 10
+)
+
+// LimitXattrs returns the extended attributes that are within the size
+// limits, skipping those that are too large by themselves or don't fit in
+// what is left of the total after the ones before them.
+func LimitXattrs(xattrs []Xattr) []Xattr {
+	var limited []Xattr
+	total := 0
+	for _, x := range xattrs {
+		size := len(x.Name) + len(x.Value)
+		if size > MaxXattrSize || total+size > MaxXattrsSize {
+			l.Debugf("skipping extended attribute %s of %d bytes", x.Name, size)
+			continue
+		}
+		total += size
+		limited = append(limited, x)
+	}
+	return limited
+}
+
 // Usage represents filesystem space usage
 type Usage struct {
 	Free  int64
@@ -137,6 +175,30 @@ func (evType EventType) String() string {
 
 var ErrWatchNotSupported = errors.New("watching is not supported")
 
+// ErrXattrsNotSupported is returned by GetXattr and SetXattr when the
+// filesystem or platform has no extended attributes.
+var ErrXattrsNotSupported = errors.New("extended attributes are not supported")
+
+// A RangeCopier is a Filesystem that can copy data between two of its files
+// without it passing through user space, or even without copying it at all
+// where the extents can be shared.
+type RangeCopier interface {
+	CopyRange(src, dst File, srcOffset, dstOffset, size int64) error
+}
+
+// ErrCopyRangeNotSupported is returned by CopyRange when the filesystem or
+// the files can't do it, and the data must be read and written instead.
+var ErrCopyRangeNotSupported = errors.New("copying file ranges is not supported")
+
+// CopyRange copies size bytes from srcOffset in src to dstOffset in dst,
+// both opened on the given filesystem, if it is a RangeCopier.
+func CopyRange(fs Filesystem, src, dst File, srcOffset, dstOffset, size int64) error {
+	if rc, ok := fs.(RangeCopier); ok {
+		return rc.CopyRange(src, dst, srcOffset, dstOffset, size)
+	}
+	return ErrCopyRangeNotSupported
+}
+
 // Equivalents from os package.
 
 const ModePerm = FileMode(os.ModePerm)
@@ -179,6 +241,17 @@ func NewFilesystem(fsType FilesystemType, uri string) Filesystem {
 		fs = newBasicFilesystem(uri)
 	case FilesystemTypeFake:
 		fs = newFakeFilesystem(uri)
+	case FilesystemTypeS3:
+		var err error
+		fs, err = newS3Filesystem(uri)
+		if err != nil {
+			l.Debugln("Invalid s3 filesystem", uri, err)
+			fs = &errorFilesystem{
+				fsType: fsType,
+				uri:    uri,
+				err:    err,
+			}
+		}
 	default:
 		l.Debugln("Unknown filesystem", fsType, uri)
 		fs = &errorFilesystem{
@@ -204,7 +277,7 @@ func NewFilesystem(fsType FilesystemType, uri string) Filesystem {
 // path must be clean (i.e., in canonical shortest form).
 func IsInternal(file string) bool {
 	// fs cannot import config, so we hard code .stfolder here (config.DefaultMarkerName)
-	internals := []string{".stfolder", ".stignore", ".stversions"}
+	internals := []string{".stfolder", ".stignore", ".stversions", ".ststaging"}
 	for _, internal := range internals {
 		if file == internal {
 			return true
@@ -9,6 +9,7 @@ package fs
 import (
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -20,9 +21,11 @@ func TestIsInternal(t *testing.T) {
 		{".stfolder", true},
 		{".stignore", true},
 		{".stversions", true},
+		{".ststaging", true},
 		{".stfolder/foo", true},
 		{".stignore/foo", true},
 		{".stversions/foo", true},
+		{".ststaging/foo", true},
 
 		{".stfolderfoo", false},
 		{".stignorefoo", false},
@@ -33,6 +36,7 @@ func TestIsInternal(t *testing.T) {
 		{"foo/.stfolder", false},
 		{"foo/.stignore", false},
 		{"foo/.stversions", false},
+		{"foo/.ststaging", false},
 	}
 
 	for _, tc := range cases {
@@ -170,3 +174,28 @@ func TestIsParent(t *testing.T) {
 		}
 	}
 }
+
+func TestLimitXattrs(t *testing.T) {
+	value := func(size int) []byte {
+		return make([]byte, size)
+	}
+	xattrs := []Xattr{
+		{Name: "user.a", Value: value(10)},
+		{Name: "user.b", Value: value(MaxXattrSize)},
+		{Name: "user.c", Value: value(MaxXattrSize - 6)},
+		{Name: "user.d", Value: value(MaxXattrSize - 6)},
+		{Name: "user.e", Value: value(MaxXattrSize - 6)},
+		{Name: "user.f", Value: value(MaxXattrSize - 6)},
+		{Name: "user.g", Value: value(10)},
+	}
+
+	// b is too large by itself, and f no longer fits after a, c, d and e,
+	// but g still does.
+	var names []string
+	for _, x := range LimitXattrs(xattrs) {
+		names = append(names, x.Name)
+	}
+	if strings.Join(names, ",") != "user.a,user.c,user.d,user.e,user.g" {
+		t.Error("Unexpected attributes within the limits:", names)
+	}
+}
@@ -158,6 +158,24 @@ func (fs *logFilesystem) Roots() ([]string, error) {
 	return roots, err
 }
 
+func (fs *logFilesystem) CopyRange(src, dst File, srcOffset, dstOffset, size int64) error {
+	err := CopyRange(fs.Filesystem, src, dst, srcOffset, dstOffset, size)
+	l.Debugln(getCaller(), fs.Type(), fs.URI(), "CopyRange", src.Name(), dst.Name(), srcOffset, dstOffset, size, err)
+	return err
+}
+
+func (fs *logFilesystem) GetXattr(name string) ([]Xattr, error) {
+	xattrs, err := fs.Filesystem.GetXattr(name)
+	l.Debugln(getCaller(), fs.Type(), fs.URI(), "GetXattr", name, len(xattrs), err)
+	return xattrs, err
+}
+
+func (fs *logFilesystem) SetXattr(name string, xattrs []Xattr) error {
+	err := fs.Filesystem.SetXattr(name, xattrs)
+	l.Debugln(getCaller(), fs.Type(), fs.URI(), "SetXattr", name, len(xattrs), err)
+	return err
+}
+
 func (fs *logFilesystem) Usage(name string) (Usage, error) {
 	usage, err := fs.Filesystem.Usage(name)
 	l.Debugln(getCaller(), fs.Type(), fs.URI(), "Usage", name, usage, err)
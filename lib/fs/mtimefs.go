@@ -139,6 +139,18 @@ func (f *MtimeFS) OpenFile(name string, flags int, mode FileMode) (File, error)
 	return &mtimeFile{fd, f}, nil
 }
 
+// CopyRange copies between the files of the underlying filesystem, which
+// are unaffected by the timestamps kept here.
+func (f *MtimeFS) CopyRange(src, dst File, srcOffset, dstOffset, size int64) error {
+	if mf, ok := src.(*mtimeFile); ok {
+		src = mf.File
+	}
+	if mf, ok := dst.(*mtimeFile); ok {
+		dst = mf.File
+	}
+	return CopyRange(f.Filesystem, src, dst, srcOffset, dstOffset, size)
+}
+
 // "real" is the on disk timestamp
 // "virtual" is what want the timestamp to be
 
@@ -0,0 +1,868 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Parts of a multipart upload must be at least 5 MiB, except the last.
+	s3MinPartSize     = 5 << 20
+	s3DefaultPartSize = 16 << 20
+
+	s3MetaMtime = "syncthing-mtime" // nanoseconds since the epoch
+	s3MetaMode  = "syncthing-mode"  // octal permission bits
+)
+
+var errS3NotSupported = errors.New("not supported on s3 filesystem")
+
+// s3fs is a filesystem backed by a bucket in an S3 compatible object
+// store. The folder root is given as an URI of the form
+//
+//     https://[access:secret@]host[:port]/bucket[/prefix][?region=...&partSize=...]
+//
+// where the prefix within the bucket is the folder root. Credentials not
+// given in the URI are taken from AWS_ACCESS_KEY_ID and
+// AWS_SECRET_ACCESS_KEY. The part size for multipart uploads is given in
+// MiB.
+//
+// - Files are stored as objects, with modification time and permissions
+//   kept as user metadata.
+//
+// - Directories are emulated. A directory exists if there is a marker
+//   object with the directory name plus a trailing slash, or if there are
+//   other objects below it.
+//
+// - Files opened for writing are spooled to a local temporary file and
+//   uploaded on Sync or Close, using a multipart upload when larger than
+//   the part size.
+//
+// - Symlinks, ownership and watching are not supported.
+//
+type s3fs struct {
+	client   *s3Client
+	prefix   string // without leading or trailing slash
+	uri      string // without credentials
+	partSize int64
+}
+
+func newS3Filesystem(root string) (*s3fs, error) {
+	u, err := url.Parse(root)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.New("s3 filesystem root must be an http or https URI")
+	}
+
+	parts := strings.SplitN(strings.Trim(u.Path, "/"), "/", 2)
+	if parts[0] == "" {
+		return nil, errors.New("s3 filesystem root must include a bucket")
+	}
+	bucket := parts[0]
+	prefix := ""
+	if len(parts) == 2 {
+		prefix = strings.Trim(path.Clean(parts[1]), "/")
+	}
+
+	params := u.Query()
+	partSize := int64(s3DefaultPartSize)
+	if mib, err := strconv.Atoi(params.Get("partSize")); err == nil && mib > 0 {
+		partSize = int64(mib) << 20
+	}
+	if partSize < s3MinPartSize {
+		partSize = s3MinPartSize
+	}
+
+	accessKey, secretKey := s3Credentials(u)
+	client := newS3Client(u, bucket, params.Get("region"), accessKey, secretKey)
+
+	clean := *u
+	clean.User = nil
+	return &s3fs{
+		client:   client,
+		prefix:   prefix,
+		uri:      clean.String(),
+		partSize: partSize,
+	}, nil
+}
+
+// key returns the object key for the given name relative to the root.
+func (fs *s3fs) key(name string) (string, error) {
+	name, err := Canonicalize(name)
+	if err != nil {
+		return "", err
+	}
+	name = filepath.ToSlash(name)
+	if name == "." {
+		return fs.prefix, nil
+	}
+	if fs.prefix == "" {
+		return name, nil
+	}
+	return fs.prefix + "/" + name, nil
+}
+
+// dirPrefix returns the prefix that all objects within the directory with
+// the given key start with. This is also the key of the directory marker.
+func dirPrefix(key string) string {
+	if key == "" {
+		return ""
+	}
+	return key + "/"
+}
+
+func (fs *s3fs) stat(name string) (*s3FileInfo, error) {
+	key, err := fs.key(name)
+	if err != nil {
+		return nil, err
+	}
+	if key == "" {
+		// The bucket itself.
+		return &s3FileInfo{name: ".", key: key, mode: 0777 | FileMode(os.ModeDir), dir: true}, nil
+	}
+
+	obj, err := fs.client.headObject(key)
+	if err == nil {
+		return newS3FileInfo(name, obj, false), nil
+	}
+	if !isS3NotFound(err) {
+		return nil, err
+	}
+
+	obj, err = fs.client.headObject(dirPrefix(key))
+	if err == nil {
+		return newS3FileInfo(name, obj, true), nil
+	}
+	if !isS3NotFound(err) {
+		return nil, err
+	}
+
+	// No marker; the directory still exists if anything is below it.
+	objs, prefixes, err := fs.client.listObjects(dirPrefix(key), "/", 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(objs) == 0 && len(prefixes) == 0 {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+	return &s3FileInfo{name: filepath.Base(name), key: key, mode: 0777 | FileMode(os.ModeDir), dir: true}, nil
+}
+
+func (fs *s3fs) Chmod(name string, mode FileMode) error {
+	return fs.updateMeta(name, func(meta map[string]string) {
+		meta[s3MetaMode] = strconv.FormatUint(uint64(mode&ModePerm), 8)
+	})
+}
+
+func (fs *s3fs) Lchown(name string, uid, gid int) error {
+	return errS3NotSupported
+}
+
+func (fs *s3fs) GetXattr(name string) ([]Xattr, error) {
+	return nil, ErrXattrsNotSupported
+}
+
+func (fs *s3fs) SetXattr(name string, xattrs []Xattr) error {
+	return ErrXattrsNotSupported
+}
+
+func (fs *s3fs) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return fs.updateMeta(name, func(meta map[string]string) {
+		meta[s3MetaMtime] = strconv.FormatInt(mtime.UnixNano(), 10)
+	})
+}
+
+// updateMeta replaces the metadata of the object (or directory marker) for
+// name, by copying it onto itself. Directories without a marker get one.
+func (fs *s3fs) updateMeta(name string, update func(map[string]string)) error {
+	info, err := fs.stat(name)
+	if err != nil {
+		return err
+	}
+	meta := info.metaMap()
+	update(meta)
+
+	if !info.dir {
+		return fs.client.copyObject(info.key, info.key, info.size, meta)
+	}
+	if info.key == "" {
+		// Nothing to store the metadata on.
+		return nil
+	}
+	if info.marker {
+		return fs.client.copyObject(dirPrefix(info.key), dirPrefix(info.key), 0, meta)
+	}
+	return fs.client.putObject(dirPrefix(info.key), nil, meta)
+}
+
+func (fs *s3fs) Create(name string) (File, error) {
+	return fs.OpenFile(name, OptReadWrite|OptCreate|OptTruncate, 0666)
+}
+
+func (fs *s3fs) CreateSymlink(target, name string) error {
+	return errS3NotSupported
+}
+
+func (fs *s3fs) DirNames(name string) ([]string, error) {
+	info, err := fs.stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if !info.dir {
+		return nil, &os.PathError{Op: "readdirent", Path: name, Err: errors.New("not a directory")}
+	}
+
+	prefix := dirPrefix(info.key)
+	objs, prefixes, err := fs.client.listObjects(prefix, "/", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(objs)+len(prefixes))
+	for _, obj := range objs {
+		if obj.key == prefix {
+			// The directory marker itself
+			continue
+		}
+		names = append(names, strings.TrimPrefix(obj.key, prefix))
+	}
+	for _, p := range prefixes {
+		names = append(names, strings.TrimSuffix(strings.TrimPrefix(p, prefix), "/"))
+	}
+	return names, nil
+}
+
+func (fs *s3fs) Lstat(name string) (FileInfo, error) {
+	info, err := fs.stat(name)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (fs *s3fs) Mkdir(name string, perm FileMode) error {
+	if _, err := fs.stat(name); err == nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	} else if !IsNotExist(err) {
+		return err
+	}
+	if err := fs.checkParent(name); err != nil {
+		return err
+	}
+	return fs.mkdir(name, perm)
+}
+
+func (fs *s3fs) mkdir(name string, perm FileMode) error {
+	key, err := fs.key(name)
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		return nil
+	}
+	return fs.client.putObject(dirPrefix(key), nil, map[string]string{
+		s3MetaMtime: strconv.FormatInt(time.Now().UnixNano(), 10),
+		s3MetaMode:  strconv.FormatUint(uint64(perm&ModePerm), 8),
+	})
+}
+
+func (fs *s3fs) MkdirAll(name string, perm FileMode) error {
+	info, err := fs.stat(name)
+	if err == nil {
+		if !info.dir {
+			return &os.PathError{Op: "mkdir", Path: name, Err: errors.New("not a directory")}
+		}
+		return nil
+	} else if !IsNotExist(err) {
+		return err
+	}
+
+	if parent := filepath.Dir(name); parent != name && parent != "." {
+		if err := fs.MkdirAll(parent, perm); err != nil {
+			return err
+		}
+	}
+	return fs.mkdir(name, perm)
+}
+
+// checkParent returns an error unless the parent directory of name exists.
+func (fs *s3fs) checkParent(name string) error {
+	parent := filepath.Dir(name)
+	info, err := fs.stat(parent)
+	if err != nil {
+		return err
+	}
+	if !info.dir {
+		return &os.PathError{Op: "open", Path: parent, Err: errors.New("not a directory")}
+	}
+	return nil
+}
+
+func (fs *s3fs) Open(name string) (File, error) {
+	return fs.OpenFile(name, OptReadOnly, 0)
+}
+
+func (fs *s3fs) OpenFile(name string, flags int, mode FileMode) (File, error) {
+	info, err := fs.stat(name)
+	switch {
+	case err == nil && info.dir:
+		return nil, &os.PathError{Op: "open", Path: name, Err: errors.New("is a directory")}
+	case err == nil && flags&OptCreate != 0 && flags&OptExclusive != 0:
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+	case IsNotExist(err) && flags&OptCreate == 0:
+		return nil, err
+	case err != nil && !IsNotExist(err):
+		return nil, err
+	}
+
+	f := &s3File{fs: fs, name: name}
+
+	if err == nil {
+		f.key = info.key
+		f.size = info.size
+		f.modified = info.modified
+		f.meta = info.metaMap()
+	} else {
+		if err := fs.checkParent(name); err != nil {
+			return nil, err
+		}
+		if f.key, err = fs.key(name); err != nil {
+			return nil, err
+		}
+		f.meta = map[string]string{
+			s3MetaMode: strconv.FormatUint(uint64(mode&ModePerm), 8),
+		}
+		f.modified = time.Now()
+		// A new file must exist from now on, even if nothing is written
+		// to it.
+		f.dirty = true
+	}
+
+	if flags&(OptWriteOnly|OptReadWrite) != 0 {
+		if err := f.openSpool(info != nil && flags&OptTruncate == 0); err != nil {
+			return nil, err
+		}
+		if flags&OptTruncate != 0 && f.size != 0 {
+			f.size = 0
+			f.modified = time.Now()
+			f.dirty = true
+		}
+		if flags&OptAppend != 0 {
+			f.offset = f.size
+		}
+	}
+
+	if f.dirty {
+		// Make the file visible immediately, like creating a local file
+		// would.
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+func (fs *s3fs) ReadSymlink(name string) (string, error) {
+	return "", errS3NotSupported
+}
+
+func (fs *s3fs) Remove(name string) error {
+	info, err := fs.stat(name)
+	if err != nil {
+		return err
+	}
+	if !info.dir {
+		return fs.client.deleteObject(info.key)
+	}
+	if info.key == "" {
+		return &os.PathError{Op: "remove", Path: name, Err: errors.New("cannot remove the bucket")}
+	}
+
+	prefix := dirPrefix(info.key)
+	objs, prefixes, err := fs.client.listObjects(prefix, "/", 2)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		if obj.key != prefix {
+			return &os.PathError{Op: "remove", Path: name, Err: errors.New("directory not empty")}
+		}
+	}
+	if len(prefixes) > 0 {
+		return &os.PathError{Op: "remove", Path: name, Err: errors.New("directory not empty")}
+	}
+	if !info.marker {
+		return nil
+	}
+	return fs.client.deleteObject(prefix)
+}
+
+func (fs *s3fs) RemoveAll(name string) error {
+	key, err := fs.key(name)
+	if err != nil {
+		return err
+	}
+
+	objs, _, err := fs.client.listObjects(dirPrefix(key), "", 0)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		if err := fs.client.deleteObject(obj.key); err != nil && !isS3NotFound(err) {
+			return err
+		}
+	}
+
+	if key == "" {
+		return nil
+	}
+	if err := fs.client.deleteObject(key); err != nil && !isS3NotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// Rename copies the object, or every object below a directory, to the new
+// name and removes the original. This is not atomic.
+func (fs *s3fs) Rename(oldname, newname string) error {
+	info, err := fs.stat(oldname)
+	if err != nil {
+		return err
+	}
+	newkey, err := fs.key(newname)
+	if err != nil {
+		return err
+	}
+	if dst, err := fs.stat(newname); err == nil && dst.dir {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: errors.New("is a directory")}
+	}
+	if err := fs.checkParent(newname); err != nil {
+		return err
+	}
+
+	if !info.dir {
+		if err := fs.client.copyObject(info.key, newkey, info.size, nil); err != nil {
+			return err
+		}
+		return fs.client.deleteObject(info.key)
+	}
+
+	prefix := dirPrefix(info.key)
+	objs, _, err := fs.client.listObjects(prefix, "", 0)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		if err := fs.client.copyObject(obj.key, dirPrefix(newkey)+strings.TrimPrefix(obj.key, prefix), obj.size, nil); err != nil {
+			return err
+		}
+	}
+	if !info.marker {
+		// Keep the renamed directory even if it was empty.
+		if err := fs.mkdir(newname, info.mode); err != nil {
+			return err
+		}
+	}
+	for _, obj := range objs {
+		if err := fs.client.deleteObject(obj.key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fs *s3fs) Stat(name string) (FileInfo, error) {
+	return fs.Lstat(name)
+}
+
+func (fs *s3fs) SymlinksSupported() bool {
+	return false
+}
+
+func (fs *s3fs) Walk(name string, walkFn WalkFunc) error {
+	// implemented in WalkFilesystem
+	return errors.New("not implemented")
+}
+
+func (fs *s3fs) Watch(path string, ignore Matcher, ctx context.Context, ignorePerms bool) (<-chan Event, <-chan error, error) {
+	return nil, nil, ErrWatchNotSupported
+}
+
+func (fs *s3fs) Hide(name string) error {
+	return nil
+}
+
+func (fs *s3fs) Unhide(name string) error {
+	return nil
+}
+
+// Glob supports patterns in the last path component only, which is all
+// that is currently required.
+func (fs *s3fs) Glob(pattern string) ([]string, error) {
+	dir, base := filepath.Split(pattern)
+	if _, err := filepath.Match(base, ""); err != nil {
+		return nil, err
+	}
+	names, err := fs.DirNames(dir)
+	if IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var matches []string
+	for _, name := range names {
+		if ok, _ := filepath.Match(base, name); ok {
+			matches = append(matches, filepath.Join(dir, name))
+		}
+	}
+	return matches, nil
+}
+
+func (fs *s3fs) Roots() ([]string, error) {
+	return []string{"/"}, nil
+}
+
+func (fs *s3fs) Usage(name string) (Usage, error) {
+	return Usage{}, errS3NotSupported
+}
+
+func (fs *s3fs) Type() FilesystemType {
+	return FilesystemTypeS3
+}
+
+func (fs *s3fs) URI() string {
+	return fs.uri
+}
+
+func (fs *s3fs) SameFile(fi1, fi2 FileInfo) bool {
+	f1, ok1 := fi1.(*s3FileInfo)
+	f2, ok2 := fi2.(*s3FileInfo)
+	if !ok1 || !ok2 {
+		return false
+	}
+	return f1.key == f2.key && f1.dir == f2.dir
+}
+
+// s3File is an open object. Reads go straight to the object store until
+// the file is written to, at which point the contents are spooled to a
+// local temporary file.
+type s3File struct {
+	fs     *s3fs
+	name   string
+	key    string
+	meta     map[string]string
+	mut      sync.Mutex
+	size     int64
+	modified time.Time
+	offset   int64
+	spool    *os.File
+	dirty    bool
+}
+
+// openSpool creates the local temporary file, optionally filled with the
+// current contents of the object.
+func (f *s3File) openSpool(fill bool) error {
+	if f.spool != nil {
+		return nil
+	}
+	fd, err := ioutil.TempFile("", "syncthing-s3-")
+	if err != nil {
+		return err
+	}
+	// The file is only accessed through the open descriptor.
+	os.Remove(fd.Name())
+	if fill && f.size > 0 {
+		if err := f.fs.client.getObject(f.key, fd); err != nil {
+			fd.Close()
+			return err
+		}
+	}
+	f.spool = fd
+	return nil
+}
+
+func (f *s3File) Close() error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	err := f.sync()
+	if f.spool != nil {
+		f.spool.Close()
+		f.spool = nil
+	}
+	return err
+}
+
+func (f *s3File) Read(p []byte) (int, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	n, err := f.readAt(p, f.offset)
+	f.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func (f *s3File) ReadAt(p []byte, offs int64) (int, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	return f.readAt(p, offs)
+}
+
+func (f *s3File) readAt(p []byte, offs int64) (int, error) {
+	if offs >= f.size {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	short := false
+	if rem := f.size - offs; int64(len(p)) > rem {
+		p = p[:rem]
+		short = true
+	}
+
+	var n int
+	var err error
+	if f.spool != nil {
+		n, err = f.spool.ReadAt(p, offs)
+	} else {
+		n, err = f.fs.client.getObjectRange(f.key, p, offs)
+	}
+	if err == nil && short {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (f *s3File) Seek(offset int64, whence int) (int64, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.size
+	}
+	if offset < 0 {
+		return f.offset, errors.New("seek before start")
+	}
+	f.offset = offset
+	return f.offset, nil
+}
+
+func (f *s3File) Write(p []byte) (int, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	n, err := f.writeAt(p, f.offset)
+	f.offset += int64(n)
+	return n, err
+}
+
+func (f *s3File) WriteAt(p []byte, off int64) (int, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	return f.writeAt(p, off)
+}
+
+func (f *s3File) writeAt(p []byte, off int64) (int, error) {
+	if f.spool == nil {
+		return 0, &os.PathError{Op: "write", Path: f.name, Err: os.ErrPermission}
+	}
+	n, err := f.spool.WriteAt(p, off)
+	if end := off + int64(n); end > f.size {
+		f.size = end
+	}
+	f.modified = time.Now()
+	f.dirty = true
+	return n, err
+}
+
+func (f *s3File) Name() string {
+	return f.name
+}
+
+func (f *s3File) Truncate(size int64) error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	if f.spool == nil {
+		return &os.PathError{Op: "truncate", Path: f.name, Err: os.ErrPermission}
+	}
+	if err := f.spool.Truncate(size); err != nil {
+		return err
+	}
+	f.size = size
+	f.modified = time.Now()
+	f.dirty = true
+	return nil
+}
+
+func (f *s3File) Stat() (FileInfo, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	info := newS3FileInfo(f.name, s3Object{key: f.key, size: f.size, meta: f.meta}, false)
+	info.modified = f.modified
+	return info, nil
+}
+
+func (f *s3File) Sync() error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	return f.sync()
+}
+
+// sync uploads the spooled contents, if changed, with the time they were
+// last changed at. Files larger than the part size are uploaded in parts.
+func (f *s3File) sync() error {
+	if !f.dirty {
+		return nil
+	}
+	f.meta[s3MetaMtime] = strconv.FormatInt(f.modified.UnixNano(), 10)
+
+	if f.size <= f.fs.partSize {
+		buf := make([]byte, f.size)
+		if f.size > 0 {
+			if _, err := f.spool.ReadAt(buf, 0); err != nil && err != io.EOF {
+				return err
+			}
+		}
+		if err := f.fs.client.putObject(f.key, buf, f.meta); err != nil {
+			return err
+		}
+		f.dirty = false
+		return nil
+	}
+
+	uploadID, err := f.fs.client.createMultipartUpload(f.key, f.meta)
+	if err != nil {
+		return err
+	}
+	var etags []string
+	buf := make([]byte, f.fs.partSize)
+	for offs := int64(0); offs < f.size; offs += f.fs.partSize {
+		part := buf
+		if rem := f.size - offs; rem < int64(len(part)) {
+			part = part[:rem]
+		}
+		if _, err := f.spool.ReadAt(part, offs); err != nil && err != io.EOF {
+			f.fs.client.abortMultipartUpload(f.key, uploadID)
+			return err
+		}
+		etag, err := f.fs.client.uploadPart(f.key, uploadID, len(etags)+1, part)
+		if err != nil {
+			f.fs.client.abortMultipartUpload(f.key, uploadID)
+			return err
+		}
+		etags = append(etags, etag)
+	}
+	if err := f.fs.client.completeMultipartUpload(f.key, uploadID, etags); err != nil {
+		f.fs.client.abortMultipartUpload(f.key, uploadID)
+		return err
+	}
+	f.dirty = false
+	return nil
+}
+
+// s3FileInfo is the stat result for an object or emulated directory.
+type s3FileInfo struct {
+	name     string
+	key      string
+	size     int64
+	mode     FileMode
+	modified time.Time
+	dir      bool
+	marker   bool // directory with a marker object
+}
+
+func newS3FileInfo(name string, obj s3Object, dir bool) *s3FileInfo {
+	info := &s3FileInfo{
+		name:     filepath.Base(name),
+		key:      strings.TrimSuffix(obj.key, "/"),
+		size:     obj.size,
+		modified: obj.modified,
+		dir:      dir,
+		marker:   dir,
+	}
+	if ns, err := strconv.ParseInt(obj.meta[s3MetaMtime], 10, 64); err == nil {
+		info.modified = time.Unix(0, ns)
+	}
+	if mode, err := strconv.ParseUint(obj.meta[s3MetaMode], 8, 32); err == nil {
+		info.mode = FileMode(mode) & ModePerm
+	} else if dir {
+		info.mode = 0777
+	} else {
+		info.mode = 0666
+	}
+	if dir {
+		info.size = 0
+		info.mode |= FileMode(os.ModeDir)
+	}
+	return info
+}
+
+// metaMap returns the metadata to store for the object.
+func (e *s3FileInfo) metaMap() map[string]string {
+	meta := map[string]string{
+		s3MetaMode: strconv.FormatUint(uint64(e.mode&ModePerm), 8),
+	}
+	if !e.modified.IsZero() {
+		meta[s3MetaMtime] = strconv.FormatInt(e.modified.UnixNano(), 10)
+	}
+	return meta
+}
+
+func (e *s3FileInfo) Name() string {
+	return e.name
+}
+
+func (e *s3FileInfo) Mode() FileMode {
+	return e.mode
+}
+
+func (e *s3FileInfo) Size() int64 {
+	return e.size
+}
+
+func (e *s3FileInfo) ModTime() time.Time {
+	return e.modified
+}
+
+func (e *s3FileInfo) IsDir() bool {
+	return e.dir
+}
+
+func (e *s3FileInfo) IsRegular() bool {
+	return !e.dir
+}
+
+func (e *s3FileInfo) IsSymlink() bool {
+	return false
+}
+
+func (e *s3FileInfo) Owner() int {
+	return -1
+}
+
+func (e *s3FileInfo) Group() int {
+	return -1
+}
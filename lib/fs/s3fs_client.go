@@ -0,0 +1,593 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/sha256"
+)
+
+const (
+	s3DefaultRegion = "us-east-1"
+	s3MetaPrefix    = "X-Amz-Meta-"
+	s3TimeFormat    = "20060102T150405Z"
+	s3DateFormat    = "20060102"
+
+	// A request fails when nothing has been sent or received for this
+	// long, however long it takes as a whole.
+	s3IdleTimeout = time.Minute
+
+	// Objects larger than this must be copied in parts, of at least
+	// s3CopyPartSize and at most s3MaxParts of them.
+	s3MaxCopySize  = 5 << 30
+	s3CopyPartSize = 512 << 20
+	s3MaxParts     = 10000
+)
+
+// s3Client is a minimal client for the subset of the S3 REST API that is
+// needed by the s3 filesystem. Requests use path style addressing
+// (http://host/bucket/key) which is what MinIO and most other S3
+// compatible servers expect, and are signed using AWS signature version 4
+// when credentials are given.
+type s3Client struct {
+	endpoint  *url.URL // scheme and host only
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+
+	idleTimeout time.Duration
+	maxCopySize int64
+}
+
+// s3Object is the result of a HEAD or list operation on a single key.
+type s3Object struct {
+	key      string
+	size     int64
+	modified time.Time
+	meta     map[string]string // lower case, without the x-amz-meta- prefix
+}
+
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+	status  int
+	method  string
+	key     string
+}
+
+func (e *s3Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("s3 %s %q: %s", e.method, e.key, http.StatusText(e.status))
+	}
+	return fmt.Sprintf("s3 %s %q: %s: %s", e.method, e.key, e.Code, e.Message)
+}
+
+func (e *s3Error) notFound() bool {
+	return e.status == http.StatusNotFound
+}
+
+func isS3NotFound(err error) bool {
+	s3err, ok := err.(*s3Error)
+	return ok && s3err.notFound()
+}
+
+func newS3Client(endpoint *url.URL, bucket, region, accessKey, secretKey string) *s3Client {
+	if region == "" {
+		region = s3DefaultRegion
+	}
+	return &s3Client{
+		endpoint:  &url.URL{Scheme: endpoint.Scheme, Host: endpoint.Host},
+		bucket:    bucket,
+		region:    region,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		client:    &http.Client{},
+
+		idleTimeout: s3IdleTimeout,
+		maxCopySize: s3MaxCopySize,
+	}
+}
+
+// headObject returns the size, modification time and user metadata of the
+// given key.
+func (c *s3Client) headObject(key string) (s3Object, error) {
+	resp, err := c.do(http.MethodHead, key, nil, nil, nil)
+	if err != nil {
+		return s3Object{}, err
+	}
+	resp.Body.Close()
+	obj := s3Object{
+		key:  key,
+		size: resp.ContentLength,
+		meta: make(map[string]string),
+	}
+	obj.modified, _ = http.ParseTime(resp.Header.Get("Last-Modified"))
+	for k, v := range resp.Header {
+		if strings.HasPrefix(k, s3MetaPrefix) && len(v) > 0 {
+			obj.meta[strings.ToLower(strings.TrimPrefix(k, s3MetaPrefix))] = v[0]
+		}
+	}
+	return obj, nil
+}
+
+// getObjectRange reads up to len(p) bytes of the given key, starting at
+// offset.
+func (c *s3Client) getObjectRange(key string, p []byte, offset int64) (int, error) {
+	hdr := http.Header{}
+	hdr.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+int64(len(p))-1))
+	resp, err := c.do(http.MethodGet, key, nil, hdr, nil)
+	if err != nil {
+		if s3err, ok := err.(*s3Error); ok && s3err.status == http.StatusRequestedRangeNotSatisfiable {
+			return 0, io.EOF
+		}
+		return 0, err
+	}
+	defer resp.Body.Close()
+	n, err := io.ReadFull(resp.Body, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// getObject writes the full contents of the given key to w.
+func (c *s3Client) getObject(key string, w io.Writer) error {
+	resp, err := c.do(http.MethodGet, key, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+func (c *s3Client) putObject(key string, data []byte, meta map[string]string) error {
+	resp, err := c.do(http.MethodPut, key, nil, metaHeader(meta), data)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// copyObject performs a server side copy of src, which is size bytes, to
+// dst. If meta is nil the metadata of the source is retained, otherwise it
+// is replaced.
+func (c *s3Client) copyObject(src, dst string, size int64, meta map[string]string) error {
+	if size > c.maxCopySize {
+		return c.copyObjectMultipart(src, dst, size, meta)
+	}
+
+	hdr := metaHeader(meta)
+	hdr.Set("X-Amz-Copy-Source", "/"+c.bucket+"/"+s3EscapePath(src))
+	if meta != nil {
+		hdr.Set("X-Amz-Metadata-Directive", "REPLACE")
+	}
+	resp, err := c.do(http.MethodPut, dst, nil, hdr, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// A copy may fail after the 200 OK has been sent, in which case the
+	// error is in the body.
+	return checkS3Body(resp, http.MethodPut, dst)
+}
+
+// copyObjectMultipart copies src to dst one range at a time, for objects
+// that are too large to copy at once.
+func (c *s3Client) copyObjectMultipart(src, dst string, size int64, meta map[string]string) error {
+	if meta == nil {
+		obj, err := c.headObject(src)
+		if err != nil {
+			return err
+		}
+		meta = obj.meta
+	}
+
+	partSize := int64(s3CopyPartSize)
+	if min := (size + s3MaxParts - 1) / s3MaxParts; partSize < min {
+		partSize = min
+	}
+
+	uploadID, err := c.createMultipartUpload(dst, meta)
+	if err != nil {
+		return err
+	}
+	var etags []string
+	for offs := int64(0); offs < size; offs += partSize {
+		last := offs + partSize - 1
+		if last >= size {
+			last = size - 1
+		}
+		etag, err := c.uploadPartCopy(dst, uploadID, len(etags)+1, src, offs, last)
+		if err != nil {
+			c.abortMultipartUpload(dst, uploadID)
+			return err
+		}
+		etags = append(etags, etag)
+	}
+	if err := c.completeMultipartUpload(dst, uploadID, etags); err != nil {
+		c.abortMultipartUpload(dst, uploadID)
+		return err
+	}
+	return nil
+}
+
+func (c *s3Client) deleteObject(key string) error {
+	resp, err := c.do(http.MethodDelete, key, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// listObjects lists the keys under the given prefix. With a non-empty
+// delimiter keys containing the delimiter after the prefix are rolled up
+// into the returned common prefixes. At most max keys are returned, or all
+// of them if max is zero.
+func (c *s3Client) listObjects(prefix, delimiter string, max int) ([]s3Object, []string, error) {
+	var objects []s3Object
+	var prefixes []string
+	token := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", prefix)
+		if delimiter != "" {
+			query.Set("delimiter", delimiter)
+		}
+		if max > 0 {
+			query.Set("max-keys", strconv.Itoa(max))
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := c.do(http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		var res struct {
+			Contents []struct {
+				Key          string    `xml:"Key"`
+				Size         int64     `xml:"Size"`
+				LastModified time.Time `xml:"LastModified"`
+			} `xml:"Contents"`
+			CommonPrefixes []struct {
+				Prefix string `xml:"Prefix"`
+			} `xml:"CommonPrefixes"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&res)
+		resp.Body.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, obj := range res.Contents {
+			objects = append(objects, s3Object{key: obj.Key, size: obj.Size, modified: obj.LastModified})
+		}
+		for _, p := range res.CommonPrefixes {
+			prefixes = append(prefixes, p.Prefix)
+		}
+
+		if !res.IsTruncated || res.NextContinuationToken == "" || (max > 0 && len(objects)+len(prefixes) >= max) {
+			return objects, prefixes, nil
+		}
+		token = res.NextContinuationToken
+	}
+}
+
+func (c *s3Client) createMultipartUpload(key string, meta map[string]string) (string, error) {
+	resp, err := c.do(http.MethodPost, key, url.Values{"uploads": {""}}, metaHeader(meta), nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var res struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return "", err
+	}
+	return res.UploadID, nil
+}
+
+func (c *s3Client) uploadPart(key, uploadID string, partNumber int, data []byte) (string, error) {
+	query := url.Values{}
+	query.Set("partNumber", strconv.Itoa(partNumber))
+	query.Set("uploadId", uploadID)
+	resp, err := c.do(http.MethodPut, key, query, nil, data)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return resp.Header.Get("ETag"), nil
+}
+
+// uploadPartCopy copies the bytes from first to last, inclusive, of src as
+// a part of the upload.
+func (c *s3Client) uploadPartCopy(key, uploadID string, partNumber int, src string, first, last int64) (string, error) {
+	query := url.Values{}
+	query.Set("partNumber", strconv.Itoa(partNumber))
+	query.Set("uploadId", uploadID)
+	hdr := http.Header{}
+	hdr.Set("X-Amz-Copy-Source", "/"+c.bucket+"/"+s3EscapePath(src))
+	hdr.Set("X-Amz-Copy-Source-Range", fmt.Sprintf("bytes=%d-%d", first, last))
+	resp, err := c.do(http.MethodPut, key, query, hdr, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	// Unlike for other parts, the ETag is in the body, which may also
+	// hold an error.
+	bs, err := ioutil.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return "", err
+	}
+	if bytes.Contains(bs, []byte("<Error>")) {
+		s3err := &s3Error{status: resp.StatusCode, method: http.MethodPut, key: key}
+		xml.Unmarshal(bs, s3err)
+		return "", s3err
+	}
+	var res struct {
+		ETag string `xml:"ETag"`
+	}
+	if err := xml.Unmarshal(bs, &res); err != nil {
+		return "", err
+	}
+	return res.ETag, nil
+}
+
+func (c *s3Client) completeMultipartUpload(key, uploadID string, etags []string) error {
+	type part struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	}
+	var req struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []part   `xml:"Part"`
+	}
+	for i, etag := range etags {
+		req.Parts = append(req.Parts, part{PartNumber: i + 1, ETag: etag})
+	}
+	body, err := xml.Marshal(req)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(http.MethodPost, key, url.Values{"uploadId": {uploadID}}, nil, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkS3Body(resp, http.MethodPost, key)
+}
+
+func (c *s3Client) abortMultipartUpload(key, uploadID string) error {
+	resp, err := c.do(http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// do performs a signed request against the given key in the bucket. Any
+// non 2xx response is returned as an *s3Error.
+func (c *s3Client) do(method, key string, query url.Values, hdr http.Header, body []byte) (*http.Response, error) {
+	u := *c.endpoint
+	u.Path = "/" + c.bucket + "/" + key
+	u.RawPath = "/" + c.bucket + "/" + s3EscapePath(key)
+	u.RawQuery = s3CanonicalQuery(query)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	for k, v := range hdr {
+		req.Header[k] = v
+	}
+	c.sign(req, u.RawPath, body, time.Now().UTC())
+
+	// Cancel the request when it stalls, which is noticed by neither
+	// the request nor the response body making progress.
+	ctx, cancel := context.WithCancel(context.Background())
+	timer := time.AfterFunc(c.idleTimeout, cancel)
+	req = req.WithContext(ctx)
+	if len(body) > 0 {
+		req.Body = &s3IdleReader{ReadCloser: req.Body, timer: timer, timeout: c.idleTimeout}
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		timer.Stop()
+		cancel()
+		return nil, err
+	}
+	resp.Body = &s3IdleReader{ReadCloser: resp.Body, timer: timer, timeout: c.idleTimeout, cancel: cancel}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		s3err := &s3Error{status: resp.StatusCode, method: method, key: key}
+		if method != http.MethodHead {
+			bs, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 64<<10))
+			xml.Unmarshal(bs, s3err)
+		}
+		return nil, s3err
+	}
+	return resp, nil
+}
+
+// s3IdleReader pushes back the timeout of the request on every read. Closing
+// the response body ends the request.
+type s3IdleReader struct {
+	io.ReadCloser
+	timer   *time.Timer
+	timeout time.Duration
+	cancel  context.CancelFunc // nil for the request body
+}
+
+func (r *s3IdleReader) Read(p []byte) (int, error) {
+	r.timer.Reset(r.timeout)
+	return r.ReadCloser.Read(p)
+}
+
+func (r *s3IdleReader) Close() error {
+	if r.cancel != nil {
+		r.timer.Stop()
+		defer r.cancel()
+	}
+	return r.ReadCloser.Close()
+}
+
+// sign adds an AWS signature version 4 authorization header to the request.
+// Anonymous requests are left unsigned.
+func (c *s3Client) sign(req *http.Request, canonicalURI string, body []byte, now time.Time) {
+	payloadHash := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	req.Header.Set("X-Amz-Date", now.Format(s3TimeFormat))
+	if c.accessKey == "" {
+		return
+	}
+
+	headers := []string{"host"}
+	canonicalHeaders := "host:" + req.URL.Host + "\n"
+	var names []string
+	for k := range req.Header {
+		lk := strings.ToLower(k)
+		if strings.HasPrefix(lk, "x-amz-") || lk == "content-type" || lk == "range" {
+			names = append(names, k)
+		}
+	}
+	sort.Slice(names, func(a, b int) bool { return strings.ToLower(names[a]) < strings.ToLower(names[b]) })
+	for _, k := range names {
+		lk := strings.ToLower(k)
+		headers = append(headers, lk)
+		canonicalHeaders += lk + ":" + strings.TrimSpace(strings.Join(req.Header[k], ",")) + "\n"
+	}
+	signedHeaders := strings.Join(headers, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI,
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := now.Format(s3DateFormat) + "/" + c.region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + now.Format(s3TimeFormat) + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := s3HMAC([]byte("AWS4"+c.secretKey), now.Format(s3DateFormat))
+	key = s3HMAC(key, c.region)
+	key = s3HMAC(key, "s3")
+	key = s3HMAC(key, "aws4_request")
+	signature := hex.EncodeToString(s3HMAC(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", c.accessKey, scope, signedHeaders, signature))
+}
+
+func s3HMAC(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// checkS3Body looks for an error document in an otherwise successful
+// response.
+func checkS3Body(resp *http.Response, method, key string) error {
+	bs, err := ioutil.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return err
+	}
+	if bytes.Contains(bs, []byte("<Error>")) {
+		s3err := &s3Error{status: resp.StatusCode, method: method, key: key}
+		xml.Unmarshal(bs, s3err)
+		return s3err
+	}
+	return nil
+}
+
+func metaHeader(meta map[string]string) http.Header {
+	hdr := http.Header{}
+	for k, v := range meta {
+		hdr.Set(s3MetaPrefix+k, v)
+	}
+	return hdr
+}
+
+// s3EscapePath escapes each segment of the key according to the rules for
+// the canonical URI in signature version 4.
+func s3EscapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i := range segments {
+		segments[i] = s3Escape(segments[i])
+	}
+	return strings.Join(segments, "/")
+}
+
+// s3CanonicalQuery returns the query string with keys sorted and values
+// escaped, as required for signing.
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, s3Escape(k)+"="+s3Escape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func s3Escape(s string) string {
+	var buf strings.Builder
+	for _, b := range []byte(s) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9', b == '-', b == '_', b == '.', b == '~':
+			buf.WriteByte(b)
+		default:
+			fmt.Fprintf(&buf, "%%%02X", b)
+		}
+	}
+	return buf.String()
+}
+
+// s3Credentials returns the access and secret key from the URI, falling
+// back to the standard AWS environment variables.
+func s3Credentials(u *url.URL) (string, string) {
+	if u.User != nil {
+		secret, _ := u.User.Password()
+		return u.User.Username(), secret
+	}
+	return os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+}
@@ -11,6 +11,7 @@ type FilesystemType int
 const (
 	FilesystemTypeBasic FilesystemType = iota // default is basic
 	FilesystemTypeFake
+	FilesystemTypeS3
 )
 
 func (t FilesystemType) String() string {
@@ -19,6 +20,8 @@ func (t FilesystemType) String() string {
 		return "basic"
 	case FilesystemTypeFake:
 		return "fake"
+	case FilesystemTypeS3:
+		return "s3"
 	default:
 		return "unknown"
 	}
@@ -34,6 +37,8 @@ func (t *FilesystemType) UnmarshalText(bs []byte) error {
 		*t = FilesystemTypeBasic
 	case "fake":
 		*t = FilesystemTypeFake
+	case "s3":
+		*t = FilesystemTypeS3
 	default:
 		*t = FilesystemTypeBasic
 	}
@@ -92,3 +92,7 @@ func (f *walkFilesystem) Walk(root string, walkFn WalkFunc) error {
 	}
 	return f.walk(root, info, walkFn)
 }
+
+func (f *walkFilesystem) CopyRange(src, dst File, srcOffset, dstOffset, size int64) error {
+	return CopyRange(f.Filesystem, src, dst, srcOffset, dstOffset, size)
+}
@@ -30,6 +30,14 @@ const (
 	resultFoldCase          = 1 << iota
 )
 
+// defaultResult is the result of a pattern without prefixes.
+var defaultResult Result = func() Result {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		return resultInclude | resultFoldCase
+	}
+	return resultInclude
+}()
+
 type Pattern struct {
 	pattern string
 	match   glob.Glob
@@ -86,6 +94,7 @@ type Matcher struct {
 	stop            chan struct{}
 	changeDetector  ChangeDetector
 	skipIgnoredDirs bool
+	selection       []string // selected subtrees, empty for everything
 	mut             sync.Mutex
 }
 
@@ -107,6 +116,22 @@ func WithChangeDetector(cd ChangeDetector) Option {
 	}
 }
 
+// WithSelection restricts the matcher to the given slash separated
+// subtrees of the folder: anything that is neither within one of them nor a
+// parent directory of one is ignored, regardless of the patterns. The
+// default is to select everything.
+func WithSelection(paths []string) Option {
+	return func(m *Matcher) {
+		m.selection = make([]string, len(paths))
+		for i, p := range paths {
+			if defaultResult.IsCaseFolded() {
+				p = strings.ToLower(p)
+			}
+			m.selection[i] = p
+		}
+	}
+}
+
 func New(fs fs.Filesystem, opts ...Option) *Matcher {
 	m := &Matcher{
 		fs:              fs,
@@ -165,7 +190,7 @@ func (m *Matcher) parseLocked(r io.Reader, file string) error {
 
 	m.lines = lines
 
-	newHash := hashPatterns(patterns)
+	newHash := hashPatterns(patterns, m.selection)
 	if newHash == m.curHash {
 		// We've already loaded exactly these patterns.
 		return err
@@ -193,6 +218,10 @@ func (m *Matcher) Match(file string) (result Result) {
 		return resultNotMatched
 	}
 
+	if !m.isSelected(file) {
+		return resultInclude
+	}
+
 	m.mut.Lock()
 	defer m.mut.Unlock()
 
@@ -235,6 +264,24 @@ func (m *Matcher) Match(file string) (result Result) {
 	return resultNotMatched
 }
 
+// isSelected returns true if file is within or a parent of the selected
+// subtrees. The selection is immutable, hence no locking.
+func (m *Matcher) isSelected(file string) bool {
+	if len(m.selection) == 0 {
+		return true
+	}
+	file = filepath.ToSlash(file)
+	if defaultResult.IsCaseFolded() {
+		file = strings.ToLower(file)
+	}
+	for _, sel := range m.selection {
+		if file == sel || strings.HasPrefix(file, sel+"/") || strings.HasPrefix(sel, file+"/") {
+			return true
+		}
+	}
+	return false
+}
+
 // Lines return a list of the unprocessed lines in .stignore at last load
 func (m *Matcher) Lines() []string {
 	m.mut.Lock()
@@ -307,12 +354,16 @@ func (m *Matcher) SkipIgnoredDirs() bool {
 	return m.skipIgnoredDirs
 }
 
-func hashPatterns(patterns []Pattern) string {
+func hashPatterns(patterns []Pattern, selection []string) string {
 	h := md5.New()
 	for _, pat := range patterns {
 		h.Write([]byte(pat.String()))
 		h.Write([]byte("\n"))
 	}
+	for _, sel := range selection {
+		// Not a valid pattern line, so it can't collide with one.
+		h.Write([]byte("\x00" + sel + "\n"))
+	}
 	return fmt.Sprintf("%x", h.Sum(nil))
 }
 
@@ -362,11 +413,6 @@ func parseIgnoreFile(fs fs.Filesystem, fd io.Reader, currentFile string, cd Chan
 	var lines []string
 	var patterns []Pattern
 
-	defaultResult := resultInclude
-	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
-		defaultResult |= resultFoldCase
-	}
-
 	addPattern := func(line string) error {
 		pattern := Pattern{
 			result: defaultResult,
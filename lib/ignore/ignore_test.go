@@ -1079,3 +1079,42 @@ func TestSpecialChars(t *testing.T) {
 		}
 	}
 }
+
+func TestSelection(t *testing.T) {
+	pats := New(fs.NewFilesystem(fs.FilesystemTypeBasic, "."), WithSelection([]string{"photos/2019", "docs"}))
+
+	if err := pats.Parse(bytes.NewBufferString("*.tmp\n"), ".stignore"); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		file    string
+		ignored bool
+	}{
+		{"photos", false}, // parent of a selected subtree
+		{"photos/2019", false},
+		{"photos/2019/img.jpg", false},
+		{"photos/2019/img.tmp", true}, // patterns still apply
+		{"photos/2018", true},
+		{"photos/2019-old", true},
+		{"docs/a/b/c", false},
+		{"music", true},
+		{"music/song.mp3", true},
+	}
+
+	for _, tc := range cases {
+		if res := pats.Match(filepath.FromSlash(tc.file)).IsIgnored(); res != tc.ignored {
+			t.Errorf("Match(%q).IsIgnored() == %v, expected %v", tc.file, res, tc.ignored)
+		}
+	}
+
+	// The selection is part of the hash, so that changing it is detected
+	// as an ignore change.
+	plain := New(fs.NewFilesystem(fs.FilesystemTypeBasic, "."))
+	if err := plain.Parse(bytes.NewBufferString("*.tmp\n"), ".stignore"); err != nil {
+		t.Fatal(err)
+	}
+	if plain.Hash() == pats.Hash() {
+		t.Error("selection should change the hash")
+	}
+}
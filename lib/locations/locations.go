@@ -28,11 +28,13 @@ const (
 	HTTPSCertFile LocationEnum = "httpsCertFile"
 	HTTPSKeyFile  LocationEnum = "httpsKeyFile"
 	Database      LocationEnum = "database"
+	BadgerDB      LocationEnum = "badgerDatabase"
 	LogFile       LocationEnum = "logFile"
 	CsrfTokens    LocationEnum = "csrfTokens"
 	PanicLog      LocationEnum = "panicLog"
 	AuditLog      LocationEnum = "auditLog"
 	GUIAssets     LocationEnum = "GUIAssets"
+	MountCache    LocationEnum = "mountCache"
 	DefFolder     LocationEnum = "defFolder"
 )
 
@@ -82,11 +84,13 @@ var locationTemplates = map[LocationEnum]string{
 	HTTPSCertFile: "${config}/https-cert.pem",
 	HTTPSKeyFile:  "${config}/https-key.pem",
 	Database:      "${config}/index-v0.14.0.db",
+	BadgerDB:      "${config}/index-badger.db",
 	LogFile:       "${config}/syncthing.log", // -logfile on Windows
 	CsrfTokens:    "${config}/csrftokens.txt",
 	PanicLog:      "${config}/panic-${timestamp}.log",
 	AuditLog:      "${config}/audit-${timestamp}.log",
 	GUIAssets:     "${config}/gui",
+	MountCache:    "${config}/mountcache",
 	DefFolder:     "${home}/Sync",
 }
 
@@ -0,0 +1,198 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// Package merge implements three way merging of text, line by line.
+package merge
+
+import (
+	"bytes"
+	"errors"
+)
+
+// The largest number of lines inserted or removed between two versions
+// that is handled. The memory needed grows with its square.
+const maxEdits = 1000
+
+var (
+	ErrConflict  = errors.New("changes overlap")
+	ErrBinary    = errors.New("not text")
+	ErrTooLarge  = errors.New("too many changes")
+	errNoChanges = errors.New("bug: unstable chunk without changes")
+)
+
+// ThreeWay merges the changes made to base in local and remote, which must
+// not overlap. Changes are made up of whole lines, and where both made the
+// same change it is only applied once. It returns ErrConflict if the changes
+// overlap, ErrBinary if any of the versions doesn't look like text and
+// ErrTooLarge if there are too many changes to handle.
+func ThreeWay(base, local, remote []byte) ([]byte, error) {
+	for _, bs := range [][]byte{base, local, remote} {
+		if bytes.IndexByte(bs, 0) >= 0 {
+			return nil, ErrBinary
+		}
+	}
+
+	o, a, b := lines(base), lines(local), lines(remote)
+	ma, err := matches(o, a)
+	if err != nil {
+		return nil, err
+	}
+	mb, err := matches(o, b)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	i, j, k := 0, 0, 0
+	for i < len(o) || j < len(a) || k < len(b) {
+		// Lines that are unchanged in both
+		if i < len(o) && ma[i] == j && mb[i] == k {
+			out.WriteString(o[i])
+			i++
+			j++
+			k++
+			continue
+		}
+
+		// A chunk with changes, which lasts until the next line that is
+		// in all three, or the end.
+		ni, nj, nk := len(o), len(a), len(b)
+		for n := i; n < len(o); n++ {
+			if ma[n] >= 0 && mb[n] >= 0 {
+				ni, nj, nk = n, ma[n], mb[n]
+				break
+			}
+		}
+
+		co, ca, cb := o[i:ni], a[j:nj], b[k:nk]
+		switch {
+		case equal(ca, co):
+			writeLines(&out, cb)
+		case equal(cb, co), equal(ca, cb):
+			writeLines(&out, ca)
+		default:
+			return nil, ErrConflict
+		}
+		if ni == i && nj == j && nk == k {
+			return nil, errNoChanges
+		}
+		i, j, k = ni, nj, nk
+	}
+
+	return out.Bytes(), nil
+}
+
+// lines splits the text into lines, each including its line ending.
+func lines(bs []byte) []string {
+	var ls []string
+	for len(bs) > 0 {
+		n := bytes.IndexByte(bs, '\n') + 1
+		if n == 0 {
+			n = len(bs)
+		}
+		ls = append(ls, string(bs[:n]))
+		bs = bs[n:]
+	}
+	return ls
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func writeLines(buf *bytes.Buffer, ls []string) {
+	for _, l := range ls {
+		buf.WriteString(l)
+	}
+}
+
+// matches returns, for each line of a, the index of the same line of b in a
+// longest common subsequence of the two, or -1 if the line isn't in it. It
+// uses the O(ND) difference algorithm by Eugene W. Myers.
+func matches(a, b []string) ([]int, error) {
+	n, m := len(a), len(b)
+	max := n + m
+	if max > maxEdits {
+		max = maxEdits
+	}
+
+	// v[off+k] is the furthest x reached on diagonal k = x - y; trace[d]
+	// holds the diagonals -d to d of it after d edits.
+	off := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+	found := false
+	for d := 0; d <= max && !found; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1] // down, an insertion
+			} else {
+				x = v[off+k-1] + 1 // right, a removal
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+		trace = append(trace, append([]int(nil), v[off-d:off+d+1]...))
+	}
+	if !found {
+		return nil, ErrTooLarge
+	}
+
+	match := make([]int, n)
+	for i := range match {
+		match[i] = -1
+	}
+
+	// Walk back from the end, recording the diagonal stretches.
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1] // diagonals -(d-1) to d-1
+		k := x - y
+		var pk int
+		if k == -d || (k != d && prev[k-1+d-1] < prev[k+1+d-1]) {
+			pk = k + 1
+		} else {
+			pk = k - 1
+		}
+		px := prev[pk+d-1]
+		py := px - pk
+		// The snake after the edit from (px, py)
+		sx := px
+		if pk == k-1 {
+			sx++
+		}
+		for x > sx {
+			x--
+			y--
+			match[x] = y
+		}
+		x, y = px, py
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		match[x] = y
+	}
+
+	return match, nil
+}
@@ -0,0 +1,109 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package merge
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestThreeWay(t *testing.T) {
+	cases := []struct {
+		base, local, remote string
+		merged              string
+		err                 error
+	}{
+		// Nothing changed
+		{"a\nb\nc\n", "a\nb\nc\n", "a\nb\nc\n", "a\nb\nc\n", nil},
+		// Changed on one side
+		{"a\nb\nc\n", "a\nB\nc\n", "a\nb\nc\n", "a\nB\nc\n", nil},
+		{"a\nb\nc\n", "a\nb\nc\n", "a\nb\nC\n", "a\nb\nC\n", nil},
+		// Separate changes on both sides
+		{"a\nb\nc\nd\ne\n", "A\nb\nc\nd\ne\n", "a\nb\nc\nd\nE\n", "A\nb\nc\nd\nE\n", nil},
+		// Insertions and removals
+		{"a\nb\nc\nd\n", "a\nx\nb\nc\nd\n", "a\nb\nc\n", "a\nx\nb\nc\n", nil},
+		{"a\nb\nc\n", "b\nc\n", "a\nb\nc\nd\n", "b\nc\nd\n", nil},
+		// The same change on both sides
+		{"a\nb\nc\n", "a\nx\nc\n", "a\nx\nc\n", "a\nx\nc\n", nil},
+		{"a\nb\nc\n", "a\nc\n", "a\nc\n", "a\nc\n", nil},
+		// Empty files
+		{"", "a\n", "", "a\n", nil},
+		{"", "", "", "", nil},
+		// Missing newline at the end
+		{"a\nb", "a\nb\nc", "x\na\nb", "x\na\nb\nc", nil},
+		{"a\nb\n", "a\nb\nc", "x\na\nb\n", "x\na\nb\nc", nil},
+		// Overlapping changes
+		{"a\nb\nc\n", "a\nx\nc\n", "a\ny\nc\n", "", ErrConflict},
+		{"a\nb\nc\n", "a\nx\nb\nc\n", "a\ny\nb\nc\n", "", ErrConflict},
+		{"a\nb\nc\n", "a\nc\n", "a\nB\nc\n", "", ErrConflict},
+		// Binary
+		{"a\x00", "a\x00b", "a\x00", "", ErrBinary},
+	}
+
+	for i, tc := range cases {
+		merged, err := ThreeWay([]byte(tc.base), []byte(tc.local), []byte(tc.remote))
+		if err != tc.err {
+			t.Errorf("%d: expected error %v, got %v", i, tc.err, err)
+			continue
+		}
+		if err == nil && string(merged) != tc.merged {
+			t.Errorf("%d: expected %q, got %q", i, tc.merged, merged)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	// The matches must make up a common subsequence as long as the known
+	// one of randomly changed text.
+	r := rand.New(rand.NewSource(42))
+	for n := 0; n < 100; n++ {
+		var a, b []string
+		common := 0
+		for i := 0; i < 200; i++ {
+			line := fmt.Sprintf("%d\n", r.Intn(20))
+			switch r.Intn(4) {
+			case 0:
+				a = append(a, line)
+			case 1:
+				b = append(b, line)
+			default:
+				a = append(a, line)
+				b = append(b, line)
+				common++
+			}
+		}
+
+		match, err := matches(a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		last, found := -1, 0
+		for i, j := range match {
+			if j < 0 {
+				continue
+			}
+			if j <= last || a[i] != b[j] {
+				t.Fatalf("Bad match of line %d to %d after %d", i, j, last)
+			}
+			last = j
+			found++
+		}
+		if found < common {
+			t.Fatalf("Found %d common lines, expected at least %d", found, common)
+		}
+	}
+}
+
+func TestTooLarge(t *testing.T) {
+	a := strings.Repeat("a\n", maxEdits)
+	b := strings.Repeat("b\n", maxEdits)
+	if _, err := ThreeWay([]byte(a), []byte(b), []byte(a)); err != ErrTooLarge {
+		t.Error("Expected too many changes, got", err)
+	}
+}
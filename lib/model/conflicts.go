@@ -0,0 +1,207 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/osutil"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// The name of a conflict copy as created by conflictName, i.e. the original
+// name with the time and the short ID of the device that made the
+// conflicting change inserted before the extension.
+var conflictNameExp = regexp.MustCompile(`^(.*)\.sync-conflict-\d{8}-\d{6}-([A-Z2-7]{7})?(.*)$`)
+
+var (
+	errNotAConflict = errors.New("not a conflict copy of the file")
+	errKeepMissing  = errors.New("the version to keep does not exist")
+)
+
+// A FolderConflict is a file with the conflict copies made of it.
+type FolderConflict struct {
+	Original ConflictFile   `json:"original"`
+	Copies   []ConflictFile `json:"copies"`
+}
+
+// A ConflictFile is either side of a conflict, as last scanned.
+type ConflictFile struct {
+	Name       string    `json:"name"`
+	ModifiedBy string    `json:"modifiedBy,omitempty"` // short device ID, for conflict copies
+	Size       int64     `json:"size"`
+	ModTime    time.Time `json:"modTime"`
+	Deleted    bool      `json:"deleted,omitempty"`
+}
+
+// parseConflictName returns the name of the file that the named conflict
+// copy was made of, and the short ID of the device that changed it.
+func parseConflictName(name string) (string, string, bool) {
+	m := conflictNameExp.FindStringSubmatch(filepath.Base(name))
+	if m == nil {
+		return "", "", false
+	}
+	return filepath.Join(filepath.Dir(name), m[1]+m[3]), m[2], true
+}
+
+// FolderConflicts returns the files in the folder that have conflict copies,
+// sorted by name. Conflict copies that are ignored are not included.
+func (m *model) FolderConflicts(folder string) ([]FolderConflict, error) {
+	m.fmut.RLock()
+	fset, ok := m.folderFiles[folder]
+	m.fmut.RUnlock()
+	if !ok {
+		return nil, errFolderMissing
+	}
+
+	return folderConflicts(fset), nil
+}
+
+func folderConflicts(fset *db.FileSet) []FolderConflict {
+	copies := make(map[string][]ConflictFile)
+	fset.WithHaveTruncated(protocol.LocalDeviceID, func(fi db.FileIntf) bool {
+		f := fi.(db.FileInfoTruncated)
+		if f.IsDeleted() || f.IsInvalid() || f.IsDirectory() || !isConflict(f.Name) {
+			return true
+		}
+		original, device, ok := parseConflictName(f.Name)
+		if !ok {
+			return true
+		}
+		copies[original] = append(copies[original], ConflictFile{
+			Name:       f.Name,
+			ModifiedBy: device,
+			Size:       f.Size,
+			ModTime:    f.ModTime(),
+		})
+		return true
+	})
+
+	conflicts := make([]FolderConflict, 0, len(copies))
+	for original, cs := range copies {
+		sort.Slice(cs, func(a, b int) bool { return cs[a].Name < cs[b].Name })
+		c := FolderConflict{
+			Original: ConflictFile{Name: original, Deleted: true},
+			Copies:   cs,
+		}
+		if f, ok := fset.Get(protocol.LocalDeviceID, original); ok && !f.IsDeleted() && !f.IsInvalid() {
+			c.Original.Size = f.Size
+			c.Original.ModTime = f.ModTime()
+			c.Original.Deleted = false
+		}
+		conflicts = append(conflicts, c)
+	}
+	sort.Slice(conflicts, func(a, b int) bool { return conflicts[a].Original.Name < conflicts[b].Original.Name })
+	return conflicts
+}
+
+// ResolveConflicts resolves the conflicts of the given files in favour of the
+// version chosen for each, either the file itself or one of its conflict
+// copies. A chosen copy takes the place of the file, and the remaining
+// versions are archived by the versioner, or removed if there is none.
+// Choosing a version that no longer exists is an error. The affected files
+// are rescanned. It returns the errors per file that could not
+// be resolved.
+func (m *model) ResolveConflicts(folder string, chosen map[string]string) (map[string]string, error) {
+	m.fmut.RLock()
+	fset, ok := m.folderFiles[folder]
+	fcfg := m.folderCfgs[folder]
+	m.fmut.RUnlock()
+	if !ok {
+		return nil, errFolderMissing
+	}
+
+	ffs := fcfg.Filesystem()
+	ver := fcfg.Versioner()
+	remove := ffs.Remove
+	if ver != nil {
+		remove = ver.Archive
+	}
+
+	conflicts := make(map[string]FolderConflict)
+	for _, c := range folderConflicts(fset) {
+		conflicts[c.Original.Name] = c
+	}
+
+	resolveErrors := make(map[string]string)
+	var scan []string
+	for original, keep := range chosen {
+		original = filepath.Clean(original)
+		keep = filepath.Clean(keep)
+		c, ok := conflicts[original]
+		if !ok {
+			resolveErrors[original] = "no conflicts for file"
+			continue
+		}
+		if err := resolveConflict(ffs, c, keep, remove); err != nil {
+			resolveErrors[original] = err.Error()
+		}
+		scan = append(scan, original)
+		for _, cf := range c.Copies {
+			scan = append(scan, cf.Name)
+		}
+	}
+
+	if len(scan) > 0 {
+		if err := m.ScanFolderSubdirs(folder, scan); err != nil {
+			l.Infof("Rescanning folder %s after resolving conflicts: %v", fcfg.Description(), err)
+		}
+	}
+
+	return resolveErrors, nil
+}
+
+// resolveConflict makes the version named keep the only one left of the
+// conflicting ones, using remove to get rid of the others.
+func resolveConflict(ffs fs.Filesystem, c FolderConflict, keep string, remove func(string) error) error {
+	found := keep == c.Original.Name
+	for _, cf := range c.Copies {
+		if cf.Name == keep {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return errNotAConflict
+	}
+
+	// Keeping a version that has since been deleted would leave nothing.
+	if keep == c.Original.Name && c.Original.Deleted {
+		return errKeepMissing
+	}
+	if _, err := ffs.Lstat(keep); fs.IsNotExist(err) {
+		return errKeepMissing
+	} else if err != nil {
+		return err
+	}
+
+	if keep != c.Original.Name {
+		if err := remove(c.Original.Name); err != nil && !fs.IsNotExist(err) {
+			return errors.Wrap(err, "removing original")
+		}
+		if err := osutil.RenameOrCopy(ffs, ffs, keep, c.Original.Name); err != nil {
+			return errors.Wrap(err, "replacing original")
+		}
+	}
+
+	for _, cf := range c.Copies {
+		if cf.Name == keep {
+			continue
+		}
+		if err := remove(cf.Name); err != nil && !fs.IsNotExist(err) {
+			return errors.Wrap(err, "removing conflict copy")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/fs"
+)
+
+func TestParseConflictName(t *testing.T) {
+	cases := []struct {
+		name     string
+		original string
+		device   string
+		ok       bool
+	}{
+		{"foo.sync-conflict-20190101-120000-ABCDEFG.txt", "foo.txt", "ABCDEFG", true},
+		{"foo.sync-conflict-20190101-120000-ABCDEFG", "foo", "ABCDEFG", true},
+		{"foo.sync-conflict-20190101-120000-.txt", "foo.txt", "", true},
+		{filepath.Join("dir", "foo.tar.sync-conflict-20190101-120000-ABCDEFG.gz"), filepath.Join("dir", "foo.tar.gz"), "ABCDEFG", true},
+		{filepath.Join("dir.sync-conflict-20190101-120000-ABCDEFG", "foo"), "", "", false},
+		{"foo.txt", "", "", false},
+	}
+
+	for _, tc := range cases {
+		original, device, ok := parseConflictName(tc.name)
+		if original != tc.original || device != tc.device || ok != tc.ok {
+			t.Errorf("parseConflictName(%q) = %q, %q, %v, expected %q, %q, %v", tc.name, original, device, ok, tc.original, tc.device, tc.ok)
+		}
+	}
+
+	// Round trip
+	name := conflictName(filepath.Join("dir", "foo.txt"), "ABCDEFG")
+	if original, device, ok := parseConflictName(name); !ok || original != filepath.Join("dir", "foo.txt") || device != "ABCDEFG" {
+		t.Errorf("Conflict name %q parsed as %q, %q, %v", name, original, device, ok)
+	}
+}
+
+func TestResolveConflicts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	must(t, err)
+	defer os.RemoveAll(dir)
+
+	fcfg := config.NewFolderConfiguration(myID, "default", "default", fs.FilesystemTypeBasic, dir)
+	fcfg.FSWatcherEnabled = false
+	ffs := fcfg.Filesystem()
+	cfg := createTmpWrapper(config.Configuration{
+		Folders: []config.FolderConfiguration{fcfg},
+	})
+	m := setupModel(cfg)
+	defer cleanupModel(m)
+
+	files := []string{
+		"a.txt",
+		"a.sync-conflict-20190101-120000-ABCDEFG.txt",
+		"a.sync-conflict-20190102-120000-HIJKLMN.txt",
+		"b",
+		"b.sync-conflict-20190101-120000-ABCDEFG",
+		"c.sync-conflict-20190101-120000-ABCDEFG", // original is gone
+		"e.sync-conflict-20190101-120000-ABCDEFG", // original is gone
+	}
+	for _, file := range files {
+		must(t, ioutil.WriteFile(filepath.Join(dir, file), []byte(file), 0644))
+	}
+	must(t, m.ScanFolder("default"))
+
+	conflicts, err := m.FolderConflicts("default")
+	must(t, err)
+	if len(conflicts) != 4 {
+		t.Fatalf("Expected four files in conflict, got %v", conflicts)
+	}
+	a := conflicts[0]
+	if a.Original.Name != "a.txt" || a.Original.Deleted || a.Original.Size != int64(len("a.txt")) {
+		t.Errorf("Unexpected original %+v", a.Original)
+	}
+	if len(a.Copies) != 2 || a.Copies[0].Name != files[1] || a.Copies[0].ModifiedBy != "ABCDEFG" || a.Copies[1].ModifiedBy != "HIJKLMN" {
+		t.Errorf("Unexpected copies %+v", a.Copies)
+	}
+	if c := conflicts[2]; c.Original.Name != "c" || !c.Original.Deleted {
+		t.Errorf("Unexpected original %+v", c.Original)
+	}
+
+	if _, err := m.FolderConflicts("nonexistent"); err == nil {
+		t.Error("Expected error for nonexistent folder")
+	}
+
+	ferr, err := m.ResolveConflicts("default", map[string]string{
+		"a.txt": files[2], // copy wins
+		"b":     "b",      // original wins
+		"c":     "a.txt",  // not one of its versions
+		"d":     "d",      // not in conflict
+		"e":     "e",      // original is gone
+	})
+	must(t, err)
+	if len(ferr) != 3 || ferr["c"] != errNotAConflict.Error() || ferr["d"] == "" || ferr["e"] != errKeepMissing.Error() {
+		t.Errorf("Unexpected errors %v", ferr)
+	}
+	if _, err := ffs.Lstat(files[6]); err != nil {
+		t.Error("Expected the conflict copy of the deleted file to be kept, got", err)
+	}
+
+	if bs, err := ioutil.ReadFile(filepath.Join(dir, "a.txt")); err != nil || string(bs) != files[2] {
+		t.Errorf("Expected a.txt to be replaced by the chosen copy, got %q, %v", bs, err)
+	}
+	if bs, err := ioutil.ReadFile(filepath.Join(dir, "b")); err != nil || string(bs) != "b" {
+		t.Errorf("Expected b to be kept, got %q, %v", bs, err)
+	}
+	for _, file := range []string{files[1], files[2], files[4]} {
+		if _, err := ffs.Lstat(file); !fs.IsNotExist(err) {
+			t.Errorf("Expected %s to be gone, got %v", file, err)
+		}
+	}
+
+	// The changes have been scanned, leaving only the unresolved one.
+	conflicts, err = m.FolderConflicts("default")
+	must(t, err)
+	if len(conflicts) != 2 || conflicts[0].Original.Name != "c" || conflicts[1].Original.Name != "e" {
+		t.Errorf("Expected only c and e to be in conflict, got %v", conflicts)
+	}
+}
@@ -7,49 +7,197 @@
 package model
 
 import (
+	"context"
+	"time"
+
 	"github.com/syncthing/syncthing/lib/protocol"
 	"github.com/syncthing/syncthing/lib/sync"
 )
 
-// deviceActivity tracks the number of outstanding requests per device and can
-// answer which device is least busy. It is safe for use from multiple
-// goroutines.
+const (
+	// A device we know nothing about yet gets this many requests, to find
+	// out how fast it is.
+	activityProbeRequests = 4
+	// A device always gets this many requests, however slow it seems, so
+	// that it can show otherwise.
+	activityMinRequests = 2
+	// The measured delivery rate and round trip time are the best seen
+	// during this long, so that the occasional slow or idle stretch
+	// doesn't count, but changes in the network are still noticed.
+	activityFilterWindow = 10 * time.Second
+	// The window of outstanding bytes is this many times the bandwidth
+	// delay product, leaving room for the rate to grow.
+	activityWindowGain = 2
+)
+
+// deviceActivity tracks the outstanding requests, round trip time and
+// delivery rate per device, and can answer which device is expected to
+// deliver a block the soonest. The number of outstanding bytes per device is
+// limited to what is needed to keep its connection busy, so that requests
+// go to the other devices rather than queue up behind a slow one. It is safe
+// for use from multiple goroutines.
 type deviceActivity struct {
-	act map[protocol.DeviceID]int
-	mut sync.Mutex
+	act     map[protocol.DeviceID]*deviceStats
+	changed chan struct{} // closed and replaced when a request is done
+	now     func() time.Time
+	mut     sync.Mutex
+}
+
+type deviceStats struct {
+	outstanding      int   // requests
+	outstandingBytes int64 // in those requests
+	delivered        int64 // bytes, in total
+
+	rate   float64 // bytes per second, zero while unknown
+	rateAt time.Time
+	rtt    time.Duration // zero while unknown
+	rttAt  time.Time
+}
+
+// An activeRequest is a request to a device selected by leastBusy, to be
+// passed to done when it has finished.
+type activeRequest struct {
+	Availability
+	size      int
+	sent      time.Time
+	delivered int64 // by the device when the request was sent
 }
 
 func newDeviceActivity() *deviceActivity {
 	return &deviceActivity{
-		act: make(map[protocol.DeviceID]int),
-		mut: sync.NewMutex(),
+		act:     make(map[protocol.DeviceID]*deviceStats),
+		changed: make(chan struct{}),
+		now:     time.Now,
+		mut:     sync.NewMutex(),
 	}
 }
 
-func (m *deviceActivity) leastBusy(availability []Availability) (Availability, bool) {
-	m.mut.Lock()
-	low := 2<<30 - 1
-	found := false
+// leastBusy selects the device expected to deliver a block of the given
+// size the soonest and registers the request with it. If all devices have
+// their window full it waits for one to have room. It returns false if there
+// are no devices or the context is cancelled.
+func (m *deviceActivity) leastBusy(ctx context.Context, availability []Availability, size int) (*activeRequest, bool) {
+	if len(availability) == 0 {
+		return nil, false
+	}
+
+	for {
+		m.mut.Lock()
+		if req, ok := m.selectLocked(availability, size); ok {
+			m.mut.Unlock()
+			return req, true
+		}
+		changed := m.changed
+		m.mut.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, false
+		}
+	}
+}
+
+func (m *deviceActivity) selectLocked(availability []Availability, size int) (*activeRequest, bool) {
 	var selected Availability
+	var selectedStats *deviceStats
+	var low time.Duration
+	found := false
 	for _, info := range availability {
-		if usage := m.act[info.ID]; usage < low {
-			low = usage
+		stats := m.statsLocked(info.ID)
+		if !stats.hasRoom(size) {
+			continue
+		}
+		if est := stats.expectedDelivery(size); !found || est < low {
+			low = est
 			selected = info
+			selectedStats = stats
 			found = true
 		}
 	}
-	m.mut.Unlock()
-	return selected, found
+	if !found {
+		return nil, false
+	}
+
+	selectedStats.outstanding++
+	selectedStats.outstandingBytes += int64(size)
+	return &activeRequest{
+		Availability: selected,
+		size:         size,
+		sent:         m.now(),
+		delivered:    selectedStats.delivered,
+	}, true
 }
 
-func (m *deviceActivity) using(availability Availability) {
+// done marks the request as finished, updating the round trip time and
+// delivery rate of the device if it was successful.
+func (m *deviceActivity) done(req *activeRequest, err error) {
 	m.mut.Lock()
-	m.act[availability.ID]++
-	m.mut.Unlock()
+	defer m.mut.Unlock()
+
+	stats := m.statsLocked(req.ID)
+	stats.outstanding--
+	stats.outstandingBytes -= int64(req.size)
+
+	close(m.changed)
+	m.changed = make(chan struct{})
+
+	if err != nil {
+		return
+	}
+
+	now := m.now()
+	stats.delivered += int64(req.size)
+	elapsed := now.Sub(req.sent)
+	if elapsed <= 0 {
+		return
+	}
+
+	if stats.rtt == 0 || elapsed <= stats.rtt || now.Sub(stats.rttAt) > activityFilterWindow {
+		stats.rtt = elapsed
+		stats.rttAt = now
+	}
+
+	// Everything the device delivered while this request was outstanding
+	// counts, as requests are pipelined.
+	rate := float64(stats.delivered-req.delivered) / elapsed.Seconds()
+	if rate >= stats.rate || now.Sub(stats.rateAt) > activityFilterWindow {
+		stats.rate = rate
+		stats.rateAt = now
+	}
 }
 
-func (m *deviceActivity) done(availability Availability) {
-	m.mut.Lock()
-	m.act[availability.ID]--
-	m.mut.Unlock()
+func (m *deviceActivity) statsLocked(id protocol.DeviceID) *deviceStats {
+	stats, ok := m.act[id]
+	if !ok {
+		stats = &deviceStats{}
+		m.act[id] = stats
+	}
+	return stats
+}
+
+// hasRoom returns whether another request of the given size fits the
+// window of outstanding bytes, which is a multiple of the bandwidth delay
+// product.
+func (s *deviceStats) hasRoom(size int) bool {
+	if s.rate == 0 {
+		return s.outstanding < activityProbeRequests
+	}
+	if s.outstanding < activityMinRequests {
+		return true
+	}
+	window := activityWindowGain * s.rate * s.rtt.Seconds()
+	return float64(s.outstandingBytes+int64(size)) <= window
+}
+
+// expectedDelivery returns how long it should take the device to deliver a
+// block of the given size, after the ones already requested. Devices not
+// measured yet are expected to be fast, so that they get to show it, and
+// the ones with the fewest outstanding requests are tried first.
+func (s *deviceStats) expectedDelivery(size int) time.Duration {
+	if s.rate == 0 {
+		return time.Duration(s.outstanding)
+	}
+	transfer := float64(s.outstandingBytes+int64(size)) / s.rate
+	return s.rtt + time.Duration(transfer*float64(time.Second))
 }
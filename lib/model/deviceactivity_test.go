@@ -7,7 +7,10 @@
 package model
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/syncthing/syncthing/lib/protocol"
 )
@@ -18,43 +21,119 @@ func TestDeviceActivity(t *testing.T) {
 	n2 := Availability{protocol.DeviceID([32]byte{9, 10, 11, 12}), false}
 	devices := []Availability{n0, n1, n2}
 	na := newDeviceActivity()
+	ctx := context.Background()
+	failed := errors.New("failed")
 
-	if lb, ok := na.leastBusy(devices); !ok || lb != n0 {
-		t.Errorf("Least busy device should be n0 (%v) not %v", n0, lb)
+	// Before anything is known, the device with the fewest outstanding
+	// requests is selected.
+	r0, ok := na.leastBusy(ctx, devices, 1024)
+	if !ok || r0.Availability != n0 {
+		t.Errorf("Least busy device should be n0 (%v) not %v", n0, r0)
 	}
-	if lb, ok := na.leastBusy(devices); !ok || lb != n0 {
-		t.Errorf("Least busy device should still be n0 (%v) not %v", n0, lb)
+	r1, ok := na.leastBusy(ctx, devices, 1024)
+	if !ok || r1.Availability != n1 {
+		t.Errorf("Least busy device should be n1 (%v) not %v", n1, r1)
+	}
+	r2, ok := na.leastBusy(ctx, devices, 1024)
+	if !ok || r2.Availability != n2 {
+		t.Errorf("Least busy device should be n2 (%v) not %v", n2, r2)
+	}
+	r3, ok := na.leastBusy(ctx, devices, 1024)
+	if !ok || r3.Availability != n0 {
+		t.Errorf("Least busy device should be n0 (%v) not %v", n0, r3)
+	}
+
+	// Failed requests don't tell us anything about the speed
+	na.done(r1, failed)
+	if r, ok := na.leastBusy(ctx, devices, 1024); !ok || r.Availability != n1 {
+		t.Errorf("Least busy device should be n1 (%v) not %v", n1, r)
+	} else {
+		na.done(r, failed)
+	}
+
+	na.done(r2, failed)
+	na.done(r0, failed)
+	na.done(r3, failed)
+	if r, ok := na.leastBusy(ctx, devices, 1024); !ok || r.Availability != n0 {
+		t.Errorf("Least busy device should be n0 (%v) not %v", n0, r)
 	}
 
-	lb, _ := na.leastBusy(devices)
-	na.using(lb)
-	if lb, ok := na.leastBusy(devices); !ok || lb != n1 {
-		t.Errorf("Least busy device should be n1 (%v) not %v", n1, lb)
+	if _, ok := na.leastBusy(ctx, nil, 1024); ok {
+		t.Error("Should not find a device among none")
 	}
-	lb, _ = na.leastBusy(devices)
-	na.using(lb)
-	if lb, ok := na.leastBusy(devices); !ok || lb != n2 {
-		t.Errorf("Least busy device should be n2 (%v) not %v", n2, lb)
+}
+
+func TestDeviceActivityThroughput(t *testing.T) {
+	fast := Availability{ID: protocol.DeviceID([32]byte{1, 2, 3, 4})}
+	slow := Availability{ID: protocol.DeviceID([32]byte{5, 6, 7, 8})}
+	devices := []Availability{slow, fast}
+	na := newDeviceActivity()
+	now := time.Unix(1500000000, 0)
+	na.now = func() time.Time { return now }
+	ctx := context.Background()
+	const size = 128 << 10
+
+	// One request each, where the fast device answers in 10 ms and the
+	// slow one in a second.
+	rs, _ := na.leastBusy(ctx, devices, size)
+	rf, _ := na.leastBusy(ctx, devices, size)
+	if rs.Availability != slow || rf.Availability != fast {
+		t.Fatal("Both devices should have been probed")
 	}
+	now = now.Add(10 * time.Millisecond)
+	na.done(rf, nil)
+	now = now.Add(990 * time.Millisecond)
+	na.done(rs, nil)
 
-	lb, _ = na.leastBusy(devices)
-	na.using(lb)
-	if lb, ok := na.leastBusy(devices); !ok || lb != n0 {
-		t.Errorf("Least busy device should be n0 (%v) not %v", n0, lb)
+	// The fast device is now preferred until its window is full, about
+	// twice its bandwidth delay product.
+	var reqs []*activeRequest
+	for i := 0; i < 2; i++ {
+		r, ok := na.leastBusy(ctx, devices, size)
+		if !ok || r.Availability != fast {
+			t.Fatalf("Request %d should go to the fast device, not %v", i, r)
+		}
+		reqs = append(reqs, r)
+	}
+	r, ok := na.leastBusy(ctx, devices, size)
+	if !ok || r.Availability != slow {
+		t.Fatalf("With the fast device busy, the slow device should be used, not %v", r)
 	}
+	reqs = append(reqs, r)
 
-	na.done(n1)
-	if lb, ok := na.leastBusy(devices); !ok || lb != n1 {
-		t.Errorf("Least busy device should be n1 (%v) not %v", n1, lb)
+	// The slow device is limited to its minimum number of requests, and
+	// then there's nothing left to do but wait.
+	r, ok = na.leastBusy(ctx, devices, size)
+	if !ok || r.Availability != slow {
+		t.Fatalf("The slow device should get a second request, not %v", r)
 	}
+	reqs = append(reqs, r)
 
-	na.done(n2)
-	if lb, ok := na.leastBusy(devices); !ok || lb != n1 {
-		t.Errorf("Least busy device should still be n1 (%v) not %v", n1, lb)
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if r, ok := na.leastBusy(cancelled, devices, size); ok {
+		t.Fatalf("All windows should be full, got %v", r)
 	}
 
-	na.done(n0)
-	if lb, ok := na.leastBusy(devices); !ok || lb != n0 {
-		t.Errorf("Least busy device should be n0 (%v) not %v", n0, lb)
+	// A request waiting for room is sent as soon as there is some.
+	res := make(chan *activeRequest)
+	go func() {
+		r, _ := na.leastBusy(ctx, devices, size)
+		res <- r
+	}()
+	select {
+	case r := <-res:
+		t.Fatalf("Should wait for room, got %v", r)
+	case <-time.After(50 * time.Millisecond):
+	}
+	now = now.Add(10 * time.Millisecond)
+	na.done(reqs[0], nil)
+	select {
+	case r := <-res:
+		if r.Availability != fast {
+			t.Errorf("Waiting request should go to the fast device, not %v", r)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Request still waiting after room was made")
 	}
 }
@@ -55,7 +55,11 @@ type folder struct {
 	scanDelay           chan time.Duration
 	initialScanFinished chan struct{}
 	scanErrors          []FileError
-	scanErrorsMut       sync.Mutex
+	hookErrors          map[int]FileError      // by index of the hook
+	scrubErrors         map[string]FileError   // by name of the corrupted file
+	scrubRepairs        map[string]scrubRepair // by name of the file to pull again
+	scanErrorsMut       sync.Mutex             // also for hookErrors, scrubErrors and scrubRepairs
+	hookQueue           chan hookRun
 
 	pullScheduled chan struct{}
 
@@ -98,6 +102,7 @@ func newFolder(model *model, fset *db.FileSet, ignores *ignore.Matcher, cfg conf
 		scanDelay:           make(chan time.Duration),
 		initialScanFinished: make(chan struct{}),
 		scanErrorsMut:       sync.NewMutex(),
+		hookQueue:           make(chan hookRun, maxQueuedHooks),
 
 		pullScheduled: make(chan struct{}, 1), // This needs to be 1-buffered so that we queue a pull if we're busy when it comes.
 
@@ -127,6 +132,15 @@ func (f *folder) serve(_ chan struct{}) {
 		f.startWatch()
 	}
 
+	// The hashes of encrypted data aren't known to us.
+	if f.ScrubIntervalS > 0 && f.Type != config.FolderTypeReceiveEncrypted {
+		go f.scrubRoutine()
+	}
+
+	if len(f.Hooks) > 0 {
+		go f.hookRoutine()
+	}
+
 	initialCompleted := f.initialScanFinished
 
 	pull := func() {
@@ -221,6 +235,10 @@ func (f *folder) Jobs(_, _ int) ([]string, []string, int) {
 	return nil, nil, 0
 }
 
+func (f *folder) QueueSize() (int, int) {
+	return 0, 0
+}
+
 func (f *folder) Scan(subdirs []string) error {
 	<-f.initialScanFinished
 	req := rescanRequest{
@@ -289,10 +307,6 @@ func (f *folder) scanSubdirs(subDirs []string) error {
 
 	mtimefs := f.fset.MtimeFS()
 
-	f.setState(FolderScanWaiting)
-	scanLimiter.take(1)
-	defer scanLimiter.give(1)
-
 	for i := range subDirs {
 		sub := osutil.NativeFilename(subDirs[i])
 
@@ -306,6 +320,13 @@ func (f *folder) scanSubdirs(subDirs []string) error {
 		subDirs[i] = sub
 	}
 
+	// The scan waits for these, as they may prepare the files for it.
+	f.runHooks(hookRun{event: config.HookPreScan, paths: subDirs})
+
+	f.setState(FolderScanWaiting)
+	scanLimiter.take(1)
+	defer scanLimiter.give(1)
+
 	// Check if the ignore patterns changed as part of scanning this folder.
 	// If they did we should schedule a pull of the folder so that we
 	// request things we might have suddenly become unignored and so on.
@@ -335,19 +356,22 @@ func (f *folder) scanSubdirs(subDirs []string) error {
 	f.setState(FolderScanning)
 
 	fchan := scanner.Walk(f.ctx, scanner.Config{
-		Folder:                f.ID,
-		Subs:                  subDirs,
-		Matcher:               f.ignores,
-		TempLifetime:          time.Duration(f.model.cfg.Options().KeepTemporariesH) * time.Hour,
-		CurrentFiler:          cFiler{f.fset},
-		Filesystem:            mtimefs,
-		IgnorePerms:           f.IgnorePerms,
-		AutoNormalize:         f.AutoNormalize,
-		Hashers:               f.model.numHashers(f.ID),
-		ShortID:               f.shortID,
-		ProgressTickIntervalS: f.ScanProgressIntervalS,
-		LocalFlags:            f.localFlags,
-		ModTimeWindow:         f.ModTimeWindow(),
+		Folder:                 f.ID,
+		Subs:                   subDirs,
+		Matcher:                f.ignores,
+		TempLifetime:           time.Duration(f.model.cfg.Options().KeepTemporariesH) * time.Hour,
+		CurrentFiler:           cFiler{f.fset},
+		Filesystem:             mtimefs,
+		IgnorePerms:            f.IgnorePerms,
+		AutoNormalize:          f.AutoNormalize,
+		Hashers:                f.model.numHashers(f.ID),
+		ShortID:                f.shortID,
+		ProgressTickIntervalS:  f.ScanProgressIntervalS,
+		LocalFlags:             f.localFlags,
+		ModTimeWindow:          f.ModTimeWindow(),
+		ContentDefinedChunking: f.model.useContentDefinedChunking(f.FolderConfiguration),
+		ScanXattrs:             f.SyncXattrs,
+		ScanOwnership:          f.SyncOwnership,
 	})
 
 	batchFn := func(fs []protocol.FileInfo) error {
@@ -544,6 +568,7 @@ func (f *folder) scanSubdirs(subDirs []string) error {
 
 	f.ScanCompleted()
 	f.setState(FolderIdle)
+	f.queueHooks(hookRun{event: config.HookPostScan, paths: subDirs})
 	return nil
 }
 
@@ -767,7 +792,12 @@ outer:
 func (f *folder) Errors() []FileError {
 	f.scanErrorsMut.Lock()
 	defer f.scanErrorsMut.Unlock()
-	return append([]FileError{}, f.scanErrors...)
+	errors := append([]FileError{}, f.scanErrors...)
+	for _, fe := range f.hookErrors {
+		errors = append(errors, fe)
+	}
+	errors = append(errors, f.scrubFileErrors()...)
+	return errors
 }
 
 // ForceRescan marks the file such that it gets rehashed on next scan and then
@@ -0,0 +1,209 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/pkg/errors"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/fs"
+)
+
+const (
+	defaultHookTimeout = time.Minute
+	// The changed paths are only passed in the environment up to this
+	// size, as there are limits to it. They are always on stdin.
+	maxHookEnvPaths = 32 << 10
+	// How much of the output of a failed hook makes it to the folder errors
+	maxHookErrorOutput = 1 << 10
+	// How many runs of hooks may wait for the ones before them to finish
+	maxQueuedHooks = 64
+	// How many paths are kept for the syncFinished hooks, beyond which they
+	// are told that the list is incomplete.
+	maxPulledPaths = 10000
+)
+
+var errHookQueueFull = errors.New("skipped, as too many hooks were waiting to run")
+
+// A hookRun is the running of the hooks for an event, with the paths changed
+// by it.
+type hookRun struct {
+	event     config.HookEvent
+	paths     []string
+	truncated bool // paths is incomplete
+}
+
+// hookRoutine runs the queued hooks in turn, until the folder stops.
+func (f *folder) hookRoutine() {
+	for {
+		select {
+		case run := <-f.hookQueue:
+			f.runHooks(run)
+		case <-f.ctx.Done():
+			return
+		}
+	}
+}
+
+// queueHooks queues the hooks for the event to run in the background, so
+// that a slow hook doesn't hold up syncing. If too many are waiting
+// already, they are skipped and that is reported as their error.
+func (f *folder) queueHooks(run hookRun) {
+	if !f.hasHooks(run.event) {
+		return
+	}
+	select {
+	case f.hookQueue <- run:
+	default:
+		l.Infof("Skipping %v hooks of folder %s, as too many are waiting to run", run.event, f.Description())
+		for i, hook := range f.Hooks {
+			if hook.Event == run.event {
+				f.setHookError(i, run.event, errHookQueueFull)
+			}
+		}
+	}
+}
+
+func (f *folder) hasHooks(event config.HookEvent) bool {
+	for _, hook := range f.Hooks {
+		if hook.Event == event {
+			return true
+		}
+	}
+	return false
+}
+
+// runHooks runs the commands hooked to the event in the folder, one after
+// the other, with the paths changed by it. Failures are kept as folder
+// errors until the hook succeeds again.
+func (f *folder) runHooks(run hookRun) {
+	for i, hook := range f.Hooks {
+		if hook.Event != run.event {
+			continue
+		}
+
+		out, err := f.runHook(hook, run)
+		if err != nil {
+			l.Infof("Running %v hook of folder %s: %v", run.event, f.Description(), err)
+			if len(out) > maxHookErrorOutput {
+				out = out[len(out)-maxHookErrorOutput:]
+			}
+			if out = strings.TrimSpace(out); out != "" {
+				err = fmt.Errorf("%v: %s", err, out)
+			}
+		}
+		f.setHookError(i, run.event, err)
+	}
+}
+
+func (f *folder) runHook(hook config.FolderHook, run hookRun) (string, error) {
+	timeout := defaultHookTimeout
+	if hook.TimeoutS > 0 {
+		timeout = time.Duration(hook.TimeoutS) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(f.ctx, timeout)
+	defer cancel()
+
+	cmd, err := f.externalCommand(ctx, hook.Command, nil)
+	if err != nil {
+		return "", err
+	}
+
+	list := strings.Join(run.paths, "\n")
+	cmd.Env = append(cmd.Env,
+		"SYNCTHING_EVENT="+hook.Event.String(),
+		"SYNCTHING_FOLDER_ID="+f.ID,
+		"SYNCTHING_FOLDER_LABEL="+f.Label,
+		"SYNCTHING_FOLDER_PATH="+f.Filesystem().URI(),
+	)
+	if len(list) <= maxHookEnvPaths {
+		cmd.Env = append(cmd.Env, "SYNCTHING_PATHS="+list)
+	}
+	if run.truncated {
+		cmd.Env = append(cmd.Env, "SYNCTHING_PATHS_TRUNCATED=true")
+	}
+	if len(run.paths) > 0 {
+		cmd.Stdin = strings.NewReader(list + "\n")
+	}
+
+	out, err := cmd.CombinedOutput()
+	l.Debugf("%v %v hook output: %s", f, hook.Event, out)
+	if ctx.Err() == context.DeadlineExceeded {
+		err = errors.Errorf("timed out after %v", timeout)
+	}
+	return string(out), err
+}
+
+// setHookError records the result of running the hook with the given index,
+// which clears the previous error if it succeeded.
+func (f *folder) setHookError(i int, event config.HookEvent, err error) {
+	f.scanErrorsMut.Lock()
+	defer f.scanErrorsMut.Unlock()
+	if err == nil {
+		delete(f.hookErrors, i)
+		return
+	}
+	if f.hookErrors == nil {
+		f.hookErrors = make(map[int]FileError)
+	}
+	f.hookErrors[i] = FileError{
+		Path: fmt.Sprintf("(%v hook)", event),
+		Err:  err.Error(),
+	}
+}
+
+// externalCommand returns the user supplied command, split into words and
+// with the placeholders for the folder and the given ones replaced. It runs
+// in the folder, without access to the GUI credentials.
+func (f *folder) externalCommand(ctx context.Context, command string, placeholders map[string]string) (*exec.Cmd, error) {
+	if command == "" {
+		return nil, errors.New("command is empty")
+	}
+	if runtime.GOOS == "windows" {
+		command = strings.Replace(command, `\`, `\\`, -1)
+	}
+
+	words, err := shellquote.Split(command)
+	if err != nil {
+		return nil, errors.Wrap(err, "command is invalid")
+	}
+
+	ffs := f.Filesystem()
+	context := map[string]string{
+		"%FOLDER_FILESYSTEM%": ffs.Type().String(),
+		"%FOLDER_PATH%":       ffs.URI(),
+	}
+	for key, val := range placeholders {
+		context[key] = val
+	}
+	for i, word := range words {
+		for key, val := range context {
+			word = strings.Replace(word, key, val, -1)
+		}
+		words[i] = word
+	}
+
+	cmd := exec.CommandContext(ctx, words[0], words[1:]...)
+	if ffs.Type() == fs.FilesystemTypeBasic {
+		cmd.Dir = ffs.URI()
+	}
+	for _, env := range os.Environ() {
+		if !strings.HasPrefix(env, "STGUIAUTH=") && !strings.HasPrefix(env, "STGUIAPIKEY=") {
+			cmd.Env = append(cmd.Env, env)
+		}
+	}
+	return cmd, nil
+}
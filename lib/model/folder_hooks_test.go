@@ -0,0 +1,220 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/stats"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+// hookRecorder returns a hook command that appends the event, folder and
+// paths it is run with to a file, and a function returning the lines in it.
+func hookRecorder(t *testing.T) (string, func() []string, func()) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("test hook requires a shell")
+	}
+	dir, err := ioutil.TempDir("", "hooks")
+	must(t, err)
+	out := filepath.Join(dir, "out")
+	command := `sh -c 'echo "$SYNCTHING_EVENT $SYNCTHING_FOLDER_ID $(cat | tr "\n" ,)|$SYNCTHING_PATHS" | tr "\n" , >> "$0"; echo >> "$0"' ` + out
+	lines := func() []string {
+		bs, err := ioutil.ReadFile(out)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		must(t, err)
+		return strings.Split(strings.TrimSpace(string(bs)), "\n")
+	}
+	return command, lines, func() { os.RemoveAll(dir) }
+}
+
+func TestScanHooks(t *testing.T) {
+	command, lines, cleanup := hookRecorder(t)
+	defer cleanup()
+
+	w, fcfg := tmpDefaultWrapper()
+	fcfg.Hooks = []config.FolderHook{
+		{Event: config.HookPreScan, Command: command},
+		{Event: config.HookPostScan, Command: command},
+	}
+	w.SetFolder(fcfg)
+	m := setupModel(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	must(t, fcfg.Filesystem().Mkdir("sub", 0755))
+	must(t, m.ScanFolderSubdirs("default", []string{"sub"}))
+
+	// The postScan hook runs in the background, as may the ones of the
+	// initial scan.
+	var got []string
+	for i := 0; i < 100 && len(got) < 2; i++ {
+		time.Sleep(50 * time.Millisecond)
+		got = got[:0]
+		for _, line := range lines() {
+			if strings.HasSuffix(line, "|sub,") {
+				got = append(got, line)
+			}
+		}
+	}
+	expected := []string{
+		"preScan default sub,|sub,",
+		"postScan default sub,|sub,",
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected hooks to run as %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Expected hook to run as %q, got %q", expected[i], got[i])
+		}
+	}
+}
+
+func TestItemFinishedHooks(t *testing.T) {
+	command, lines, cleanup := hookRecorder(t)
+	defer cleanup()
+
+	m, f := setupSendReceiveFolder()
+	ffs := f.Filesystem()
+	defer func() {
+		os.Remove(m.cfg.ConfigPath())
+		os.RemoveAll(ffs.URI())
+	}()
+	f.FolderStatisticsReference = stats.NewFolderStatisticsReference(m.db, f.ID)
+	f.Hooks = []config.FolderHook{
+		{Event: config.HookItemFinished, Command: command},
+		{Event: config.HookSyncFinished, Command: command},
+	}
+
+	dbUpdateChan := make(chan dbUpdateJob, 3)
+	dbUpdateChan <- dbUpdateJob{protocol.FileInfo{Name: "a", Version: protocol.Vector{}.Update(device1.Short())}, dbUpdateHandleFile}
+	dbUpdateChan <- dbUpdateJob{protocol.FileInfo{Name: "b", Version: protocol.Vector{}.Update(device1.Short()), Deleted: true}, dbUpdateDeleteFile}
+	dbUpdateChan <- dbUpdateJob{protocol.FileInfo{Name: "c", RawInvalid: true}, dbUpdateInvalidate}
+	close(dbUpdateChan)
+	f.dbUpdaterRoutine(dbUpdateChan)
+
+	// The hooks are queued rather than run by the db updater.
+	if got := lines(); len(got) != 0 {
+		t.Errorf("Unexpected hook runs %v", got)
+	}
+	select {
+	case run := <-f.hookQueue:
+		f.runHooks(run)
+	default:
+		t.Fatal("Expected the itemFinished hook to be queued")
+	}
+	if got := lines(); len(got) != 1 || got[0] != "itemFinished default a,b,|a,b," {
+		t.Errorf("Unexpected hook runs %v", got)
+	}
+	if len(f.pulledPaths) != 2 {
+		t.Errorf("Expected the paths to be kept for when in sync, got %v", f.pulledPaths)
+	}
+}
+
+func TestPulledPathsLimit(t *testing.T) {
+	f := &sendReceiveFolder{folder: folder{FolderConfiguration: config.FolderConfiguration{
+		Hooks: []config.FolderHook{{Event: config.HookSyncFinished, Command: "true"}},
+	}}}
+
+	paths := make([]string, maxPulledPaths/2+1)
+	f.addPulledPaths(paths)
+	if len(f.pulledPaths) != len(paths) || f.pulledPathsTruncated {
+		t.Fatalf("Expected %d paths, got %d (truncated %v)", len(paths), len(f.pulledPaths), f.pulledPathsTruncated)
+	}
+	f.addPulledPaths(paths)
+	f.addPulledPaths(paths)
+	if len(f.pulledPaths) != maxPulledPaths || !f.pulledPathsTruncated {
+		t.Errorf("Expected %d paths, got %d (truncated %v)", maxPulledPaths, len(f.pulledPaths), f.pulledPathsTruncated)
+	}
+}
+
+func TestHookQueueFull(t *testing.T) {
+	cfg := config.NewFolderConfiguration(myID, "default", "default", fs.FilesystemTypeFake, "/TestHookQueueFull")
+	cfg.Hooks = []config.FolderHook{
+		{Event: config.HookPostScan, Command: "true"},
+	}
+	f := &folder{
+		FolderConfiguration: cfg,
+		ctx:                 context.Background(),
+		scanErrorsMut:       sync.NewMutex(),
+		hookQueue:           make(chan hookRun, 1),
+	}
+
+	// Events without hooks aren't queued at all.
+	f.queueHooks(hookRun{event: config.HookPreScan})
+	f.queueHooks(hookRun{event: config.HookPostScan})
+	if errs := f.Errors(); len(errs) != 0 {
+		t.Fatal("Unexpected errors", errs)
+	}
+	f.queueHooks(hookRun{event: config.HookPostScan})
+	if errs := f.Errors(); len(errs) != 1 || errs[0].Err != errHookQueueFull.Error() {
+		t.Fatal("Expected the hook to be skipped, got", errs)
+	}
+
+	// Running it clears the error.
+	f.runHooks(<-f.hookQueue)
+	if errs := f.Errors(); len(errs) != 0 {
+		t.Fatal("Unexpected errors", errs)
+	}
+}
+
+func TestHookErrors(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test hook requires a shell")
+	}
+
+	cfg := config.NewFolderConfiguration(myID, "default", "default", fs.FilesystemTypeFake, "/TestHookErrors")
+	cfg.Hooks = []config.FolderHook{
+		{Event: config.HookPostScan, Command: `sh -c 'echo broken; exit 3'`},
+		{Event: config.HookPostScan, Command: `sleep 10`, TimeoutS: 1},
+		{Event: config.HookPreScan, Command: `true`},
+	}
+	f := &folder{
+		FolderConfiguration: cfg,
+		ctx:                 context.Background(),
+		scanErrorsMut:       sync.NewMutex(),
+	}
+	f.runHooks(hookRun{event: config.HookPreScan})
+	if errs := f.Errors(); len(errs) != 0 {
+		t.Fatal("Unexpected errors", errs)
+	}
+
+	f.runHooks(hookRun{event: config.HookPostScan})
+	errs := f.Errors()
+	if len(errs) != 2 {
+		t.Fatal("Expected two errors, got", errs)
+	}
+	for _, fe := range errs {
+		if fe.Path != "(postScan hook)" {
+			t.Error("Unexpected error path", fe.Path)
+		}
+		if !strings.Contains(fe.Err, "exit status 3: broken") && !strings.Contains(fe.Err, "timed out") {
+			t.Error("Unexpected error", fe.Err)
+		}
+	}
+
+	// Errors are cleared when the hook succeeds again.
+	f.Hooks[0].Command = "true"
+	f.Hooks[1].Command = "true"
+	f.runHooks(hookRun{event: config.HookPostScan})
+	if errs := f.Errors(); len(errs) != 0 {
+		t.Fatal("Unexpected errors", errs)
+	}
+}
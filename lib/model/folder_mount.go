@@ -0,0 +1,171 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/locations"
+	"github.com/syncthing/syncthing/lib/ondemand"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+const defaultMountCacheMiB = 1024
+
+// folderMount presents the global state of a folder at its mount path. File
+// data is read from the local copy when it is up to date, and otherwise
+// fetched from the devices that have it as it is read.
+type folderMount struct {
+	model *model
+	fset  *db.FileSet
+	cfg   config.FolderConfiguration
+	ffs   fs.Filesystem
+}
+
+func newFolderMount(m *model, fset *db.FileSet, cfg config.FolderConfiguration) *folderMount {
+	return &folderMount{
+		model: m,
+		fset:  fset,
+		cfg:   cfg,
+		ffs:   cfg.Filesystem(),
+	}
+}
+
+func (fm *folderMount) serve(stop chan struct{}) {
+	cacheMiB := fm.cfg.MountCacheMiB
+	if cacheMiB <= 0 {
+		cacheMiB = defaultMountCacheMiB
+	}
+	// Blocks are cached by hash, so even if two folder IDs sanitize to the
+	// same name, no harm is done.
+	cacheDir := filepath.Join(locations.Get(locations.MountCache), sanitizePath(fm.cfg.ID))
+
+	mnt, err := fm.mount(cacheDir, int64(cacheMiB)<<20)
+	if err != nil {
+		// Retrying won't help until the config changes, which restarts us.
+		l.Warnf("Mounting folder %s at %s: %v", fm.cfg.Description(), fm.cfg.MountPath, err)
+		<-stop
+		return
+	}
+	l.Infof("Mounted folder %s at %s", fm.cfg.Description(), fm.cfg.MountPath)
+
+	<-stop
+
+	if err := mnt.Unmount(); err != nil {
+		l.Warnf("Unmounting folder %s from %s: %v", fm.cfg.Description(), fm.cfg.MountPath, err)
+	}
+}
+
+func (fm *folderMount) mount(cacheDir string, cacheSize int64) (ondemand.Mounted, error) {
+	cache, err := ondemand.NewCache(cacheDir, cacheSize)
+	if err != nil {
+		return nil, errors.Wrap(err, "block cache")
+	}
+	return ondemand.Mount(fm.cfg.MountPath, ondemand.NewView(fm, cache))
+}
+
+func (fm *folderMount) GlobalFile(name string) (protocol.FileInfo, bool) {
+	return fm.fset.GetGlobal(filepath.FromSlash(name))
+}
+
+func (fm *folderMount) GlobalChildren(dir string) []protocol.FileInfo {
+	prefix := filepath.FromSlash(dir)
+	if prefix == "." {
+		prefix = ""
+	}
+
+	var children []protocol.FileInfo
+	fm.fset.WithGlobalChildrenTruncated(prefix, func(fi db.FileIntf) bool {
+		f := fi.(db.FileInfoTruncated)
+		children = append(children, protocol.FileInfo{
+			Name:          filepath.ToSlash(f.Name),
+			Type:          f.Type,
+			Size:          f.Size,
+			Permissions:   f.Permissions,
+			ModifiedS:     f.ModifiedS,
+			ModifiedNs:    f.ModifiedNs,
+			Deleted:       f.Deleted,
+			RawInvalid:    f.RawInvalid,
+			NoPermissions: f.NoPermissions,
+			SymlinkTarget: f.SymlinkTarget,
+			LocalFlags:    f.LocalFlags,
+		})
+		return true
+	})
+	return children
+}
+
+func (fm *folderMount) Block(ctx context.Context, file protocol.FileInfo, block protocol.BlockInfo) ([]byte, error) {
+	if buf, ok := fm.localBlock(file, block); ok {
+		return buf, nil
+	}
+
+	lastError := errNoDevice
+	candidates := fm.model.Availability(fm.cfg.ID, file, block)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		selected, found := activity.leastBusy(ctx, candidates, int(block.Size))
+		if !found {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, lastError
+		}
+		candidates = removeAvailability(candidates, selected.Availability)
+
+		var buf []byte
+		blockNo := file.BlockIndex(block.Offset)
+		buf, lastError = fm.model.requestGlobal(selected.ID, fm.cfg.ID, file.Name, blockNo, block.Offset, int(block.Size), block.Hash, block.WeakHash, selected.FromTemporary)
+		activity.done(selected, lastError)
+		if lastError != nil {
+			l.Debugln("mount request:", fm.cfg.ID, file.Name, block.Offset, block.Size, "returned error:", lastError)
+			continue
+		}
+
+		if lastError = verifyBuffer(buf, block); lastError != nil {
+			l.Debugln("mount request:", fm.cfg.ID, file.Name, block.Offset, block.Size, "hash mismatch")
+			continue
+		}
+		return buf, nil
+	}
+}
+
+// localBlock returns the block from the local copy of the file, if that is
+// the global version.
+func (fm *folderMount) localBlock(file protocol.FileInfo, block protocol.BlockInfo) ([]byte, bool) {
+	cur, ok := fm.fset.Get(protocol.LocalDeviceID, file.Name)
+	if !ok || cur.IsInvalid() || cur.IsDeleted() || !cur.Version.Equal(file.Version) {
+		return nil, false
+	}
+
+	fd, err := fm.ffs.Open(file.Name)
+	if err != nil {
+		return nil, false
+	}
+	defer fd.Close()
+
+	buf := make([]byte, block.Size)
+	if _, err := fd.ReadAt(buf, block.Offset); err != nil {
+		return nil, false
+	}
+	if err := verifyBuffer(buf, block); err != nil {
+		// Changed since it was scanned
+		return nil, false
+	}
+	return buf, true
+}
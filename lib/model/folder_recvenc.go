@@ -0,0 +1,55 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"fmt"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/ignore"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/versioner"
+)
+
+func init() {
+	folderFactories[config.FolderTypeReceiveEncrypted] = newReceiveEncryptedFolder
+}
+
+/*
+receiveEncryptedFolder is the folder type used on an untrusted device, for
+folders that are shared with it in encrypted form. The devices that know
+the password encrypt file names, contents and metadata before sending them
+to us, and decrypt what we send back. From our point of view the files are
+just opaque data:
+
+- The block hashes we receive are encrypted and don't match the data, so
+  we can only verify the length of the blocks we pull.
+
+- Nothing that happens locally is meaningful to the other devices, so like
+  in a receive only folder local changes are flagged and not propagated,
+  and can be reverted.
+*/
+type receiveEncryptedFolder struct {
+	*receiveOnlyFolder
+}
+
+func newReceiveEncryptedFolder(model *model, fset *db.FileSet, ignores *ignore.Matcher, cfg config.FolderConfiguration, ver versioner.Versioner, fs fs.Filesystem) service {
+	ro := newReceiveOnlyFolder(model, fset, ignores, cfg, ver, fs).(*receiveOnlyFolder)
+	ro.verifier = verifyBufferLength
+	return &receiveEncryptedFolder{ro}
+}
+
+// verifyBufferLength is the block verifier for encrypted folders, where the
+// block hash can't be checked.
+func verifyBufferLength(buf []byte, block protocol.BlockInfo) error {
+	if len(buf) != int(block.Size) {
+		return fmt.Errorf("length mismatch %d != %d", len(buf), block.Size)
+	}
+	return nil
+}
@@ -59,6 +59,9 @@ type receiveOnlyFolder struct {
 func newReceiveOnlyFolder(model *model, fset *db.FileSet, ignores *ignore.Matcher, cfg config.FolderConfiguration, ver versioner.Versioner, fs fs.Filesystem) service {
 	sr := newSendReceiveFolder(model, fset, ignores, cfg, ver, fs).(*sendReceiveFolder)
 	sr.localFlags = protocol.FlagLocalReceiveOnly // gets propagated to the scanner, and set on locally changed files
+	if cfg.StageChanges {
+		sr.staging = newStagingArea(fs, fset)
+	}
 	return &receiveOnlyFolder{sr}
 }
 
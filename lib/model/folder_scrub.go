@@ -0,0 +1,275 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// How fast data is read when scrubbing, unless configured otherwise.
+const defaultScrubRateKiBs = 10 << 10
+
+// scrubRoutine verifies the data in the folder against the stored hashes
+// every ScrubIntervalS, counted from the end of the last complete scrub.
+func (f *folder) scrubRoutine() {
+	interval := time.Duration(f.ScrubIntervalS) * time.Second
+
+	select {
+	case <-f.initialScanFinished:
+	case <-f.ctx.Done():
+		return
+	}
+
+	for {
+		timer := time.NewTimer(time.Until(f.GetLastScrubTime().Add(interval)))
+		select {
+		case <-timer.C:
+		case <-f.ctx.Done():
+			timer.Stop()
+			return
+		}
+
+		if err := f.scrub(); err != nil {
+			l.Debugln(f, "scrubbing stopped:", err)
+			return
+		}
+		f.ScrubCompleted()
+	}
+}
+
+// scrub reads all files in the folder at a limited rate and checks their
+// blocks against the hashes in the database. Files where they don't match
+// are reported as folder errors and, if configured, pulled again. Files
+// changed since they were last scanned are left to the scanner.
+func (f *folder) scrub() error {
+	l.Infof("Scrubbing folder %v", f.Description())
+
+	kibs := f.ScrubRateKiBs
+	if kibs <= 0 {
+		kibs = defaultScrubRateKiBs
+	}
+	limiter := rate.NewLimiter(rate.Limit(kibs*1024), protocol.MaxBlockSize)
+
+	var names []string
+	f.fset.WithHaveTruncated(protocol.LocalDeviceID, func(fi db.FileIntf) bool {
+		file := fi.(db.FileInfoTruncated)
+		if file.Type == protocol.FileInfoTypeFile && !file.IsDeleted() && !file.IsInvalid() {
+			names = append(names, file.Name)
+		}
+		return true
+	})
+
+	mtimefs := f.fset.MtimeFS()
+	corrupted := make(map[string]FileError)
+	for _, name := range names {
+		// Scrubbing competes with scanning for the disk.
+		scanLimiter.take(1)
+		bad, err := f.scrubFile(mtimefs, name, limiter)
+		scanLimiter.give(1)
+		if err != nil {
+			if f.ctx.Err() != nil {
+				return f.ctx.Err()
+			}
+			l.Debugln(f, "scrubbing", name, err)
+			continue
+		}
+		if len(bad) == 0 {
+			continue
+		}
+
+		repairing := f.ScrubRepair && f.repairFile(name, bad)
+		l.Warnf("Scrubbing folder %v: %s is corrupted in %d of its blocks", f.Description(), name, len(bad))
+		events.Default.Log(events.FileCorrupted, map[string]interface{}{
+			"folder":    f.ID,
+			"item":      name,
+			"blocks":    bad,
+			"repairing": repairing,
+		})
+
+		fe := FileError{
+			Path: name,
+			Err:  fmt.Sprintf("data corrupted in %d blocks", len(bad)),
+		}
+		if repairing {
+			fe.Err += ", pulling them again"
+		}
+		corrupted[name] = fe
+		f.scanErrorsMut.Lock()
+		if f.scrubErrors == nil {
+			f.scrubErrors = make(map[string]FileError)
+		}
+		f.scrubErrors[name] = fe
+		f.scanErrorsMut.Unlock()
+	}
+
+	// Whatever wasn't found this time is fine now, or gone.
+	f.scanErrorsMut.Lock()
+	f.scrubErrors = corrupted
+	for name := range f.scrubRepairs {
+		if _, ok := corrupted[name]; !ok {
+			delete(f.scrubRepairs, name)
+		}
+	}
+	f.scanErrorsMut.Unlock()
+
+	l.Infof("Completed scrubbing folder %v, found %d corrupted files", f.Description(), len(corrupted))
+	return nil
+}
+
+// scrubFile returns the indexes of the blocks of the file that don't match
+// the stored hashes.
+func (f *folder) scrubFile(mtimefs fs.Filesystem, name string, limiter *rate.Limiter) ([]int, error) {
+	file, ok := f.fset.Get(protocol.LocalDeviceID, name)
+	if !ok || file.Type != protocol.FileInfoTypeFile || file.IsDeleted() || file.IsInvalid() {
+		return nil, nil
+	}
+	unchanged := func() bool {
+		info, err := mtimefs.Lstat(name)
+		if err != nil || !info.IsRegular() || info.Size() != file.Size || !protocol.ModTimeEqual(file.ModTime(), info.ModTime(), f.ModTimeWindow()) {
+			return false
+		}
+		cur, ok := f.fset.Get(protocol.LocalDeviceID, name)
+		return ok && cur.Sequence == file.Sequence
+	}
+	if !unchanged() {
+		return nil, nil
+	}
+
+	fd, err := mtimefs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	if file.Chunking != protocol.BlockChunkingContentDefined {
+		populateOffsets(file.Blocks)
+	}
+
+	buf := protocol.BufferPool.Get(protocol.MinBlockSize)
+	defer protocol.BufferPool.Put(buf)
+
+	var bad []int
+	for i, block := range file.Blocks {
+		if err := limiter.WaitN(f.ctx, int(block.Size)); err != nil {
+			return nil, err
+		}
+		buf = protocol.BufferPool.Upgrade(buf, int(block.Size))
+		if _, err := fd.ReadAt(buf, block.Offset); err != nil {
+			return nil, errors.Wrap(err, "reading")
+		}
+		if verifyBuffer(buf, block) != nil {
+			bad = append(bad, i)
+		}
+	}
+
+	// Changes while reading aren't corruption, the scanner will pick them
+	// up instead.
+	if len(bad) > 0 && !unchanged() {
+		return nil, nil
+	}
+	return bad, nil
+}
+
+// A scrubRepair is a file found corrupted, to be pulled again where the
+// hashes of its blocks don't match.
+type scrubRepair struct {
+	version protocol.Vector
+	bad     []int // indexes of the corrupted blocks
+}
+
+// repairFile has the given blocks of the file pulled again from devices
+// that have the same version of it, if there are any. It returns whether
+// it does. What we have in the database, and announce to the other devices,
+// stays as it is.
+func (f *folder) repairFile(name string, bad []int) bool {
+	if f.Type == config.FolderTypeSendOnly || f.Type == config.FolderTypeReceiveEncrypted {
+		return false
+	}
+
+	file, ok := f.fset.Get(protocol.LocalDeviceID, name)
+	if !ok {
+		return false
+	}
+	global, ok := f.fset.GetGlobal(name)
+	if !ok || !global.Version.Equal(file.Version) {
+		return false
+	}
+	available := false
+	for _, dev := range f.fset.Availability(name) {
+		if dev != protocol.LocalDeviceID && dev != f.model.id {
+			available = true
+			break
+		}
+	}
+	if !available {
+		return false
+	}
+
+	f.scanErrorsMut.Lock()
+	if f.scrubRepairs == nil {
+		f.scrubRepairs = make(map[string]scrubRepair)
+	}
+	f.scrubRepairs[name] = scrubRepair{version: file.Version, bad: bad}
+	f.scanErrorsMut.Unlock()
+	f.SchedulePull()
+	return true
+}
+
+// scrubRepairNames returns the names of the files to pull again.
+func (f *folder) scrubRepairNames() []string {
+	f.scanErrorsMut.Lock()
+	defer f.scanErrorsMut.Unlock()
+	names := make([]string, 0, len(f.scrubRepairs))
+	for name := range f.scrubRepairs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// withoutCorruptedBlocks returns the local file, as the puller is to
+// consider it, with the hashes of the corrupted blocks unknown so that they
+// are pulled again. The repair is taken on, so only happens once.
+func (f *folder) withoutCorruptedBlocks(file protocol.FileInfo) protocol.FileInfo {
+	f.scanErrorsMut.Lock()
+	repair, ok := f.scrubRepairs[file.Name]
+	delete(f.scrubRepairs, file.Name)
+	f.scanErrorsMut.Unlock()
+	if !ok || !repair.version.Equal(file.Version) {
+		return file
+	}
+
+	file.Blocks = append([]protocol.BlockInfo(nil), file.Blocks...)
+	for _, i := range repair.bad {
+		if i < len(file.Blocks) {
+			file.Blocks[i].Hash = nil
+		}
+	}
+	return file
+}
+
+// scrubFileErrors returns the files found corrupted by the last scrub,
+// sorted by name. It must be called with scanErrorsMut held.
+func (f *folder) scrubFileErrors() []FileError {
+	errs := make([]FileError, 0, len(f.scrubErrors))
+	for _, fe := range f.scrubErrors {
+		errs = append(errs, fe)
+	}
+	sort.Slice(errs, func(a, b int) bool { return errs[a].Path < errs[b].Path })
+	return errs
+}
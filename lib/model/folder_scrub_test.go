@@ -0,0 +1,128 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/scanner"
+)
+
+func TestScrub(t *testing.T) {
+	m, f := setupSendReceiveFolder()
+	ffs := f.Filesystem()
+	defer func() {
+		os.Remove(m.cfg.ConfigPath())
+		os.RemoveAll(ffs.URI())
+	}()
+	f.ScrubRepair = true
+
+	// Two files of two blocks each, shared with another device
+
+	data := bytes.Repeat([]byte("scrub"), protocol.MinBlockSize*2/5)
+	blocks, err := scanner.Blocks(context.TODO(), bytes.NewReader(data), protocol.MinBlockSize, -1, nil, false)
+	must(t, err)
+	var files []protocol.FileInfo
+	for _, name := range []string{"intact", "corrupted"} {
+		must(t, ioutil.WriteFile(filepath.Join(ffs.URI(), name), data, 0644))
+		info, err := ffs.Lstat(name)
+		must(t, err)
+		files = append(files, protocol.FileInfo{
+			Name:        name,
+			Type:        protocol.FileInfoTypeFile,
+			Size:        int64(len(data)),
+			Permissions: 0644,
+			ModifiedS:   info.ModTime().Unix(),
+			ModifiedNs:  int32(info.ModTime().Nanosecond()),
+			Blocks:      blocks,
+			Version:     protocol.Vector{}.Update(myID.Short()),
+		})
+	}
+	f.updateLocalsFromScanning(files)
+	f.fset.Update(device1, files)
+
+	// Flip the second block of one of them, without it looking changed
+
+	fd, err := ffs.OpenFile("corrupted", os.O_RDWR, 0644)
+	must(t, err)
+	_, err = fd.WriteAt([]byte("SCRUB"), protocol.MinBlockSize+10)
+	must(t, err)
+	must(t, fd.Close())
+	modTime := time.Unix(files[1].ModifiedS, int64(files[1].ModifiedNs))
+	must(t, ffs.Chtimes("corrupted", modTime, modTime))
+
+	sub := events.Default.Subscribe(events.FileCorrupted)
+	defer events.Default.Unsubscribe(sub)
+
+	must(t, f.scrub())
+
+	ev, err := sub.Poll(time.Second)
+	must(t, err)
+	data2 := ev.Data.(map[string]interface{})
+	if data2["item"] != "corrupted" || data2["repairing"] != true {
+		t.Errorf("Unexpected event data %v", data2)
+	}
+	if bad := data2["blocks"].([]int); len(bad) != 1 || bad[0] != 1 {
+		t.Errorf("Expected the second block to be corrupted, got %v", bad)
+	}
+	if _, err := sub.Poll(100 * time.Millisecond); err != events.ErrTimeout {
+		t.Error("Expected only one corrupted file, got", err)
+	}
+
+	errs := f.Errors()
+	if len(errs) != 1 || errs[0].Path != "corrupted" {
+		t.Errorf("Expected a folder error for the corrupted file, got %v", errs)
+	}
+
+	// The corrupted block is pulled again, without changing what we have in
+	// the database
+
+	cur, _ := f.fset.Get(protocol.LocalDeviceID, "corrupted")
+	if !cur.Version.Equal(files[1].Version) || !protocol.BlocksEqual(cur.Blocks, blocks) {
+		t.Errorf("Expected the local file to be unchanged, got %v", cur)
+	}
+	f.fset.WithNeedTruncated(protocol.LocalDeviceID, func(fi db.FileIntf) bool {
+		t.Error("Unexpected need for", fi.FileName())
+		return true
+	})
+
+	_, _, _, err = f.processNeeded(make(chan dbUpdateJob), nil, make(chan string))
+	must(t, err)
+	if _, ok := f.pullErrors["corrupted"]; !ok {
+		t.Error("Expected the corrupted file to be queued for pulling, but it wasn't")
+	}
+	f.clearPullErrors()
+	repaired := f.withoutCorruptedBlocks(cur)
+	if repaired.Blocks[0].Hash == nil || repaired.Blocks[1].Hash != nil {
+		t.Error("Expected only the hash of the second block to be unknown")
+	}
+	if cur.Blocks[1].Hash == nil {
+		t.Error("Expected the stored blocks to be left alone")
+	}
+	if again := f.withoutCorruptedBlocks(cur); again.Blocks[1].Hash == nil {
+		t.Error("Expected the repair to happen only once")
+	}
+
+	// Once pulled, scrubbing again clears the error
+
+	must(t, ioutil.WriteFile(filepath.Join(ffs.URI(), "corrupted"), data, 0644))
+	must(t, ffs.Chtimes("corrupted", modTime, modTime))
+
+	must(t, f.scrub())
+	if errs := f.Errors(); len(errs) != 0 {
+		t.Error("Expected no folder errors, got", errs)
+	}
+}
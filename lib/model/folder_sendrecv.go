@@ -8,10 +8,14 @@ package model
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"path/filepath"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -22,6 +26,7 @@ import (
 	"github.com/syncthing/syncthing/lib/events"
 	"github.com/syncthing/syncthing/lib/fs"
 	"github.com/syncthing/syncthing/lib/ignore"
+	"github.com/syncthing/syncthing/lib/merge"
 	"github.com/syncthing/syncthing/lib/osutil"
 	"github.com/syncthing/syncthing/lib/protocol"
 	"github.com/syncthing/syncthing/lib/rand"
@@ -57,6 +62,10 @@ type copyBlocksState struct {
 	have   int
 }
 
+// The conflict command is stopped if it hasn't merged the file by then, so
+// that it can't hold up pulling forever.
+var conflictCommandTimeout = time.Minute
+
 // Which filemode bits to preserve
 const retainBits = fs.ModeSetgid | fs.ModeSetuid | fs.ModeSticky
 
@@ -89,6 +98,10 @@ const (
 	defaultPullerPendingKiB = 2 * protocol.MaxBlockSize / 1024
 
 	maxPullerIterations = 3
+
+	// Conflicting versions of larger files are not merged, as they are
+	// held in memory and unlikely to be text.
+	maxMergeSize = 16 << 20
 )
 
 type dbUpdateJob struct {
@@ -101,11 +114,17 @@ type sendReceiveFolder struct {
 
 	fs        fs.Filesystem
 	versioner versioner.Versioner
+	verifier  func(buf []byte, block protocol.BlockInfo) error
 
 	queue *jobQueue
 
 	pullErrors    map[string]string // path -> error string
 	pullErrorsMut sync.Mutex
+
+	pulledPaths          []string // since the folder was last in sync, for hooks
+	pulledPathsTruncated bool     // more than maxPulledPaths were pulled
+
+	staging *stagingArea // holds incoming changes until approved, if set
 }
 
 func newSendReceiveFolder(model *model, fset *db.FileSet, ignores *ignore.Matcher, cfg config.FolderConfiguration, ver versioner.Versioner, fs fs.Filesystem) service {
@@ -113,6 +132,7 @@ func newSendReceiveFolder(model *model, fset *db.FileSet, ignores *ignore.Matche
 		folder:        newFolder(model, fset, ignores, cfg),
 		fs:            fs,
 		versioner:     ver,
+		verifier:      verifyBuffer,
 		queue:         newJobQueue(),
 		pullErrorsMut: sync.NewMutex(),
 	}
@@ -202,6 +222,9 @@ func (f *sendReceiveFolder) pull() bool {
 			// No files were changed by the puller, so we are in
 			// sync. Any errors were just transitional.
 			f.clearPullErrors()
+			f.queueHooks(hookRun{event: config.HookSyncFinished, paths: f.pulledPaths, truncated: f.pulledPathsTruncated})
+			f.pulledPaths = nil
+			f.pulledPathsTruncated = false
 			return true
 		}
 	}
@@ -300,6 +323,10 @@ func (f *sendReceiveFolder) processNeeded(dbUpdateChan chan<- dbUpdateJob, copyC
 	fileDeletions := map[string]protocol.FileInfo{}
 	buckets := map[string][]protocol.FileInfo{}
 
+	if f.staging != nil {
+		f.staging.begin()
+	}
+
 	// Iterate the list of items that we need and sort them into piles.
 	// Regular files to pull goes into the file queue, everything else
 	// (directories, symlinks and deletes) goes into the "process directly"
@@ -340,6 +367,9 @@ func (f *sendReceiveFolder) processNeeded(dbUpdateChan chan<- dbUpdateJob, copyC
 				f.newPullError(file.Name, fs.ErrInvalidFilename)
 			}
 
+		case f.staging != nil && f.holdStaged(file):
+			l.Debugln(f, "Holding staged", file.Name)
+
 		case file.IsDeleted():
 			if file.IsDirectory() {
 				// Perform directory deletions at the end, as we may have
@@ -411,6 +441,22 @@ func (f *sendReceiveFolder) processNeeded(dbUpdateChan chan<- dbUpdateJob, copyC
 	default:
 	}
 
+	if f.staging != nil {
+		f.staging.prune()
+	}
+
+	// Files found corrupted by scrubbing are pulled again, unless they are
+	// needed and queued anyway.
+	for _, name := range f.scrubRepairNames() {
+		cur, ok := f.fset.Get(protocol.LocalDeviceID, name)
+		if !ok {
+			continue
+		}
+		if global, ok := f.fset.GetGlobal(name); ok && global.Version.Equal(cur.Version) {
+			f.queue.Push(name, cur.Size, cur.ModTime())
+		}
+	}
+
 	// Now do the file queue. Reorder it according to configuration.
 
 	switch f.Order {
@@ -459,7 +505,9 @@ nextFile:
 			continue
 		}
 
-		if !f.checkParent(fi.Name, scanChan) {
+		// Files held back don't go into the folder yet.
+		held := f.staging.holds(fi)
+		if !held && !f.checkParent(fi.Name, scanChan) {
 			f.queue.Done(fileName)
 			continue
 		}
@@ -468,6 +516,9 @@ nextFile:
 		// we can just do a rename instead.
 		key := string(fi.Blocks[0].Hash)
 		for i, candidate := range buckets[key] {
+			if held {
+				break
+			}
 			if protocol.BlocksEqual(candidate.Blocks, fi.Blocks) {
 				// Remove the candidate from the bucket
 				lidx := len(buckets[key]) - 1
@@ -587,16 +638,15 @@ func (f *sendReceiveFolder) handleDir(file protocol.FileInfo, dbUpdateChan chan<
 
 		// Remove it to replace with the dir.
 		if !curFile.IsSymlink() && f.inConflict(curFile.Version, file.Version) {
-			// The new file has been changed in conflict with the existing one. We
-			// should file it away as a conflict instead of just removing or
-			// archiving. Also merge with the version vector we had, to indicate
-			// we have resolved the conflict.
+			// The new file has been changed in conflict with the existing one.
+			// What happens to the existing one depends on the conflict
+			// policy, and it may be kept instead of the new one.
 			// Symlinks aren't checked for conflicts.
 
-			file.Version = file.Version.Merge(curFile.Version)
-			err = f.inWritableDir(func(name string) error {
-				return f.moveForConflict(name, file.ModifiedBy.String(), scanChan)
-			}, curFile.Name)
+			var apply bool
+			if apply, err = f.handleConflict(&file, curFile, "", dbUpdateChan, scanChan); err == nil && !apply {
+				return
+			}
 		} else {
 			err = f.deleteItemOnDisk(curFile, scanChan)
 		}
@@ -613,9 +663,18 @@ func (f *sendReceiveFolder) handleDir(file protocol.FileInfo, dbUpdateChan chan<
 		// not MkdirAll because the parent should already exist.
 		mkdir := func(path string) error {
 			err = f.fs.Mkdir(path, mode)
-			if err != nil || f.IgnorePerms || file.NoPermissions {
+			if err != nil {
+				return err
+			}
+			if err := f.setOwnership(path, &file); err != nil {
+				return err
+			}
+			if err := f.setXattrs(path, file); err != nil {
 				return err
 			}
+			if f.IgnorePerms || file.NoPermissions {
+				return nil
+			}
 
 			// Copy the parent owner and group, if we are supposed to do that.
 			if err := f.maybeCopyOwner(path); err != nil {
@@ -655,6 +714,14 @@ func (f *sendReceiveFolder) handleDir(file protocol.FileInfo, dbUpdateChan chan<
 			return
 		}
 	}
+	if err := f.setOwnership(file.Name, &file); err != nil {
+		f.newPullError(file.Name, err)
+		return
+	}
+	if err := f.setXattrs(file.Name, file); err != nil {
+		f.newPullError(file.Name, err)
+		return
+	}
 	dbUpdateChan <- dbUpdateJob{file, dbUpdateHandleDir}
 }
 
@@ -741,16 +808,15 @@ func (f *sendReceiveFolder) handleSymlink(file protocol.FileInfo, dbUpdateChan c
 		// Remove it to replace with the symlink. This also handles the
 		// "change symlink type" path.
 		if !curFile.IsDirectory() && !curFile.IsSymlink() && f.inConflict(curFile.Version, file.Version) {
-			// The new file has been changed in conflict with the existing one. We
-			// should file it away as a conflict instead of just removing or
-			// archiving. Also merge with the version vector we had, to indicate
-			// we have resolved the conflict.
+			// The new file has been changed in conflict with the existing one.
+			// What happens to the existing one depends on the conflict
+			// policy, and it may be kept instead of the new one.
 			// Directories and symlinks aren't checked for conflicts.
 
-			file.Version = file.Version.Merge(curFile.Version)
-			err = f.inWritableDir(func(name string) error {
-				return f.moveForConflict(name, file.ModifiedBy.String(), scanChan)
-			}, curFile.Name)
+			var apply bool
+			if apply, err = f.handleConflict(&file, curFile, "", dbUpdateChan, scanChan); err == nil && !apply {
+				return
+			}
 		} else {
 			err = f.deleteItemOnDisk(curFile, scanChan)
 		}
@@ -766,7 +832,10 @@ func (f *sendReceiveFolder) handleSymlink(file protocol.FileInfo, dbUpdateChan c
 		if err := f.fs.CreateSymlink(file.SymlinkTarget, path); err != nil {
 			return err
 		}
-		return f.maybeCopyOwner(path)
+		if err := f.maybeCopyOwner(path); err != nil {
+			return err
+		}
+		return f.setOwnership(path, &file)
 	}
 
 	if err = f.inWritableDir(createLink, file.Name); err == nil {
@@ -860,12 +929,18 @@ func (f *sendReceiveFolder) deleteFileWithCurrent(file, cur protocol.FileInfo, h
 	}
 
 	if f.inConflict(cur.Version, file.Version) {
-		// There is a conflict here, which shouldn't happen as deletions
-		// always lose. Merge the version vector of the file we have
-		// locally and commit it to db to resolve the conflict.
-		cur.Version = cur.Version.Merge(file.Version)
-		dbUpdateChan <- dbUpdateJob{cur, dbUpdateHandleFile}
-		return
+		if f.conflictAction(cur, file) != conflictReplace {
+			// There is a conflict here, which shouldn't happen as deletions
+			// always lose unless the conflict policy says otherwise. Merge
+			// the version vector of the file we have locally and commit it
+			// to db to resolve the conflict.
+			cur.Version = cur.Version.Merge(file.Version)
+			dbUpdateChan <- dbUpdateJob{cur, dbUpdateHandleFile}
+			return
+		}
+		// The deletion wins. Merge the version vector we had, to indicate
+		// we have resolved the conflict.
+		file.Version = file.Version.Merge(cur.Version)
 	}
 
 	if f.versioner != nil && !cur.IsSymlink() {
@@ -1037,10 +1112,19 @@ func (f *sendReceiveFolder) renameFile(cur, source, target protocol.FileInfo, db
 // changed file.
 func (f *sendReceiveFolder) handleFile(file protocol.FileInfo, copyChan chan<- copyBlocksState, dbUpdateChan chan<- dbUpdateJob) {
 	curFile, hasCurFile := f.fset.Get(protocol.LocalDeviceID, file.Name)
+	curFile = f.withoutCorruptedBlocks(curFile)
 
 	have, _ := blockDiff(curFile.Blocks, file.Blocks)
 
 	tempName := fs.TempName(file.Name)
+	if f.staging.holds(file) {
+		var err error
+		if tempName, err = f.staging.tempName(file.Name); err != nil {
+			f.newPullError(file.Name, err)
+			f.queue.Done(file.Name)
+			return
+		}
+	}
 
 	populateOffsets(file.Blocks)
 
@@ -1050,7 +1134,15 @@ func (f *sendReceiveFolder) handleFile(file protocol.FileInfo, copyChan chan<- c
 
 	// Check for an old temporary file which might have some blocks we could
 	// reuse.
-	tempBlocks, err := scanner.HashFile(f.ctx, f.fs, tempName, file.BlockSize(), nil, false)
+	var tempBlocks []protocol.BlockInfo
+	var err error
+	if file.Chunking == protocol.BlockChunkingContentDefined {
+		// Where blocks are still missing the temp file would be chunked
+		// differently, so check the blocks where we expect them instead.
+		tempBlocks, err = hashBlocksAt(f.fs, tempName, file.Blocks)
+	} else {
+		tempBlocks, err = scanner.HashFile(f.ctx, f.fs, tempName, file.BlockSize(), protocol.BlockChunkingFixed, nil, false)
+	}
 	if err == nil {
 		// Check for any reusable blocks in the temp file
 		tempCopyBlocks, _ := blockDiff(tempBlocks, file.Blocks)
@@ -1133,7 +1225,9 @@ func (f *sendReceiveFolder) handleFile(file protocol.FileInfo, copyChan chan<- c
 }
 
 // blockDiff returns lists of common and missing (to transform src into tgt)
-// blocks. Both block lists must have been created with the same block size.
+// blocks. A block is common when src has the same block at the same offset,
+// so the lists may have been created with different block sizes or with
+// content defined chunking.
 func blockDiff(src, tgt []protocol.BlockInfo) ([]protocol.BlockInfo, []protocol.BlockInfo) {
 	if len(tgt) == 0 {
 		return nil, nil
@@ -1147,21 +1241,54 @@ func blockDiff(src, tgt []protocol.BlockInfo) ([]protocol.BlockInfo, []protocol.
 	have := make([]protocol.BlockInfo, 0, len(src))
 	need := make([]protocol.BlockInfo, 0, len(tgt))
 
-	for i := range tgt {
-		if i >= len(src) {
-			return have, append(need, tgt[i:]...)
+	// The offsets are calculated rather than taken from the blocks, as
+	// they are not guaranteed to be set yet.
+	var srcOffset, tgtOffset int64
+	j := 0
+	for _, block := range tgt {
+		for j < len(src) && srcOffset < tgtOffset {
+			srcOffset += int64(src[j].Size)
+			j++
 		}
-		if !bytes.Equal(tgt[i].Hash, src[i].Hash) {
-			// Copy differing block
-			need = append(need, tgt[i])
+		if j < len(src) && srcOffset == tgtOffset && src[j].Size == block.Size && bytes.Equal(src[j].Hash, block.Hash) {
+			have = append(have, block)
 		} else {
-			have = append(have, tgt[i])
+			// Copy differing block
+			need = append(need, block)
 		}
+		tgtOffset += int64(block.Size)
 	}
 
 	return have, need
 }
 
+// hashBlocksAt returns the blocks found in the file at the offsets and sizes
+// of the given blocks, up to the end of the file.
+func hashBlocksAt(filesystem fs.Filesystem, name string, blocks []protocol.BlockInfo) ([]protocol.BlockInfo, error) {
+	fd, err := filesystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	found := make([]protocol.BlockInfo, 0, len(blocks))
+	var buf []byte
+	for _, block := range blocks {
+		buf = protocol.BufferPool.Upgrade(buf, int(block.Size))
+		if _, err := fd.ReadAt(buf, block.Offset); err != nil {
+			break
+		}
+		hash := sha256.Sum256(buf)
+		found = append(found, protocol.BlockInfo{
+			Offset: block.Offset,
+			Size:   block.Size,
+			Hash:   hash[:],
+		})
+	}
+	protocol.BufferPool.Put(buf)
+	return found, nil
+}
+
 // populateOffsets sets the Offset field on each block
 func populateOffsets(blocks []protocol.BlockInfo) {
 	var offset int64
@@ -1203,6 +1330,16 @@ func (f *sendReceiveFolder) shortcutFile(file, curFile protocol.FileInfo, dbUpda
 		}
 	}
 
+	if err = f.setOwnership(file.Name, &file); err != nil {
+		f.newPullError(file.Name, err)
+		return
+	}
+
+	if err = f.setXattrs(file.Name, file); err != nil {
+		f.newPullError(file.Name, err)
+		return
+	}
+
 	f.fs.Chtimes(file.Name, file.ModTime(), file.ModTime()) // never fails
 
 	// This may have been a conflict. We should merge the version vectors so
@@ -1245,7 +1382,16 @@ func (f *sendReceiveFolder) copierRoutine(in <-chan copyBlocksState, pullChan ch
 			blocksPercentChanged = (tot - state.have) * 100 / tot
 		}
 
-		if blocksPercentChanged >= f.WeakHashThresholdPct {
+		// Blocks in other files are found through the block map. It can
+		// only tell the index of a block in the file it is found in, whose
+		// blocks may be content defined or of another size than ours, so
+		// we look up the offsets in those files as we go.
+		contentDefined := state.file.Chunking == protocol.BlockChunkingContentDefined
+		sourceBlocks := make(map[[2]string][]protocol.BlockInfo)
+
+		if contentDefined {
+			l.Debugf("not weak hashing %s. blocks are content defined", state.file.Name)
+		} else if blocksPercentChanged >= f.WeakHashThresholdPct {
 			hashesToFind := make([]uint32, 0, len(state.blocks))
 			for _, block := range state.blocks {
 				if block.WeakHash != 0 {
@@ -1293,11 +1439,11 @@ func (f *sendReceiveFolder) copierRoutine(in <-chan copyBlocksState, pullChan ch
 			buf = protocol.BufferPool.Upgrade(buf, int(block.Size))
 
 			found, err := weakHashFinder.Iterate(block.WeakHash, buf, func(offset int64) bool {
-				if verifyBuffer(buf, block) != nil {
+				if f.verifier(buf, block) != nil {
 					return true
 				}
 
-				_, err = dstFd.WriteAt(buf, block.Offset)
+				err = f.copyBlock(f.fs, file, dstFd, offset, buf, block)
 				if err != nil {
 					state.fail(errors.Wrap(err, "dst write"))
 
@@ -1316,24 +1462,36 @@ func (f *sendReceiveFolder) copierRoutine(in <-chan copyBlocksState, pullChan ch
 
 			if !found {
 				found = f.model.finder.Iterate(folders, block.Hash, func(folder, path string, index int32) bool {
-					fs := folderFilesystems[folder]
-					fd, err := fs.Open(path)
+					key := [2]string{folder, path}
+					blocks, ok := sourceBlocks[key]
+					if !ok {
+						cf, _ := f.model.CurrentFolderFile(folder, path)
+						blocks = cf.Blocks
+						sourceBlocks[key] = blocks
+					}
+					if int(index) >= len(blocks) {
+						return false
+					}
+					offset := blocks[index].Offset
+
+					ffs := folderFilesystems[folder]
+					fd, err := ffs.Open(path)
 					if err != nil {
 						return false
 					}
+					defer fd.Close()
 
-					_, err = fd.ReadAt(buf, int64(state.file.BlockSize())*int64(index))
-					fd.Close()
+					_, err = fd.ReadAt(buf, offset)
 					if err != nil {
 						return false
 					}
 
-					if err := verifyBuffer(buf, block); err != nil {
+					if err := f.verifier(buf, block); err != nil {
 						l.Debugln("Finder failed to verify buffer", err)
 						return false
 					}
 
-					_, err = dstFd.WriteAt(buf, block.Offset)
+					err = f.copyBlock(ffs, fd, dstFd, offset, buf, block)
 					if err != nil {
 						state.fail(errors.Wrap(err, "dst write"))
 					}
@@ -1387,6 +1545,23 @@ func verifyBuffer(buf []byte, block protocol.BlockInfo) error {
 	return nil
 }
 
+// copyBlock writes the block, read from src at the given offset into buf,
+// to the temp file. Where the filesystem can, it copies the range between
+// the files instead, which may share the data on disk.
+func (f *sendReceiveFolder) copyBlock(srcFs fs.Filesystem, src fs.File, dst io.WriterAt, offset int64, buf []byte, block protocol.BlockInfo) error {
+	if lw, ok := dst.(lockedWriterAt); ok && srcFs.Type() == f.fs.Type() {
+		err := lw.copyRange(f.fs, src, offset, block.Offset, int64(block.Size))
+		if err == nil {
+			return nil
+		}
+		if err != fs.ErrCopyRangeNotSupported {
+			l.Debugln("Copying range, falling back to writing:", err)
+		}
+	}
+	_, err := dst.WriteAt(buf, block.Offset)
+	return err
+}
+
 func (f *sendReceiveFolder) pullerRoutine(in <-chan pullBlockState, out chan<- *sharedPullerState) {
 	requestLimiter := newByteSemaphore(f.PullerMaxPendingKiB * 1024)
 	wg := sync.NewWaitGroup()
@@ -1445,11 +1620,14 @@ func (f *sendReceiveFolder) pullBlock(state pullBlockState, out chan<- *sharedPu
 		default:
 		}
 
-		// Select the least busy device to pull the block from. If we found no
-		// feasible device at all, fail the block (and in the long run, the
-		// file).
-		selected, found := activity.leastBusy(candidates)
+		// Select the device expected to deliver the block soonest. If we
+		// found no feasible device at all, fail the block (and in the long
+		// run, the file).
+		selected, found := activity.leastBusy(f.ctx, candidates, int(state.block.Size))
 		if !found {
+			if f.ctx.Err() != nil {
+				continue
+			}
 			if lastError != nil {
 				state.fail(errors.Wrap(lastError, "pull"))
 			} else {
@@ -1458,14 +1636,15 @@ func (f *sendReceiveFolder) pullBlock(state pullBlockState, out chan<- *sharedPu
 			break
 		}
 
-		candidates = removeAvailability(candidates, selected)
+		candidates = removeAvailability(candidates, selected.Availability)
 
-		// Fetch the block, while marking the selected device as in use so that
-		// leastBusy can select another device when someone else asks.
-		activity.using(selected)
+		// Fetch the block. The selected device counts as busy with it until
+		// we're done, so that leastBusy can select another device when
+		// someone else asks.
 		var buf []byte
-		buf, lastError = f.model.requestGlobal(selected.ID, f.folderID, state.file.Name, state.block.Offset, int(state.block.Size), state.block.Hash, state.block.WeakHash, selected.FromTemporary)
-		activity.done(selected)
+		blockNo := state.file.BlockIndex(state.block.Offset)
+		buf, lastError = f.model.requestGlobal(selected.ID, f.folderID, state.file.Name, blockNo, state.block.Offset, int(state.block.Size), state.block.Hash, state.block.WeakHash, selected.FromTemporary)
+		activity.done(selected, lastError)
 		if lastError != nil {
 			l.Debugln("request:", f.folderID, state.file.Name, state.block.Offset, state.block.Size, "returned error:", lastError)
 			continue
@@ -1473,7 +1652,7 @@ func (f *sendReceiveFolder) pullBlock(state pullBlockState, out chan<- *sharedPu
 
 		// Verify that the received block matches the desired hash, if not
 		// try pulling it from another device.
-		lastError = verifyBuffer(buf, state.block)
+		lastError = f.verifier(buf, state.block)
 		if lastError != nil {
 			l.Debugln("request:", f.folderID, state.file.Name, state.block.Offset, state.block.Size, "hash mismatch")
 			continue
@@ -1504,6 +1683,14 @@ func (f *sendReceiveFolder) performFinish(file, curFile protocol.FileInfo, hasCu
 		return err
 	}
 
+	if err := f.setOwnership(tempName, &file); err != nil {
+		return err
+	}
+
+	if err := f.setXattrs(tempName, file); err != nil {
+		return err
+	}
+
 	if stat, err := f.fs.Lstat(file.Name); err == nil {
 		// There is an old file or directory already in place. We need to
 		// handle that.
@@ -1515,20 +1702,23 @@ func (f *sendReceiveFolder) performFinish(file, curFile protocol.FileInfo, hasCu
 		}
 
 		if !curFile.IsDirectory() && !curFile.IsSymlink() && f.inConflict(curFile.Version, file.Version) {
-			// The new file has been changed in conflict with the existing one. We
-			// should file it away as a conflict instead of just removing or
-			// archiving. Also merge with the version vector we had, to indicate
-			// we have resolved the conflict.
+			// The new file has been changed in conflict with the existing one.
+			// What happens to the existing one depends on the conflict
+			// policy, and it may be kept instead of the new one, in which
+			// case the new one is of no further use.
 			// Directories and symlinks aren't checked for conflicts.
 
-			file.Version = file.Version.Merge(curFile.Version)
-			err = f.inWritableDir(func(name string) error {
-				return f.moveForConflict(name, file.ModifiedBy.String(), scanChan)
-			}, curFile.Name)
-		} else {
-			err = f.deleteItemOnDisk(curFile, scanChan)
-		}
-		if err != nil {
+			apply, err := f.handleConflict(&file, curFile, tempName, dbUpdateChan, scanChan)
+			if err != nil {
+				return err
+			}
+			if !apply {
+				if err := f.fs.Remove(tempName); err != nil && !fs.IsNotExist(err) {
+					l.Debugln(f, "removing unused temp file", err)
+				}
+				return nil
+			}
+		} else if err := f.deleteItemOnDisk(curFile, scanChan); err != nil {
 			return err
 		}
 	}
@@ -1547,6 +1737,19 @@ func (f *sendReceiveFolder) performFinish(file, curFile protocol.FileInfo, hasCu
 	return nil
 }
 
+// addPulledPaths keeps the pulled paths for the syncFinished hooks, up to
+// maxPulledPaths of them.
+func (f *sendReceiveFolder) addPulledPaths(paths []string) {
+	if f.pulledPathsTruncated || !f.hasHooks(config.HookSyncFinished) {
+		return
+	}
+	if len(f.pulledPaths)+len(paths) > maxPulledPaths {
+		paths = paths[:maxPulledPaths-len(f.pulledPaths)]
+		f.pulledPathsTruncated = true
+	}
+	f.pulledPaths = append(f.pulledPaths, paths...)
+}
+
 func (f *sendReceiveFolder) finisherRoutine(in <-chan *sharedPullerState, dbUpdateChan chan<- dbUpdateJob, scanChan chan<- string) {
 	for state := range in {
 		if closed, err := state.finalClose(); closed {
@@ -1555,7 +1758,12 @@ func (f *sendReceiveFolder) finisherRoutine(in <-chan *sharedPullerState, dbUpda
 			f.queue.Done(state.file.Name)
 
 			if err == nil {
-				err = f.performFinish(state.file, state.curFile, state.hasCurFile, state.tempName, dbUpdateChan, scanChan)
+				if f.staging.holds(state.file) {
+					l.Debugln(f, "staged", state.file.Name)
+					f.staging.stage(state.file)
+				} else {
+					err = f.performFinish(state.file, state.curFile, state.hasCurFile, state.tempName, dbUpdateChan, scanChan)
+				}
 			}
 
 			if err != nil {
@@ -1595,6 +1803,10 @@ func (f *sendReceiveFolder) Jobs(page, perpage int) ([]string, []string, int) {
 	return f.queue.Jobs(page, perpage)
 }
 
+func (f *sendReceiveFolder) QueueSize() (int, int) {
+	return f.queue.lenProgress(), f.queue.lenQueued()
+}
+
 // dbUpdaterRoutine aggregates db updates and commits them in batches no
 // larger than 1000 items, and no more delayed than 2 seconds.
 func (f *sendReceiveFolder) dbUpdaterRoutine(dbUpdateChan <-chan dbUpdateJob) {
@@ -1627,6 +1839,19 @@ func (f *sendReceiveFolder) dbUpdaterRoutine(dbUpdateChan <-chan dbUpdateJob) {
 		// (across the network) use this call to updateLocals
 		f.updateLocalsFromPulling(files)
 
+		if len(f.Hooks) > 0 {
+			paths := make([]string, 0, len(files))
+			for _, file := range files {
+				if !file.IsInvalid() {
+					paths = append(paths, file.Name)
+				}
+			}
+			if len(paths) > 0 {
+				f.addPulledPaths(paths)
+				f.queueHooks(hookRun{event: config.HookItemFinished, paths: paths})
+			}
+		}
+
 		if found {
 			f.ReceivedFile(lastFile.Name, lastFile.IsDeleted())
 			found = false
@@ -1662,6 +1887,15 @@ loop:
 
 			job.file.Sequence = 0
 
+			// Extended attributes and ownership we didn't apply aren't
+			// what we have.
+			if !f.SyncXattrs {
+				job.file.Xattrs = nil
+			}
+			if !f.SyncOwnership {
+				job.file.Ownership = nil
+			}
+
 			batch.append(job.file)
 
 			batch.flushIfFull()
@@ -1761,6 +1995,260 @@ func (f *sendReceiveFolder) moveForConflict(name, lastModBy string, scanChan cha
 	return err
 }
 
+// A conflictResolution is what happens to a local file when a change to it
+// by another device is in conflict with it.
+type conflictResolution int
+
+const (
+	conflictMove      conflictResolution = iota // to a conflict copy, making room for the change
+	conflictReplace                             // removed or archived, making room for the change
+	conflictKeepLocal                           // kept, ignoring the change
+	conflictMerge                               // merged with the change by an external command, and kept
+)
+
+// conflictAction returns what the conflict policy of the folder says should
+// happen to the local file cur, when file is in conflict with it.
+func (f *sendReceiveFolder) conflictAction(cur, file protocol.FileInfo) conflictResolution {
+	switch f.ConflictPolicy {
+	case config.ConflictNewestWins:
+		if cur.ModTime().After(file.ModTime()) {
+			return conflictKeepLocal
+		}
+		return conflictReplace
+	case config.ConflictKeepLocal:
+		return conflictKeepLocal
+	case config.ConflictKeepRemote:
+		return conflictReplace
+	case config.ConflictExternal:
+		if file.IsDirectory() || file.IsSymlink() || file.IsDeleted() {
+			// There's nothing to merge the local file with.
+			return conflictMove
+		}
+		return conflictMerge
+	default:
+		return conflictMove
+	}
+}
+
+// handleConflict resolves a conflict between the local file cur and the
+// change file, according to the conflict policy of the folder. The content
+// of file, if any, is in the temp file tempName. It returns whether the
+// change should be applied, with the version vector of the local file merged
+// into it, in which case room has been made for it on disk. Otherwise the
+// local file has been kept and recorded as the resolution of the conflict.
+func (f *sendReceiveFolder) handleConflict(file *protocol.FileInfo, cur protocol.FileInfo, tempName string, dbUpdateChan chan<- dbUpdateJob, scanChan chan<- string) (bool, error) {
+	action := f.conflictAction(cur, *file)
+	if action == conflictMove && tempName != "" && f.isMergeable(cur.Name) {
+		if err := f.mergeText(cur, *file, tempName); err != nil {
+			l.Infof("Not merging conflicting versions of %s in folder %s: %v", cur.Name, f.Description(), err)
+		} else {
+			// The local file now holds the merged content, which is a new
+			// version of both.
+			merged, err := f.mergedFile(cur, *file)
+			if err != nil {
+				scanChan <- cur.Name
+				return false, errors.Wrap(err, "hashing merged file")
+			}
+			dbUpdateChan <- dbUpdateJob{merged, dbUpdateHandleFile}
+			return false, nil
+		}
+	}
+	if action == conflictMerge {
+		if tempName == "" {
+			action = conflictMove
+		} else if err := f.mergeForConflict(cur.Name, tempName); err != nil {
+			l.Warnf("Merging conflicting versions of %s in folder %s: %v; keeping a conflict copy instead", cur.Name, f.Description(), err)
+			action = conflictMove
+		} else {
+			// The local file now holds the merged content, which is a new
+			// version of both.
+			merged, err := f.mergedFile(cur, *file)
+			if err != nil {
+				scanChan <- cur.Name
+				return false, errors.Wrap(err, "hashing merged file")
+			}
+			dbUpdateChan <- dbUpdateJob{merged, dbUpdateHandleFile}
+			return false, nil
+		}
+	}
+
+	switch action {
+	case conflictKeepLocal:
+		// Merge the version vector of the remote change into the one of the
+		// file we keep, and commit it to db to resolve the conflict.
+		cur.Version = cur.Version.Merge(file.Version)
+		dbUpdateChan <- dbUpdateJob{cur, dbUpdateHandleFile}
+		return false, nil
+	case conflictReplace:
+		file.Version = file.Version.Merge(cur.Version)
+		return true, f.deleteItemOnDisk(cur, scanChan)
+	default:
+		file.Version = file.Version.Merge(cur.Version)
+		return true, f.inWritableDir(func(name string) error {
+			return f.moveForConflict(name, file.ModifiedBy.String(), scanChan)
+		}, cur.Name)
+	}
+}
+
+// mergedFile returns the local file cur as it is on disk, after the change
+// file has been merged into it.
+func (f *sendReceiveFolder) mergedFile(cur, file protocol.FileInfo) (protocol.FileInfo, error) {
+	info, err := f.fs.Lstat(cur.Name)
+	if err != nil {
+		return protocol.FileInfo{}, err
+	}
+
+	merged := cur
+	merged.Size = info.Size()
+	merged.ModifiedS = info.ModTime().Unix()
+	merged.ModifiedNs = int32(info.ModTime().Nanosecond())
+	merged.ModifiedBy = f.shortID
+	if !f.IgnorePerms {
+		merged.Permissions = uint32(info.Mode() & fs.ModePerm)
+	}
+	merged.RawBlockSize = int32(protocol.BlockSize(merged.Size))
+	merged.Chunking = protocol.BlockChunkingFixed
+	if f.model.useContentDefinedChunking(f.FolderConfiguration) {
+		merged.Chunking = protocol.BlockChunkingContentDefined
+	}
+	merged.Blocks, err = scanner.HashFile(f.ctx, f.fs, cur.Name, merged.BlockSize(), merged.Chunking, nil, true)
+	if err != nil {
+		return protocol.FileInfo{}, err
+	}
+	merged.Version = cur.Version.Merge(file.Version).Update(f.shortID)
+	return merged, nil
+}
+
+// isMergeable returns whether the named file matches any of the merge
+// patterns of the folder, either by its full name or the last element.
+func (f *sendReceiveFolder) isMergeable(name string) bool {
+	for _, pattern := range f.MergePatterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, filepath.Base(name)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeText merges the changes in the remote version of the file, held in
+// the temp file tempName, into the local file cur. The common ancestor is the
+// most recent version in the archive of the versioner that is older than
+// both, and the merge fails if the changes from it overlap.
+func (f *sendReceiveFolder) mergeText(cur, file protocol.FileInfo, tempName string) error {
+	if cur.Size > maxMergeSize || file.Size > maxMergeSize {
+		return errors.New("file too large to merge")
+	}
+	base, err := f.mergeBase(cur, file)
+	if err != nil {
+		return err
+	}
+	local, err := f.readFile(cur.Name)
+	if err != nil {
+		return err
+	}
+	remote, err := f.readFile(tempName)
+	if err != nil {
+		return err
+	}
+
+	merged, err := merge.ThreeWay(base, local, remote)
+	if err != nil {
+		return err
+	}
+
+	// The remote version has served its purpose, so the temp file is
+	// reused for the merged one, which then replaces the local file.
+	fd, err := f.fs.Create(tempName)
+	if err != nil {
+		return err
+	}
+	if _, err := fd.Write(merged); err != nil {
+		fd.Close()
+		return err
+	}
+	if err := fd.Close(); err != nil {
+		return err
+	}
+	return osutil.RenameOrCopy(f.fs, f.fs, tempName, cur.Name)
+}
+
+// mergeBase returns the content of the common ancestor of the local file cur
+// and the remote version file, looked up among the versions archived by the
+// versioner.
+func (f *sendReceiveFolder) mergeBase(cur, file protocol.FileInfo) ([]byte, error) {
+	opener, ok := f.versioner.(versioner.Opener)
+	if !ok {
+		return nil, errors.New("no common ancestor in the versioner archive")
+	}
+	versions, err := f.versioner.GetVersions()
+	if err != nil {
+		return nil, errors.Wrap(err, "listing versions")
+	}
+
+	// Archived versions have their modification time in whole seconds.
+	before := cur.ModTime()
+	if file.ModTime().Before(before) {
+		before = file.ModTime()
+	}
+	before = before.Truncate(time.Second)
+	var ancestor versioner.FileVersion
+	found := false
+	for _, v := range versions[osutil.NormalizedFilename(cur.Name)] {
+		if !v.ModTime.After(before) && v.Size <= maxMergeSize && (!found || v.VersionTime.After(ancestor.VersionTime)) {
+			ancestor = v
+			found = true
+		}
+	}
+	if !found {
+		return nil, errors.New("no common ancestor in the versioner archive")
+	}
+
+	fd, err := opener.Open(cur.Name, ancestor.VersionTime)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening common ancestor")
+	}
+	defer fd.Close()
+	base, err := ioutil.ReadAll(fd)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading common ancestor")
+	}
+	return base, nil
+}
+
+func (f *sendReceiveFolder) readFile(name string) ([]byte, error) {
+	fd, err := f.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	return ioutil.ReadAll(fd)
+}
+
+// mergeForConflict runs the conflict command of the folder with the local
+// file name and the temp file holding the conflicting remote version, which
+// is expected to leave the result of merging them in the local file.
+func (f *sendReceiveFolder) mergeForConflict(name, tempName string) error {
+	ctx, cancel := context.WithTimeout(f.ctx, conflictCommandTimeout)
+	defer cancel()
+	root := f.fs.URI()
+	cmd, err := f.externalCommand(ctx, f.ConflictCommand, map[string]string{
+		"%LOCAL_PATH%":  filepath.Join(root, name),
+		"%REMOTE_PATH%": filepath.Join(root, tempName),
+	})
+	if err != nil {
+		return err
+	}
+	out, err := cmd.CombinedOutput()
+	l.Debugln(f, "conflict command output:", string(out))
+	if ctx.Err() == context.DeadlineExceeded {
+		return errors.Errorf("conflict command timed out after %v", conflictCommandTimeout)
+	}
+	return err
+}
+
 func (f *sendReceiveFolder) newPullError(path string, err error) {
 	f.pullErrorsMut.Lock()
 	defer f.pullErrorsMut.Unlock()
@@ -1963,6 +2451,87 @@ func (f *sendReceiveFolder) maybeCopyOwner(path string) error {
 	return nil
 }
 
+// setXattrs makes the extended attributes of the item at path those of the
+// file, if we are supposed to do that and they are known.
+func (f *sendReceiveFolder) setXattrs(path string, file protocol.FileInfo) error {
+	if !f.SyncXattrs || file.Xattrs == nil {
+		return nil
+	}
+
+	xattrs := make([]fs.Xattr, len(file.Xattrs.Entries))
+	for i, attr := range file.Xattrs.Entries {
+		xattrs[i] = fs.Xattr{Name: attr.Name, Value: attr.Value}
+	}
+	if err := f.fs.SetXattr(path, xattrs); err != nil && err != fs.ErrXattrsNotSupported {
+		return errors.Wrap(err, "setting extended attributes")
+	}
+	return nil
+}
+
+// setOwnership makes the owner and group of the item at path those of the
+// file, mapped as configured for the device that changed it, if we are
+// supposed to do that and they are known. The ownership of the file becomes
+// what was applied, which is what a scan finds, also when we aren't allowed
+// to change it.
+func (f *sendReceiveFolder) setOwnership(path string, file *protocol.FileInfo) error {
+	if !f.SyncOwnership || file.Ownership == nil || runtime.GOOS == "windows" {
+		return nil
+	}
+
+	var device config.DeviceConfiguration
+	for id, cfg := range f.model.cfg.Devices() {
+		if id.Short() == file.ModifiedBy {
+			device = cfg
+			break
+		}
+	}
+	uid := localOwnerID(device, false, file.Ownership.OwnerName, int(file.Ownership.Uid))
+	gid := localOwnerID(device, true, file.Ownership.GroupName, int(file.Ownership.Gid))
+
+	if err := f.fs.Lchown(path, uid, gid); fs.IsPermission(err) {
+		// Only root may give items away, so they keep the ownership they
+		// were created with, which is what we record instead.
+		l.Debugln(f, "setting ownership:", err)
+		info, err := f.fs.Lstat(path)
+		if err != nil {
+			return errors.Wrap(err, "checking ownership")
+		}
+		uid, gid = info.Owner(), info.Group()
+	} else if err != nil {
+		return errors.Wrap(err, "setting ownership")
+	}
+	file.Ownership = &protocol.Ownership{
+		OwnerName: osutil.UserName(uid),
+		GroupName: osutil.GroupName(gid),
+		Uid:       int32(uid),
+		Gid:       int32(gid),
+	}
+	return nil
+}
+
+// localOwnerID returns the id of the local owner, or group, for the one with
+// the given name and id on the device. That is what it's mapped to, if
+// anything, else the one with the same name, else the same id.
+func localOwnerID(device config.DeviceConfiguration, group bool, name string, id int) int {
+	if local, ok := device.MapOwner(group, name, id); ok {
+		if n, err := strconv.Atoi(local); err == nil {
+			return n
+		}
+		name = local
+	}
+
+	lookup := osutil.UserID
+	if group {
+		lookup = osutil.GroupID
+	}
+	if name != "" {
+		if n, ok := lookup(name); ok {
+			return n
+		}
+	}
+	return id
+}
+
 func (f *sendReceiveFolder) inWritableDir(fn func(string) error, path string) error {
 	return inWritableDir(fn, f.fs, path, f.IgnorePerms)
 }
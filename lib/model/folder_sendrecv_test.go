@@ -10,6 +10,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"io"
 	"io/ioutil"
 	"os"
@@ -23,9 +24,11 @@ import (
 	"github.com/syncthing/syncthing/lib/events"
 	"github.com/syncthing/syncthing/lib/fs"
 	"github.com/syncthing/syncthing/lib/ignore"
+	"github.com/syncthing/syncthing/lib/osutil"
 	"github.com/syncthing/syncthing/lib/protocol"
 	"github.com/syncthing/syncthing/lib/scanner"
 	"github.com/syncthing/syncthing/lib/sync"
+	"github.com/syncthing/syncthing/lib/versioner"
 )
 
 var blocks = []protocol.BlockInfo{
@@ -102,8 +105,11 @@ func setupSendReceiveFolder(files ...protocol.FileInfo) (*model, *sendReceiveFol
 			initialScanFinished: make(chan struct{}),
 			ctx:                 context.TODO(),
 			FolderConfiguration: fcfg,
+			scanErrorsMut:       sync.NewMutex(),
+			hookQueue:           make(chan hookRun, maxQueuedHooks),
 		},
 
+		verifier:      verifyBuffer,
 		queue:         newJobQueue(),
 		pullErrors:    make(map[string]string),
 		pullErrorsMut: sync.NewMutex(),
@@ -286,7 +292,7 @@ func TestCopierFinder(t *testing.T) {
 	}
 
 	// Verify that the fetched blocks have actually been written to the temp file
-	blks, err := scanner.HashFile(context.TODO(), f.Filesystem(), tempFile, protocol.MinBlockSize, nil, false)
+	blks, err := scanner.HashFile(context.TODO(), f.Filesystem(), tempFile, protocol.MinBlockSize, protocol.BlockChunkingFixed, nil, false)
 	if err != nil {
 		t.Log(err)
 	}
@@ -299,6 +305,75 @@ func TestCopierFinder(t *testing.T) {
 	finish.fd.Close()
 }
 
+func TestCopierFinderOffsets(t *testing.T) {
+	// A block found in a file with other block boundaries than ours must
+	// be read from where it is in that file.
+
+	junk := make([]byte, 1000)
+	data := make([]byte, protocol.MinBlockSize)
+	_, err := io.ReadFull(rand.Reader, junk)
+	must(t, err)
+	_, err = io.ReadFull(rand.Reader, data)
+	must(t, err)
+	junkHash := sha256.Sum256(junk)
+	dataHash := sha256.Sum256(data)
+
+	sourceFile := protocol.FileInfo{
+		Name:     "source",
+		Size:     int64(len(junk) + len(data)),
+		Chunking: protocol.BlockChunkingContentDefined,
+		Blocks: []protocol.BlockInfo{
+			{Offset: 0, Size: int32(len(junk)), Hash: junkHash[:]},
+			{Offset: int64(len(junk)), Size: int32(len(data)), Hash: dataHash[:]},
+		},
+		Version: protocol.Vector{}.Update(myID.Short()),
+	}
+	requiredFile := protocol.FileInfo{
+		Name:    "target",
+		Size:    int64(len(data)),
+		Blocks:  []protocol.BlockInfo{{Offset: 0, Size: int32(len(data)), Hash: dataHash[:]}},
+		Version: protocol.Vector{}.Update(device1.Short()),
+	}
+
+	m, f := setupSendReceiveFolder(sourceFile)
+	defer func() {
+		os.Remove(m.cfg.ConfigPath())
+		os.RemoveAll(f.Filesystem().URI())
+	}()
+	// The copier finds other files through the config.
+	w, err := m.cfg.SetFolder(f.FolderConfiguration)
+	must(t, err)
+	w.Wait()
+	fd, err := f.fs.Create("source")
+	must(t, err)
+	_, err = fd.Write(append(junk, data...))
+	must(t, err)
+	must(t, fd.Close())
+
+	copyChan := make(chan copyBlocksState)
+	pullChan := make(chan pullBlockState, 1)
+	finisherChan := make(chan *sharedPullerState, 1)
+	dbUpdateChan := make(chan dbUpdateJob, 1)
+
+	go f.copierRoutine(copyChan, pullChan, finisherChan)
+
+	f.handleFile(requiredFile, copyChan, dbUpdateChan)
+
+	finish := <-finisherChan
+	finish.fd.Close()
+	select {
+	case <-pullChan:
+		t.Fatal("Block was pulled instead of copied")
+	default:
+	}
+
+	got, err := ioutil.ReadFile(filepath.Join(f.Filesystem().URI(), fs.TempName("target")))
+	must(t, err)
+	if !bytes.Equal(got, data) {
+		t.Error("Copied data does not match the source block")
+	}
+}
+
 func TestWeakHash(t *testing.T) {
 	// Setup the model/pull environment
 	model, fo := setupSendReceiveFolder()
@@ -723,6 +798,41 @@ func TestDiffEmpty(t *testing.T) {
 	}
 }
 
+func TestDiffContentDefined(t *testing.T) {
+	block := func(size int32, hash string) protocol.BlockInfo {
+		return protocol.BlockInfo{Size: size, Hash: []byte(hash)}
+	}
+
+	// Data was inserted into the second block, which grew and shifted the
+	// rest of the file. Only blocks found at the same offset with the same
+	// size and hash can be kept in place.
+	src := []protocol.BlockInfo{block(10, "a"), block(20, "b"), block(30, "c")}
+	tgt := []protocol.BlockInfo{block(10, "a"), block(25, "x"), block(30, "c")}
+	have, need := blockDiff(src, tgt)
+	if len(have) != 1 || string(have[0].Hash) != "a" {
+		t.Errorf("unexpected have: %v", have)
+	}
+	if len(need) != 2 || string(need[0].Hash) != "x" || string(need[1].Hash) != "c" {
+		t.Errorf("unexpected need: %v", need)
+	}
+
+	// Same hash but a different size is not the same block.
+	src = []protocol.BlockInfo{block(10, "a"), block(20, "b")}
+	tgt = []protocol.BlockInfo{block(10, "a"), block(15, "b")}
+	have, need = blockDiff(src, tgt)
+	if len(have) != 1 || len(need) != 1 {
+		t.Errorf("unexpected diff: have %v, need %v", have, need)
+	}
+
+	// Blocks after a change that resynchronizes at the same offset are kept.
+	src = []protocol.BlockInfo{block(10, "a"), block(20, "b"), block(30, "c")}
+	tgt = []protocol.BlockInfo{block(15, "x"), block(15, "y"), block(30, "c")}
+	have, need = blockDiff(src, tgt)
+	if len(have) != 1 || string(have[0].Hash) != "c" || len(need) != 2 {
+		t.Errorf("unexpected diff: have %v, need %v", have, need)
+	}
+}
+
 // TestDeleteIgnorePerms checks, that a file gets deleted when the IgnorePerms
 // option is true and the permissions do not match between the file on disk and
 // in the db.
@@ -863,6 +973,190 @@ func TestCopyOwner(t *testing.T) {
 	}
 }
 
+func TestSyncOwnership(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("ownership not supported on Windows")
+	}
+
+	m, f := setupSendReceiveFolder()
+	defer os.Remove(m.cfg.ConfigPath())
+	f.folder.FolderConfiguration = config.NewFolderConfiguration(m.id, f.ID, f.Label, fs.FilesystemTypeFake, "/TestSyncOwnership")
+	f.folder.FolderConfiguration.SyncOwnership = true
+
+	f.fs = f.Filesystem()
+
+	// The other device has a different id for the owner
+
+	dev := m.cfg.Devices()[device1]
+	dev.OwnershipMappings = []config.OwnershipMapping{{Remote: "1234", Local: "4321"}}
+	_, err := m.cfg.SetDevice(dev)
+	must(t, err)
+
+	dir := protocol.FileInfo{
+		Name:        "foo",
+		Type:        protocol.FileInfoTypeDirectory,
+		Permissions: 0755,
+		ModifiedBy:  device1.Short(),
+		Ownership:   &protocol.Ownership{Uid: 1234, Gid: 5678},
+	}
+
+	dbUpdateChan := make(chan dbUpdateJob, 1)
+	defer close(dbUpdateChan)
+	f.handleDir(dir, dbUpdateChan, nil)
+	job := <-dbUpdateChan
+
+	info, err := f.fs.Lstat("foo")
+	must(t, err)
+	if info.Owner() != 4321 || info.Group() != 5678 {
+		t.Errorf("Expected dir owner/group to be 4321/5678, not %d/%d", info.Owner(), info.Group())
+	}
+
+	// What we record is what was applied, as a scan finds it
+
+	expected := protocol.Ownership{
+		OwnerName: osutil.UserName(4321),
+		GroupName: osutil.GroupName(5678),
+		Uid:       4321,
+		Gid:       5678,
+	}
+	if job.file.Ownership == nil || *job.file.Ownership != expected {
+		t.Errorf("Expected recorded ownership %v, got %v", expected, job.file.Ownership)
+	}
+
+	// The mapping is only for that device
+
+	dir.ModifiedBy = device2.Short()
+	f.handleDir(dir, dbUpdateChan, nil)
+	<-dbUpdateChan
+
+	info, err = f.fs.Lstat("foo")
+	must(t, err)
+	if info.Owner() != 1234 || info.Group() != 5678 {
+		t.Errorf("Expected dir owner/group to be 1234/5678, not %d/%d", info.Owner(), info.Group())
+	}
+}
+
+// nonRootFilesystem refuses to change ownership, like a filesystem does
+// for a user other than root.
+type nonRootFilesystem struct {
+	fs.Filesystem
+}
+
+func (nonRootFilesystem) Lchown(name string, uid, gid int) error {
+	return &os.PathError{Op: "lchown", Path: name, Err: os.ErrPermission}
+}
+
+func TestSyncOwnershipNonRoot(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("ownership not supported on Windows")
+	}
+
+	m, f := setupSendReceiveFolder()
+	defer os.Remove(m.cfg.ConfigPath())
+	f.folder.FolderConfiguration = config.NewFolderConfiguration(m.id, f.ID, f.Label, fs.FilesystemTypeFake, "/TestSyncOwnershipNonRoot")
+	f.folder.FolderConfiguration.SyncOwnership = true
+
+	f.fs = nonRootFilesystem{f.Filesystem()}
+
+	dir := protocol.FileInfo{
+		Name:        "foo",
+		Type:        protocol.FileInfoTypeDirectory,
+		Permissions: 0755,
+		ModifiedBy:  device1.Short(),
+		Ownership:   &protocol.Ownership{Uid: 1234, Gid: 5678},
+	}
+
+	dbUpdateChan := make(chan dbUpdateJob, 1)
+	defer close(dbUpdateChan)
+	f.handleDir(dir, dbUpdateChan, nil)
+	if len(f.pullErrors) != 0 {
+		t.Fatal("Expected the directory to be pulled, got", f.pullErrors)
+	}
+	job := <-dbUpdateChan
+
+	// What we record is the ownership the directory was created with
+
+	info, err := f.fs.Lstat("foo")
+	must(t, err)
+	expected := protocol.Ownership{
+		OwnerName: osutil.UserName(info.Owner()),
+		GroupName: osutil.GroupName(info.Group()),
+		Uid:       int32(info.Owner()),
+		Gid:       int32(info.Group()),
+	}
+	if job.file.Ownership == nil || *job.file.Ownership != expected {
+		t.Errorf("Expected recorded ownership %v, got %v", expected, job.file.Ownership)
+	}
+}
+
+func TestSyncXattrs(t *testing.T) {
+	m, f := setupSendReceiveFolder()
+	defer os.Remove(m.cfg.ConfigPath())
+	f.folder.FolderConfiguration = config.NewFolderConfiguration(m.id, f.ID, f.Label, fs.FilesystemTypeFake, "/TestSyncXattrs")
+	f.folder.FolderConfiguration.SyncXattrs = true
+
+	f.fs = f.Filesystem()
+
+	xattrs := &protocol.Xattrs{Entries: []protocol.Xattr{{Name: "user.tag", Value: []byte("red")}}}
+	expectXattrs := func(name string, exp *protocol.Xattrs) {
+		t.Helper()
+		got, err := f.fs.GetXattr(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(exp.Entries) || len(got) > 0 && (got[0].Name != exp.Entries[0].Name || !bytes.Equal(got[0].Value, exp.Entries[0].Value)) {
+			t.Errorf("Expected attributes %v on %s, got %v", exp.Entries, name, got)
+		}
+	}
+
+	// A new directory, then a change to its attributes only
+
+	dir := protocol.FileInfo{
+		Name:        "foo",
+		Type:        protocol.FileInfoTypeDirectory,
+		Permissions: 0755,
+		Xattrs:      xattrs,
+	}
+
+	dbUpdateChan := make(chan dbUpdateJob, 1)
+	defer close(dbUpdateChan)
+	f.handleDir(dir, dbUpdateChan, nil)
+	<-dbUpdateChan
+	expectXattrs("foo", xattrs)
+
+	dir.Xattrs = &protocol.Xattrs{}
+	f.handleDir(dir, dbUpdateChan, nil)
+	<-dbUpdateChan
+	expectXattrs("foo", dir.Xattrs)
+
+	// A new file, which is zero sized to avoid having to handle copies and
+	// pulls.
+
+	file := protocol.FileInfo{
+		Name:        "foo/bar",
+		Type:        protocol.FileInfoTypeFile,
+		Permissions: 0644,
+		Xattrs:      xattrs,
+	}
+
+	finisherChan := make(chan *sharedPullerState)
+	defer close(finisherChan)
+	copierChan := make(chan copyBlocksState)
+	defer close(copierChan)
+	go f.copierRoutine(copierChan, nil, finisherChan)
+	go f.finisherRoutine(finisherChan, dbUpdateChan, nil)
+	f.handleFile(file, copierChan, nil)
+	<-dbUpdateChan
+	expectXattrs("foo/bar", xattrs)
+
+	// Unknown attributes are left alone
+
+	file.Xattrs = nil
+	f.shortcutFile(file, file, dbUpdateChan)
+	<-dbUpdateChan
+	expectXattrs("foo/bar", xattrs)
+}
+
 // TestSRConflictReplaceFileByDir checks that a conflict is created when an existing file
 // is replaced with a directory and versions are conflicting
 func TestSRConflictReplaceFileByDir(t *testing.T) {
@@ -933,3 +1227,313 @@ func TestSRConflictReplaceFileByLink(t *testing.T) {
 		t.Fatal("Expected request to scan", confls[0], "got", scan)
 	}
 }
+
+// TestSRConflictPolicy checks that the conflict policy decides what happens to
+// an existing file that is replaced by a directory in conflict with it.
+func TestSRConflictPolicy(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		policy   config.ConflictPolicy
+		remoteAt time.Time
+		keep     bool
+		confls   int
+	}{
+		{config.ConflictRename, now.Add(-time.Hour), false, 1},
+		{config.ConflictKeepLocal, now.Add(time.Hour), true, 0},
+		{config.ConflictKeepRemote, now.Add(-time.Hour), false, 0},
+		{config.ConflictNewestWins, now.Add(-time.Hour), true, 0},
+		{config.ConflictNewestWins, now.Add(time.Hour), false, 0},
+		{config.ConflictExternal, now.Add(-time.Hour), false, 1}, // nothing to merge with a directory
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.policy.String(), func(t *testing.T) {
+			m, f := setupSendReceiveFolder()
+			ffs := f.Filesystem()
+			defer func() {
+				os.Remove(m.cfg.ConfigPath())
+				os.RemoveAll(ffs.URI())
+			}()
+			f.ConflictPolicy = tc.policy
+
+			name := "foo"
+
+			// create local file
+			file := createFile(t, name, ffs)
+			must(t, ffs.Chtimes(name, now, now))
+			file = createFile(t, name, ffs)
+			file.Version = protocol.Vector{}.Update(myID.Short())
+			f.updateLocalsFromScanning([]protocol.FileInfo{file})
+			local := file
+
+			// Simulate remote creating a dir with the same name
+			file.Type = protocol.FileInfoTypeDirectory
+			file.ModifiedS = tc.remoteAt.Unix()
+			rem := device1.Short()
+			file.Version = protocol.Vector{}.Update(rem)
+			file.ModifiedBy = rem
+
+			dbUpdateChan := make(chan dbUpdateJob, 1)
+			scanChan := make(chan string, 1)
+
+			f.handleDir(file, dbUpdateChan, scanChan)
+
+			if confls := existingConflicts(name, ffs); len(confls) != tc.confls {
+				t.Fatalf("Expected %d conflicts, got %d", tc.confls, len(confls))
+			}
+			info, err := ffs.Lstat(name)
+			must(t, err)
+			if tc.keep == info.IsDir() {
+				t.Fatal("Expected local file to be kept:", tc.keep)
+			}
+
+			job := <-dbUpdateChan
+			if job.file.IsDirectory() == tc.keep {
+				t.Fatalf("Unexpected db update %v", job.file)
+			}
+			if !job.file.Version.GreaterEqual(local.Version) || !job.file.Version.GreaterEqual(file.Version) {
+				t.Errorf("Version %v should have been merged from %v and %v", job.file.Version, local.Version, file.Version)
+			}
+		})
+	}
+}
+
+// TestSRConflictPolicyDelete checks that a deletion in conflict with a local
+// change only wins when the conflict policy says so.
+func TestSRConflictPolicyDelete(t *testing.T) {
+	for _, policy := range []config.ConflictPolicy{config.ConflictRename, config.ConflictKeepRemote} {
+		t.Run(policy.String(), func(t *testing.T) {
+			m, f := setupSendReceiveFolder()
+			ffs := f.Filesystem()
+			defer func() {
+				os.Remove(m.cfg.ConfigPath())
+				os.RemoveAll(ffs.URI())
+			}()
+			f.ConflictPolicy = policy
+
+			name := "foo"
+			cur := createFile(t, name, ffs)
+			cur.Version = protocol.Vector{}.Update(myID.Short())
+			f.updateLocalsFromScanning([]protocol.FileInfo{cur})
+
+			file := cur
+			file.Deleted = true
+			file.Version = protocol.Vector{}.Update(device1.Short())
+
+			dbUpdateChan := make(chan dbUpdateJob, 1)
+			scanChan := make(chan string, 1)
+
+			f.deleteFileWithCurrent(file, cur, true, dbUpdateChan, scanChan)
+
+			job := <-dbUpdateChan
+			_, err := ffs.Lstat(name)
+			if policy == config.ConflictKeepRemote {
+				if !fs.IsNotExist(err) || job.jobType != dbUpdateDeleteFile {
+					t.Fatal("Expected file to be deleted, got", err, job.jobType)
+				}
+			} else if err != nil || job.jobType != dbUpdateHandleFile {
+				t.Fatal("Expected file to be kept, got", err, job.jobType)
+			}
+			if !job.file.Version.GreaterEqual(cur.Version) || !job.file.Version.GreaterEqual(file.Version) {
+				t.Errorf("Version %v should have been merged from %v and %v", job.file.Version, cur.Version, file.Version)
+			}
+		})
+	}
+}
+
+// TestSRConflictExternal checks that the conflict command gets to merge the
+// remote version of a file into the local one.
+func TestSRConflictExternal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test command requires a shell")
+	}
+
+	m, f := setupSendReceiveFolder()
+	ffs := f.Filesystem()
+	defer func() {
+		os.Remove(m.cfg.ConfigPath())
+		os.RemoveAll(ffs.URI())
+	}()
+	f.ConflictPolicy = config.ConflictExternal
+	f.ConflictCommand = `sh -c 'cat "$1" >> "$0"' %LOCAL_PATH% %REMOTE_PATH%`
+
+	name := "foo"
+	must(t, ioutil.WriteFile(filepath.Join(ffs.URI(), name), []byte("local\n"), 0644))
+	info, err := ffs.Lstat(name)
+	must(t, err)
+	cur, err := scanner.CreateFileInfo(info, name, ffs)
+	must(t, err)
+	cur.Version = protocol.Vector{}.Update(myID.Short())
+	f.updateLocalsFromScanning([]protocol.FileInfo{cur})
+
+	tempName := fs.TempName(name)
+	must(t, ioutil.WriteFile(filepath.Join(ffs.URI(), tempName), []byte("remote\n"), 0644))
+	file := cur
+	file.Version = protocol.Vector{}.Update(device1.Short())
+	file.ModifiedBy = device1.Short()
+
+	dbUpdateChan := make(chan dbUpdateJob, 1)
+	scanChan := make(chan string, 1)
+
+	must(t, f.performFinish(file, cur, true, tempName, dbUpdateChan, scanChan))
+
+	fd, err := ffs.Open(name)
+	must(t, err)
+	bs, err := ioutil.ReadAll(fd)
+	fd.Close()
+	must(t, err)
+	if string(bs) != "local\nremote\n" {
+		t.Errorf("Unexpected merged content %q", bs)
+	}
+	if _, err := ffs.Lstat(tempName); !fs.IsNotExist(err) {
+		t.Error("Temp file should have been removed, got", err)
+	}
+	if confls := existingConflicts(name, ffs); len(confls) != 0 {
+		t.Error("Expected no conflicts, got", confls)
+	}
+	job := <-dbUpdateChan
+	if !job.file.Version.GreaterEqual(file.Version) || !job.file.Version.GreaterEqual(cur.Version) {
+		t.Errorf("Version %v should have been merged from %v and %v", job.file.Version, cur.Version, file.Version)
+	}
+	checkMergedFile(t, ffs, job.file, bs)
+}
+
+// TestSRConflictExternalTimeout checks that a conflict command that doesn't
+// finish leaves a conflict copy instead of holding up the puller.
+func TestSRConflictExternalTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test command requires sleep")
+	}
+
+	oldTimeout := conflictCommandTimeout
+	conflictCommandTimeout = 100 * time.Millisecond
+	defer func() {
+		conflictCommandTimeout = oldTimeout
+	}()
+
+	m, f := setupSendReceiveFolder()
+	ffs := f.Filesystem()
+	defer func() {
+		os.Remove(m.cfg.ConfigPath())
+		os.RemoveAll(ffs.URI())
+	}()
+	f.ConflictPolicy = config.ConflictExternal
+	f.ConflictCommand = "sleep 60"
+
+	name := "foo"
+	must(t, ioutil.WriteFile(filepath.Join(ffs.URI(), name), []byte("local\n"), 0644))
+	info, err := ffs.Lstat(name)
+	must(t, err)
+	cur, err := scanner.CreateFileInfo(info, name, ffs)
+	must(t, err)
+	cur.Version = protocol.Vector{}.Update(myID.Short())
+	f.updateLocalsFromScanning([]protocol.FileInfo{cur})
+
+	tempName := fs.TempName(name)
+	must(t, ioutil.WriteFile(filepath.Join(ffs.URI(), tempName), []byte("remote\n"), 0644))
+	file := cur
+	file.Version = protocol.Vector{}.Update(device1.Short())
+	file.ModifiedBy = device1.Short()
+
+	dbUpdateChan := make(chan dbUpdateJob, 1)
+	scanChan := make(chan string, 1)
+
+	t0 := time.Now()
+	must(t, f.performFinish(file, cur, true, tempName, dbUpdateChan, scanChan))
+	if d := time.Since(t0); d > 30*time.Second {
+		t.Errorf("Conflict command ran for %v", d)
+	}
+	if confls := existingConflicts(name, ffs); len(confls) != 1 {
+		t.Error("Expected a conflict copy, got", confls)
+	}
+}
+
+// checkMergedFile checks that the file committed after merging describes
+// the merged content on disk.
+func checkMergedFile(t *testing.T, ffs fs.Filesystem, file protocol.FileInfo, content []byte) {
+	t.Helper()
+	info, err := ffs.Lstat(file.Name)
+	must(t, err)
+	hash := sha256.Sum256(content)
+	if file.Size != int64(len(content)) || !file.ModTime().Equal(info.ModTime()) {
+		t.Errorf("Committed size %d and mtime %v, expected %d and %v", file.Size, file.ModTime(), len(content), info.ModTime())
+	}
+	if len(file.Blocks) != 1 || !bytes.Equal(file.Blocks[0].Hash, hash[:]) {
+		t.Errorf("Committed blocks %v don't match the merged content", file.Blocks)
+	}
+}
+
+// TestSRConflictMerge checks that conflicting changes to text files are
+// merged, using the archived version as common ancestor, unless they overlap
+// or there is no such version.
+func TestSRConflictMerge(t *testing.T) {
+	cases := []struct {
+		base          string // empty for no known common ancestor
+		local, remote string
+		merged        string // empty for a conflict copy
+	}{
+		{"1\n2\n3\n", "one\n2\n3\n", "1\n2\nthree\n", "one\n2\nthree\n"},
+		{"1\n2\n3\n", "one\n2\n3\n", "uno\n2\n3\n", ""},
+		{"", "one\n2\n3\n", "1\n2\nthree\n", ""},
+	}
+
+	for i, tc := range cases {
+		m, f := setupSendReceiveFolder()
+		ffs := f.Filesystem()
+		defer func() {
+			os.Remove(m.cfg.ConfigPath())
+			os.RemoveAll(ffs.URI())
+		}()
+		f.MergePatterns = []string{"*.txt"}
+		f.versioner = versioner.NewSimple(f.ID, ffs, nil)
+
+		name := filepath.Join("dir", "notes.txt")
+		root := ffs.URI()
+		must(t, ffs.MkdirAll("dir", 0755))
+		then := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+		// The common ancestor, archived when last replaced.
+		if tc.base != "" {
+			must(t, ioutil.WriteFile(filepath.Join(root, name), []byte(tc.base), 0644))
+			must(t, ffs.Chtimes(name, then, then))
+			must(t, f.versioner.Archive(name))
+		}
+
+		must(t, ioutil.WriteFile(filepath.Join(root, name), []byte(tc.local), 0644))
+		info, err := ffs.Lstat(name)
+		must(t, err)
+		cur, err := scanner.CreateFileInfo(info, name, ffs)
+		must(t, err)
+		cur.Version = protocol.Vector{}.Update(myID.Short())
+		f.updateLocalsFromScanning([]protocol.FileInfo{cur})
+
+		tempName := fs.TempName(name)
+		must(t, ioutil.WriteFile(filepath.Join(root, tempName), []byte(tc.remote), 0644))
+		file := cur
+		file.Version = protocol.Vector{}.Update(device1.Short())
+		file.ModifiedBy = device1.Short()
+
+		dbUpdateChan := make(chan dbUpdateJob, 1)
+		scanChan := make(chan string, 1)
+
+		must(t, f.performFinish(file, cur, true, tempName, dbUpdateChan, scanChan))
+
+		bs, err := ioutil.ReadFile(filepath.Join(root, name))
+		must(t, err)
+		confls := existingConflicts(name, ffs)
+		job := <-dbUpdateChan
+		if tc.merged == "" {
+			if string(bs) != tc.remote || len(confls) != 1 {
+				t.Errorf("%d: Expected remote version and a conflict copy, got %q and %v", i, bs, confls)
+			}
+			continue
+		}
+		if string(bs) != tc.merged || len(confls) != 0 {
+			t.Errorf("%d: Expected merged %q and no conflict copy, got %q and %v", i, tc.merged, bs, confls)
+		}
+		if !job.file.Version.GreaterEqual(file.Version) || !job.file.Version.GreaterEqual(cur.Version) {
+			t.Errorf("%d: Version %v should have been merged from %v and %v", i, job.file.Version, cur.Version, file.Version)
+		}
+		checkMergedFile(t, ffs, job.file, []byte(tc.merged))
+	}
+}
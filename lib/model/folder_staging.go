@@ -0,0 +1,400 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/osutil"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+// The directory, relative to the folder root, where the contents of changed
+// files are held until approved. It is an internal name to the scanner.
+const stagingDir = ".ststaging"
+
+var errNotStaging = errors.New("folder does not stage changes")
+
+// A StagedChange is an incoming change that is held back until approved,
+// compared to what we currently have.
+type StagedChange struct {
+	Name       string    `json:"name"`
+	Action     string    `json:"action"` // "added", "modified" or "deleted"
+	Type       string    `json:"type"`   // "file", "dir" or "symlink"
+	Size       int64     `json:"size"`
+	OldSize    int64     `json:"oldSize"`
+	ModifiedBy string    `json:"modifiedBy"` // short device ID
+	ModTime    time.Time `json:"modTime"`
+}
+
+// stagingArea keeps track of the needed items that are held back, and of
+// the ones that were approved to be applied by the next pull. Files are
+// pulled into the staging directory, reusing the temporary file handling
+// of the puller, and moved back to their temporary name once approved.
+// The items and approvals are kept in the database as well, so that they
+// survive a restart.
+type stagingArea struct {
+	fs       fs.Filesystem
+	kv       *db.NamespacedKV
+	items    map[string]protocol.FileInfo // held back, with the content in place
+	approved map[string]protocol.Vector   // to be applied, in this version
+	seen     map[string]struct{}          // needed in the current pull
+	mut      sync.Mutex
+}
+
+// The keys of the items and approvals in the database, followed by the name.
+const (
+	stagedItemKey     = "item/"
+	stagedApprovalKey = "approved/"
+)
+
+func newStagingArea(filesystem fs.Filesystem, fset *db.FileSet) *stagingArea {
+	s := &stagingArea{
+		fs:       filesystem,
+		kv:       fset.Namespace("staging"),
+		items:    make(map[string]protocol.FileInfo),
+		approved: make(map[string]protocol.Vector),
+		seen:     make(map[string]struct{}),
+		mut:      sync.NewMutex(),
+	}
+
+	s.kv.Each(stagedItemKey, func(key string, val []byte) bool {
+		var file protocol.FileInfo
+		if err := file.Unmarshal(val); err != nil {
+			l.Debugln("loading staged item:", err)
+			return true
+		}
+		s.items[strings.TrimPrefix(key, stagedItemKey)] = file
+		return true
+	})
+	s.kv.Each(stagedApprovalKey, func(key string, val []byte) bool {
+		var version protocol.Vector
+		if err := version.Unmarshal(val); err != nil {
+			l.Debugln("loading staging approval:", err)
+			return true
+		}
+		s.approved[strings.TrimPrefix(key, stagedApprovalKey)] = version
+		return true
+	})
+
+	return s
+}
+
+func (s *stagingArea) putItem(file protocol.FileInfo) {
+	s.items[file.Name] = file
+	bs, err := file.Marshal()
+	if err != nil {
+		l.Debugln("storing staged item:", err)
+		return
+	}
+	s.kv.PutBytes(stagedItemKey+file.Name, bs)
+}
+
+func (s *stagingArea) deleteItem(name string) {
+	if _, ok := s.items[name]; !ok {
+		return
+	}
+	delete(s.items, name)
+	s.kv.Delete(stagedItemKey + name)
+}
+
+func (s *stagingArea) putApproval(name string, version protocol.Vector) {
+	s.approved[name] = version
+	bs, err := version.Marshal()
+	if err != nil {
+		l.Debugln("storing staging approval:", err)
+		return
+	}
+	s.kv.PutBytes(stagedApprovalKey+name, bs)
+}
+
+func (s *stagingArea) deleteApproval(name string) {
+	delete(s.approved, name)
+	s.kv.Delete(stagedApprovalKey + name)
+}
+
+// holds returns whether the given version of the item is to be held back,
+// i.e. it is not approved. It is safe to call on a nil staging area, which
+// holds nothing.
+func (s *stagingArea) holds(file protocol.FileInfo) bool {
+	if s == nil {
+		return false
+	}
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	v, ok := s.approved[file.Name]
+	return !ok || !v.Equal(file.Version)
+}
+
+// tempName returns where the file is pulled to while held back, creating
+// the directories leading to it.
+func (s *stagingArea) tempName(name string) (string, error) {
+	tempName := filepath.Join(stagingDir, name)
+	if err := s.fs.MkdirAll(filepath.Dir(tempName), 0700); err != nil {
+		return "", errors.Wrap(err, "creating staging directory")
+	}
+	return tempName, nil
+}
+
+// begin is called before going through the needed items, which are then
+// passed to hold.
+func (s *stagingArea) begin() {
+	s.mut.Lock()
+	s.seen = make(map[string]struct{})
+	s.mut.Unlock()
+}
+
+// hold returns whether the needed item is held back until approved, in which
+// case it isn't to be handled by the puller. Changed files that aren't in
+// the staging area yet are not held, so that the puller gets their content
+// into it. An error means the content of an approved file couldn't be put
+// in place for the puller, and it stays held.
+func (s *stagingArea) hold(file, cur protocol.FileInfo, hasCur bool) (bool, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.seen[file.Name] = struct{}{}
+
+	if v, ok := s.approved[file.Name]; ok && v.Equal(file.Version) {
+		// The content we already have is picked up by the puller as that
+		// of an interrupted download. If it is gone, the file is pulled
+		// again.
+		if staged, ok := s.items[file.Name]; ok && staged.Type == protocol.FileInfoTypeFile && !staged.IsDeleted() {
+			if err := s.restore(file.Name); err != nil && !fs.IsNotExist(err) {
+				return true, err
+			}
+		}
+		s.deleteItem(file.Name)
+		return false, nil
+	}
+
+	if staged, ok := s.items[file.Name]; ok && staged.Version.Equal(file.Version) {
+		return true, nil
+	}
+	s.deleteItem(file.Name)
+
+	if file.Type == protocol.FileInfoTypeFile && !file.IsDeleted() {
+		if _, need := blockDiff(cur.Blocks, file.Blocks); !hasCur || len(need) > 0 {
+			return false, nil
+		}
+	}
+
+	s.putItem(file)
+	return true, nil
+}
+
+// restore moves the staged content of the named file to its temporary name,
+// next to where it goes, creating the directories leading to it.
+func (s *stagingArea) restore(name string) error {
+	if _, err := s.fs.Lstat(filepath.Join(stagingDir, name)); err != nil {
+		return err
+	}
+	tempName := fs.TempName(name)
+	if err := s.fs.MkdirAll(filepath.Dir(tempName), 0755); err != nil {
+		return err
+	}
+	return osutil.RenameOrCopy(s.fs, s.fs, filepath.Join(stagingDir, name), tempName)
+}
+
+// stage records that the content of the file was pulled into the staging
+// area.
+func (s *stagingArea) stage(file protocol.FileInfo) {
+	s.mut.Lock()
+	s.putItem(file)
+	s.mut.Unlock()
+}
+
+// prune forgets about the items that are no longer needed, and removes
+// their content from the staging directory. It is called after all needed
+// items went through hold.
+func (s *stagingArea) prune() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	for name := range s.items {
+		if _, ok := s.seen[name]; !ok {
+			s.deleteItem(name)
+		}
+	}
+	for name := range s.approved {
+		if _, ok := s.seen[name]; !ok {
+			s.deleteApproval(name)
+		}
+	}
+
+	var dirs []string
+	s.fs.Walk(stagingDir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			dirs = append(dirs, path)
+			return nil
+		}
+		name := strings.TrimPrefix(path, stagingDir+string(fs.PathSeparator))
+		if _, ok := s.seen[name]; !ok {
+			l.Debugln("removing stale staged file", name)
+			s.fs.Remove(path)
+		}
+		return nil
+	})
+	// Directories that are left empty go, starting with the innermost.
+	for i := len(dirs) - 1; i >= 0; i-- {
+		s.fs.Remove(dirs[i])
+	}
+}
+
+// approve marks the held items in the given paths, or all of them if none
+// are given, to be applied by the next pull. New directories leading to
+// the paths are approved with them.
+func (s *stagingArea) approve(paths []string) {
+	for i := range paths {
+		paths[i] = filepath.Clean(paths[i])
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	for name, file := range s.items {
+		if len(paths) > 0 && !inApprovedPaths(file, paths) {
+			continue
+		}
+		l.Debugln("approving staged", name)
+		s.putApproval(name, file.Version)
+	}
+}
+
+func inApprovedPaths(file protocol.FileInfo, paths []string) bool {
+	for _, path := range paths {
+		if file.Name == path || fs.IsParent(file.Name, path) {
+			return true
+		}
+		if file.IsDirectory() && !file.IsDeleted() && fs.IsParent(path, file.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// holdStaged returns whether the needed item is held back until approved.
+func (f *sendReceiveFolder) holdStaged(file protocol.FileInfo) bool {
+	cur, hasCur := f.fset.Get(protocol.LocalDeviceID, file.Name)
+	held, err := f.staging.hold(file, cur, hasCur)
+	if err != nil {
+		f.newPullError(file.Name, errors.Wrap(err, "restoring staged file"))
+	} else if held {
+		f.resetPullError(file.Name)
+	}
+	return held
+}
+
+// StagedChanges returns the changes held back until approved, sorted by
+// name.
+func (f *sendReceiveFolder) StagedChanges() ([]StagedChange, error) {
+	if f.staging == nil {
+		return nil, errNotStaging
+	}
+
+	f.staging.mut.Lock()
+	files := make([]protocol.FileInfo, 0, len(f.staging.items))
+	for _, file := range f.staging.items {
+		files = append(files, file)
+	}
+	f.staging.mut.Unlock()
+
+	changes := make([]StagedChange, 0, len(files))
+	for _, file := range files {
+		change := StagedChange{
+			Name:       file.Name,
+			Action:     "modified",
+			Type:       "file",
+			Size:       file.FileSize(),
+			ModifiedBy: file.ModifiedBy.String(),
+			ModTime:    file.ModTime(),
+		}
+		switch {
+		case file.IsDirectory():
+			change.Type = "dir"
+		case file.IsSymlink():
+			change.Type = "symlink"
+		}
+		cur, hasCur := f.fset.Get(protocol.LocalDeviceID, file.Name)
+		if hasCur && !cur.IsDeleted() {
+			change.OldSize = cur.FileSize()
+		}
+		switch {
+		case file.IsDeleted():
+			change.Action = "deleted"
+			change.Size = 0
+		case !hasCur || cur.IsDeleted():
+			change.Action = "added"
+		}
+		changes = append(changes, change)
+	}
+
+	sort.Slice(changes, func(a, b int) bool { return changes[a].Name < changes[b].Name })
+	return changes, nil
+}
+
+// ApproveStaged lets the held back changes in the given paths, or all of
+// them if none are given, through to the folder.
+func (f *sendReceiveFolder) ApproveStaged(paths []string) error {
+	if f.staging == nil {
+		return errNotStaging
+	}
+	f.staging.approve(paths)
+	f.SchedulePull()
+	return nil
+}
+
+// stagingFolder is a folder that can hold incoming changes until approved.
+type stagingFolder interface {
+	StagedChanges() ([]StagedChange, error)
+	ApproveStaged(paths []string) error
+}
+
+func (m *model) stagingFolder(folder string) (stagingFolder, error) {
+	m.fmut.RLock()
+	defer m.fmut.RUnlock()
+	if err := m.checkFolderRunningLocked(folder); err != nil {
+		return nil, err
+	}
+	sf, ok := m.folderRunners[folder].(stagingFolder)
+	if !ok {
+		return nil, errNotStaging
+	}
+	return sf, nil
+}
+
+// StagedChanges returns the incoming changes to the folder that are held
+// back until approved.
+func (m *model) StagedChanges(folder string) ([]StagedChange, error) {
+	sf, err := m.stagingFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+	return sf.StagedChanges()
+}
+
+// ApproveStaged applies the held back changes to the folder that are in the
+// given paths, either files or directories, or all of them if none are
+// given.
+func (m *model) ApproveStaged(folder string, paths []string) error {
+	sf, err := m.stagingFolder(folder)
+	if err != nil {
+		return err
+	}
+	return sf.ApproveStaged(paths)
+}
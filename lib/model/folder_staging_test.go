@@ -0,0 +1,260 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/ignore"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/scanner"
+)
+
+func setupStagingFolder(t *testing.T) (*model, *sendReceiveFolder) {
+	t.Helper()
+	m, f := setupSendReceiveFolder()
+	// The copier finds blocks in the configured folders
+	_, err := m.cfg.SetFolder(f.FolderConfiguration)
+	must(t, err)
+	f.ignores = ignore.New(f.fs)
+	f.staging = newStagingArea(f.fs, f.fset)
+	return m, f
+}
+
+func TestStagedChanges(t *testing.T) {
+	m, f := setupStagingFolder(t)
+	ffs := f.Filesystem()
+	defer func() {
+		os.Remove(m.cfg.ConfigPath())
+		os.RemoveAll(ffs.URI())
+	}()
+
+	// A local file deleted remotely, and a remotely added directory
+
+	del := createFile(t, "del", ffs)
+	del.Version = protocol.Vector{}.Update(myID.Short())
+	f.updateLocalsFromScanning([]protocol.FileInfo{del})
+
+	rem := device1.Short()
+	del.Deleted = true
+	del.Version = del.Version.Update(rem)
+	del.ModifiedBy = rem
+	dir := protocol.FileInfo{
+		Name:        "dir",
+		Type:        protocol.FileInfoTypeDirectory,
+		Permissions: 0755,
+		Version:     protocol.Vector{}.Update(rem),
+		ModifiedBy:  rem,
+	}
+	f.fset.Update(device1, []protocol.FileInfo{del, dir})
+
+	dbUpdateChan := make(chan dbUpdateJob, 10)
+	scanChan := make(chan string, 10)
+
+	// Both are held back
+
+	changed, fileDeletions, dirDeletions, err := f.processNeeded(dbUpdateChan, nil, scanChan)
+	must(t, err)
+	if changed != 0 || len(fileDeletions) != 0 || len(dirDeletions) != 0 || len(dbUpdateChan) != 0 {
+		t.Fatalf("Expected nothing to change, got %d changed, %d deletions and %d updates", changed, len(fileDeletions), len(dbUpdateChan))
+	}
+	if _, err := ffs.Lstat("dir"); !fs.IsNotExist(err) {
+		t.Error("Expected staged directory not to exist, got", err)
+	}
+	if _, err := ffs.Lstat("del"); err != nil {
+		t.Error("Expected staged deletion not to happen, got", err)
+	}
+
+	changes, err := f.StagedChanges()
+	must(t, err)
+	expected := []StagedChange{
+		{Name: "del", Action: "deleted", Type: "file"},
+		{Name: "dir", Action: "added", Type: "dir"},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %d staged changes, got %+v", len(expected), changes)
+	}
+	for i, c := range changes {
+		if c.Name != expected[i].Name || c.Action != expected[i].Action || c.Type != expected[i].Type || c.ModifiedBy != rem.String() {
+			t.Errorf("Expected staged change %+v, got %+v", expected[i], c)
+		}
+	}
+
+	// Only the approved directory gets created
+
+	must(t, f.ApproveStaged([]string{"dir"}))
+
+	changed, _, _, err = f.processNeeded(dbUpdateChan, nil, scanChan)
+	must(t, err)
+	if changed != 1 {
+		t.Errorf("Expected one change, got %d", changed)
+	}
+	if info, err := ffs.Lstat("dir"); err != nil || !info.IsDir() {
+		t.Error("Expected approved directory to be created, got", err)
+	}
+	if job := <-dbUpdateChan; job.file.Name != "dir" {
+		t.Error("Unexpected db update for", job.file.Name)
+	}
+
+	changes, err = f.StagedChanges()
+	must(t, err)
+	if len(changes) != 1 || changes[0].Name != "del" {
+		t.Errorf("Expected only the deletion to be staged, got %+v", changes)
+	}
+}
+
+func TestStagedFile(t *testing.T) {
+	m, f := setupStagingFolder(t)
+	ffs := f.Filesystem()
+	defer func() {
+		os.Remove(m.cfg.ConfigPath())
+		os.RemoveAll(ffs.URI())
+	}()
+
+	// A local file with the blocks to copy the remotely added one from
+
+	data := []byte("staged content\n")
+	must(t, ioutil.WriteFile(filepath.Join(ffs.URI(), "local"), data, 0644))
+	blocks, err := scanner.Blocks(context.TODO(), bytes.NewReader(data), protocol.MinBlockSize, -1, nil, false)
+	must(t, err)
+	local := protocol.FileInfo{
+		Name:        "local",
+		Type:        protocol.FileInfoTypeFile,
+		Size:        int64(len(data)),
+		Permissions: 0644,
+		Blocks:      blocks,
+		Version:     protocol.Vector{}.Update(myID.Short()),
+	}
+	f.updateLocalsFromScanning([]protocol.FileInfo{local})
+
+	file := local
+	file.Name = "new"
+	file.Version = protocol.Vector{}.Update(device1.Short())
+	f.fset.Update(device1, []protocol.FileInfo{file})
+
+	dbUpdateChan := make(chan dbUpdateJob, 1)
+	scanChan := make(chan string, 1)
+
+	pull := func() {
+		t.Helper()
+		copyChan := make(chan copyBlocksState)
+		defer close(copyChan)
+		finisherChan := make(chan *sharedPullerState, 1)
+		go f.copierRoutine(copyChan, nil, finisherChan)
+		f.handleFile(file, copyChan, dbUpdateChan)
+
+		finished := make(chan *sharedPullerState, 1)
+		finished <- <-finisherChan
+		close(finished)
+		f.finisherRoutine(finished, dbUpdateChan, scanChan)
+	}
+
+	// The file isn't held until its content is in the staging area
+
+	if f.holdStaged(file) {
+		t.Fatal("Expected file to be pulled before it's held")
+	}
+	pull()
+
+	if _, err := ffs.Lstat("new"); !fs.IsNotExist(err) {
+		t.Error("Expected staged file not to exist, got", err)
+	}
+	if bs, err := ioutil.ReadFile(filepath.Join(ffs.URI(), stagingDir, "new")); err != nil || !bytes.Equal(bs, data) {
+		t.Errorf("Expected staged content %q, got %q (%v)", data, bs, err)
+	}
+	if len(dbUpdateChan) != 0 {
+		t.Error("Unexpected db update for staged file")
+	}
+	if !f.holdStaged(file) {
+		t.Error("Expected staged file to be held")
+	}
+
+	// Once approved the staged content is used
+
+	must(t, f.ApproveStaged(nil))
+	if f.holdStaged(file) {
+		t.Fatal("Expected approved file not to be held")
+	}
+	if _, err := ffs.Lstat(fs.TempName("new")); err != nil {
+		t.Fatal("Expected staged content to be moved to the temp file, got", err)
+	}
+	pull()
+
+	if bs, err := ioutil.ReadFile(filepath.Join(ffs.URI(), "new")); err != nil || !bytes.Equal(bs, data) {
+		t.Errorf("Expected content %q, got %q (%v)", data, bs, err)
+	}
+	if job := <-dbUpdateChan; job.file.Name != "new" {
+		t.Error("Unexpected db update for", job.file.Name)
+	}
+
+	// Nothing is needed anymore, so the staging area is cleaned up
+
+	f.updateLocalsFromPulling([]protocol.FileInfo{file})
+	f.staging.begin()
+	f.staging.prune()
+	if _, err := ffs.Lstat(stagingDir); !fs.IsNotExist(err) {
+		t.Error("Expected staging directory to be removed, got", err)
+	}
+}
+
+func TestStagingPersistence(t *testing.T) {
+	m, f := setupStagingFolder(t)
+	ffs := f.Filesystem()
+	defer func() {
+		os.Remove(m.cfg.ConfigPath())
+		os.RemoveAll(ffs.URI())
+	}()
+
+	// A file in a new directory, with its content staged and approved
+
+	name := filepath.Join("dir", "new")
+	file := protocol.FileInfo{
+		Name:    name,
+		Type:    protocol.FileInfoTypeFile,
+		Size:    4,
+		Version: protocol.Vector{}.Update(device1.Short()),
+	}
+	tempName, err := f.staging.tempName(name)
+	must(t, err)
+	must(t, ioutil.WriteFile(filepath.Join(ffs.URI(), tempName), []byte("data"), 0644))
+	f.staging.stage(file)
+	must(t, f.ApproveStaged(nil))
+
+	// Both are still known after a restart
+
+	f.staging = newStagingArea(f.fs, f.fset)
+	changes, err := f.StagedChanges()
+	must(t, err)
+	if len(changes) != 1 || changes[0].Name != name {
+		t.Fatalf("Expected %s to be staged, got %+v", name, changes)
+	}
+	if !f.staging.approved[name].Equal(file.Version) {
+		t.Fatalf("Expected %s to be approved, got %v", name, f.staging.approved)
+	}
+
+	// The directory leading to the file is created to restore its content
+
+	if f.holdStaged(file) {
+		t.Fatal("Expected approved file not to be held")
+	}
+	if bs, err := ioutil.ReadFile(filepath.Join(ffs.URI(), fs.TempName(name))); err != nil || string(bs) != "data" {
+		t.Errorf("Expected staged content to be moved to the temp file, got %q (%v)", bs, err)
+	}
+	if _, ok := f.staging.items[name]; ok {
+		t.Error("Expected restored item to be forgotten")
+	}
+	f.staging = newStagingArea(f.fs, f.fset)
+	if _, ok := f.staging.items[name]; ok {
+		t.Error("Expected restored item to be forgotten after a restart")
+	}
+}
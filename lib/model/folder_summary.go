@@ -93,7 +93,7 @@ func (c *folderSummaryService) Summary(folder string) (map[string]interface{}, e
 	need := c.model.NeedSize(folder)
 	res["needFiles"], res["needDirectories"], res["needSymlinks"], res["needDeletes"], res["needBytes"], res["needTotalItems"] = need.Files, need.Directories, need.Symlinks, need.Deleted, need.Bytes, need.TotalItems()
 
-	if c.cfg.Folders()[folder].Type == config.FolderTypeReceiveOnly {
+	if typ := c.cfg.Folders()[folder].Type; typ == config.FolderTypeReceiveOnly || typ == config.FolderTypeReceiveEncrypted {
 		// Add statistics for things that have changed locally in a receive
 		// only folder.
 		ro := c.model.ReceiveOnlyChangedSize(folder)
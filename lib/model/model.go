@@ -50,6 +50,7 @@ type service interface {
 	DelayScan(d time.Duration)
 	SchedulePull()                                    // something relevant changed, we should try a pull
 	Jobs(page, perpage int) ([]string, []string, int) // In progress, Queued, skipped
+	QueueSize() (int, int)                            // In progress, Queued
 	Scan(subs []string) error
 	Serve()
 	Stop()
@@ -91,9 +92,14 @@ type Model interface {
 
 	GetFolderVersions(folder string) (map[string][]versioner.FileVersion, error)
 	RestoreFolderVersions(folder string, versions map[string]time.Time) (map[string]string, error)
+	FolderConflicts(folder string) ([]FolderConflict, error)
+	ResolveConflicts(folder string, chosen map[string]string) (map[string]string, error)
+	StagedChanges(folder string) ([]StagedChange, error)
+	ApproveStaged(folder string, paths []string) error
 
 	LocalChangedFiles(folder string, page, perpage int) []db.FileInfoTruncated
 	NeedFolderFiles(folder string, page, perpage int) ([]db.FileInfoTruncated, []db.FileInfoTruncated, []db.FileInfoTruncated)
+	PullerQueueSize(folder string) (int, int)
 	RemoteNeedFolderFiles(device protocol.DeviceID, folder string, page, perpage int) ([]db.FileInfoTruncated, error)
 	CurrentFolderFile(folder string, file string) (protocol.FileInfo, bool)
 	CurrentGlobalFile(folder string, file string) (protocol.FileInfo, bool)
@@ -148,6 +154,8 @@ type model struct {
 	helloMessages       map[protocol.DeviceID]protocol.HelloResult
 	deviceDownloads     map[protocol.DeviceID]*deviceDownloadState
 	remotePausedFolders map[protocol.DeviceID][]string // deviceID -> folders
+	remoteChunking      map[protocol.DeviceID][]string // deviceID -> folders with content defined chunking, kept after disconnecting
+	encryptionMismatch  map[protocol.DeviceID][]string // deviceID -> folders where we disagree about encryption
 
 	foldersRunning int32 // for testing only
 }
@@ -168,6 +176,7 @@ var (
 	errNetworkNotAllowed = errors.New("network not allowed")
 	// errors about why a connection is closed
 	errIgnoredFolderRemoved = errors.New("folder no longer ignored")
+	errEncryptionChanged    = errors.New("folder encryption changed")
 	errReplacingConnection  = errors.New("replacing connection")
 	errStopped              = errors.New("Syncthing is being stopped")
 )
@@ -205,6 +214,8 @@ func NewModel(cfg config.Wrapper, id protocol.DeviceID, clientName, clientVersio
 		helloMessages:       make(map[protocol.DeviceID]protocol.HelloResult),
 		deviceDownloads:     make(map[protocol.DeviceID]*deviceDownloadState),
 		remotePausedFolders: make(map[protocol.DeviceID][]string),
+		remoteChunking:      make(map[protocol.DeviceID][]string),
+		encryptionMismatch:  make(map[protocol.DeviceID][]string),
 		fmut:                sync.NewRWMutex(),
 		pmut:                sync.NewRWMutex(),
 	}
@@ -303,6 +314,11 @@ func (m *model) startFolderLocked(cfg config.FolderConfiguration) {
 		m.folderRunnerTokens[folder] = append(m.folderRunnerTokens[folder], token)
 	}
 
+	if cfg.MountPath != "" {
+		token := m.Add(util.AsService(newFolderMount(m, fset, cfg).serve))
+		m.folderRunnerTokens[folder] = append(m.folderRunnerTokens[folder], token)
+	}
+
 	ffs := fset.MtimeFS()
 
 	// These are our metadata files, and they should always be hidden.
@@ -376,7 +392,7 @@ func (m *model) addFolderLocked(cfg config.FolderConfiguration, fset *db.FileSet
 	m.folderCfgs[cfg.ID] = cfg
 	m.folderFiles[cfg.ID] = fset
 
-	ignores := ignore.New(cfg.Filesystem(), ignore.WithCache(m.cacheIgnoredFiles))
+	ignores := ignore.New(cfg.Filesystem(), ignore.WithCache(m.cacheIgnoredFiles), ignore.WithSelection(cfg.SelectedPaths))
 	if err := ignores.Load(".stignore"); err != nil && !fs.IsNotExist(err) {
 		l.Warnln("Loading ignores:", err)
 	}
@@ -901,7 +917,7 @@ func (m *model) LocalChangedFiles(folder string, page, perpage int) []db.FileInf
 	if !ok {
 		return nil
 	}
-	if fcfg.Type != config.FolderTypeReceiveOnly {
+	if fcfg.Type != config.FolderTypeReceiveOnly && fcfg.Type != config.FolderTypeReceiveEncrypted {
 		return nil
 	}
 	if rf.ReceiveOnlyChangedSize().TotalItems() == 0 {
@@ -986,6 +1002,9 @@ func (m *model) handleIndex(deviceID protocol.DeviceID, folder string, fs []prot
 	} else if cfg.Paused {
 		l.Debugf("%v for paused folder (ID %q) sent from device %q.", op, folder, deviceID)
 		return
+	} else if m.encryptionMismatched(deviceID, folder) {
+		l.Debugf("%v for folder (ID %q) sent from device %q, which disagrees about encryption.", op, folder, deviceID)
+		return
 	}
 
 	m.fmut.RLock()
@@ -1066,9 +1085,12 @@ func (m *model) ClusterConfig(deviceID protocol.DeviceID, cm protocol.ClusterCon
 
 	m.fmut.Lock()
 	defer m.fmut.Unlock()
-	var paused []string
+	var paused, chunking, mismatched []string
 	for _, folder := range cm.Folders {
 		cfg, ok := m.cfg.Folder(folder.ID)
+		if ok && folder.ContentDefinedChunking {
+			chunking = append(chunking, folder.ID)
+		}
 		if !ok || !cfg.SharedWith(deviceID) {
 			if deviceCfg.IgnoredFolder(folder.ID) {
 				l.Infof("Ignoring folder %s from device %s since we are configured to", folder.Description(), deviceID)
@@ -1084,6 +1106,18 @@ func (m *model) ClusterConfig(deviceID protocol.DeviceID, cm protocol.ClusterCon
 			l.Infof("Unexpected folder %s sent from device %q; ensure that the folder exists and that this device is selected under \"Share With\" in the folder configuration.", folder.Description(), deviceID)
 			continue
 		}
+		if folder.Encrypted != encryptedWith(cfg, deviceID) {
+			// Either we'd send them our data in plain text while they
+			// expect it to be encrypted, or the other way around. Don't
+			// exchange anything for this folder until that's fixed.
+			mismatched = append(mismatched, folder.ID)
+			if folder.Encrypted {
+				l.Warnf("Not syncing folder %s with device %s, which treats it as encrypted while we don't", folder.Description(), deviceID)
+			} else {
+				l.Warnf("Not syncing folder %s with device %s, which doesn't treat it as encrypted while we do", folder.Description(), deviceID)
+			}
+			continue
+		}
 		if folder.Paused {
 			paused = append(paused, folder.ID)
 			continue
@@ -1189,6 +1223,8 @@ func (m *model) ClusterConfig(deviceID protocol.DeviceID, cm protocol.ClusterCon
 
 	m.pmut.Lock()
 	m.remotePausedFolders[deviceID] = paused
+	m.remoteChunking[deviceID] = chunking
+	m.encryptionMismatch[deviceID] = mismatched
 	m.pmut.Unlock()
 
 	// This breaks if we send multiple CM messages during the same connection.
@@ -1392,12 +1428,17 @@ func (m *model) introduceDevice(device protocol.Device, introducerCfg config.Dev
 
 	l.Infof("Adding device %v to config (vouched for by introducer %v)", device.ID, introducerCfg.DeviceID)
 	newDeviceCfg := config.DeviceConfiguration{
-		DeviceID:     device.ID,
-		Name:         device.Name,
-		Compression:  introducerCfg.Compression,
-		Addresses:    addresses,
-		CertName:     device.CertName,
-		IntroducedBy: introducerCfg.DeviceID,
+		DeviceID:             device.ID,
+		Name:                 device.Name,
+		Compression:          introducerCfg.Compression,
+		CompressionAlgorithm: device.CompressionAlgorithm,
+		Addresses:            addresses,
+		CertName:             device.CertName,
+		IntroducedBy:         introducerCfg.DeviceID,
+	}
+	if newDeviceCfg.CompressionAlgorithm == protocol.MessageCompressionNone {
+		// The introducer is too old to tell us
+		newDeviceCfg.CompressionAlgorithm = introducerCfg.CompressionAlgorithm
 	}
 
 	// The introducers' introducers are also our introducers.
@@ -1426,6 +1467,7 @@ func (m *model) Closed(conn protocol.Connection, err error) {
 	delete(m.helloMessages, device)
 	delete(m.deviceDownloads, device)
 	delete(m.remotePausedFolders, device)
+	delete(m.encryptionMismatch, device)
 	closed := m.closed[device]
 	delete(m.closed, device)
 
@@ -1462,6 +1504,26 @@ func (m *model) closeConn(dev protocol.DeviceID, err error) config.Waiter {
 	return m.closeConns([]protocol.DeviceID{dev}, err)
 }
 
+// encryptionMismatched returns whether the device disagrees with us about
+// whether the folder is encrypted.
+func (m *model) encryptionMismatched(device protocol.DeviceID, folder string) bool {
+	m.pmut.RLock()
+	defer m.pmut.RUnlock()
+	for _, mismatched := range m.encryptionMismatch[device] {
+		if mismatched == folder {
+			return true
+		}
+	}
+	return false
+}
+
+// encryptedWith returns whether the folder is exchanged with the device in
+// encrypted form, either because we encrypt it for them or because we only
+// ever store it encrypted.
+func encryptedWith(cfg config.FolderConfiguration, device protocol.DeviceID) bool {
+	return cfg.Type == config.FolderTypeReceiveEncrypted || cfg.EncryptionPassword(device) != ""
+}
+
 type channelWaiter struct {
 	chans []chan struct{}
 }
@@ -1527,6 +1589,10 @@ func (m *model) Request(deviceID protocol.DeviceID, folder, name string, size in
 		l.Debugf("Request from %s for file %s in paused folder %q", deviceID, name, folder)
 		return nil, protocol.ErrGeneric
 	}
+	if m.encryptionMismatched(deviceID, folder) {
+		l.Debugf("Request from %s for file %s in folder %q, which it disagrees about encryption of", deviceID, name, folder)
+		return nil, protocol.ErrGeneric
+	}
 
 	// Make sure the path is valid and in canonical form
 	if name, err = fs.Canonicalize(name); err != nil {
@@ -1636,7 +1702,7 @@ func (m *model) recheckFile(deviceID protocol.DeviceID, folderFs fs.Filesystem,
 		return
 	}
 
-	blockIndex := int(offset / int64(cf.BlockSize()))
+	blockIndex := cf.BlockIndex(offset)
 	if blockIndex >= len(cf.Blocks) {
 		l.Debugf("%v recheckFile: %s: %q / %q i=%d: block index too far", m, deviceID, folder, name, blockIndex)
 		return
@@ -1795,13 +1861,17 @@ func (m *model) OnHello(remoteID protocol.DeviceID, addr net.Addr, hello protoco
 // GetHello is called when we are about to connect to some remote device.
 func (m *model) GetHello(id protocol.DeviceID) protocol.HelloIntf {
 	name := ""
-	if _, ok := m.cfg.Device(id); ok {
+	numConns := 0
+	if cfg, ok := m.cfg.Device(id); ok {
 		name = m.cfg.MyName()
+		numConns = cfg.NumConnections
 	}
 	return &protocol.Hello{
-		DeviceName:    name,
-		ClientName:    m.clientName,
-		ClientVersion: m.clientVersion,
+		DeviceName:            name,
+		ClientName:            m.clientName,
+		ClientVersion:         m.clientVersion,
+		CompressionAlgorithms: protocol.CompressionAlgorithms(),
+		NumConnections:        int32(numConns),
 	}
 }
 
@@ -2055,7 +2125,7 @@ func (s *indexSender) sendIndexTo() error {
 	return err
 }
 
-func (m *model) requestGlobal(deviceID protocol.DeviceID, folder, name string, offset int64, size int, hash []byte, weakHash uint32, fromTemporary bool) ([]byte, error) {
+func (m *model) requestGlobal(deviceID protocol.DeviceID, folder, name string, blockNo int, offset int64, size int, hash []byte, weakHash uint32, fromTemporary bool) ([]byte, error) {
 	m.pmut.RLock()
 	nc, ok := m.conn[deviceID]
 	m.pmut.RUnlock()
@@ -2064,9 +2134,9 @@ func (m *model) requestGlobal(deviceID protocol.DeviceID, folder, name string, o
 		return nil, fmt.Errorf("requestGlobal: no such device: %s", deviceID)
 	}
 
-	l.Debugf("%v REQ(out): %s: %q / %q o=%d s=%d h=%x wh=%x ft=%t", m, deviceID, folder, name, offset, size, hash, weakHash, fromTemporary)
+	l.Debugf("%v REQ(out): %s: %q / %q b=%d o=%d s=%d h=%x wh=%x ft=%t", m, deviceID, folder, name, blockNo, offset, size, hash, weakHash, fromTemporary)
 
-	return nc.Request(folder, name, offset, size, hash, weakHash, fromTemporary)
+	return nc.Request(folder, name, blockNo, offset, size, hash, weakHash, fromTemporary)
 }
 
 func (m *model) ScanFolders() map[string]error {
@@ -2125,6 +2195,35 @@ func (m *model) DelayScan(folder string, next time.Duration) {
 	runner.DelayScan(next)
 }
 
+// useContentDefinedChunking returns whether new and changed files in the
+// folder should be cut into blocks by content. It must be enabled for the
+// folder here and, as far as we know, on all other devices sharing it, as
+// older versions and devices not asking for it expect fixed size blocks.
+func (m *model) useContentDefinedChunking(cfg config.FolderConfiguration) bool {
+	if !cfg.ContentDefinedChunking {
+		return false
+	}
+
+	m.pmut.RLock()
+	defer m.pmut.RUnlock()
+	for _, device := range cfg.DeviceIDs() {
+		if device == m.id {
+			continue
+		}
+		found := false
+		for _, folder := range m.remoteChunking[device] {
+			if folder == cfg.ID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
 // numHashers returns the number of hasher routines to use for a given folder,
 // taking into account configuration and available CPU cores.
 func (m *model) numHashers(folder string) int {
@@ -2168,13 +2267,15 @@ func (m *model) generateClusterConfig(device protocol.DeviceID) protocol.Cluster
 		}
 
 		protocolFolder := protocol.Folder{
-			ID:                 folderCfg.ID,
-			Label:              folderCfg.Label,
-			ReadOnly:           folderCfg.Type == config.FolderTypeSendOnly,
-			IgnorePermissions:  folderCfg.IgnorePerms,
-			IgnoreDelete:       folderCfg.IgnoreDelete,
-			DisableTempIndexes: folderCfg.DisableTempIndexes,
-			Paused:             folderCfg.Paused,
+			ID:                     folderCfg.ID,
+			Label:                  folderCfg.Label,
+			ReadOnly:               folderCfg.Type == config.FolderTypeSendOnly,
+			IgnorePermissions:      folderCfg.IgnorePerms,
+			IgnoreDelete:           folderCfg.IgnoreDelete,
+			DisableTempIndexes:     folderCfg.DisableTempIndexes,
+			Paused:                 folderCfg.Paused,
+			ContentDefinedChunking: folderCfg.ContentDefinedChunking,
+			Encrypted:              encryptedWith(folderCfg, device),
 		}
 
 		var fs *db.FileSet
@@ -2186,12 +2287,13 @@ func (m *model) generateClusterConfig(device protocol.DeviceID) protocol.Cluster
 			deviceCfg, _ := m.cfg.Device(device.DeviceID)
 
 			protocolDevice := protocol.Device{
-				ID:          deviceCfg.DeviceID,
-				Name:        deviceCfg.Name,
-				Addresses:   deviceCfg.Addresses,
-				Compression: deviceCfg.Compression,
-				CertName:    deviceCfg.CertName,
-				Introducer:  deviceCfg.Introducer,
+				ID:                   deviceCfg.DeviceID,
+				Name:                 deviceCfg.Name,
+				Addresses:            deviceCfg.Addresses,
+				Compression:          deviceCfg.Compression,
+				CompressionAlgorithm: deviceCfg.CompressionAlgorithm,
+				CertName:             deviceCfg.CertName,
+				Introducer:           deviceCfg.Introducer,
 			}
 
 			if fs != nil {
@@ -2441,6 +2543,11 @@ next:
 				continue next
 			}
 		}
+		for _, mismatchedFolder := range m.encryptionMismatch[device] {
+			if mismatchedFolder == folder {
+				continue next
+			}
+		}
 		_, ok := m.conn[device]
 		if ok {
 			availabilities = append(availabilities, Availability{ID: device, FromTemporary: false})
@@ -2448,7 +2555,7 @@ next:
 	}
 
 	for _, device := range cfg.Devices {
-		if m.deviceDownloads[device.DeviceID].Has(folder, file.Name, file.Version, int32(block.Offset/int64(file.BlockSize()))) {
+		if m.deviceDownloads[device.DeviceID].Has(folder, file.Name, file.Version, int32(file.BlockIndex(block.Offset))) {
 			availabilities = append(availabilities, Availability{ID: device.DeviceID, FromTemporary: true})
 		}
 	}
@@ -2456,6 +2563,19 @@ next:
 	return availabilities
 }
 
+// PullerQueueSize returns the number of files currently being pulled and
+// queued to be pulled in the given folder.
+func (m *model) PullerQueueSize(folder string) (int, int) {
+	m.fmut.RLock()
+	runner, ok := m.folderRunners[folder]
+	m.fmut.RUnlock()
+
+	if !ok {
+		return 0, 0
+	}
+	return runner.QueueSize()
+}
+
 // BringToFront bumps the given files priority in the job queue.
 func (m *model) BringToFront(folder, file string) {
 	m.fmut.RLock()
@@ -2535,6 +2655,14 @@ func (m *model) CommitConfiguration(from, to config.Configuration) bool {
 	// At some point model.Close() will get called for that device which will
 	// clean residue device state that is not part of any folder.
 
+	// The connection encrypts and decrypts folders with the passwords that
+	// were set when it was established, so reconnect to use new ones.
+	for deviceID := range to.DeviceMap() {
+		if !reflect.DeepEqual(config.EncryptionPasswords(from.Folders, deviceID), config.EncryptionPasswords(to.Folders, deviceID)) {
+			m.closeConn(deviceID, errEncryptionChanged)
+		}
+	}
+
 	// Pausing a device, unpausing is handled by the connection service.
 	fromDevices := from.DeviceMap()
 	toDevices := to.DeviceMap()
@@ -254,7 +254,7 @@ func BenchmarkRequestOut(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		data, err := m.requestGlobal(device1, "default", files[i%n].Name, 0, 32, nil, 0, false)
+		data, err := m.requestGlobal(device1, "default", files[i%n].Name, 0, 0, 32, nil, 0, false)
 		if err != nil {
 			b.Error(err)
 		}
@@ -3278,7 +3278,7 @@ func TestConnCloseOnRestart(t *testing.T) {
 
 	br := &testutils.BlockingRW{}
 	nw := &testutils.NoopRW{}
-	m.AddConnection(newFakeProtoConn(protocol.NewConnection(device1, br, nw, m, "testConn", protocol.CompressNever)), protocol.HelloResult{})
+	m.AddConnection(newFakeProtoConn(protocol.NewConnection(device1, br, nw, m, "testConn", protocol.CompressNever, protocol.MessageCompressionLZ4, nil)), protocol.HelloResult{})
 	m.pmut.RLock()
 	if len(m.closed) != 1 {
 		t.Fatalf("Expected just one conn (len(m.conn) == %v)", len(m.conn))
@@ -3384,3 +3384,89 @@ func TestDevicePause(t *testing.T) {
 		t.Fatal("Timed out before device was paused")
 	}
 }
+
+func TestContentDefinedChunkingAgreement(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	m := setupModel(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+	addFakeConn(m, device1)
+
+	if m.useContentDefinedChunking(fcfg) {
+		t.Error("should not chunk by content when not configured")
+	}
+
+	fcfg.ContentDefinedChunking = true
+	if m.useContentDefinedChunking(fcfg) {
+		t.Error("should not chunk by content before the other device agrees")
+	}
+
+	cc := protocol.ClusterConfig{
+		Folders: []protocol.Folder{
+			{
+				ID:                     fcfg.ID,
+				ContentDefinedChunking: true,
+				Devices: []protocol.Device{
+					{ID: myID},
+					{ID: device1},
+				},
+			},
+		},
+	}
+	m.ClusterConfig(device1, cc)
+	if !m.useContentDefinedChunking(fcfg) {
+		t.Error("should chunk by content when all devices agree")
+	}
+
+	// A device running an older version doesn't announce anything
+	cc.Folders[0].ContentDefinedChunking = false
+	m.ClusterConfig(device1, cc)
+	if m.useContentDefinedChunking(fcfg) {
+		t.Error("should not chunk by content after the other device disagrees")
+	}
+}
+
+func TestEncryptionAgreement(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	m := setupModel(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+	addFakeConn(m, device1)
+
+	cc := protocol.ClusterConfig{
+		Folders: []protocol.Folder{
+			{
+				ID:        fcfg.ID,
+				Encrypted: true,
+				Devices: []protocol.Device{
+					{ID: myID},
+					{ID: device1},
+				},
+			},
+		},
+	}
+	m.ClusterConfig(device1, cc)
+	if !m.encryptionMismatched(device1, fcfg.ID) {
+		t.Fatal("Expected a mismatch when only the other device encrypts")
+	}
+
+	m.Index(device1, fcfg.ID, []protocol.FileInfo{{Name: "foo", Version: protocol.Vector{}.Update(device1.Short())}})
+	m.fmut.RLock()
+	fset := m.folderFiles[fcfg.ID]
+	m.fmut.RUnlock()
+	if _, ok := fset.Get(device1, "foo"); ok {
+		t.Error("Index was accepted despite the mismatch")
+	}
+	if _, err := m.Request(device1, fcfg.ID, "foo", 10, 0, nil, 0, false); err != protocol.ErrGeneric {
+		t.Error("Expected request to be refused, got", err)
+	}
+
+	cc.Folders[0].Encrypted = false
+	m.ClusterConfig(device1, cc)
+	if m.encryptionMismatched(device1, fcfg.ID) {
+		t.Error("Expected no mismatch when neither device encrypts")
+	}
+
+	fcfg.Devices = []config.FolderDeviceConfiguration{{DeviceID: myID}, {DeviceID: device1, EncryptionPassword: "secret"}}
+	if !encryptedWith(fcfg, device1) || encryptedWith(fcfg, myID) {
+		t.Error("Expected the folder to be encrypted only for the device with a password")
+	}
+}
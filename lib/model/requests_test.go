@@ -1014,3 +1014,60 @@ func TestNeedFolderFiles(t *testing.T) {
 		}
 	}
 }
+
+func TestRequestSelectedPaths(t *testing.T) {
+	// Verify that only files within the selected paths are pulled, while
+	// the others are still known globally.
+
+	w, fcfg := tmpDefaultWrapper()
+	fcfg.SelectedPaths = []string{"sel"}
+	waiter, _ := w.SetFolder(fcfg)
+	waiter.Wait()
+	m, fc := setupModelWithConnectionFromWrapper(w)
+	tfs := fcfg.Filesystem()
+	defer cleanupModelAndRemoveDir(m, tfs.URI())
+
+	done := make(chan struct{})
+	fc.mut.Lock()
+	fc.indexFn = func(folder string, fs []protocol.FileInfo) {
+		for _, f := range fs {
+			if f.Name == filepath.Join("sel", "file") {
+				close(done)
+				return
+			}
+		}
+	}
+	fc.requestFn = func(folder, name string, offset int64, size int, hash []byte, fromTemporary bool) ([]byte, error) {
+		if name != filepath.Join("sel", "file") {
+			t.Errorf("unexpected request for %v", name)
+		}
+		return fc.fileData[name], nil
+	}
+	fc.mut.Unlock()
+
+	contents := []byte("test file contents\n")
+	fc.addFile("sel", 0755, protocol.FileInfoTypeDirectory, nil)
+	fc.addFile(filepath.Join("sel", "file"), 0644, protocol.FileInfoTypeFile, contents)
+	fc.addFile("other", 0755, protocol.FileInfoTypeDirectory, nil)
+	fc.addFile(filepath.Join("other", "file"), 0644, protocol.FileInfoTypeFile, contents)
+	fc.sendIndexUpdate()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the selected file")
+	}
+
+	if err := equalContents(filepath.Join(tfs.URI(), "sel", "file"), contents); err != nil {
+		t.Error("Selected file did not sync correctly:", err)
+	}
+	if _, err := tfs.Lstat("other"); !fs.IsNotExist(err) {
+		t.Error("Unselected directory should not exist, got", err)
+	}
+	if _, ok := m.CurrentGlobalFile("default", filepath.Join("other", "file")); !ok {
+		t.Error("Unselected file should be in the global index")
+	}
+	if size := m.NeedSize("default"); size.Files != 0 || size.Directories != 0 {
+		t.Error("Unselected files should not be needed, got", size)
+	}
+}
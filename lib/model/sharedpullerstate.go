@@ -75,6 +75,18 @@ func (w lockedWriterAt) WriteAt(p []byte, off int64) (n int, err error) {
 	return w.wr.WriteAt(p, off)
 }
 
+// copyRange copies from src to the file like fs.CopyRange, synchronized
+// like WriteAt.
+func (w lockedWriterAt) copyRange(filesystem fs.Filesystem, src fs.File, srcOffset, dstOffset, size int64) error {
+	dst, ok := w.wr.(fs.File)
+	if !ok {
+		return fs.ErrCopyRangeNotSupported
+	}
+	(*w.mut).Lock()
+	defer (*w.mut).Unlock()
+	return fs.CopyRange(filesystem, src, dst, srcOffset, dstOffset, size)
+}
+
 // tempFile returns the fd for the temporary file, reusing an open fd
 // or creating the file as necessary.
 func (s *sharedPullerState) tempFile() (io.WriterAt, error) {
@@ -204,7 +216,7 @@ func (s *sharedPullerState) copyDone(block protocol.BlockInfo) {
 	s.mut.Lock()
 	s.copyNeeded--
 	s.updated = time.Now()
-	s.available = append(s.available, int32(block.Offset/int64(s.file.BlockSize())))
+	s.available = append(s.available, int32(s.file.BlockIndex(block.Offset)))
 	s.availableUpdated = time.Now()
 	l.Debugln("sharedPullerState", s.folder, s.file.Name, "copyNeeded ->", s.copyNeeded)
 	s.mut.Unlock()
@@ -240,7 +252,7 @@ func (s *sharedPullerState) pullDone(block protocol.BlockInfo) {
 	s.mut.Lock()
 	s.pullNeeded--
 	s.updated = time.Now()
-	s.available = append(s.available, int32(block.Offset/int64(s.file.BlockSize())))
+	s.available = append(s.available, int32(s.file.BlockIndex(block.Offset)))
 	s.availableUpdated = time.Now()
 	l.Debugln("sharedPullerState", s.folder, s.file.Name, "pullNeeded done ->", s.pullNeeded)
 	s.mut.Unlock()
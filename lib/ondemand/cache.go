@@ -0,0 +1,223 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package ondemand
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/sha256"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+const cacheTempPrefix = "tmp-"
+
+// A Cache keeps blocks on disk, keyed by their hash, and evicts the least
+// recently used ones when it grows beyond its maximum size. It survives
+// restarts, using the modification times of the block files to restore the
+// order of use.
+type Cache struct {
+	dir     string
+	maxSize int64
+
+	mut     sync.Mutex
+	size    int64
+	lru     *list.List               // of *cacheEntry, most recently used at the front
+	entries map[string]*list.Element // hex hash -> element in lru
+}
+
+type cacheEntry struct {
+	key  string
+	size int64
+}
+
+// NewCache returns a cache keeping at most maxSize bytes of blocks in dir,
+// picking up the blocks already there.
+func NewCache(dir string, maxSize int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	c := &Cache{
+		dir:     dir,
+		maxSize: maxSize,
+		mut:     sync.NewMutex(),
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+
+	type existing struct {
+		key     string
+		size    int64
+		modTime time.Time
+	}
+	var found []existing
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		name := info.Name()
+		if strings.HasPrefix(name, cacheTempPrefix) {
+			// Left over from an interrupted Put.
+			os.Remove(path)
+			return nil
+		}
+		if _, err := hex.DecodeString(name); err != nil || path != c.path(name) {
+			return nil
+		}
+		found = append(found, existing{name, info.Size(), info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(found, func(a, b int) bool {
+		return found[a].modTime.After(found[b].modTime)
+	})
+	c.mut.Lock()
+	for _, e := range found {
+		c.entries[e.key] = c.lru.PushBack(&cacheEntry{key: e.key, size: e.size})
+		c.size += e.size
+	}
+	c.evictLocked()
+	c.mut.Unlock()
+
+	l.Debugf("Opened block cache %s with %d blocks, %d bytes", dir, len(c.entries), c.size)
+	return c, nil
+}
+
+// Get returns the data of the block with the given hash, if it is cached
+// and still intact.
+func (c *Cache) Get(hash []byte) ([]byte, bool) {
+	key := hex.EncodeToString(hash)
+
+	c.mut.Lock()
+	el, ok := c.entries[key]
+	if ok {
+		c.lru.MoveToFront(el)
+	}
+	c.mut.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	path := c.path(key)
+	data, err := ioutil.ReadFile(path)
+	if err == nil {
+		sum := sha256.Sum256(data)
+		if !bytes.Equal(sum[:], hash) {
+			err = errCorruptBlock
+		}
+	}
+	if err != nil {
+		l.Debugf("Dropping cached block %s: %v", key, err)
+		c.remove(key)
+		return nil, false
+	}
+
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return data, true
+}
+
+// Put stores the data of the block with the given hash, evicting other
+// blocks as necessary.
+func (c *Cache) Put(hash []byte, data []byte) error {
+	size := int64(len(data))
+	if size > c.maxSize {
+		return nil
+	}
+	key := hex.EncodeToString(hash)
+
+	c.mut.Lock()
+	el, ok := c.entries[key]
+	if ok {
+		c.lru.MoveToFront(el)
+	}
+	c.mut.Unlock()
+	if ok {
+		return nil
+	}
+
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	fd, err := ioutil.TempFile(filepath.Dir(path), cacheTempPrefix)
+	if err != nil {
+		return err
+	}
+	if _, err := fd.Write(data); err != nil {
+		fd.Close()
+		os.Remove(fd.Name())
+		return err
+	}
+	if err := fd.Close(); err != nil {
+		os.Remove(fd.Name())
+		return err
+	}
+	if err := os.Rename(fd.Name(), path); err != nil {
+		os.Remove(fd.Name())
+		return err
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if _, ok := c.entries[key]; ok {
+		// Someone else put it while we were writing.
+		return nil
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, size: size})
+	c.size += size
+	c.evictLocked()
+	return nil
+}
+
+// Size returns the number of bytes currently cached.
+func (c *Cache) Size() int64 {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return c.size
+}
+
+func (c *Cache) remove(key string) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.removeLocked(el)
+	}
+}
+
+func (c *Cache) evictLocked() {
+	for c.size > c.maxSize {
+		c.removeLocked(c.lru.Back())
+	}
+}
+
+func (c *Cache) removeLocked(el *list.Element) {
+	e := c.lru.Remove(el).(*cacheEntry)
+	delete(c.entries, e.key)
+	c.size -= e.size
+	os.Remove(c.path(e.key))
+}
+
+// path returns the location of a block, spread over subdirectories to keep
+// the directories reasonably small.
+func (c *Cache) path(key string) string {
+	if len(key) < 2 {
+		return filepath.Join(c.dir, key)
+	}
+	return filepath.Join(c.dir, key[:2], key)
+}
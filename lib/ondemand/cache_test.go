@@ -0,0 +1,115 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package ondemand
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func testBlock(i int) ([]byte, []byte) {
+	data := bytes.Repeat([]byte{byte(i)}, 100)
+	hash := sha256.Sum256(data)
+	return data, hash[:]
+}
+
+func TestCacheEviction(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syncthing-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c, err := NewCache(dir, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		data, hash := testBlock(i)
+		if err := c.Put(hash, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.Size() != 300 {
+		t.Error("unexpected size", c.Size())
+	}
+
+	// Use block 0, making block 1 the least recently used one, which is
+	// evicted when adding another.
+	_, hash := testBlock(0)
+	if _, ok := c.Get(hash); !ok {
+		t.Fatal("block 0 should be cached")
+	}
+	data, hash := testBlock(3)
+	if err := c.Put(hash, data); err != nil {
+		t.Fatal(err)
+	}
+	if c.Size() != 300 {
+		t.Error("unexpected size", c.Size())
+	}
+
+	for i, exp := range []bool{true, false, true, true} {
+		data, hash := testBlock(i)
+		got, ok := c.Get(hash)
+		if ok != exp {
+			t.Errorf("block %d cached: %v, expected %v", i, ok, exp)
+		}
+		if ok && !bytes.Equal(got, data) {
+			t.Errorf("block %d has wrong data", i)
+		}
+	}
+}
+
+func TestCacheReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syncthing-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c, err := NewCache(dir, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		data, hash := testBlock(i)
+		if err := c.Put(hash, data); err != nil {
+			t.Fatal(err)
+		}
+		// Make sure the order of use is kept, regardless of the timestamp
+		// resolution of the filesystem.
+		when := time.Now().Add(time.Duration(i-10) * time.Minute)
+		os.Chtimes(c.path(hex.EncodeToString(hash)), when, when)
+	}
+
+	// A corrupt block is dropped when read.
+	_, hash := testBlock(2)
+	if err := ioutil.WriteFile(c.path(hex.EncodeToString(hash)), []byte("garbage"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopening with a smaller size evicts the oldest block.
+	c, err = NewCache(dir, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, exp := range []bool{false, true, false} {
+		_, hash := testBlock(i)
+		if _, ok := c.Get(hash); ok != exp {
+			t.Errorf("block %d cached: %v, expected %v", i, ok, exp)
+		}
+	}
+	if c.Size() != 100 {
+		t.Error("unexpected size", c.Size())
+	}
+}
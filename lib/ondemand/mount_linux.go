@@ -0,0 +1,156 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build linux
+
+package ondemand
+
+import (
+	"context"
+	"io"
+	"path"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// How long the kernel may cache names and attributes. Changes to the global
+// state become visible after this time at the latest.
+const attrTimeout = time.Second
+
+// Mount presents the view as a read only FUSE filesystem at dir, until
+// the returned Mounted is unmounted.
+func Mount(dir string, v *View) (Mounted, error) {
+	timeout := attrTimeout
+	server, err := fs.Mount(dir, &node{view: v}, &fs.Options{
+		MountOptions: fuse.MountOptions{
+			FsName:        "syncthing",
+			Name:          "syncthing",
+			DisableXAttrs: true,
+		},
+		EntryTimeout:    &timeout,
+		AttrTimeout:     &timeout,
+		NegativeTimeout: &timeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return server, nil
+}
+
+// A node is a file, directory or symlink in the view. It only keeps the
+// name and looks up the current global version on each operation.
+type node struct {
+	fs.Inode
+	view *View
+	name string
+}
+
+var (
+	_ = (fs.NodeLookuper)((*node)(nil))
+	_ = (fs.NodeReaddirer)((*node)(nil))
+	_ = (fs.NodeGetattrer)((*node)(nil))
+	_ = (fs.NodeOpener)((*node)(nil))
+	_ = (fs.NodeReader)((*node)(nil))
+	_ = (fs.NodeReadlinker)((*node)(nil))
+)
+
+func (n *node) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	childName := path.Join(n.name, name)
+	f, ok := n.view.Lookup(childName)
+	if !ok {
+		return nil, syscall.ENOENT
+	}
+	setAttr(&out.Attr, f)
+	child := &node{view: n.view, name: childName}
+	return n.NewInode(ctx, child, fs.StableAttr{Mode: fileMode(f) & syscall.S_IFMT}), 0
+}
+
+func (n *node) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	children := n.view.ReadDir(n.name)
+	entries := make([]fuse.DirEntry, len(children))
+	for i, f := range children {
+		entries[i] = fuse.DirEntry{
+			Name: path.Base(f.Name),
+			Mode: fileMode(f),
+		}
+	}
+	return fs.NewListDirStream(entries), 0
+}
+
+func (n *node) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	f, ok := n.view.Lookup(n.name)
+	if !ok {
+		return syscall.ENOENT
+	}
+	setAttr(&out.Attr, f)
+	return 0
+}
+
+func (n *node) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	if flags&(syscall.O_WRONLY|syscall.O_RDWR|syscall.O_TRUNC|syscall.O_APPEND) != 0 {
+		return nil, 0, syscall.EROFS
+	}
+	return nil, fuse.FOPEN_KEEP_CACHE, 0
+}
+
+func (n *node) Read(ctx context.Context, fh fs.FileHandle, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	f, ok := n.view.Lookup(n.name)
+	if !ok {
+		return nil, syscall.ENOENT
+	}
+	read, err := n.view.ReadAt(ctx, f, dest, off)
+	if err != nil && err != io.EOF {
+		l.Infof("Reading %s: %v", n.name, err)
+		return nil, syscall.EIO
+	}
+	return fuse.ReadResultData(dest[:read]), 0
+}
+
+func (n *node) Readlink(ctx context.Context) ([]byte, syscall.Errno) {
+	f, ok := n.view.Lookup(n.name)
+	if !ok {
+		return nil, syscall.ENOENT
+	}
+	if !f.IsSymlink() {
+		return nil, syscall.EINVAL
+	}
+	return []byte(f.SymlinkTarget), 0
+}
+
+func setAttr(out *fuse.Attr, f protocol.FileInfo) {
+	out.Mode = fileMode(f)
+	out.Nlink = 1
+	if !f.IsDirectory() {
+		out.Size = uint64(f.Size)
+		out.Blocks = (out.Size + 511) / 512
+	}
+	mtime := f.ModTime()
+	out.SetTimes(&mtime, &mtime, &mtime)
+}
+
+func fileMode(f protocol.FileInfo) uint32 {
+	perm := f.Permissions & 0777
+	switch {
+	case f.IsDirectory():
+		if f.NoPermissions || perm == 0 {
+			perm = 0755
+		}
+		return syscall.S_IFDIR | perm
+	case f.IsSymlink():
+		return syscall.S_IFLNK | 0777
+	default:
+		if f.NoPermissions || perm == 0 {
+			perm = 0644
+		}
+		// Nothing can be written, so don't pretend otherwise.
+		return syscall.S_IFREG | perm&^0222
+	}
+}
@@ -0,0 +1,19 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build !linux
+
+package ondemand
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Mount is not supported on this platform.
+func Mount(dir string, v *View) (Mounted, error) {
+	return nil, fmt.Errorf("on demand mounts are not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,125 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// Package ondemand presents the global state of a folder as a read only
+// filesystem, where file data is fetched block by block as it is read.
+package ondemand
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+var (
+	errCorruptBlock = errors.New("block data does not match hash")
+	errShortBlock   = errors.New("block data has wrong size")
+)
+
+// A Source provides the global state of a folder and the data of its
+// blocks. Names are slash separated and relative to the folder root.
+type Source interface {
+	// GlobalFile returns the global version of the named item.
+	GlobalFile(name string) (protocol.FileInfo, bool)
+	// GlobalChildren returns the global versions of the items directly
+	// within the named directory. Their block lists may be left out.
+	GlobalChildren(dir string) []protocol.FileInfo
+	// Block returns the verified data of a block of the file, from a local
+	// copy or the cluster.
+	Block(ctx context.Context, file protocol.FileInfo, block protocol.BlockInfo) ([]byte, error)
+}
+
+// A View presents the items of a Source that currently exist, caching the
+// blocks read from it.
+type View struct {
+	src   Source
+	cache *Cache
+}
+
+func NewView(src Source, cache *Cache) *View {
+	return &View{
+		src:   src,
+		cache: cache,
+	}
+}
+
+// Lookup returns the named item, if it exists. The root of the folder is the
+// empty name.
+func (v *View) Lookup(name string) (protocol.FileInfo, bool) {
+	if name == "" || name == "." {
+		return protocol.FileInfo{Type: protocol.FileInfoTypeDirectory, Permissions: 0755}, true
+	}
+	f, ok := v.src.GlobalFile(name)
+	if !ok || !exists(f) {
+		return protocol.FileInfo{}, false
+	}
+	return f, true
+}
+
+// ReadDir returns the existing items directly within the named directory.
+func (v *View) ReadDir(name string) []protocol.FileInfo {
+	children := v.src.GlobalChildren(name)
+	existing := children[:0]
+	for _, f := range children {
+		if exists(f) {
+			existing = append(existing, f)
+		}
+	}
+	return existing
+}
+
+// ReadAt reads len(p) bytes of the file starting at offset off, like
+// io.ReaderAt, fetching the blocks that aren't cached.
+func (v *View) ReadAt(ctx context.Context, file protocol.FileInfo, p []byte, off int64) (int, error) {
+	if off >= file.Size {
+		return 0, io.EOF
+	}
+
+	n := 0
+	for n < len(p) {
+		pos := off + int64(n)
+		idx := file.BlockIndex(pos)
+		if pos >= file.Size || idx >= len(file.Blocks) {
+			return n, io.EOF
+		}
+		block := file.Blocks[idx]
+		data, err := v.block(ctx, file, block)
+		if err != nil {
+			return n, err
+		}
+		n += copy(p[n:], data[pos-block.Offset:])
+	}
+	return n, nil
+}
+
+func (v *View) block(ctx context.Context, file protocol.FileInfo, block protocol.BlockInfo) ([]byte, error) {
+	if block.IsEmpty() {
+		// No need to fetch or store a block of all zeroes.
+		return make([]byte, block.Size), nil
+	}
+
+	if data, ok := v.cache.Get(block.Hash); ok && len(data) == int(block.Size) {
+		return data, nil
+	}
+
+	data, err := v.src.Block(ctx, file, block)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != int(block.Size) {
+		return nil, errShortBlock
+	}
+	if err := v.cache.Put(block.Hash, data); err != nil {
+		l.Infof("Caching block of %s: %v", file.Name, err)
+	}
+	return data, nil
+}
+
+func exists(f protocol.FileInfo) bool {
+	return !f.IsDeleted() && !f.IsInvalid()
+}
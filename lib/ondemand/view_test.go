@@ -0,0 +1,174 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package ondemand
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// fakeSource is an in memory Source, counting the blocks requested from it.
+type fakeSource struct {
+	files    map[string]protocol.FileInfo
+	data     map[string][]byte
+	requests int
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{
+		files: make(map[string]protocol.FileInfo),
+		data:  make(map[string][]byte),
+	}
+}
+
+func (s *fakeSource) addFile(name string, data []byte, blockSize int) {
+	f := protocol.FileInfo{Name: name, Type: protocol.FileInfoTypeFile, Size: int64(len(data)), RawBlockSize: int32(blockSize)}
+	for off := 0; off < len(data); off += blockSize {
+		end := off + blockSize
+		if end > len(data) {
+			end = len(data)
+		}
+		hash := sha256.Sum256(data[off:end])
+		f.Blocks = append(f.Blocks, protocol.BlockInfo{Offset: int64(off), Size: int32(end - off), Hash: hash[:]})
+		s.data[string(hash[:])] = data[off:end]
+	}
+	s.files[name] = f
+}
+
+func (s *fakeSource) GlobalFile(name string) (protocol.FileInfo, bool) {
+	f, ok := s.files[name]
+	return f, ok
+}
+
+func (s *fakeSource) GlobalChildren(dir string) []protocol.FileInfo {
+	var children []protocol.FileInfo
+	for name, f := range s.files {
+		if path.Dir(name) == dir || (dir == "" && !strings.Contains(name, "/")) {
+			children = append(children, f)
+		}
+	}
+	return children
+}
+
+func (s *fakeSource) Block(ctx context.Context, file protocol.FileInfo, block protocol.BlockInfo) ([]byte, error) {
+	s.requests++
+	data, ok := s.data[string(block.Hash)]
+	if !ok {
+		return nil, errCorruptBlock
+	}
+	return data, nil
+}
+
+func newTestView(t *testing.T, src Source) (*View, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "syncthing-view")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := NewCache(dir, 1<<20)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return NewView(src, cache), func() { os.RemoveAll(dir) }
+}
+
+func TestViewLookup(t *testing.T) {
+	src := newFakeSource()
+	src.files["dir"] = protocol.FileInfo{Name: "dir", Type: protocol.FileInfoTypeDirectory}
+	src.files["dir/file"] = protocol.FileInfo{Name: "dir/file"}
+	src.files["dir/deleted"] = protocol.FileInfo{Name: "dir/deleted", Deleted: true}
+	src.files["dir/invalid"] = protocol.FileInfo{Name: "dir/invalid", RawInvalid: true}
+
+	v, cleanup := newTestView(t, src)
+	defer cleanup()
+
+	if f, ok := v.Lookup(""); !ok || !f.IsDirectory() {
+		t.Error("root should be a directory")
+	}
+	if _, ok := v.Lookup("dir/file"); !ok {
+		t.Error("dir/file should exist")
+	}
+	for _, name := range []string{"dir/deleted", "dir/invalid", "dir/nonexistent"} {
+		if _, ok := v.Lookup(name); ok {
+			t.Errorf("%s should not exist", name)
+		}
+	}
+
+	children := v.ReadDir("dir")
+	if len(children) != 1 || children[0].Name != "dir/file" {
+		t.Errorf("unexpected children %v", children)
+	}
+}
+
+func TestViewReadAt(t *testing.T) {
+	const bs = protocol.MinBlockSize
+	data := make([]byte, 10*bs)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	// The last block is all zeroes, which is never requested.
+	data = append(data, make([]byte, bs)...)
+
+	src := newFakeSource()
+	src.addFile("file", data, bs)
+
+	v, cleanup := newTestView(t, src)
+	defer cleanup()
+	f, _ := v.Lookup("file")
+
+	// Across block boundaries
+	buf := make([]byte, 2*bs+bs/2)
+	n, err := v.ReadAt(context.Background(), f, buf, bs/2)
+	if err != nil || n != len(buf) {
+		t.Fatal(n, err)
+	}
+	if !bytes.Equal(buf, data[bs/2:3*bs]) {
+		t.Error("read the wrong data")
+	}
+	if src.requests != 3 {
+		t.Error("unexpected number of requests", src.requests)
+	}
+
+	// The same blocks again come from the cache
+	n, err = v.ReadAt(context.Background(), f, buf, 0)
+	if err != nil || n != len(buf) {
+		t.Fatal(n, err)
+	}
+	if !bytes.Equal(buf, data[:len(buf)]) {
+		t.Error("read the wrong data")
+	}
+	if src.requests != 3 {
+		t.Error("unexpected number of requests", src.requests)
+	}
+
+	// Up to the end of the file
+	n, err = v.ReadAt(context.Background(), f, buf, 9*bs+bs/2)
+	if err != io.EOF || n != bs+bs/2 {
+		t.Fatal(n, err)
+	}
+	if !bytes.Equal(buf[:n], data[9*bs+bs/2:]) {
+		t.Error("read the wrong data")
+	}
+	if src.requests != 4 {
+		t.Error("unexpected number of requests", src.requests)
+	}
+
+	// Beyond the end of the file
+	if n, err := v.ReadAt(context.Background(), f, buf, 20*bs); err != io.EOF || n != 0 {
+		t.Error(n, err)
+	}
+}
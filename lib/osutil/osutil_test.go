@@ -155,3 +155,22 @@ func TestRenameOrCopy(t *testing.T) {
 		}
 	}
 }
+
+func TestOwnerLookup(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no numeric user ids on Windows")
+	}
+
+	uid := os.Getuid()
+	name := osutil.UserName(uid)
+	if name == "" {
+		t.Skip("current user has no name")
+	}
+	if id, ok := osutil.UserID(name); !ok || id != uid {
+		t.Errorf("Expected user %q to have id %d, got %d", name, uid, id)
+	}
+
+	if _, ok := osutil.UserID("syncthing-no-such-user"); ok {
+		t.Error("Expected unknown user not to be found")
+	}
+}
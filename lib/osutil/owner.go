@@ -0,0 +1,144 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package osutil
+
+import (
+	"os/user"
+	"strconv"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+// Looking up users and groups may mean reading /etc/passwd or asking a
+// directory service, for every file scanned or pulled. They rarely change,
+// so the results are kept. Failed lookups are only remembered for a while,
+// as the user or group may yet be created.
+var (
+	ownerMut    = sync.NewMutex()
+	userNames   = make(map[int]string)
+	groupNames  = make(map[int]string)
+	userIDs     = make(map[string]int)
+	groupIDs    = make(map[string]int)
+	ownerMisses = make(map[string]time.Time)
+)
+
+var ownerMissExpiry = time.Minute
+
+const (
+	unknownName = ""
+	unknownID   = -1
+)
+
+// UserName returns the name of the user with the given id, or the empty
+// string if there is no such user.
+func UserName(uid int) string {
+	return lookupName(userNames, "uid", uid, func(id string) (string, error) {
+		u, err := user.LookupId(id)
+		if err != nil {
+			return "", err
+		}
+		return u.Username, nil
+	})
+}
+
+// GroupName returns the name of the group with the given id, or the empty
+// string if there is no such group.
+func GroupName(gid int) string {
+	return lookupName(groupNames, "gid", gid, func(id string) (string, error) {
+		g, err := user.LookupGroupId(id)
+		if err != nil {
+			return "", err
+		}
+		return g.Name, nil
+	})
+}
+
+// UserID returns the id of the user with the given name, and whether there
+// is such a user.
+func UserID(name string) (int, bool) {
+	return lookupID(userIDs, "user", name, func(name string) (string, error) {
+		u, err := user.Lookup(name)
+		if err != nil {
+			return "", err
+		}
+		return u.Uid, nil
+	})
+}
+
+// GroupID returns the id of the group with the given name, and whether
+// there is such a group.
+func GroupID(name string) (int, bool) {
+	return lookupID(groupIDs, "group", name, func(name string) (string, error) {
+		g, err := user.LookupGroup(name)
+		if err != nil {
+			return "", err
+		}
+		return g.Gid, nil
+	})
+}
+
+func lookupName(cache map[int]string, kind string, id int, lookup func(string) (string, error)) string {
+	ownerMut.Lock()
+	defer ownerMut.Unlock()
+	if name, ok := cache[id]; ok {
+		return name
+	}
+	key := kind + ":" + strconv.Itoa(id)
+	if recentMiss(key) {
+		return unknownName
+	}
+	name, err := lookup(strconv.Itoa(id))
+	if err != nil {
+		ownerMisses[key] = time.Now()
+		return unknownName
+	}
+	delete(ownerMisses, key)
+	cache[id] = name
+	return name
+}
+
+func lookupID(cache map[string]int, kind, name string, lookup func(string) (string, error)) (int, bool) {
+	ownerMut.Lock()
+	defer ownerMut.Unlock()
+	if id, ok := cache[name]; ok {
+		return id, true
+	}
+	key := kind + ":" + name
+	if recentMiss(key) {
+		return unknownID, false
+	}
+	// Ids are numeric on Unixes, but not on Windows.
+	s, err := lookup(name)
+	if err != nil {
+		ownerMisses[key] = time.Now()
+		return unknownID, false
+	}
+	id, err := strconv.Atoi(s)
+	if err != nil {
+		ownerMisses[key] = time.Now()
+		return unknownID, false
+	}
+	delete(ownerMisses, key)
+	cache[name] = id
+	return id, true
+}
+
+// recentMiss returns whether the lookup with the given key failed recently,
+// forgetting it if it was long enough ago to try again. The caller holds
+// ownerMut.
+func recentMiss(key string) bool {
+	t, ok := ownerMisses[key]
+	if !ok {
+		return false
+	}
+	if time.Since(t) < ownerMissExpiry {
+		return true
+	}
+	delete(ownerMisses, key)
+	return false
+}
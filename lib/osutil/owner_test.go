@@ -0,0 +1,51 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package osutil
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLookupIDMissExpires(t *testing.T) {
+	oldExpiry := ownerMissExpiry
+	defer func() {
+		ownerMissExpiry = oldExpiry
+	}()
+	ownerMissExpiry = time.Hour
+
+	cache := make(map[string]int)
+	lookups := 0
+	exists := false
+	lookup := func(name string) (string, error) {
+		lookups++
+		if !exists {
+			return "", errors.New("no such user")
+		}
+		return "1234", nil
+	}
+
+	if _, ok := lookupID(cache, "test", "someone", lookup); ok || lookups != 1 {
+		t.Fatalf("Expected a failed lookup, got %v after %d lookups", ok, lookups)
+	}
+
+	// The miss is remembered while it's recent
+	exists = true
+	if _, ok := lookupID(cache, "test", "someone", lookup); ok || lookups != 1 {
+		t.Fatalf("Expected the miss to be remembered, got %v after %d lookups", ok, lookups)
+	}
+
+	// and looked up again once it has expired, after which the user is
+	// known for good.
+	ownerMissExpiry = 0
+	for i := 0; i < 2; i++ {
+		if id, ok := lookupID(cache, "test", "someone", lookup); !ok || id != 1234 || lookups != 2 {
+			t.Fatalf("Expected id 1234 from a second lookup, got %d, %v after %d lookups", id, ok, lookups)
+		}
+	}
+}
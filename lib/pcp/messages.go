@@ -0,0 +1,187 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package pcp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// The message formats of RFC 6887, without options, which we neither send
+// nor need to understand.
+
+const (
+	version = 2
+
+	opcodeAnnounce = 0
+	opcodeMap      = 1
+	responseBit    = 0x80
+
+	headerSize     = 24
+	mapPayloadSize = 36
+	nonceSize      = 12
+
+	protocolTCP = 6
+	protocolUDP = 17
+)
+
+type resultCode uint8
+
+const (
+	resultSuccess resultCode = iota
+	resultUnsuppVersion
+	resultNotAuthorized
+	resultMalformedRequest
+	resultUnsuppOpcode
+	resultUnsuppOption
+	resultMalformedOption
+	resultNetworkFailure
+	resultNoResources
+	resultUnsuppProtocol
+	resultUserExQuota
+	resultCannotProvideExternal
+	resultAddressMismatch
+	resultExcessiveRemotePeers
+)
+
+var resultNames = []string{
+	"success",
+	"unsupported version",
+	"not authorized",
+	"malformed request",
+	"unsupported opcode",
+	"unsupported option",
+	"malformed option",
+	"network failure",
+	"no resources",
+	"unsupported protocol",
+	"user exceeded quota",
+	"cannot provide external",
+	"address mismatch",
+	"excessive remote peers",
+}
+
+func (r resultCode) Error() string {
+	if int(r) < len(resultNames) {
+		return "PCP: " + resultNames[r]
+	}
+	return fmt.Sprintf("PCP: result code %d", r)
+}
+
+var errMalformedResponse = errors.New("PCP: malformed response")
+
+// request is the common header of all requests, followed by the opcode
+// specific payload.
+type request struct {
+	opcode   uint8
+	lifetime uint32
+	clientIP net.IP
+	payload  []byte
+}
+
+func (r request) marshal() []byte {
+	bs := make([]byte, headerSize+len(r.payload))
+	bs[0] = version
+	bs[1] = r.opcode
+	binary.BigEndian.PutUint32(bs[4:], r.lifetime)
+	copy(bs[8:24], r.clientIP.To16())
+	copy(bs[headerSize:], r.payload)
+	return bs
+}
+
+func unmarshalRequest(bs []byte) (request, error) {
+	if len(bs) < headerSize || bs[0] != version || bs[1]&responseBit != 0 {
+		return request{}, resultMalformedRequest
+	}
+	return request{
+		opcode:   bs[1],
+		lifetime: binary.BigEndian.Uint32(bs[4:]),
+		clientIP: net.IP(bs[8:24]),
+		payload:  bs[headerSize:],
+	}, nil
+}
+
+// response is the common header of all responses, followed by the opcode
+// specific payload.
+type response struct {
+	opcode   uint8
+	result   resultCode
+	lifetime uint32
+	epoch    uint32
+	payload  []byte
+}
+
+func (r response) marshal() []byte {
+	bs := make([]byte, headerSize+len(r.payload))
+	bs[0] = version
+	bs[1] = r.opcode | responseBit
+	bs[3] = byte(r.result)
+	binary.BigEndian.PutUint32(bs[4:], r.lifetime)
+	binary.BigEndian.PutUint32(bs[8:], r.epoch)
+	copy(bs[headerSize:], r.payload)
+	return bs
+}
+
+func unmarshalResponse(bs []byte) (response, error) {
+	if len(bs) < 4 || bs[1]&responseBit == 0 {
+		return response{}, errMalformedResponse
+	}
+	if bs[0] != version {
+		// Servers that only speak NAT-PMP answer with their version.
+		return response{}, resultUnsuppVersion
+	}
+	if len(bs) < headerSize {
+		return response{}, errMalformedResponse
+	}
+	return response{
+		opcode:   bs[1] &^ responseBit,
+		result:   resultCode(bs[3]),
+		lifetime: binary.BigEndian.Uint32(bs[4:]),
+		epoch:    binary.BigEndian.Uint32(bs[8:]),
+		payload:  bs[headerSize:],
+	}, nil
+}
+
+// mapPayload is the payload of both MAP requests and responses; in
+// requests the external port and IP are suggestions, in responses they
+// are what was assigned.
+type mapPayload struct {
+	nonce        [nonceSize]byte
+	protocol     uint8
+	internalPort uint16
+	externalPort uint16
+	externalIP   net.IP
+}
+
+func (p mapPayload) marshal() []byte {
+	bs := make([]byte, mapPayloadSize)
+	copy(bs, p.nonce[:])
+	bs[12] = p.protocol
+	binary.BigEndian.PutUint16(bs[16:], p.internalPort)
+	binary.BigEndian.PutUint16(bs[18:], p.externalPort)
+	copy(bs[20:36], p.externalIP.To16())
+	return bs
+}
+
+func unmarshalMapPayload(bs []byte) (mapPayload, error) {
+	if len(bs) < mapPayloadSize {
+		return mapPayload{}, errMalformedResponse
+	}
+	var p mapPayload
+	copy(p.nonce[:], bs)
+	p.protocol = bs[12]
+	p.internalPort = binary.BigEndian.Uint16(bs[16:])
+	p.externalPort = binary.BigEndian.Uint16(bs[18:])
+	p.externalIP = make(net.IP, net.IPv6len)
+	copy(p.externalIP, bs[20:36])
+	if ip4 := p.externalIP.To4(); ip4 != nil {
+		p.externalIP = ip4
+	}
+	return p, nil
+}
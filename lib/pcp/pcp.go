@@ -0,0 +1,340 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// Package pcp maps ports using the Port Control Protocol, RFC 6887, the
+// successor of NAT-PMP. Besides NATs it can open pinholes in IPv6
+// firewalls, for which the default IPv6 router is discovered on Linux.
+package pcp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	stdsync "sync"
+	"time"
+
+	"github.com/jackpal/gateway"
+
+	"github.com/syncthing/syncthing/lib/nat"
+	"github.com/syncthing/syncthing/lib/rand"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+const (
+	serverPort = 5351
+
+	// The first retransmission is after this, and each one after that
+	// waits twice as long as the one before.
+	initialRetransmit = 250 * time.Millisecond
+
+	maxMessageSize = 1100
+)
+
+var (
+	errNoExternalAddress = errors.New("PCP: external address unknown until a port is mapped")
+	errNoIPv6Router      = errors.New("PCP: no default IPv6 router")
+)
+
+func init() {
+	nat.Register(Discover)
+}
+
+// Discover looks for PCP servers on the default IPv4 gateway, and on the
+// default IPv6 router, where ports are mapped for a global address of the
+// interface leading to it.
+func Discover(renewal, timeout time.Duration) []nat.Device {
+	var wg stdsync.WaitGroup
+	clients := make([]*Client, 2)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		clients[0] = discoverIPv4(renewal, timeout)
+	}()
+	go func() {
+		defer wg.Done()
+		clients[1] = discoverIPv6(renewal, timeout)
+	}()
+	wg.Wait()
+
+	var devices []nat.Device
+	for _, c := range clients {
+		if c != nil {
+			devices = append(devices, c)
+		}
+	}
+	return devices
+}
+
+func discoverIPv4(renewal, timeout time.Duration) *Client {
+	ip, err := gateway.DiscoverGateway()
+	if err != nil {
+		l.Debugln("Failed to discover gateway", err)
+		return nil
+	}
+	if ip == nil || ip.IsUnspecified() {
+		return nil
+	}
+
+	l.Debugln("Discovered gateway at", ip)
+
+	c, err := NewClient(&net.UDPAddr{IP: ip, Port: serverPort}, renewal, timeout)
+	if err != nil {
+		l.Debugln("Failed to create PCP client", err)
+		return nil
+	}
+	return c.probe()
+}
+
+func discoverIPv6(renewal, timeout time.Duration) *Client {
+	ip, iface, err := discoverIPv6Router()
+	if err != nil {
+		l.Debugln("Failed to discover IPv6 router", err)
+		return nil
+	}
+
+	l.Debugln("Discovered IPv6 router at", ip, "on", iface)
+
+	localIP, err := globalIPv6(iface)
+	if err != nil {
+		l.Debugln("No address to map IPv6 ports for", err)
+		return nil
+	}
+
+	// The router is usually known by its link local address, which is
+	// only valid on the interface.
+	server := &net.UDPAddr{IP: ip, Port: serverPort}
+	if ip.IsLinkLocalUnicast() {
+		server.Zone = iface
+	}
+	return newClient(server, localIP, renewal, timeout).probe()
+}
+
+// probe returns the client if the server answers, else it is assumed not
+// to speak PCP.
+func (c *Client) probe() *Client {
+	if err := c.announce(); err != nil {
+		l.Debugln("No PCP server at", c.server, err)
+		return nil
+	}
+	return c
+}
+
+// globalIPv6 returns a global IPv6 address of the named interface, which
+// is what the router opens ports to.
+func globalIPv6(iface string) (net.IP, error) {
+	intf, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := intf.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if ok && isGlobalIPv6(ipnet.IP) {
+			return ipnet.IP, nil
+		}
+	}
+	return nil, fmt.Errorf("no global IPv6 address on %s", iface)
+}
+
+// isGlobalIPv6 returns whether the address is an IPv6 address reachable
+// from the internet, which excludes unique local addresses.
+func isGlobalIPv6(ip net.IP) bool {
+	return ip.To4() == nil && ip.IsGlobalUnicast() && ip[0]&0xfe != 0xfc
+}
+
+// Client maps ports on a PCP server. The mappings are kept alive by
+// mapping the same ports again before their lifetime is up.
+type Client struct {
+	server  *net.UDPAddr
+	localIP net.IP
+	renewal time.Duration
+	timeout time.Duration
+
+	mut        sync.Mutex
+	nonces     map[mappingKey][nonceSize]byte
+	externalIP net.IP
+}
+
+// A mapping is identified by the protocol and internal port, and renewing
+// it takes the same nonce as was used to create it.
+type mappingKey struct {
+	protocol     uint8
+	internalPort uint16
+}
+
+// NewClient returns a client for the PCP server at the given address.
+// Mappings with an unspecified lifetime are made for the renewal interval,
+// and requests time out after the given timeout.
+func NewClient(server *net.UDPAddr, renewal, timeout time.Duration) (*Client, error) {
+	// We need the address the server sees us at for the requests.
+	conn, err := net.DialUDP("udp", nil, server)
+	if err != nil {
+		return nil, err
+	}
+	localIP := conn.LocalAddr().(*net.UDPAddr).IP
+	conn.Close()
+
+	return newClient(server, localIP, renewal, timeout), nil
+}
+
+// newClient returns a client for the PCP server at the given address, which
+// maps ports for the given local address.
+func newClient(server *net.UDPAddr, localIP net.IP, renewal, timeout time.Duration) *Client {
+	return &Client{
+		server:  server,
+		localIP: localIP,
+		renewal: renewal,
+		timeout: timeout,
+		mut:     sync.NewMutex(),
+		nonces:  make(map[mappingKey][nonceSize]byte),
+	}
+}
+
+func (c *Client) ID() string {
+	return fmt.Sprintf("PCP@%s", c.server.IP.String())
+}
+
+func (c *Client) GetLocalIPAddress() net.IP {
+	return c.localIP
+}
+
+func (c *Client) AddPortMapping(protocol nat.Protocol, internalPort, externalPort int, description string, duration time.Duration) (int, error) {
+	// A lifetime of zero deletes the mapping. Swap the zero with the
+	// renewal value, which should make the lease for the exact amount of
+	// time between the calls.
+	if duration == 0 {
+		duration = c.renewal
+	}
+
+	key := mappingKey{protocolTCP, uint16(internalPort)}
+	if protocol == nat.UDP {
+		key.protocol = protocolUDP
+	}
+	c.mut.Lock()
+	nonce, ok := c.nonces[key]
+	if !ok {
+		io.ReadFull(rand.Reader, nonce[:])
+		c.nonces[key] = nonce
+	}
+	c.mut.Unlock()
+
+	// Any external address of the same family as ours will do.
+	suggestedIP := net.IPv6zero
+	if c.localIP.To4() != nil {
+		suggestedIP = net.IPv4zero
+	}
+	req := request{
+		opcode:   opcodeMap,
+		lifetime: uint32(duration / time.Second),
+		clientIP: c.localIP,
+		payload: mapPayload{
+			nonce:        nonce,
+			protocol:     key.protocol,
+			internalPort: key.internalPort,
+			externalPort: uint16(externalPort),
+			externalIP:   suggestedIP,
+		}.marshal(),
+	}
+
+	var mapped mapPayload
+	resp, err := c.do(req, func(resp response) bool {
+		p, err := unmarshalMapPayload(resp.payload)
+		if err != nil || p.nonce != nonce || p.protocol != key.protocol || p.internalPort != key.internalPort {
+			return false
+		}
+		mapped = p
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+	if time.Duration(resp.lifetime)*time.Second < duration {
+		l.Debugf("%s: mapping of %s port %d only lasts %ds", c.ID(), protocol, internalPort, resp.lifetime)
+	}
+
+	c.mut.Lock()
+	c.externalIP = mapped.externalIP
+	c.mut.Unlock()
+	return int(mapped.externalPort), nil
+}
+
+// GetExternalIPAddress returns the external address of the last mapping;
+// PCP has no other way of finding it.
+func (c *Client) GetExternalIPAddress() (net.IP, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if c.externalIP == nil {
+		return nil, errNoExternalAddress
+	}
+	return c.externalIP, nil
+}
+
+// announce checks that there is a PCP server, which must answer an
+// ANNOUNCE request.
+func (c *Client) announce() error {
+	req := request{
+		opcode:   opcodeAnnounce,
+		clientIP: c.localIP,
+	}
+	_, err := c.do(req, func(response) bool { return true })
+	return err
+}
+
+// do sends the request to the server until a response to it arrives, for
+// which matches must return true, or the timeout is reached. It returns an
+// error if the server doesn't answer, or doesn't grant the request.
+func (c *Client) do(req request, matches func(response) bool) (response, error) {
+	// The server only maps ports for the address requests come from.
+	conn, err := net.DialUDP("udp", &net.UDPAddr{IP: c.localIP}, c.server)
+	if err != nil {
+		return response{}, err
+	}
+	defer conn.Close()
+
+	msg := req.marshal()
+	buf := make([]byte, maxMessageSize)
+	deadline := time.Now().Add(c.timeout)
+
+	for wait := initialRetransmit; ; wait *= 2 {
+		if _, err := conn.Write(msg); err != nil {
+			return response{}, err
+		}
+		next := time.Now().Add(wait)
+		if next.After(deadline) {
+			next = deadline
+		}
+		conn.SetReadDeadline(next)
+
+		for {
+			n, err := conn.Read(buf)
+			if err, ok := err.(net.Error); ok && err.Timeout() {
+				if time.Now().Before(deadline) {
+					break // send again
+				}
+				return response{}, fmt.Errorf("PCP: no response from %s", c.server)
+			} else if err != nil {
+				return response{}, err
+			}
+
+			resp, err := unmarshalResponse(buf[:n])
+			if err == resultUnsuppVersion {
+				return response{}, err
+			}
+			if err != nil || resp.opcode != req.opcode || !matches(resp) {
+				// Not an answer to this request
+				continue
+			}
+			if resp.result != resultSuccess {
+				return resp, resp.result
+			}
+			return resp, nil
+		}
+	}
+}
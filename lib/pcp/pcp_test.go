@@ -0,0 +1,183 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package pcp
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/nat"
+)
+
+var testExternalIP = net.IPv4(192, 0, 2, 1).To4()
+
+// fakeServer answers ANNOUNCE and MAP requests, mapping each new nonce to
+// the next free external port from 40000.
+type fakeServer struct {
+	conn *net.UDPConn
+
+	mut      sync.Mutex
+	result   resultCode
+	ports    map[[nonceSize]byte]uint16
+	next     uint16
+	requests int
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{
+		conn:  conn,
+		ports: make(map[[nonceSize]byte]uint16),
+		next:  40000,
+	}
+	go s.serve()
+	return s
+}
+
+func (s *fakeServer) serve() {
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, addr, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		req, err := unmarshalRequest(buf[:n])
+		if err != nil {
+			continue
+		}
+
+		s.mut.Lock()
+		s.requests++
+		// Error responses echo the request payload.
+		resp := response{opcode: req.opcode, result: s.result, lifetime: req.lifetime, payload: req.payload}
+		if req.opcode == opcodeMap && s.result == resultSuccess {
+			p, err := unmarshalMapPayload(req.payload)
+			if err != nil {
+				s.mut.Unlock()
+				continue
+			}
+			port, ok := s.ports[p.nonce]
+			if !ok {
+				port = s.next
+				s.next++
+				s.ports[p.nonce] = port
+			}
+			p.externalPort = port
+			p.externalIP = testExternalIP
+			resp.payload = p.marshal()
+		}
+		s.mut.Unlock()
+
+		s.conn.WriteToUDP(resp.marshal(), addr)
+	}
+}
+
+func (s *fakeServer) setResult(result resultCode) {
+	s.mut.Lock()
+	s.result = result
+	s.mut.Unlock()
+}
+
+func (s *fakeServer) client(t *testing.T) *Client {
+	c, err := NewClient(s.conn.LocalAddr().(*net.UDPAddr), time.Hour, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestAnnounce(t *testing.T) {
+	s := newFakeServer(t)
+	defer s.conn.Close()
+
+	if err := s.client(t).announce(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAnnounceNoServer(t *testing.T) {
+	s := newFakeServer(t)
+	c := s.client(t)
+	s.conn.Close()
+
+	c.timeout = 100 * time.Millisecond
+	if err := c.announce(); err == nil {
+		t.Fatal("Expected an error without a server")
+	}
+}
+
+func TestAddPortMapping(t *testing.T) {
+	s := newFakeServer(t)
+	defer s.conn.Close()
+	c := s.client(t)
+
+	if _, err := c.GetExternalIPAddress(); err != errNoExternalAddress {
+		t.Error("Expected no external address before mapping, got", err)
+	}
+
+	port, err := c.AddPortMapping(nat.TCP, 22000, 22000, "syncthing", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port != 40000 {
+		t.Errorf("Expected external port 40000, got %d", port)
+	}
+	if ip, err := c.GetExternalIPAddress(); err != nil || !ip.Equal(testExternalIP) {
+		t.Errorf("Expected external address %v, got %v (%v)", testExternalIP, ip, err)
+	}
+
+	// Renewing reuses the nonce, and hence the mapping.
+	if port, err := c.AddPortMapping(nat.TCP, 22000, 22000, "syncthing", 0); err != nil || port != 40000 {
+		t.Errorf("Expected renewal to keep external port 40000, got %d (%v)", port, err)
+	}
+
+	// Another protocol is another mapping.
+	if port, err := c.AddPortMapping(nat.UDP, 22000, 22000, "syncthing", 0); err != nil || port != 40001 {
+		t.Errorf("Expected external port 40001, got %d (%v)", port, err)
+	}
+}
+
+func TestAddPortMappingRefused(t *testing.T) {
+	s := newFakeServer(t)
+	defer s.conn.Close()
+	c := s.client(t)
+
+	s.setResult(resultNotAuthorized)
+	if _, err := c.AddPortMapping(nat.TCP, 22000, 22000, "syncthing", time.Minute); err != resultNotAuthorized {
+		t.Fatal("Expected not authorized error, got", err)
+	}
+
+	s.mut.Lock()
+	requests := s.requests
+	s.mut.Unlock()
+	if requests != 1 {
+		t.Errorf("Expected an error not to be retried, got %d requests", requests)
+	}
+}
+
+func TestIsGlobalIPv6(t *testing.T) {
+	cases := []struct {
+		ip     string
+		global bool
+	}{
+		{"2001:db8::1", true},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"::1", false},
+		{"192.0.2.1", false},
+	}
+	for _, tc := range cases {
+		if global := isGlobalIPv6(net.ParseIP(tc.ip)); global != tc.global {
+			t.Errorf("isGlobalIPv6(%s) = %v, expected %v", tc.ip, global, tc.global)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package pcp
+
+import (
+	"bufio"
+	"encoding/hex"
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+// discoverIPv6Router returns the address of the default IPv6 router and the
+// interface it is reached through, from the routing table of the kernel.
+func discoverIPv6Router() (net.IP, string, error) {
+	fd, err := os.Open("/proc/net/ipv6_route")
+	if err != nil {
+		return nil, "", err
+	}
+	defer fd.Close()
+	return parseIPv6Routes(fd)
+}
+
+// parseIPv6Routes finds the default route in the format of
+// /proc/net/ipv6_route, where each line has the destination, its prefix
+// length, the source, its prefix length, the next hop, the metric, three
+// counters and flags, and the interface name.
+func parseIPv6Routes(r io.Reader) (net.IP, string, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 10 {
+			continue
+		}
+		dest, destLen, nextHop, iface := fields[0], fields[1], fields[4], fields[9]
+		if destLen != "00" || strings.Trim(dest, "0") != "" || iface == "lo" {
+			continue
+		}
+		ip, err := hex.DecodeString(nextHop)
+		if err != nil || len(ip) != net.IPv6len || net.IP(ip).IsUnspecified() {
+			continue
+		}
+		return ip, iface, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, "", err
+	}
+	return nil, "", errNoIPv6Router
+}
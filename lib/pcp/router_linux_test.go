@@ -0,0 +1,34 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package pcp
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestParseIPv6Routes(t *testing.T) {
+	routes := `20010db8000000000000000000000000 40 00000000000000000000000000000000 00 00000000000000000000000000000000 00000100 00000001 00000000 00000001     eth0
+fe800000000000000000000000000000 40 00000000000000000000000000000000 00 00000000000000000000000000000000 00000100 00000001 00000000 00000001     eth0
+00000000000000000000000000000000 00 00000000000000000000000000000000 00 fe800000000000000000000000000001 00000400 00000001 00000000 00000003     eth0
+00000000000000000000000000000000 00 00000000000000000000000000000000 00 00000000000000000000000000000000 ffffffff 00000001 00000000 00200200       lo
+`
+	ip, iface, err := parseIPv6Routes(strings.NewReader(routes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ip.Equal(net.ParseIP("fe80::1")) || iface != "eth0" {
+		t.Errorf("Expected router fe80::1 on eth0, got %v on %s", ip, iface)
+	}
+
+	// Without a default route there is no router
+	noDefault := strings.SplitN(routes, "\n", 3)
+	if _, _, err := parseIPv6Routes(strings.NewReader(noDefault[0] + "\n" + noDefault[1])); err != errNoIPv6Router {
+		t.Error("Expected no router without a default route, got", err)
+	}
+}
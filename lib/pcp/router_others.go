@@ -0,0 +1,17 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build !linux
+
+package pcp
+
+import "net"
+
+// discoverIPv6Router is only implemented on Linux, so elsewhere ports are
+// only mapped over IPv4.
+func discoverIPv6Router() (net.IP, string, error) {
+	return nil, "", errNoIPv6Router
+}
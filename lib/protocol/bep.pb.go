@@ -59,7 +59,7 @@ func (x MessageType) String() string {
 	return proto.EnumName(MessageType_name, int32(x))
 }
 func (MessageType) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_bep_83d45b003aedf660, []int{0}
+	return fileDescriptor_bep_3fa15a2c6d14c675, []int{0}
 }
 
 type MessageCompression int32
@@ -67,22 +67,25 @@ type MessageCompression int32
 const (
 	MessageCompressionNone MessageCompression = 0
 	MessageCompressionLZ4  MessageCompression = 1
+	MessageCompressionZstd MessageCompression = 2
 )
 
 var MessageCompression_name = map[int32]string{
 	0: "NONE",
 	1: "LZ4",
+	2: "ZSTD",
 }
 var MessageCompression_value = map[string]int32{
 	"NONE": 0,
 	"LZ4":  1,
+	"ZSTD": 2,
 }
 
 func (x MessageCompression) String() string {
 	return proto.EnumName(MessageCompression_name, int32(x))
 }
 func (MessageCompression) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_bep_83d45b003aedf660, []int{1}
+	return fileDescriptor_bep_3fa15a2c6d14c675, []int{1}
 }
 
 type Compression int32
@@ -108,7 +111,7 @@ func (x Compression) String() string {
 	return proto.EnumName(Compression_name, int32(x))
 }
 func (Compression) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_bep_83d45b003aedf660, []int{2}
+	return fileDescriptor_bep_3fa15a2c6d14c675, []int{2}
 }
 
 type FileInfoType int32
@@ -140,7 +143,30 @@ func (x FileInfoType) String() string {
 	return proto.EnumName(FileInfoType_name, int32(x))
 }
 func (FileInfoType) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_bep_83d45b003aedf660, []int{3}
+	return fileDescriptor_bep_3fa15a2c6d14c675, []int{3}
+}
+
+type BlockChunking int32
+
+const (
+	BlockChunkingFixed          BlockChunking = 0
+	BlockChunkingContentDefined BlockChunking = 1
+)
+
+var BlockChunking_name = map[int32]string{
+	0: "FIXED",
+	1: "CONTENT_DEFINED",
+}
+var BlockChunking_value = map[string]int32{
+	"FIXED":           0,
+	"CONTENT_DEFINED": 1,
+}
+
+func (x BlockChunking) String() string {
+	return proto.EnumName(BlockChunking_name, int32(x))
+}
+func (BlockChunking) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_bep_3fa15a2c6d14c675, []int{4}
 }
 
 type ErrorCode int32
@@ -169,7 +195,7 @@ func (x ErrorCode) String() string {
 	return proto.EnumName(ErrorCode_name, int32(x))
 }
 func (ErrorCode) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_bep_83d45b003aedf660, []int{4}
+	return fileDescriptor_bep_3fa15a2c6d14c675, []int{5}
 }
 
 type FileDownloadProgressUpdateType int32
@@ -192,20 +218,23 @@ func (x FileDownloadProgressUpdateType) String() string {
 	return proto.EnumName(FileDownloadProgressUpdateType_name, int32(x))
 }
 func (FileDownloadProgressUpdateType) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_bep_83d45b003aedf660, []int{5}
+	return fileDescriptor_bep_3fa15a2c6d14c675, []int{6}
 }
 
 type Hello struct {
-	DeviceName    string `protobuf:"bytes,1,opt,name=device_name,json=deviceName,proto3" json:"device_name,omitempty"`
-	ClientName    string `protobuf:"bytes,2,opt,name=client_name,json=clientName,proto3" json:"client_name,omitempty"`
-	ClientVersion string `protobuf:"bytes,3,opt,name=client_version,json=clientVersion,proto3" json:"client_version,omitempty"`
+	DeviceName            string               `protobuf:"bytes,1,opt,name=device_name,json=deviceName,proto3" json:"device_name,omitempty"`
+	ClientName            string               `protobuf:"bytes,2,opt,name=client_name,json=clientName,proto3" json:"client_name,omitempty"`
+	ClientVersion         string               `protobuf:"bytes,3,opt,name=client_version,json=clientVersion,proto3" json:"client_version,omitempty"`
+	CompressionAlgorithms []MessageCompression `protobuf:"varint,4,rep,packed,name=compression_algorithms,json=compressionAlgorithms,proto3,enum=protocol.MessageCompression" json:"compression_algorithms,omitempty"`
+	NumConnections        int32                `protobuf:"varint,5,opt,name=num_connections,json=numConnections,proto3" json:"num_connections,omitempty"`
+	Secondary             bool                 `protobuf:"varint,6,opt,name=secondary,proto3" json:"secondary,omitempty"`
 }
 
 func (m *Hello) Reset()         { *m = Hello{} }
 func (m *Hello) String() string { return proto.CompactTextString(m) }
 func (*Hello) ProtoMessage()    {}
 func (*Hello) Descriptor() ([]byte, []int) {
-	return fileDescriptor_bep_83d45b003aedf660, []int{0}
+	return fileDescriptor_bep_3fa15a2c6d14c675, []int{0}
 }
 func (m *Hello) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -243,7 +272,7 @@ func (m *Header) Reset()         { *m = Header{} }
 func (m *Header) String() string { return proto.CompactTextString(m) }
 func (*Header) ProtoMessage()    {}
 func (*Header) Descriptor() ([]byte, []int) {
-	return fileDescriptor_bep_83d45b003aedf660, []int{1}
+	return fileDescriptor_bep_3fa15a2c6d14c675, []int{1}
 }
 func (m *Header) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -280,7 +309,7 @@ func (m *ClusterConfig) Reset()         { *m = ClusterConfig{} }
 func (m *ClusterConfig) String() string { return proto.CompactTextString(m) }
 func (*ClusterConfig) ProtoMessage()    {}
 func (*ClusterConfig) Descriptor() ([]byte, []int) {
-	return fileDescriptor_bep_83d45b003aedf660, []int{2}
+	return fileDescriptor_bep_3fa15a2c6d14c675, []int{2}
 }
 func (m *ClusterConfig) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -310,21 +339,23 @@ func (m *ClusterConfig) XXX_DiscardUnknown() {
 var xxx_messageInfo_ClusterConfig proto.InternalMessageInfo
 
 type Folder struct {
-	ID                 string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Label              string   `protobuf:"bytes,2,opt,name=label,proto3" json:"label,omitempty"`
-	ReadOnly           bool     `protobuf:"varint,3,opt,name=read_only,json=readOnly,proto3" json:"read_only,omitempty"`
-	IgnorePermissions  bool     `protobuf:"varint,4,opt,name=ignore_permissions,json=ignorePermissions,proto3" json:"ignore_permissions,omitempty"`
-	IgnoreDelete       bool     `protobuf:"varint,5,opt,name=ignore_delete,json=ignoreDelete,proto3" json:"ignore_delete,omitempty"`
-	DisableTempIndexes bool     `protobuf:"varint,6,opt,name=disable_temp_indexes,json=disableTempIndexes,proto3" json:"disable_temp_indexes,omitempty"`
-	Paused             bool     `protobuf:"varint,7,opt,name=paused,proto3" json:"paused,omitempty"`
-	Devices            []Device `protobuf:"bytes,16,rep,name=devices,proto3" json:"devices"`
+	ID                     string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Label                  string   `protobuf:"bytes,2,opt,name=label,proto3" json:"label,omitempty"`
+	ReadOnly               bool     `protobuf:"varint,3,opt,name=read_only,json=readOnly,proto3" json:"read_only,omitempty"`
+	IgnorePermissions      bool     `protobuf:"varint,4,opt,name=ignore_permissions,json=ignorePermissions,proto3" json:"ignore_permissions,omitempty"`
+	IgnoreDelete           bool     `protobuf:"varint,5,opt,name=ignore_delete,json=ignoreDelete,proto3" json:"ignore_delete,omitempty"`
+	DisableTempIndexes     bool     `protobuf:"varint,6,opt,name=disable_temp_indexes,json=disableTempIndexes,proto3" json:"disable_temp_indexes,omitempty"`
+	Paused                 bool     `protobuf:"varint,7,opt,name=paused,proto3" json:"paused,omitempty"`
+	ContentDefinedChunking bool     `protobuf:"varint,8,opt,name=content_defined_chunking,json=contentDefinedChunking,proto3" json:"content_defined_chunking,omitempty"`
+	Encrypted              bool     `protobuf:"varint,9,opt,name=encrypted,proto3" json:"encrypted,omitempty"`
+	Devices                []Device `protobuf:"bytes,16,rep,name=devices,proto3" json:"devices"`
 }
 
 func (m *Folder) Reset()         { *m = Folder{} }
 func (m *Folder) String() string { return proto.CompactTextString(m) }
 func (*Folder) ProtoMessage()    {}
 func (*Folder) Descriptor() ([]byte, []int) {
-	return fileDescriptor_bep_83d45b003aedf660, []int{3}
+	return fileDescriptor_bep_3fa15a2c6d14c675, []int{3}
 }
 func (m *Folder) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -354,22 +385,23 @@ func (m *Folder) XXX_DiscardUnknown() {
 var xxx_messageInfo_Folder proto.InternalMessageInfo
 
 type Device struct {
-	ID                       DeviceID    `protobuf:"bytes,1,opt,name=id,proto3,customtype=DeviceID" json:"id"`
-	Name                     string      `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
-	Addresses                []string    `protobuf:"bytes,3,rep,name=addresses,proto3" json:"addresses,omitempty"`
-	Compression              Compression `protobuf:"varint,4,opt,name=compression,proto3,enum=protocol.Compression" json:"compression,omitempty"`
-	CertName                 string      `protobuf:"bytes,5,opt,name=cert_name,json=certName,proto3" json:"cert_name,omitempty"`
-	MaxSequence              int64       `protobuf:"varint,6,opt,name=max_sequence,json=maxSequence,proto3" json:"max_sequence,omitempty"`
-	Introducer               bool        `protobuf:"varint,7,opt,name=introducer,proto3" json:"introducer,omitempty"`
-	IndexID                  IndexID     `protobuf:"varint,8,opt,name=index_id,json=indexId,proto3,customtype=IndexID" json:"index_id"`
-	SkipIntroductionRemovals bool        `protobuf:"varint,9,opt,name=skip_introduction_removals,json=skipIntroductionRemovals,proto3" json:"skip_introduction_removals,omitempty"`
+	ID                       DeviceID           `protobuf:"bytes,1,opt,name=id,proto3,customtype=DeviceID" json:"id"`
+	Name                     string             `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Addresses                []string           `protobuf:"bytes,3,rep,name=addresses,proto3" json:"addresses,omitempty"`
+	Compression              Compression        `protobuf:"varint,4,opt,name=compression,proto3,enum=protocol.Compression" json:"compression,omitempty"`
+	CertName                 string             `protobuf:"bytes,5,opt,name=cert_name,json=certName,proto3" json:"cert_name,omitempty"`
+	MaxSequence              int64              `protobuf:"varint,6,opt,name=max_sequence,json=maxSequence,proto3" json:"max_sequence,omitempty"`
+	Introducer               bool               `protobuf:"varint,7,opt,name=introducer,proto3" json:"introducer,omitempty"`
+	IndexID                  IndexID            `protobuf:"varint,8,opt,name=index_id,json=indexId,proto3,customtype=IndexID" json:"index_id"`
+	SkipIntroductionRemovals bool               `protobuf:"varint,9,opt,name=skip_introduction_removals,json=skipIntroductionRemovals,proto3" json:"skip_introduction_removals,omitempty"`
+	CompressionAlgorithm     MessageCompression `protobuf:"varint,10,opt,name=compression_algorithm,json=compressionAlgorithm,proto3,enum=protocol.MessageCompression" json:"compression_algorithm,omitempty"`
 }
 
 func (m *Device) Reset()         { *m = Device{} }
 func (m *Device) String() string { return proto.CompactTextString(m) }
 func (*Device) ProtoMessage()    {}
 func (*Device) Descriptor() ([]byte, []int) {
-	return fileDescriptor_bep_83d45b003aedf660, []int{4}
+	return fileDescriptor_bep_3fa15a2c6d14c675, []int{4}
 }
 func (m *Device) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -407,7 +439,7 @@ func (m *Index) Reset()         { *m = Index{} }
 func (m *Index) String() string { return proto.CompactTextString(m) }
 func (*Index) ProtoMessage()    {}
 func (*Index) Descriptor() ([]byte, []int) {
-	return fileDescriptor_bep_83d45b003aedf660, []int{5}
+	return fileDescriptor_bep_3fa15a2c6d14c675, []int{5}
 }
 func (m *Index) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -445,7 +477,7 @@ func (m *IndexUpdate) Reset()         { *m = IndexUpdate{} }
 func (m *IndexUpdate) String() string { return proto.CompactTextString(m) }
 func (*IndexUpdate) ProtoMessage()    {}
 func (*IndexUpdate) Descriptor() ([]byte, []int) {
-	return fileDescriptor_bep_83d45b003aedf660, []int{6}
+	return fileDescriptor_bep_3fa15a2c6d14c675, []int{6}
 }
 func (m *IndexUpdate) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -475,21 +507,25 @@ func (m *IndexUpdate) XXX_DiscardUnknown() {
 var xxx_messageInfo_IndexUpdate proto.InternalMessageInfo
 
 type FileInfo struct {
-	Name          string       `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Type          FileInfoType `protobuf:"varint,2,opt,name=type,proto3,enum=protocol.FileInfoType" json:"type,omitempty"`
-	Size          int64        `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
-	Permissions   uint32       `protobuf:"varint,4,opt,name=permissions,proto3" json:"permissions,omitempty"`
-	ModifiedS     int64        `protobuf:"varint,5,opt,name=modified_s,json=modifiedS,proto3" json:"modified_s,omitempty"`
-	ModifiedNs    int32        `protobuf:"varint,11,opt,name=modified_ns,json=modifiedNs,proto3" json:"modified_ns,omitempty"`
-	ModifiedBy    ShortID      `protobuf:"varint,12,opt,name=modified_by,json=modifiedBy,proto3,customtype=ShortID" json:"modified_by"`
-	Deleted       bool         `protobuf:"varint,6,opt,name=deleted,proto3" json:"deleted,omitempty"`
-	RawInvalid    bool         `protobuf:"varint,7,opt,name=invalid,proto3" json:"invalid,omitempty"`
-	NoPermissions bool         `protobuf:"varint,8,opt,name=no_permissions,json=noPermissions,proto3" json:"no_permissions,omitempty"`
-	Version       Vector       `protobuf:"bytes,9,opt,name=version,proto3" json:"version"`
-	Sequence      int64        `protobuf:"varint,10,opt,name=sequence,proto3" json:"sequence,omitempty"`
-	RawBlockSize  int32        `protobuf:"varint,13,opt,name=block_size,json=blockSize,proto3" json:"block_size,omitempty"`
-	Blocks        []BlockInfo  `protobuf:"bytes,16,rep,name=Blocks,proto3" json:"Blocks"`
-	SymlinkTarget string       `protobuf:"bytes,17,opt,name=symlink_target,json=symlinkTarget,proto3" json:"symlink_target,omitempty"`
+	Name          string        `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Type          FileInfoType  `protobuf:"varint,2,opt,name=type,proto3,enum=protocol.FileInfoType" json:"type,omitempty"`
+	Size          int64         `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
+	Permissions   uint32        `protobuf:"varint,4,opt,name=permissions,proto3" json:"permissions,omitempty"`
+	ModifiedS     int64         `protobuf:"varint,5,opt,name=modified_s,json=modifiedS,proto3" json:"modified_s,omitempty"`
+	ModifiedNs    int32         `protobuf:"varint,11,opt,name=modified_ns,json=modifiedNs,proto3" json:"modified_ns,omitempty"`
+	ModifiedBy    ShortID       `protobuf:"varint,12,opt,name=modified_by,json=modifiedBy,proto3,customtype=ShortID" json:"modified_by"`
+	Deleted       bool          `protobuf:"varint,6,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	RawInvalid    bool          `protobuf:"varint,7,opt,name=invalid,proto3" json:"invalid,omitempty"`
+	NoPermissions bool          `protobuf:"varint,8,opt,name=no_permissions,json=noPermissions,proto3" json:"no_permissions,omitempty"`
+	Version       Vector        `protobuf:"bytes,9,opt,name=version,proto3" json:"version"`
+	Sequence      int64         `protobuf:"varint,10,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	RawBlockSize  int32         `protobuf:"varint,13,opt,name=block_size,json=blockSize,proto3" json:"block_size,omitempty"`
+	Blocks        []BlockInfo   `protobuf:"bytes,16,rep,name=Blocks,proto3" json:"Blocks"`
+	SymlinkTarget string        `protobuf:"bytes,17,opt,name=symlink_target,json=symlinkTarget,proto3" json:"symlink_target,omitempty"`
+	Chunking      BlockChunking `protobuf:"varint,18,opt,name=chunking,proto3,enum=protocol.BlockChunking" json:"chunking,omitempty"`
+	Encrypted     []byte        `protobuf:"bytes,19,opt,name=encrypted,proto3" json:"encrypted,omitempty"`
+	Xattrs        *Xattrs       `protobuf:"bytes,20,opt,name=xattrs,proto3" json:"xattrs,omitempty"`
+	Ownership     *Ownership    `protobuf:"bytes,21,opt,name=ownership,proto3" json:"ownership,omitempty"`
 	// The local_flags fields stores flags that are relevant to the local
 	// host only. It is not part of the protocol, doesn't get sent or
 	// received (we make sure to zero it), nonetheless we need it on our
@@ -500,7 +536,7 @@ type FileInfo struct {
 func (m *FileInfo) Reset()      { *m = FileInfo{} }
 func (*FileInfo) ProtoMessage() {}
 func (*FileInfo) Descriptor() ([]byte, []int) {
-	return fileDescriptor_bep_83d45b003aedf660, []int{7}
+	return fileDescriptor_bep_3fa15a2c6d14c675, []int{7}
 }
 func (m *FileInfo) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -529,6 +565,125 @@ func (m *FileInfo) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_FileInfo proto.InternalMessageInfo
 
+// The extended attributes of a file or directory. When not set they are
+// unknown, as opposed to there being none.
+type Xattrs struct {
+	Entries []Xattr `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries"`
+}
+
+func (m *Xattrs) Reset()         { *m = Xattrs{} }
+func (m *Xattrs) String() string { return proto.CompactTextString(m) }
+func (*Xattrs) ProtoMessage()    {}
+func (*Xattrs) Descriptor() ([]byte, []int) {
+	return fileDescriptor_bep_3fa15a2c6d14c675, []int{8}
+}
+func (m *Xattrs) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Xattrs) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Xattrs.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (dst *Xattrs) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Xattrs.Merge(dst, src)
+}
+func (m *Xattrs) XXX_Size() int {
+	return m.ProtoSize()
+}
+func (m *Xattrs) XXX_DiscardUnknown() {
+	xxx_messageInfo_Xattrs.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Xattrs proto.InternalMessageInfo
+
+type Xattr struct {
+	Name  string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Value []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (m *Xattr) Reset()         { *m = Xattr{} }
+func (m *Xattr) String() string { return proto.CompactTextString(m) }
+func (*Xattr) ProtoMessage()    {}
+func (*Xattr) Descriptor() ([]byte, []int) {
+	return fileDescriptor_bep_3fa15a2c6d14c675, []int{9}
+}
+func (m *Xattr) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Xattr) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Xattr.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (dst *Xattr) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Xattr.Merge(dst, src)
+}
+func (m *Xattr) XXX_Size() int {
+	return m.ProtoSize()
+}
+func (m *Xattr) XXX_DiscardUnknown() {
+	xxx_messageInfo_Xattr.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Xattr proto.InternalMessageInfo
+
+// The owner and group of a file or directory, by name where they have one
+// and by numeric id. When not set they are unknown.
+type Ownership struct {
+	OwnerName string `protobuf:"bytes,1,opt,name=owner_name,json=ownerName,proto3" json:"owner_name,omitempty"`
+	GroupName string `protobuf:"bytes,2,opt,name=group_name,json=groupName,proto3" json:"group_name,omitempty"`
+	Uid       int32  `protobuf:"varint,3,opt,name=uid,proto3" json:"uid,omitempty"`
+	Gid       int32  `protobuf:"varint,4,opt,name=gid,proto3" json:"gid,omitempty"`
+}
+
+func (m *Ownership) Reset()         { *m = Ownership{} }
+func (m *Ownership) String() string { return proto.CompactTextString(m) }
+func (*Ownership) ProtoMessage()    {}
+func (*Ownership) Descriptor() ([]byte, []int) {
+	return fileDescriptor_bep_3fa15a2c6d14c675, []int{10}
+}
+func (m *Ownership) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Ownership) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Ownership.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (dst *Ownership) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Ownership.Merge(dst, src)
+}
+func (m *Ownership) XXX_Size() int {
+	return m.ProtoSize()
+}
+func (m *Ownership) XXX_DiscardUnknown() {
+	xxx_messageInfo_Ownership.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Ownership proto.InternalMessageInfo
+
 type BlockInfo struct {
 	Offset   int64  `protobuf:"varint,1,opt,name=offset,proto3" json:"offset,omitempty"`
 	Size     int32  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
@@ -539,7 +694,7 @@ type BlockInfo struct {
 func (m *BlockInfo) Reset()      { *m = BlockInfo{} }
 func (*BlockInfo) ProtoMessage() {}
 func (*BlockInfo) Descriptor() ([]byte, []int) {
-	return fileDescriptor_bep_83d45b003aedf660, []int{8}
+	return fileDescriptor_bep_3fa15a2c6d14c675, []int{11}
 }
 func (m *BlockInfo) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -576,7 +731,7 @@ func (m *Vector) Reset()         { *m = Vector{} }
 func (m *Vector) String() string { return proto.CompactTextString(m) }
 func (*Vector) ProtoMessage()    {}
 func (*Vector) Descriptor() ([]byte, []int) {
-	return fileDescriptor_bep_83d45b003aedf660, []int{9}
+	return fileDescriptor_bep_3fa15a2c6d14c675, []int{12}
 }
 func (m *Vector) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -614,7 +769,7 @@ func (m *Counter) Reset()         { *m = Counter{} }
 func (m *Counter) String() string { return proto.CompactTextString(m) }
 func (*Counter) ProtoMessage()    {}
 func (*Counter) Descriptor() ([]byte, []int) {
-	return fileDescriptor_bep_83d45b003aedf660, []int{10}
+	return fileDescriptor_bep_3fa15a2c6d14c675, []int{13}
 }
 func (m *Counter) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -658,7 +813,7 @@ func (m *Request) Reset()         { *m = Request{} }
 func (m *Request) String() string { return proto.CompactTextString(m) }
 func (*Request) ProtoMessage()    {}
 func (*Request) Descriptor() ([]byte, []int) {
-	return fileDescriptor_bep_83d45b003aedf660, []int{11}
+	return fileDescriptor_bep_3fa15a2c6d14c675, []int{14}
 }
 func (m *Request) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -697,7 +852,7 @@ func (m *Response) Reset()         { *m = Response{} }
 func (m *Response) String() string { return proto.CompactTextString(m) }
 func (*Response) ProtoMessage()    {}
 func (*Response) Descriptor() ([]byte, []int) {
-	return fileDescriptor_bep_83d45b003aedf660, []int{12}
+	return fileDescriptor_bep_3fa15a2c6d14c675, []int{15}
 }
 func (m *Response) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -735,7 +890,7 @@ func (m *DownloadProgress) Reset()         { *m = DownloadProgress{} }
 func (m *DownloadProgress) String() string { return proto.CompactTextString(m) }
 func (*DownloadProgress) ProtoMessage()    {}
 func (*DownloadProgress) Descriptor() ([]byte, []int) {
-	return fileDescriptor_bep_83d45b003aedf660, []int{13}
+	return fileDescriptor_bep_3fa15a2c6d14c675, []int{16}
 }
 func (m *DownloadProgress) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -775,7 +930,7 @@ func (m *FileDownloadProgressUpdate) Reset()         { *m = FileDownloadProgress
 func (m *FileDownloadProgressUpdate) String() string { return proto.CompactTextString(m) }
 func (*FileDownloadProgressUpdate) ProtoMessage()    {}
 func (*FileDownloadProgressUpdate) Descriptor() ([]byte, []int) {
-	return fileDescriptor_bep_83d45b003aedf660, []int{14}
+	return fileDescriptor_bep_3fa15a2c6d14c675, []int{17}
 }
 func (m *FileDownloadProgressUpdate) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -811,7 +966,7 @@ func (m *Ping) Reset()         { *m = Ping{} }
 func (m *Ping) String() string { return proto.CompactTextString(m) }
 func (*Ping) ProtoMessage()    {}
 func (*Ping) Descriptor() ([]byte, []int) {
-	return fileDescriptor_bep_83d45b003aedf660, []int{15}
+	return fileDescriptor_bep_3fa15a2c6d14c675, []int{18}
 }
 func (m *Ping) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -848,7 +1003,7 @@ func (m *Close) Reset()         { *m = Close{} }
 func (m *Close) String() string { return proto.CompactTextString(m) }
 func (*Close) ProtoMessage()    {}
 func (*Close) Descriptor() ([]byte, []int) {
-	return fileDescriptor_bep_83d45b003aedf660, []int{16}
+	return fileDescriptor_bep_3fa15a2c6d14c675, []int{19}
 }
 func (m *Close) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -886,6 +1041,9 @@ func init() {
 	proto.RegisterType((*Index)(nil), "protocol.Index")
 	proto.RegisterType((*IndexUpdate)(nil), "protocol.IndexUpdate")
 	proto.RegisterType((*FileInfo)(nil), "protocol.FileInfo")
+	proto.RegisterType((*Xattrs)(nil), "protocol.Xattrs")
+	proto.RegisterType((*Xattr)(nil), "protocol.Xattr")
+	proto.RegisterType((*Ownership)(nil), "protocol.Ownership")
 	proto.RegisterType((*BlockInfo)(nil), "protocol.BlockInfo")
 	proto.RegisterType((*Vector)(nil), "protocol.Vector")
 	proto.RegisterType((*Counter)(nil), "protocol.Counter")
@@ -899,6 +1057,7 @@ func init() {
 	proto.RegisterEnum("protocol.MessageCompression", MessageCompression_name, MessageCompression_value)
 	proto.RegisterEnum("protocol.Compression", Compression_name, Compression_value)
 	proto.RegisterEnum("protocol.FileInfoType", FileInfoType_name, FileInfoType_value)
+	proto.RegisterEnum("protocol.BlockChunking", BlockChunking_name, BlockChunking_value)
 	proto.RegisterEnum("protocol.ErrorCode", ErrorCode_name, ErrorCode_value)
 	proto.RegisterEnum("protocol.FileDownloadProgressUpdateType", FileDownloadProgressUpdateType_name, FileDownloadProgressUpdateType_value)
 }
@@ -935,6 +1094,38 @@ func (m *Hello) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintBep(dAtA, i, uint64(len(m.ClientVersion)))
 		i += copy(dAtA[i:], m.ClientVersion)
 	}
+	if len(m.CompressionAlgorithms) > 0 {
+		dAtA2 := make([]byte, len(m.CompressionAlgorithms)*10)
+		var j1 int
+		for _, num := range m.CompressionAlgorithms {
+			for num >= 1<<7 {
+				dAtA2[j1] = uint8(uint64(num)&0x7f | 0x80)
+				num >>= 7
+				j1++
+			}
+			dAtA2[j1] = uint8(num)
+			j1++
+		}
+		dAtA[i] = 0x22
+		i++
+		i = encodeVarintBep(dAtA, i, uint64(j1))
+		i += copy(dAtA[i:], dAtA2[:j1])
+	}
+	if m.NumConnections != 0 {
+		dAtA[i] = 0x28
+		i++
+		i = encodeVarintBep(dAtA, i, uint64(m.NumConnections))
+	}
+	if m.Secondary {
+		dAtA[i] = 0x30
+		i++
+		if m.Secondary {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
 	return i, nil
 }
 
@@ -1073,6 +1264,26 @@ func (m *Folder) MarshalTo(dAtA []byte) (int, error) {
 		}
 		i++
 	}
+	if m.ContentDefinedChunking {
+		dAtA[i] = 0x40
+		i++
+		if m.ContentDefinedChunking {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	if m.Encrypted {
+		dAtA[i] = 0x48
+		i++
+		if m.Encrypted {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
 	if len(m.Devices) > 0 {
 		for _, msg := range m.Devices {
 			dAtA[i] = 0x82
@@ -1108,11 +1319,11 @@ func (m *Device) MarshalTo(dAtA []byte) (int, error) {
 	dAtA[i] = 0xa
 	i++
 	i = encodeVarintBep(dAtA, i, uint64(m.ID.ProtoSize()))
-	n1, err := m.ID.MarshalTo(dAtA[i:])
+	n3, err := m.ID.MarshalTo(dAtA[i:])
 	if err != nil {
 		return 0, err
 	}
-	i += n1
+	i += n3
 	if len(m.Name) > 0 {
 		dAtA[i] = 0x12
 		i++
@@ -1175,6 +1386,11 @@ func (m *Device) MarshalTo(dAtA []byte) (int, error) {
 		}
 		i++
 	}
+	if m.CompressionAlgorithm != 0 {
+		dAtA[i] = 0x50
+		i++
+		i = encodeVarintBep(dAtA, i, uint64(m.CompressionAlgorithm))
+	}
 	return i, nil
 }
 
@@ -1324,11 +1540,11 @@ func (m *FileInfo) MarshalTo(dAtA []byte) (int, error) {
 	dAtA[i] = 0x4a
 	i++
 	i = encodeVarintBep(dAtA, i, uint64(m.Version.ProtoSize()))
-	n2, err := m.Version.MarshalTo(dAtA[i:])
+	n4, err := m.Version.MarshalTo(dAtA[i:])
 	if err != nil {
 		return 0, err
 	}
-	i += n2
+	i += n4
 	if m.Sequence != 0 {
 		dAtA[i] = 0x50
 		i++
@@ -1371,6 +1587,45 @@ func (m *FileInfo) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintBep(dAtA, i, uint64(len(m.SymlinkTarget)))
 		i += copy(dAtA[i:], m.SymlinkTarget)
 	}
+	if m.Chunking != 0 {
+		dAtA[i] = 0x90
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintBep(dAtA, i, uint64(m.Chunking))
+	}
+	if len(m.Encrypted) > 0 {
+		dAtA[i] = 0x9a
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintBep(dAtA, i, uint64(len(m.Encrypted)))
+		i += copy(dAtA[i:], m.Encrypted)
+	}
+	if m.Xattrs != nil {
+		dAtA[i] = 0xa2
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintBep(dAtA, i, uint64(m.Xattrs.ProtoSize()))
+		n5, err := m.Xattrs.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n5
+	}
+	if m.Ownership != nil {
+		dAtA[i] = 0xaa
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintBep(dAtA, i, uint64(m.Ownership.ProtoSize()))
+		n6, err := m.Ownership.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n6
+	}
 	if m.LocalFlags != 0 {
 		dAtA[i] = 0xc0
 		i++
@@ -1381,6 +1636,106 @@ func (m *FileInfo) MarshalTo(dAtA []byte) (int, error) {
 	return i, nil
 }
 
+func (m *Xattrs) Marshal() (dAtA []byte, err error) {
+	size := m.ProtoSize()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Xattrs) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if len(m.Entries) > 0 {
+		for _, msg := range m.Entries {
+			dAtA[i] = 0xa
+			i++
+			i = encodeVarintBep(dAtA, i, uint64(msg.ProtoSize()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	return i, nil
+}
+
+func (m *Xattr) Marshal() (dAtA []byte, err error) {
+	size := m.ProtoSize()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Xattr) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if len(m.Name) > 0 {
+		dAtA[i] = 0xa
+		i++
+		i = encodeVarintBep(dAtA, i, uint64(len(m.Name)))
+		i += copy(dAtA[i:], m.Name)
+	}
+	if len(m.Value) > 0 {
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintBep(dAtA, i, uint64(len(m.Value)))
+		i += copy(dAtA[i:], m.Value)
+	}
+	return i, nil
+}
+
+func (m *Ownership) Marshal() (dAtA []byte, err error) {
+	size := m.ProtoSize()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Ownership) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if len(m.OwnerName) > 0 {
+		dAtA[i] = 0xa
+		i++
+		i = encodeVarintBep(dAtA, i, uint64(len(m.OwnerName)))
+		i += copy(dAtA[i:], m.OwnerName)
+	}
+	if len(m.GroupName) > 0 {
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintBep(dAtA, i, uint64(len(m.GroupName)))
+		i += copy(dAtA[i:], m.GroupName)
+	}
+	if m.Uid != 0 {
+		dAtA[i] = 0x18
+		i++
+		i = encodeVarintBep(dAtA, i, uint64(m.Uid))
+	}
+	if m.Gid != 0 {
+		dAtA[i] = 0x20
+		i++
+		i = encodeVarintBep(dAtA, i, uint64(m.Gid))
+	}
+	return i, nil
+}
+
 func (m *BlockInfo) Marshal() (dAtA []byte, err error) {
 	size := m.ProtoSize()
 	dAtA = make([]byte, size)
@@ -1643,11 +1998,11 @@ func (m *FileDownloadProgressUpdate) MarshalTo(dAtA []byte) (int, error) {
 	dAtA[i] = 0x1a
 	i++
 	i = encodeVarintBep(dAtA, i, uint64(m.Version.ProtoSize()))
-	n3, err := m.Version.MarshalTo(dAtA[i:])
+	n7, err := m.Version.MarshalTo(dAtA[i:])
 	if err != nil {
 		return 0, err
 	}
-	i += n3
+	i += n7
 	if len(m.BlockIndexes) > 0 {
 		for _, num := range m.BlockIndexes {
 			dAtA[i] = 0x20
@@ -1727,6 +2082,19 @@ func (m *Hello) ProtoSize() (n int) {
 	if l > 0 {
 		n += 1 + l + sovBep(uint64(l))
 	}
+	if len(m.CompressionAlgorithms) > 0 {
+		l = 0
+		for _, e := range m.CompressionAlgorithms {
+			l += sovBep(uint64(e))
+		}
+		n += 1 + sovBep(uint64(l)) + l
+	}
+	if m.NumConnections != 0 {
+		n += 1 + sovBep(uint64(m.NumConnections))
+	}
+	if m.Secondary {
+		n += 2
+	}
 	return n
 }
 
@@ -1789,6 +2157,12 @@ func (m *Folder) ProtoSize() (n int) {
 	if m.Paused {
 		n += 2
 	}
+	if m.ContentDefinedChunking {
+		n += 2
+	}
+	if m.Encrypted {
+		n += 2
+	}
 	if len(m.Devices) > 0 {
 		for _, e := range m.Devices {
 			l = e.ProtoSize()
@@ -1835,6 +2209,9 @@ func (m *Device) ProtoSize() (n int) {
 	if m.SkipIntroductionRemovals {
 		n += 2
 	}
+	if m.CompressionAlgorithm != 0 {
+		n += 1 + sovBep(uint64(m.CompressionAlgorithm))
+	}
 	return n
 }
 
@@ -1931,42 +2308,35 @@ func (m *FileInfo) ProtoSize() (n int) {
 	if l > 0 {
 		n += 2 + l + sovBep(uint64(l))
 	}
-	if m.LocalFlags != 0 {
-		n += 2 + sovBep(uint64(m.LocalFlags))
-	}
-	return n
-}
-
-func (m *BlockInfo) ProtoSize() (n int) {
-	if m == nil {
-		return 0
+	if m.Chunking != 0 {
+		n += 2 + sovBep(uint64(m.Chunking))
 	}
-	var l int
-	_ = l
-	if m.Offset != 0 {
-		n += 1 + sovBep(uint64(m.Offset))
+	l = len(m.Encrypted)
+	if l > 0 {
+		n += 2 + l + sovBep(uint64(l))
 	}
-	if m.Size != 0 {
-		n += 1 + sovBep(uint64(m.Size))
+	if m.Xattrs != nil {
+		l = m.Xattrs.ProtoSize()
+		n += 2 + l + sovBep(uint64(l))
 	}
-	l = len(m.Hash)
-	if l > 0 {
-		n += 1 + l + sovBep(uint64(l))
+	if m.Ownership != nil {
+		l = m.Ownership.ProtoSize()
+		n += 2 + l + sovBep(uint64(l))
 	}
-	if m.WeakHash != 0 {
-		n += 1 + sovBep(uint64(m.WeakHash))
+	if m.LocalFlags != 0 {
+		n += 2 + sovBep(uint64(m.LocalFlags))
 	}
 	return n
 }
 
-func (m *Vector) ProtoSize() (n int) {
+func (m *Xattrs) ProtoSize() (n int) {
 	if m == nil {
 		return 0
 	}
 	var l int
 	_ = l
-	if len(m.Counters) > 0 {
-		for _, e := range m.Counters {
+	if len(m.Entries) > 0 {
+		for _, e := range m.Entries {
 			l = e.ProtoSize()
 			n += 1 + l + sovBep(uint64(l))
 		}
@@ -1974,17 +2344,94 @@ func (m *Vector) ProtoSize() (n int) {
 	return n
 }
 
-func (m *Counter) ProtoSize() (n int) {
+func (m *Xattr) ProtoSize() (n int) {
 	if m == nil {
 		return 0
 	}
 	var l int
 	_ = l
-	if m.ID != 0 {
-		n += 1 + sovBep(uint64(m.ID))
+	l = len(m.Name)
+	if l > 0 {
+		n += 1 + l + sovBep(uint64(l))
 	}
-	if m.Value != 0 {
-		n += 1 + sovBep(uint64(m.Value))
+	l = len(m.Value)
+	if l > 0 {
+		n += 1 + l + sovBep(uint64(l))
+	}
+	return n
+}
+
+func (m *Ownership) ProtoSize() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.OwnerName)
+	if l > 0 {
+		n += 1 + l + sovBep(uint64(l))
+	}
+	l = len(m.GroupName)
+	if l > 0 {
+		n += 1 + l + sovBep(uint64(l))
+	}
+	if m.Uid != 0 {
+		n += 1 + sovBep(uint64(m.Uid))
+	}
+	if m.Gid != 0 {
+		n += 1 + sovBep(uint64(m.Gid))
+	}
+	return n
+}
+
+func (m *BlockInfo) ProtoSize() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Offset != 0 {
+		n += 1 + sovBep(uint64(m.Offset))
+	}
+	if m.Size != 0 {
+		n += 1 + sovBep(uint64(m.Size))
+	}
+	l = len(m.Hash)
+	if l > 0 {
+		n += 1 + l + sovBep(uint64(l))
+	}
+	if m.WeakHash != 0 {
+		n += 1 + sovBep(uint64(m.WeakHash))
+	}
+	return n
+}
+
+func (m *Vector) ProtoSize() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Counters) > 0 {
+		for _, e := range m.Counters {
+			l = e.ProtoSize()
+			n += 1 + l + sovBep(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *Counter) ProtoSize() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.ID != 0 {
+		n += 1 + sovBep(uint64(m.ID))
+	}
+	if m.Value != 0 {
+		n += 1 + sovBep(uint64(m.Value))
 	}
 	return n
 }
@@ -2237,6 +2684,111 @@ func (m *Hello) Unmarshal(dAtA []byte) error {
 			}
 			m.ClientVersion = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
+		case 4:
+			if wireType == 0 {
+				var v MessageCompression
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowBep
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					v |= (MessageCompression(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				m.CompressionAlgorithms = append(m.CompressionAlgorithms, v)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowBep
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= (int(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthBep
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				var elementCount int
+				if elementCount != 0 && len(m.CompressionAlgorithms) == 0 {
+					m.CompressionAlgorithms = make([]MessageCompression, 0, elementCount)
+				}
+				for iNdEx < postIndex {
+					var v MessageCompression
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowBep
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						v |= (MessageCompression(b) & 0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					m.CompressionAlgorithms = append(m.CompressionAlgorithms, v)
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field CompressionAlgorithms", wireType)
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field NumConnections", wireType)
+			}
+			m.NumConnections = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.NumConnections |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Secondary", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Secondary = bool(v != 0)
 		default:
 			iNdEx = preIndex
 			skippy, err := skipBep(dAtA[iNdEx:])
@@ -2614,6 +3166,46 @@ func (m *Folder) Unmarshal(dAtA []byte) error {
 				}
 			}
 			m.Paused = bool(v != 0)
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ContentDefinedChunking", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.ContentDefinedChunking = bool(v != 0)
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Encrypted", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Encrypted = bool(v != 0)
 		case 16:
 			if wireType != 2 {
 				return fmt.Errorf("proto: wrong wireType = %d for field Devices", wireType)
@@ -2909,6 +3501,25 @@ func (m *Device) Unmarshal(dAtA []byte) error {
 				}
 			}
 			m.SkipIntroductionRemovals = bool(v != 0)
+		case 10:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CompressionAlgorithm", wireType)
+			}
+			m.CompressionAlgorithm = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.CompressionAlgorithm |= (MessageCompression(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipBep(dAtA[iNdEx:])
@@ -3510,11 +4121,11 @@ func (m *FileInfo) Unmarshal(dAtA []byte) error {
 			}
 			m.SymlinkTarget = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 1000:
+		case 18:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field LocalFlags", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Chunking", wireType)
 			}
-			m.LocalFlags = 0
+			m.Chunking = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBep
@@ -3524,7 +4135,460 @@ func (m *FileInfo) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.LocalFlags |= (uint32(b) & 0x7F) << shift
+				m.Chunking |= (BlockChunking(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 19:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Encrypted", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Encrypted = append(m.Encrypted[:0], dAtA[iNdEx:postIndex]...)
+			if m.Encrypted == nil {
+				m.Encrypted = []byte{}
+			}
+			iNdEx = postIndex
+		case 20:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Xattrs", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Xattrs == nil {
+				m.Xattrs = &Xattrs{}
+			}
+			if err := m.Xattrs.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 21:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ownership", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Ownership == nil {
+				m.Ownership = &Ownership{}
+			}
+			if err := m.Ownership.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 1000:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LocalFlags", wireType)
+			}
+			m.LocalFlags = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.LocalFlags |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipBep(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Xattrs) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowBep
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Xattrs: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Xattrs: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Entries", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Entries = append(m.Entries, Xattr{})
+			if err := m.Entries[len(m.Entries)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipBep(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Xattr) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowBep
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Xattr: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Xattr: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Name = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Value = append(m.Value[:0], dAtA[iNdEx:postIndex]...)
+			if m.Value == nil {
+				m.Value = []byte{}
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipBep(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Ownership) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowBep
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Ownership: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Ownership: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field OwnerName", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.OwnerName = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field GroupName", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.GroupName = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Uid", wireType)
+			}
+			m.Uid = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Uid |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Gid", wireType)
+			}
+			m.Gid = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Gid |= (int32(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -4756,121 +5820,142 @@ var (
 	ErrIntOverflowBep   = fmt.Errorf("proto: integer overflow")
 )
 
-func init() { proto.RegisterFile("bep.proto", fileDescriptor_bep_83d45b003aedf660) }
-
-var fileDescriptor_bep_83d45b003aedf660 = []byte{
-	// 1800 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x56, 0x4f, 0x6f, 0xdb, 0xc8,
-	0x15, 0x17, 0x25, 0xea, 0xdf, 0x93, 0xec, 0xa5, 0x27, 0x89, 0xcb, 0x72, 0xb3, 0x12, 0xa3, 0x24,
-	0x1b, 0xad, 0xb1, 0x4d, 0xd2, 0xdd, 0x6d, 0x8b, 0x16, 0x6d, 0x01, 0xfd, 0xa1, 0x1d, 0xa1, 0x8e,
-	0xe4, 0x8e, 0xe4, 0x6c, 0xb3, 0x87, 0x12, 0xb4, 0x38, 0x92, 0x89, 0x50, 0x1c, 0x95, 0xa4, 0xec,
-	0x68, 0x3f, 0x82, 0x4e, 0x3d, 0xf6, 0x22, 0x60, 0x81, 0x9e, 0xfa, 0x4d, 0x72, 0x4c, 0x7b, 0x28,
-	0x8a, 0x1e, 0x8c, 0xae, 0x73, 0xd9, 0x63, 0x3f, 0x41, 0x51, 0xcc, 0x0c, 0x29, 0x51, 0x76, 0xb2,
-	0xc8, 0x61, 0x4f, 0x9c, 0x79, 0xef, 0x37, 0x33, 0x7c, 0xbf, 0xf7, 0x7b, 0x6f, 0x06, 0x8a, 0x27,
-	0x64, 0xfa, 0x70, 0xea, 0xd3, 0x90, 0xa2, 0x02, 0xff, 0x0c, 0xa9, 0xab, 0xdd, 0xf5, 0xc9, 0x94,
-	0x06, 0x8f, 0xf8, 0xfc, 0x64, 0x36, 0x7a, 0x34, 0xa6, 0x63, 0xca, 0x27, 0x7c, 0x24, 0xe0, 0xb5,
-	0x29, 0x64, 0x9f, 0x10, 0xd7, 0xa5, 0xa8, 0x0a, 0x25, 0x9b, 0x9c, 0x39, 0x43, 0x62, 0x7a, 0xd6,
-	0x84, 0xa8, 0x92, 0x2e, 0xd5, 0x8b, 0x18, 0x84, 0xa9, 0x6b, 0x4d, 0x08, 0x03, 0x0c, 0x5d, 0x87,
-	0x78, 0xa1, 0x00, 0xa4, 0x05, 0x40, 0x98, 0x38, 0xe0, 0x3e, 0x6c, 0x47, 0x80, 0x33, 0xe2, 0x07,
-	0x0e, 0xf5, 0xd4, 0x0c, 0xc7, 0x6c, 0x09, 0xeb, 0x33, 0x61, 0xac, 0x05, 0x90, 0x7b, 0x42, 0x2c,
-	0x9b, 0xf8, 0xe8, 0x13, 0x90, 0xc3, 0xf9, 0x54, 0x9c, 0xb5, 0xfd, 0xd9, 0xad, 0x87, 0xf1, 0x9f,
-	0x3f, 0x7c, 0x4a, 0x82, 0xc0, 0x1a, 0x93, 0xc1, 0x7c, 0x4a, 0x30, 0x87, 0xa0, 0xdf, 0x42, 0x69,
-	0x48, 0x27, 0x53, 0x9f, 0x04, 0x7c, 0xe3, 0x34, 0x5f, 0x71, 0xfb, 0xda, 0x8a, 0xd6, 0x1a, 0x83,
-	0x93, 0x0b, 0x6a, 0x0d, 0xd8, 0x6a, 0xb9, 0xb3, 0x20, 0x24, 0x7e, 0x8b, 0x7a, 0x23, 0x67, 0x8c,
-	0x1e, 0x43, 0x7e, 0x44, 0x5d, 0x9b, 0xf8, 0x81, 0x2a, 0xe9, 0x99, 0x7a, 0xe9, 0x33, 0x65, 0xbd,
-	0xd9, 0x3e, 0x77, 0x34, 0xe5, 0x57, 0x17, 0xd5, 0x14, 0x8e, 0x61, 0xb5, 0xbf, 0xa6, 0x21, 0x27,
-	0x3c, 0x68, 0x17, 0xd2, 0x8e, 0x2d, 0x28, 0x6a, 0xe6, 0x2e, 0x2f, 0xaa, 0xe9, 0x4e, 0x1b, 0xa7,
-	0x1d, 0x1b, 0xdd, 0x84, 0xac, 0x6b, 0x9d, 0x10, 0x37, 0x22, 0x47, 0x4c, 0xd0, 0x87, 0x50, 0xf4,
-	0x89, 0x65, 0x9b, 0xd4, 0x73, 0xe7, 0x9c, 0x92, 0x02, 0x2e, 0x30, 0x43, 0xcf, 0x73, 0xe7, 0xe8,
-	0x27, 0x80, 0x9c, 0xb1, 0x47, 0x7d, 0x62, 0x4e, 0x89, 0x3f, 0x71, 0xf8, 0xdf, 0x06, 0xaa, 0xcc,
-	0x51, 0x3b, 0xc2, 0x73, 0xb4, 0x76, 0xa0, 0xbb, 0xb0, 0x15, 0xc1, 0x6d, 0xe2, 0x92, 0x90, 0xa8,
-	0x59, 0x8e, 0x2c, 0x0b, 0x63, 0x9b, 0xdb, 0xd0, 0x63, 0xb8, 0x69, 0x3b, 0x81, 0x75, 0xe2, 0x12,
-	0x33, 0x24, 0x93, 0xa9, 0xe9, 0x78, 0x36, 0x79, 0x49, 0x02, 0x35, 0xc7, 0xb1, 0x28, 0xf2, 0x0d,
-	0xc8, 0x64, 0xda, 0x11, 0x1e, 0xb4, 0x0b, 0xb9, 0xa9, 0x35, 0x0b, 0x88, 0xad, 0xe6, 0x39, 0x26,
-	0x9a, 0x31, 0x96, 0x84, 0x02, 0x02, 0x55, 0xb9, 0xca, 0x52, 0x9b, 0x3b, 0x62, 0x96, 0x22, 0x58,
-	0xed, 0xbf, 0x69, 0xc8, 0x09, 0x0f, 0xfa, 0x78, 0xc5, 0x52, 0xb9, 0xb9, 0xcb, 0x50, 0xff, 0xbe,
-	0xa8, 0x16, 0x84, 0xaf, 0xd3, 0x4e, 0xb0, 0x86, 0x40, 0x4e, 0x28, 0x8a, 0x8f, 0xd1, 0x6d, 0x28,
-	0x5a, 0xb6, 0xcd, 0xb2, 0x47, 0x02, 0x35, 0xa3, 0x67, 0xea, 0x45, 0xbc, 0x36, 0xa0, 0x5f, 0x6c,
-	0xaa, 0x41, 0xbe, 0xaa, 0x9f, 0x77, 0xc9, 0x80, 0xa5, 0x62, 0x48, 0xfc, 0x48, 0xc1, 0x59, 0x7e,
-	0x5e, 0x81, 0x19, 0xb8, 0x7e, 0xef, 0x40, 0x79, 0x62, 0xbd, 0x34, 0x03, 0xf2, 0xa7, 0x19, 0xf1,
-	0x86, 0x84, 0xd3, 0x95, 0xc1, 0xa5, 0x89, 0xf5, 0xb2, 0x1f, 0x99, 0x50, 0x05, 0xc0, 0xf1, 0x42,
-	0x9f, 0xda, 0xb3, 0x21, 0xf1, 0x23, 0xae, 0x12, 0x16, 0xf4, 0x33, 0x28, 0x70, 0xb2, 0x4d, 0xc7,
-	0x56, 0x0b, 0xba, 0x54, 0x97, 0x9b, 0x5a, 0x14, 0x78, 0x9e, 0x53, 0xcd, 0xe3, 0x8e, 0x87, 0x38,
-	0xcf, 0xb1, 0x1d, 0x1b, 0xfd, 0x1a, 0xb4, 0xe0, 0x85, 0xc3, 0x12, 0x25, 0x76, 0x0a, 0x1d, 0xea,
-	0x99, 0x3e, 0x99, 0xd0, 0x33, 0xcb, 0x0d, 0xd4, 0x22, 0x3f, 0x46, 0x65, 0x88, 0x4e, 0x02, 0x80,
-	0x23, 0x7f, 0xad, 0x07, 0x59, 0xbe, 0x23, 0xcb, 0xa2, 0x10, 0x6b, 0x54, 0xbd, 0xd1, 0x0c, 0x3d,
-	0x84, 0xec, 0xc8, 0x71, 0x49, 0xa0, 0xa6, 0x79, 0x0e, 0x51, 0x42, 0xe9, 0x8e, 0x4b, 0x3a, 0xde,
-	0x88, 0x46, 0x59, 0x14, 0xb0, 0xda, 0x31, 0x94, 0xf8, 0x86, 0xc7, 0x53, 0xdb, 0x0a, 0xc9, 0x0f,
-	0xb6, 0xed, 0x85, 0x0c, 0x85, 0xd8, 0xb3, 0x4a, 0xba, 0x94, 0x48, 0xfa, 0x5e, 0xd4, 0x0f, 0x44,
-	0x75, 0xef, 0x5e, 0xdf, 0x2f, 0xd1, 0x10, 0x10, 0xc8, 0x81, 0xf3, 0x35, 0xe1, 0xf5, 0x94, 0xc1,
-	0x7c, 0x8c, 0x74, 0x28, 0x5d, 0x2d, 0xa2, 0x2d, 0x9c, 0x34, 0xa1, 0x8f, 0x00, 0x26, 0xd4, 0x76,
-	0x46, 0x0e, 0xb1, 0xcd, 0x80, 0x0b, 0x20, 0x83, 0x8b, 0xb1, 0xa5, 0x8f, 0x54, 0x26, 0x77, 0x56,
-	0x42, 0x76, 0x54, 0x2b, 0xf1, 0x14, 0xd5, 0x21, 0xef, 0x78, 0x67, 0x96, 0xeb, 0x44, 0x15, 0xd2,
-	0xdc, 0xbe, 0xbc, 0xa8, 0x02, 0xb6, 0xce, 0x3b, 0xc2, 0x8a, 0x63, 0x37, 0xeb, 0x82, 0x1e, 0xdd,
-	0x28, 0xe6, 0x02, 0xdf, 0x6a, 0xcb, 0xa3, 0xc9, 0x42, 0x7e, 0x0c, 0xf9, 0xb8, 0x4b, 0xb2, 0xfc,
-	0x6e, 0x54, 0xd6, 0x33, 0x32, 0x0c, 0xe9, 0xaa, 0xff, 0x44, 0x30, 0xa4, 0x41, 0x61, 0x25, 0x4d,
-	0xe0, 0x7f, 0xbe, 0x9a, 0xb3, 0xde, 0xbc, 0x8a, 0xcb, 0x0b, 0xd4, 0x92, 0x2e, 0xd5, 0xb3, 0x78,
-	0x15, 0x6a, 0x97, 0x1d, 0xb7, 0x06, 0x9c, 0xcc, 0xd5, 0x32, 0xd7, 0xe6, 0x07, 0xb1, 0x36, 0xfb,
-	0xa7, 0xd4, 0x0f, 0x3b, 0xed, 0xf5, 0x8a, 0xe6, 0x1c, 0x3d, 0x02, 0x38, 0x71, 0xe9, 0xf0, 0x85,
-	0xc9, 0x69, 0xde, 0x62, 0x3b, 0x36, 0x95, 0xcb, 0x8b, 0x6a, 0x19, 0x5b, 0xe7, 0x4d, 0xe6, 0xe8,
-	0x3b, 0x5f, 0x13, 0x5c, 0x3c, 0x89, 0x87, 0xe8, 0xa7, 0x90, 0xe3, 0xf6, 0xb8, 0x55, 0xdc, 0x58,
-	0x07, 0xc4, 0xed, 0x09, 0x41, 0x44, 0x40, 0xc6, 0x55, 0x30, 0x9f, 0xb8, 0x8e, 0xf7, 0xc2, 0x0c,
-	0x2d, 0x7f, 0x4c, 0x42, 0x75, 0x47, 0xdc, 0x18, 0x91, 0x75, 0xc0, 0x8d, 0x2c, 0xaf, 0x2e, 0x1d,
-	0x5a, 0xae, 0x39, 0x72, 0xad, 0x71, 0xa0, 0x7e, 0x97, 0xe7, 0x89, 0x05, 0x6e, 0xdb, 0x67, 0xa6,
-	0x5f, 0xc9, 0x7f, 0xf9, 0xa6, 0x9a, 0xaa, 0x79, 0x50, 0x5c, 0x9d, 0xc4, 0x54, 0x4b, 0x47, 0xa3,
-	0x80, 0x84, 0x5c, 0x62, 0x19, 0x1c, 0xcd, 0x56, 0xc2, 0x49, 0x73, 0x8e, 0x84, 0x70, 0x10, 0xc8,
-	0xa7, 0x56, 0x70, 0xca, 0xc5, 0x54, 0xc6, 0x7c, 0xcc, 0x5a, 0xc5, 0x39, 0xb1, 0x5e, 0x98, 0xdc,
-	0x21, 0xa4, 0x54, 0x60, 0x86, 0x27, 0x56, 0x70, 0x1a, 0x9d, 0xf7, 0x1b, 0xc8, 0x89, 0x54, 0xa1,
-	0xcf, 0xa1, 0x30, 0xa4, 0x33, 0x2f, 0x5c, 0x5f, 0x27, 0x3b, 0xc9, 0x6e, 0xc4, 0x3d, 0x51, 0xec,
-	0x2b, 0x60, 0x6d, 0x1f, 0xf2, 0x91, 0x0b, 0xdd, 0x5f, 0xb5, 0x4a, 0xb9, 0x79, 0xeb, 0x4a, 0x56,
-	0x36, 0xef, 0x97, 0x33, 0xcb, 0x9d, 0x89, 0x9f, 0x97, 0xb1, 0x98, 0xd4, 0xfe, 0x2e, 0x41, 0x1e,
-	0x33, 0x25, 0x04, 0x61, 0xe2, 0x66, 0xca, 0x6e, 0xdc, 0x4c, 0xeb, 0x1a, 0x4e, 0x6f, 0xd4, 0x70,
-	0x5c, 0x86, 0x99, 0x44, 0x19, 0xae, 0x99, 0x93, 0xdf, 0xca, 0x5c, 0xf6, 0x2d, 0xcc, 0xe5, 0x12,
-	0xcc, 0xdd, 0x87, 0xed, 0x91, 0x4f, 0x27, 0xfc, 0xee, 0xa1, 0xbe, 0xe5, 0xcf, 0xa3, 0x46, 0xb9,
-	0xc5, 0xac, 0x83, 0xd8, 0xb8, 0x49, 0x70, 0x61, 0x93, 0xe0, 0x9a, 0x09, 0x05, 0x4c, 0x82, 0x29,
-	0xf5, 0x02, 0xf2, 0xce, 0x98, 0x10, 0xc8, 0xb6, 0x15, 0x5a, 0x3c, 0xa2, 0x32, 0xe6, 0x63, 0xf4,
-	0x00, 0xe4, 0x21, 0xb5, 0x45, 0x3c, 0xdb, 0x49, 0x09, 0x1a, 0xbe, 0x4f, 0xfd, 0x16, 0xb5, 0x09,
-	0xe6, 0x80, 0xda, 0x14, 0x94, 0x36, 0x3d, 0xf7, 0x5c, 0x6a, 0xd9, 0x47, 0x3e, 0x1d, 0xb3, 0x0b,
-	0xe2, 0x9d, 0x8d, 0xae, 0x0d, 0xf9, 0x19, 0x6f, 0x85, 0x71, 0xab, 0xbb, 0xb7, 0xd9, 0x9a, 0xae,
-	0x6e, 0x24, 0xfa, 0x66, 0x5c, 0xbf, 0xd1, 0xd2, 0xda, 0x3f, 0x25, 0xd0, 0xde, 0x8d, 0x46, 0x1d,
-	0x28, 0x09, 0xa4, 0x99, 0x78, 0x13, 0xd5, 0xdf, 0xe7, 0x20, 0xde, 0x15, 0x61, 0xb6, 0x1a, 0xbf,
-	0xf5, 0x42, 0x4d, 0xf4, 0x9b, 0xcc, 0xfb, 0xf5, 0x9b, 0x07, 0xb0, 0x25, 0x1a, 0x40, 0xfc, 0x7c,
-	0x90, 0xf5, 0x4c, 0x3d, 0xdb, 0x4c, 0x2b, 0x29, 0x5c, 0x3e, 0x11, 0x65, 0xc6, 0xed, 0xb5, 0x1c,
-	0xc8, 0x47, 0x8e, 0x37, 0xae, 0x55, 0x21, 0xdb, 0x72, 0x29, 0x4f, 0x58, 0xce, 0x27, 0x56, 0x40,
-	0xbd, 0x98, 0x47, 0x31, 0xdb, 0xfb, 0x47, 0x1a, 0x4a, 0x89, 0xa7, 0x1d, 0x7a, 0x0c, 0xdb, 0xad,
-	0xc3, 0xe3, 0xfe, 0xc0, 0xc0, 0x66, 0xab, 0xd7, 0xdd, 0xef, 0x1c, 0x28, 0x29, 0xed, 0xf6, 0x62,
-	0xa9, 0xab, 0x93, 0x35, 0x68, 0xf3, 0xd5, 0x56, 0x85, 0x6c, 0xa7, 0xdb, 0x36, 0xfe, 0xa0, 0x48,
-	0xda, 0xcd, 0xc5, 0x52, 0x57, 0x12, 0x40, 0x71, 0x05, 0x7e, 0x0a, 0x65, 0x0e, 0x30, 0x8f, 0x8f,
-	0xda, 0x8d, 0x81, 0xa1, 0xa4, 0x35, 0x6d, 0xb1, 0xd4, 0x77, 0xaf, 0xe2, 0x22, 0xce, 0xef, 0x42,
-	0x1e, 0x1b, 0xbf, 0x3f, 0x36, 0xfa, 0x03, 0x25, 0xa3, 0xed, 0x2e, 0x96, 0x3a, 0x4a, 0x00, 0xe3,
-	0x92, 0xba, 0x0f, 0x05, 0x6c, 0xf4, 0x8f, 0x7a, 0xdd, 0xbe, 0xa1, 0xc8, 0xda, 0x8f, 0x16, 0x4b,
-	0xfd, 0xc6, 0x06, 0x2a, 0x52, 0xe9, 0xcf, 0x61, 0xa7, 0xdd, 0xfb, 0xb2, 0x7b, 0xd8, 0x6b, 0xb4,
-	0xcd, 0x23, 0xdc, 0x3b, 0xc0, 0x46, 0xbf, 0xaf, 0x64, 0xb5, 0xea, 0x62, 0xa9, 0x7f, 0x98, 0xc0,
-	0x5f, 0x13, 0xdd, 0x47, 0x20, 0x1f, 0x75, 0xba, 0x07, 0x4a, 0x4e, 0xbb, 0xb1, 0x58, 0xea, 0x1f,
-	0x24, 0xa0, 0x8c, 0x54, 0x16, 0x71, 0xeb, 0xb0, 0xd7, 0x37, 0x94, 0xfc, 0xb5, 0x88, 0x39, 0xd9,
-	0x7b, 0x7f, 0x04, 0x74, 0xfd, 0xf1, 0x8b, 0xee, 0x81, 0xdc, 0xed, 0x75, 0x0d, 0x25, 0x25, 0xe2,
-	0xbf, 0x8e, 0xe8, 0x52, 0x8f, 0xa0, 0x1a, 0x64, 0x0e, 0xbf, 0xfa, 0x42, 0x91, 0xb4, 0x1f, 0x2f,
-	0x96, 0xfa, 0xad, 0xeb, 0xa0, 0xc3, 0xaf, 0xbe, 0xd8, 0xa3, 0x50, 0x4a, 0x6e, 0x5c, 0x83, 0xc2,
-	0x53, 0x63, 0xd0, 0x68, 0x37, 0x06, 0x0d, 0x25, 0x25, 0x7e, 0x29, 0x76, 0x3f, 0x25, 0xa1, 0xc5,
-	0x8b, 0xf0, 0x36, 0x64, 0xbb, 0xc6, 0x33, 0x03, 0x2b, 0x92, 0xb6, 0xb3, 0x58, 0xea, 0x5b, 0x31,
-	0xa0, 0x4b, 0xce, 0x88, 0x8f, 0x2a, 0x90, 0x6b, 0x1c, 0x7e, 0xd9, 0x78, 0xde, 0x57, 0xd2, 0x1a,
-	0x5a, 0x2c, 0xf5, 0xed, 0xd8, 0xdd, 0x70, 0xcf, 0xad, 0x79, 0xb0, 0xf7, 0x3f, 0x09, 0xca, 0xc9,
-	0x0b, 0x1f, 0x55, 0x40, 0xde, 0xef, 0x1c, 0x1a, 0xf1, 0x71, 0x49, 0x1f, 0x1b, 0xa3, 0x3a, 0x14,
-	0xdb, 0x1d, 0x6c, 0xb4, 0x06, 0x3d, 0xfc, 0x3c, 0x8e, 0x25, 0x09, 0x6a, 0x3b, 0x3e, 0x17, 0xf8,
-	0x1c, 0xfd, 0x12, 0xca, 0xfd, 0xe7, 0x4f, 0x0f, 0x3b, 0xdd, 0xdf, 0x99, 0x7c, 0xc7, 0xb4, 0xf6,
-	0x60, 0xb1, 0xd4, 0xef, 0x6c, 0x80, 0xc9, 0xd4, 0x27, 0x43, 0x2b, 0x24, 0x76, 0x5f, 0xdc, 0x41,
-	0xcc, 0x59, 0x90, 0x50, 0x0b, 0x76, 0xe2, 0xa5, 0xeb, 0xc3, 0x32, 0xda, 0xa7, 0x8b, 0xa5, 0xfe,
-	0xf1, 0xf7, 0xae, 0x5f, 0x9d, 0x5e, 0x90, 0xd0, 0x3d, 0xc8, 0x47, 0x9b, 0xc4, 0x4a, 0x4a, 0x2e,
-	0x8d, 0x16, 0xec, 0xfd, 0x4d, 0x82, 0xe2, 0xaa, 0x5d, 0x31, 0xc2, 0xbb, 0x3d, 0xd3, 0xc0, 0xb8,
-	0x87, 0x63, 0x06, 0x56, 0xce, 0x2e, 0xe5, 0x43, 0x74, 0x07, 0xf2, 0x07, 0x46, 0xd7, 0xc0, 0x9d,
-	0x56, 0x5c, 0x18, 0x2b, 0xc8, 0x01, 0xf1, 0x88, 0xef, 0x0c, 0xd1, 0x27, 0x50, 0xee, 0xf6, 0xcc,
-	0xfe, 0x71, 0xeb, 0x49, 0x1c, 0x3a, 0x3f, 0x3f, 0xb1, 0x55, 0x7f, 0x36, 0x3c, 0xe5, 0x7c, 0xee,
-	0xb1, 0x1a, 0x7a, 0xd6, 0x38, 0xec, 0xb4, 0x05, 0x34, 0xa3, 0xa9, 0x8b, 0xa5, 0x7e, 0x73, 0x05,
-	0x8d, 0x9e, 0x3c, 0x0c, 0xbb, 0x67, 0x43, 0xe5, 0xfb, 0x1b, 0x13, 0xd2, 0x21, 0xd7, 0x38, 0x3a,
-	0x32, 0xba, 0xed, 0xf8, 0xef, 0xd7, 0xbe, 0xc6, 0x74, 0x4a, 0x3c, 0x9b, 0x21, 0xf6, 0x7b, 0xf8,
-	0xc0, 0x18, 0xc4, 0x3f, 0xbf, 0x46, 0xec, 0x53, 0xf6, 0x00, 0x68, 0xd6, 0x5f, 0x7d, 0x5b, 0x49,
-	0xbd, 0xfe, 0xb6, 0x92, 0x7a, 0x75, 0x59, 0x91, 0x5e, 0x5f, 0x56, 0xa4, 0xff, 0x5c, 0x56, 0x52,
-	0xdf, 0x5d, 0x56, 0xa4, 0x3f, 0xbf, 0xa9, 0xa4, 0xbe, 0x79, 0x53, 0x91, 0x5e, 0xbf, 0xa9, 0xa4,
-	0xfe, 0xf5, 0xa6, 0x92, 0x3a, 0xc9, 0xf1, 0xa6, 0xf6, 0xf9, 0xff, 0x03, 0x00, 0x00, 0xff, 0xff,
-	0x02, 0x4d, 0x9a, 0x1c, 0x11, 0x0f, 0x00, 0x00,
+func init() { proto.RegisterFile("bep.proto", fileDescriptor_bep_3fa15a2c6d14c675) }
+
+var fileDescriptor_bep_3fa15a2c6d14c675 = []byte{
+	// 2133 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x57, 0xcd, 0x6f, 0xdb, 0xc8,
+	0x15, 0x17, 0xf5, 0xad, 0x67, 0xd9, 0xa1, 0x27, 0xb6, 0x97, 0xd5, 0x66, 0x65, 0x46, 0x49, 0x36,
+	0x5a, 0x63, 0x9b, 0xaf, 0x4d, 0x3f, 0xb6, 0x68, 0x0b, 0xe8, 0x83, 0x76, 0x84, 0x3a, 0x92, 0x77,
+	0x24, 0x67, 0x93, 0x5c, 0x08, 0x5a, 0x1c, 0xc9, 0x44, 0x28, 0x8e, 0x4a, 0x52, 0x76, 0xb4, 0xe7,
+	0x9e, 0x84, 0xa2, 0xe8, 0xb1, 0x17, 0x01, 0x7b, 0xed, 0xdf, 0xd1, 0x4b, 0x8e, 0x69, 0x0f, 0x45,
+	0xd1, 0x83, 0xd1, 0x75, 0x2e, 0xfb, 0x3f, 0x14, 0x28, 0x8a, 0x99, 0x21, 0x29, 0xca, 0x76, 0xb6,
+	0x39, 0xf4, 0xa4, 0x99, 0xdf, 0xfb, 0xcd, 0x70, 0xe6, 0xbd, 0xf7, 0x7b, 0xf3, 0x04, 0x85, 0x23,
+	0x32, 0xbe, 0x37, 0x76, 0xa9, 0x4f, 0x51, 0x9e, 0xff, 0xf4, 0xa9, 0x5d, 0xba, 0xe5, 0x92, 0x31,
+	0xf5, 0xee, 0xf3, 0xf9, 0xd1, 0x64, 0x70, 0x7f, 0x48, 0x87, 0x94, 0x4f, 0xf8, 0x48, 0xd0, 0x2b,
+	0xbf, 0x4f, 0x42, 0xe6, 0x09, 0xb1, 0x6d, 0x8a, 0xb6, 0x61, 0xc5, 0x24, 0x27, 0x56, 0x9f, 0xe8,
+	0x8e, 0x31, 0x22, 0x8a, 0xa4, 0x4a, 0xd5, 0x02, 0x06, 0x01, 0xb5, 0x8d, 0x11, 0x61, 0x84, 0xbe,
+	0x6d, 0x11, 0xc7, 0x17, 0x84, 0xa4, 0x20, 0x08, 0x88, 0x13, 0xee, 0xc0, 0x5a, 0x40, 0x38, 0x21,
+	0xae, 0x67, 0x51, 0x47, 0x49, 0x71, 0xce, 0xaa, 0x40, 0x9f, 0x09, 0x10, 0x75, 0x61, 0xab, 0x4f,
+	0x47, 0x63, 0x97, 0x78, 0x6c, 0xaa, 0x1b, 0xf6, 0x90, 0xba, 0x96, 0x7f, 0x3c, 0xf2, 0x94, 0xb4,
+	0x9a, 0xaa, 0xae, 0x3d, 0xba, 0x71, 0x2f, 0xbc, 0xc2, 0xbd, 0xa7, 0xc4, 0xf3, 0x8c, 0x21, 0x69,
+	0x2c, 0xe8, 0x78, 0x33, 0xb6, 0xb6, 0x16, 0x2d, 0x45, 0x77, 0xe1, 0x9a, 0x33, 0x19, 0xe9, 0x7d,
+	0xea, 0x38, 0xa4, 0xef, 0x5b, 0xd4, 0xf1, 0x94, 0x8c, 0x2a, 0x55, 0x33, 0x78, 0xcd, 0x99, 0x8c,
+	0x1a, 0x0b, 0x14, 0xdd, 0x80, 0x82, 0x47, 0xfa, 0xd4, 0x31, 0x0d, 0x77, 0xaa, 0x64, 0x55, 0xa9,
+	0x9a, 0xc7, 0x0b, 0xa0, 0xe2, 0x41, 0xf6, 0x09, 0x31, 0x4c, 0xe2, 0xa2, 0xcf, 0x20, 0xed, 0x4f,
+	0xc7, 0xc2, 0x0f, 0x6b, 0x8f, 0x36, 0x2f, 0x9d, 0xa9, 0x37, 0x1d, 0x13, 0xcc, 0x29, 0xe8, 0xd7,
+	0xb0, 0x12, 0x3b, 0x14, 0x77, 0xcc, 0xff, 0xba, 0x45, 0x7c, 0x41, 0xa5, 0x06, 0xab, 0x0d, 0x7b,
+	0xe2, 0xf9, 0xc4, 0x6d, 0x50, 0x67, 0x60, 0x0d, 0xd1, 0x03, 0xc8, 0x0d, 0xa8, 0x6d, 0x12, 0xd7,
+	0x53, 0x24, 0x35, 0x55, 0x5d, 0x79, 0x24, 0x2f, 0x36, 0xdb, 0xe5, 0x86, 0x7a, 0xfa, 0xcd, 0xd9,
+	0x76, 0x02, 0x87, 0xb4, 0xca, 0xbf, 0x93, 0x90, 0x15, 0x16, 0xb4, 0x05, 0x49, 0xcb, 0x14, 0xe1,
+	0xab, 0x67, 0xcf, 0xcf, 0xb6, 0x93, 0xad, 0x26, 0x4e, 0x5a, 0x26, 0xda, 0x80, 0x8c, 0x6d, 0x1c,
+	0x11, 0x3b, 0x08, 0x9c, 0x98, 0xa0, 0x8f, 0xa1, 0xe0, 0x12, 0xc3, 0xd4, 0xa9, 0x63, 0x4f, 0x79,
+	0xb8, 0xf2, 0x38, 0xcf, 0x80, 0x8e, 0x63, 0x4f, 0xd1, 0x8f, 0x01, 0x59, 0x43, 0x87, 0xba, 0x44,
+	0x1f, 0x13, 0x77, 0x64, 0xf1, 0xd3, 0xb2, 0x28, 0x31, 0xd6, 0xba, 0xb0, 0x1c, 0x2c, 0x0c, 0xe8,
+	0x16, 0xac, 0x06, 0x74, 0x93, 0xd8, 0xc4, 0x27, 0x3c, 0x02, 0x79, 0x5c, 0x14, 0x60, 0x93, 0x63,
+	0xe8, 0x01, 0x6c, 0x98, 0x96, 0x67, 0x1c, 0xd9, 0x44, 0xf7, 0xc9, 0x68, 0xac, 0x5b, 0x8e, 0x49,
+	0x5e, 0x13, 0x2f, 0x08, 0x05, 0x0a, 0x6c, 0x3d, 0x32, 0x1a, 0xb7, 0x84, 0x05, 0x6d, 0x41, 0x76,
+	0x6c, 0x4c, 0x3c, 0x62, 0x2a, 0x39, 0xce, 0x09, 0x66, 0xe8, 0xe7, 0xa0, 0xf4, 0xa9, 0xe3, 0xb3,
+	0x7c, 0x33, 0xc9, 0xc0, 0x72, 0x88, 0xa9, 0xf7, 0x8f, 0x27, 0xce, 0x2b, 0xcb, 0x19, 0x2a, 0x79,
+	0xce, 0xdc, 0x0a, 0xec, 0x4d, 0x61, 0x6e, 0x04, 0x56, 0x96, 0x03, 0xc4, 0xe9, 0xbb, 0xd3, 0xb1,
+	0x4f, 0x4c, 0xa5, 0x20, 0x72, 0x20, 0x02, 0x98, 0xf7, 0x45, 0xd6, 0x7b, 0x8a, 0x7c, 0xd1, 0xfb,
+	0x4d, 0x6e, 0x08, 0xbd, 0x1f, 0xd0, 0x2a, 0x7f, 0x49, 0x41, 0x56, 0x58, 0xd0, 0xa7, 0x91, 0xf7,
+	0x8b, 0xf5, 0x2d, 0xc6, 0xfa, 0xe7, 0xd9, 0x76, 0x5e, 0xd8, 0x5a, 0xcd, 0x58, 0x34, 0x10, 0xa4,
+	0x63, 0x2a, 0xe2, 0x63, 0x76, 0x2c, 0xc3, 0x34, 0x59, 0x56, 0x10, 0x4f, 0x49, 0xa9, 0xa9, 0x6a,
+	0x01, 0x2f, 0x00, 0xf4, 0xb3, 0xe5, 0x2c, 0x4b, 0x5f, 0xcc, 0xcb, 0xf7, 0xa5, 0x17, 0x0b, 0x71,
+	0x9f, 0xb8, 0x81, 0x6a, 0x33, 0xfc, 0x7b, 0x79, 0x06, 0x70, 0xcd, 0xde, 0x84, 0xe2, 0xc8, 0x78,
+	0xad, 0x7b, 0xe4, 0xb7, 0x13, 0xe2, 0xf4, 0x09, 0x0f, 0x43, 0x0a, 0xaf, 0x8c, 0x8c, 0xd7, 0xdd,
+	0x00, 0x42, 0x65, 0x00, 0xcb, 0xf1, 0x5d, 0x6a, 0x4e, 0xfa, 0xc4, 0x0d, 0x62, 0x10, 0x43, 0xd0,
+	0x4f, 0x20, 0xcf, 0x83, 0xa8, 0x5b, 0x26, 0xf7, 0x7b, 0xba, 0x5e, 0x0a, 0x2e, 0x9e, 0xe3, 0x21,
+	0xe4, 0xf7, 0x0e, 0x87, 0x38, 0xc7, 0xb9, 0x2d, 0x13, 0xfd, 0x12, 0x4a, 0xde, 0x2b, 0x6b, 0xac,
+	0x87, 0x3b, 0x31, 0x79, 0xea, 0x2e, 0x19, 0xd1, 0x13, 0xc3, 0xf6, 0x82, 0xa8, 0x28, 0x8c, 0xd1,
+	0x8a, 0x11, 0x70, 0x60, 0x47, 0x5f, 0xc1, 0xe6, 0x95, 0x45, 0x44, 0x81, 0x0f, 0x50, 0xdf, 0xc6,
+	0x55, 0x35, 0xa4, 0xd2, 0x81, 0x0c, 0x3f, 0x24, 0x4b, 0x38, 0xa1, 0xab, 0xa0, 0x08, 0x06, 0x33,
+	0x74, 0x0f, 0x32, 0x03, 0xcb, 0x26, 0x9e, 0x92, 0xe4, 0x69, 0x81, 0x62, 0xa2, 0xb4, 0x6c, 0xd2,
+	0x72, 0x06, 0x34, 0x48, 0x0c, 0x41, 0xab, 0x1c, 0xc2, 0x0a, 0xdf, 0xf0, 0x70, 0x6c, 0x1a, 0x3e,
+	0xf9, 0xbf, 0x6d, 0xfb, 0x87, 0x2c, 0xe4, 0x43, 0x4b, 0x94, 0x47, 0x52, 0x2c, 0x8f, 0x76, 0x82,
+	0xd2, 0x25, 0x0a, 0xd1, 0xd6, 0xe5, 0xfd, 0x62, 0xb5, 0x0b, 0x41, 0xda, 0xb3, 0xbe, 0x21, 0x5c,
+	0xfa, 0x29, 0xcc, 0xc7, 0x48, 0x85, 0x95, 0x8b, 0x7a, 0x5f, 0xc5, 0x71, 0x08, 0x7d, 0x02, 0x30,
+	0xa2, 0xa6, 0x35, 0xb0, 0x88, 0xa9, 0x8b, 0x42, 0x9b, 0xc2, 0x85, 0x10, 0xe9, 0x22, 0x85, 0x29,
+	0x88, 0xa9, 0xdd, 0x0c, 0x64, 0x1d, 0x4e, 0x51, 0x15, 0x72, 0x96, 0x73, 0x62, 0xd8, 0x56, 0x20,
+	0xe6, 0xfa, 0xda, 0xf9, 0xd9, 0x36, 0x60, 0xe3, 0xb4, 0x25, 0x50, 0x1c, 0x9a, 0xd9, 0x63, 0xe2,
+	0xd0, 0xa5, 0xba, 0x23, 0x34, 0xbd, 0xea, 0xd0, 0x78, 0xcd, 0x79, 0x00, 0xb9, 0xf0, 0xb1, 0x61,
+	0x29, 0xb3, 0x24, 0xd6, 0x67, 0xa4, 0xef, 0xd3, 0xa8, 0x54, 0x06, 0x34, 0x54, 0x82, 0x7c, 0x94,
+	0xed, 0xc0, 0x4f, 0x1e, 0xcd, 0xd9, 0x13, 0x17, 0xdd, 0xcb, 0xf1, 0x94, 0x15, 0xfe, 0x82, 0x44,
+	0x57, 0x6d, 0xb3, 0xcf, 0x2d, 0x08, 0x47, 0x53, 0xa5, 0xc8, 0xd3, 0xfd, 0x5a, 0x98, 0xee, 0xdd,
+	0x63, 0xea, 0xfa, 0xad, 0xe6, 0x62, 0x45, 0x7d, 0x8a, 0xee, 0x03, 0x1c, 0xd9, 0xb4, 0xff, 0x4a,
+	0xe7, 0x6e, 0x5e, 0x65, 0x3b, 0xd6, 0xe5, 0xf3, 0xb3, 0xed, 0x22, 0x36, 0x4e, 0xeb, 0xcc, 0xd0,
+	0xb5, 0xbe, 0x21, 0xb8, 0x70, 0x14, 0x0e, 0xd1, 0x43, 0xc8, 0x72, 0x3c, 0xac, 0x3e, 0xd7, 0x17,
+	0x17, 0xe2, 0x78, 0x2c, 0x21, 0x02, 0x22, 0xf3, 0x95, 0x37, 0x1d, 0xd9, 0x96, 0xf3, 0x4a, 0xf7,
+	0x0d, 0x77, 0x48, 0x7c, 0x65, 0x5d, 0x3c, 0xbc, 0x01, 0xda, 0xe3, 0x20, 0xfa, 0x02, 0xf2, 0x51,
+	0x81, 0x44, 0x3c, 0x37, 0x3e, 0xba, 0xb0, 0x77, 0x58, 0x21, 0x71, 0x44, 0x5c, 0xae, 0x95, 0xd7,
+	0x59, 0x5d, 0x8b, 0xd7, 0xca, 0x2a, 0x64, 0x5f, 0x1b, 0xbe, 0xef, 0x7a, 0xca, 0xc6, 0x45, 0xef,
+	0x3f, 0xe7, 0x38, 0x0e, 0xec, 0xe8, 0x21, 0x14, 0xe8, 0xa9, 0x43, 0x5c, 0xef, 0xd8, 0x1a, 0x2b,
+	0x9b, 0xaa, 0xb4, 0x7c, 0xb3, 0x4e, 0x68, 0xc2, 0x0b, 0x16, 0xcb, 0x43, 0x9b, 0xf6, 0x0d, 0x5b,
+	0x1f, 0xd8, 0xc6, 0xd0, 0x53, 0xbe, 0xcf, 0xf1, 0x44, 0x04, 0x8e, 0xed, 0x32, 0xe8, 0x17, 0xe9,
+	0x3f, 0x7d, 0xbb, 0x9d, 0xa8, 0x7c, 0x09, 0x59, 0xf1, 0x31, 0x74, 0x1f, 0x72, 0xc4, 0xf1, 0x5d,
+	0x8b, 0x84, 0x0f, 0xe7, 0xb5, 0x0b, 0xe7, 0x09, 0x93, 0x21, 0x60, 0x55, 0x1e, 0x42, 0x86, 0xe3,
+	0x57, 0xea, 0x68, 0x03, 0x32, 0x27, 0x86, 0x3d, 0x11, 0x42, 0x2a, 0x62, 0x31, 0xa9, 0x8c, 0xa0,
+	0x10, 0x9d, 0x96, 0x09, 0x81, 0x9f, 0x37, 0xde, 0x33, 0x89, 0x1b, 0xf0, 0xea, 0xfa, 0x09, 0xc0,
+	0xd0, 0xa5, 0x93, 0x71, 0xbc, 0x63, 0x2a, 0x70, 0x84, 0x9b, 0x65, 0x48, 0x4d, 0x2c, 0x93, 0x6b,
+	0x2f, 0x83, 0xd9, 0x90, 0x21, 0x43, 0xcb, 0xe4, 0x92, 0xcb, 0x60, 0x36, 0xac, 0x38, 0x50, 0x88,
+	0xc2, 0xce, 0x4a, 0x08, 0x1d, 0x0c, 0x3c, 0xe2, 0xf3, 0x4f, 0xa5, 0x70, 0x30, 0x8b, 0x54, 0x9c,
+	0xe4, 0xeb, 0xf8, 0x98, 0x61, 0xc7, 0x86, 0x77, 0xcc, 0x77, 0x2f, 0x62, 0x3e, 0x66, 0x4f, 0xc1,
+	0x29, 0x31, 0x5e, 0xe9, 0xdc, 0x20, 0x74, 0x9d, 0x67, 0xc0, 0x13, 0xc3, 0x3b, 0x0e, 0x9c, 0xf9,
+	0x2b, 0xc8, 0x0a, 0xdd, 0xf0, 0x74, 0xa1, 0x13, 0xc7, 0x5f, 0xb4, 0x21, 0xeb, 0xf1, 0xd7, 0x86,
+	0x5b, 0x02, 0x7f, 0x46, 0xc4, 0xca, 0x2e, 0xe4, 0x02, 0x13, 0xba, 0x13, 0x3d, 0x85, 0xe9, 0xfa,
+	0xe6, 0x05, 0x89, 0x2c, 0xf7, 0x25, 0x0b, 0x2f, 0xa7, 0x43, 0x2f, 0xff, 0x55, 0x82, 0x1c, 0x66,
+	0xb2, 0xf4, 0xfc, 0x58, 0x47, 0x93, 0x59, 0xea, 0x68, 0x16, 0x05, 0x35, 0xb9, 0x54, 0x50, 0xc3,
+	0x58, 0xa6, 0x62, 0xb1, 0x5c, 0x78, 0x2e, 0x7d, 0xa5, 0xe7, 0x32, 0x57, 0x78, 0x2e, 0x1b, 0xf3,
+	0xdc, 0x1d, 0x58, 0x1b, 0xb8, 0x74, 0xc4, 0x7b, 0x16, 0xea, 0xb2, 0xde, 0x51, 0x3c, 0x84, 0xab,
+	0x0c, 0xed, 0x85, 0xe0, 0xb2, 0x83, 0xf3, 0xcb, 0x0e, 0xae, 0xe8, 0x90, 0xc7, 0xc4, 0x1b, 0x53,
+	0xc7, 0x23, 0xef, 0xbd, 0x13, 0x82, 0xb4, 0x69, 0xf8, 0x46, 0x90, 0x72, 0x7c, 0x8c, 0xee, 0x42,
+	0xba, 0x4f, 0x4d, 0x71, 0x9f, 0xb5, 0xb8, 0x6a, 0x34, 0xd7, 0xa5, 0x6e, 0x83, 0x9a, 0x04, 0x73,
+	0x42, 0x65, 0x0c, 0x72, 0x93, 0x9e, 0x3a, 0x36, 0x35, 0xcc, 0x03, 0x97, 0x0e, 0xd9, 0x0b, 0xf7,
+	0xde, 0x57, 0xa7, 0x09, 0xb9, 0x09, 0x7f, 0x97, 0xc2, 0x77, 0xe7, 0xf6, 0xf2, 0x3b, 0x71, 0x71,
+	0x23, 0xf1, 0x88, 0x85, 0xfa, 0x09, 0x96, 0x56, 0xfe, 0x2e, 0x41, 0xe9, 0xfd, 0x6c, 0xd4, 0x82,
+	0x15, 0xc1, 0xd4, 0x63, 0xbd, 0x74, 0xf5, 0x43, 0x3e, 0xc4, 0x9f, 0x28, 0x98, 0x44, 0xe3, 0x2b,
+	0x1b, 0xa6, 0x58, 0xf1, 0x4f, 0x7d, 0x58, 0xf1, 0xbf, 0x0b, 0xab, 0xa2, 0x1a, 0x87, 0x6d, 0x27,
+	0xfb, 0xcb, 0x91, 0xa9, 0x27, 0xe5, 0x04, 0x2e, 0x1e, 0x09, 0x99, 0x71, 0xbc, 0x92, 0x85, 0xf4,
+	0x81, 0xe5, 0x0c, 0x2b, 0xdb, 0x90, 0x69, 0xd8, 0x94, 0x07, 0x2c, 0xeb, 0x12, 0xc3, 0xa3, 0x4e,
+	0xe8, 0x47, 0x31, 0xdb, 0xf9, 0x5b, 0x12, 0x56, 0x62, 0x7f, 0x09, 0xd0, 0x03, 0x58, 0x6b, 0xec,
+	0x1f, 0x76, 0x7b, 0x1a, 0xd6, 0x1b, 0x9d, 0xf6, 0x6e, 0x6b, 0x4f, 0x4e, 0x94, 0x6e, 0xcc, 0xe6,
+	0xaa, 0x32, 0x5a, 0x90, 0x96, 0xbb, 0xfd, 0x6d, 0xc8, 0xb4, 0xda, 0x4d, 0xed, 0xb9, 0x2c, 0x95,
+	0x36, 0x66, 0x73, 0x55, 0x8e, 0x11, 0x45, 0x3f, 0xf2, 0x39, 0x14, 0x39, 0x41, 0x3f, 0x3c, 0x68,
+	0xd6, 0x7a, 0x9a, 0x9c, 0x2c, 0x95, 0x66, 0x73, 0x75, 0xeb, 0x22, 0x2f, 0xf0, 0xf9, 0x2d, 0xc8,
+	0x61, 0xed, 0xab, 0x43, 0xad, 0xdb, 0x93, 0x53, 0xa5, 0xad, 0xd9, 0x5c, 0x45, 0x31, 0x62, 0x28,
+	0xa9, 0x3b, 0x90, 0xc7, 0x5a, 0xf7, 0xa0, 0xd3, 0xee, 0x6a, 0x72, 0xba, 0xf4, 0xd1, 0x6c, 0xae,
+	0x5e, 0x5f, 0x62, 0x05, 0x59, 0xfa, 0x53, 0x58, 0x6f, 0x76, 0xbe, 0x6e, 0xef, 0x77, 0x6a, 0x4d,
+	0xfd, 0x00, 0x77, 0xf6, 0xb0, 0xd6, 0xed, 0xca, 0x99, 0xd2, 0xf6, 0x6c, 0xae, 0x7e, 0x1c, 0xe3,
+	0x5f, 0x4a, 0xba, 0x4f, 0x20, 0x7d, 0xd0, 0x6a, 0xef, 0xc9, 0xd9, 0xd2, 0xf5, 0xd9, 0x5c, 0xbd,
+	0x16, 0xa3, 0x32, 0xa7, 0xb2, 0x1b, 0x37, 0xf6, 0x3b, 0x5d, 0x4d, 0xce, 0x5d, 0xba, 0x31, 0x77,
+	0xf6, 0xce, 0xef, 0x24, 0x40, 0x97, 0xfb, 0x36, 0x74, 0x1b, 0xd2, 0xed, 0x4e, 0x5b, 0x93, 0x13,
+	0xc2, 0x01, 0x97, 0x19, 0x6d, 0xea, 0x10, 0x54, 0x81, 0xd4, 0xfe, 0xcb, 0xc7, 0xb2, 0x54, 0xfa,
+	0xd1, 0x6c, 0xae, 0x6e, 0x5e, 0x26, 0xed, 0xbf, 0x7c, 0xcc, 0x76, 0x7a, 0xd9, 0xed, 0x35, 0x43,
+	0x57, 0x5e, 0x26, 0xbd, 0xf4, 0x7c, 0x73, 0x87, 0xc2, 0x4a, 0xfc, 0xf3, 0x15, 0xc8, 0x3f, 0xd5,
+	0x7a, 0xb5, 0x66, 0xad, 0x57, 0x93, 0x13, 0xe2, 0xe4, 0xa1, 0xf9, 0x29, 0xf1, 0x0d, 0xae, 0xd5,
+	0x1b, 0x90, 0x69, 0x6b, 0xcf, 0x34, 0x2c, 0x4b, 0xa5, 0xf5, 0xd9, 0x5c, 0x5d, 0x0d, 0x09, 0x6d,
+	0x72, 0x42, 0x5c, 0x54, 0x86, 0x6c, 0x6d, 0xff, 0xeb, 0xda, 0x8b, 0xae, 0x9c, 0x2c, 0xa1, 0xd9,
+	0x5c, 0x5d, 0x0b, 0xcd, 0x35, 0xfb, 0xd4, 0x98, 0x7a, 0x3b, 0xff, 0x91, 0xa0, 0x18, 0x6f, 0xd2,
+	0x50, 0x19, 0xd2, 0xbb, 0xad, 0x7d, 0x2d, 0xfc, 0x5c, 0xdc, 0xc6, 0xc6, 0xa8, 0x0a, 0x85, 0x66,
+	0x0b, 0x6b, 0x8d, 0x5e, 0x07, 0xbf, 0x08, 0x6f, 0x1c, 0x27, 0x35, 0x2d, 0x97, 0xeb, 0x60, 0x8a,
+	0xbe, 0x84, 0x62, 0xf7, 0xc5, 0xd3, 0xfd, 0x56, 0xfb, 0x37, 0x3a, 0xdf, 0x31, 0x59, 0xba, 0x3b,
+	0x9b, 0xab, 0x37, 0x97, 0xc8, 0x64, 0xec, 0x92, 0xbe, 0xe1, 0x13, 0xb3, 0x2b, 0xfa, 0x06, 0x66,
+	0xcc, 0x4b, 0xa8, 0x01, 0xeb, 0xe1, 0xd2, 0xc5, 0xc7, 0x52, 0xa5, 0xcf, 0x67, 0x73, 0xf5, 0xd3,
+	0x1f, 0x5c, 0x1f, 0x7d, 0x3d, 0x2f, 0xa1, 0xdb, 0x90, 0x0b, 0x36, 0x09, 0x13, 0x2e, 0xbe, 0x34,
+	0x58, 0xb0, 0x73, 0x0c, 0xab, 0x4b, 0x8d, 0x08, 0xba, 0x09, 0x99, 0xdd, 0xd6, 0x73, 0xad, 0x29,
+	0x27, 0x44, 0x2e, 0x2f, 0x59, 0x77, 0xad, 0xd7, 0xc4, 0x44, 0x8f, 0xe1, 0x5a, 0xa3, 0xd3, 0xee,
+	0x69, 0xed, 0x9e, 0xde, 0xd4, 0x76, 0x5b, 0x6d, 0xad, 0x29, 0x4b, 0x22, 0x45, 0x97, 0xc8, 0x8d,
+	0xa5, 0xff, 0x82, 0x3b, 0x7f, 0x96, 0xa0, 0x10, 0xd5, 0x4f, 0x16, 0xda, 0x76, 0x47, 0xd7, 0x30,
+	0xee, 0xe0, 0xd0, 0xd7, 0x91, 0xb1, 0x4d, 0xf9, 0x10, 0xdd, 0x84, 0xdc, 0x9e, 0xd6, 0xd6, 0x70,
+	0xab, 0x11, 0x2a, 0x35, 0xa2, 0xec, 0x11, 0x87, 0xb8, 0x56, 0x1f, 0x7d, 0x06, 0xc5, 0x76, 0x47,
+	0xef, 0x1e, 0x36, 0x9e, 0x84, 0x4e, 0xe6, 0x37, 0x8d, 0x6d, 0xd5, 0x9d, 0xf4, 0x8f, 0x79, 0xe4,
+	0x76, 0x98, 0xa8, 0x9f, 0xd5, 0xf6, 0x5b, 0x4d, 0x41, 0x4d, 0x95, 0x94, 0xd9, 0x5c, 0xdd, 0x88,
+	0xa8, 0x41, 0x43, 0xcc, 0xb8, 0x3b, 0x26, 0x94, 0x7f, 0xb8, 0x52, 0x22, 0x15, 0xb2, 0xb5, 0x83,
+	0x03, 0xad, 0xdd, 0x0c, 0x4f, 0xbf, 0xb0, 0xd5, 0xc6, 0x63, 0xe2, 0x98, 0x8c, 0xb1, 0xdb, 0xc1,
+	0x7b, 0x5a, 0x4f, 0x96, 0x2e, 0x32, 0x76, 0x29, 0x6b, 0x0f, 0xeb, 0xd5, 0x37, 0xdf, 0x95, 0x13,
+	0x6f, 0xbf, 0x2b, 0x27, 0xde, 0x9c, 0x97, 0xa5, 0xb7, 0xe7, 0x65, 0xe9, 0x5f, 0xe7, 0xe5, 0xc4,
+	0xf7, 0xe7, 0x65, 0xe9, 0x8f, 0xef, 0xca, 0x89, 0x6f, 0xdf, 0x95, 0xa5, 0xb7, 0xef, 0xca, 0x89,
+	0x7f, 0xbc, 0x2b, 0x27, 0x8e, 0xb2, 0xbc, 0xca, 0x7e, 0xf1, 0xdf, 0x01, 0x00, 0xfc, 0xea, 0x76,
+	0xac, 0x77, 0x12, 0x00, 0x00,
 }
@@ -11,6 +11,7 @@ import (
 	"errors"
 	"fmt"
 	"runtime"
+	"sort"
 	"time"
 
 	"github.com/syncthing/syncthing/lib/rand"
@@ -104,6 +105,18 @@ func (f FileInfo) BlockSize() int {
 	return int(f.RawBlockSize)
 }
 
+// BlockIndex returns the index of the block containing the given offset.
+// Fixed size blocks are found by division, content defined ones by searching
+// the block list.
+func (f FileInfo) BlockIndex(offset int64) int {
+	if f.Chunking != BlockChunkingContentDefined {
+		return int(offset / int64(f.BlockSize()))
+	}
+	return sort.Search(len(f.Blocks), func(i int) bool {
+		return f.Blocks[i].Offset+int64(f.Blocks[i].Size) > offset
+	})
+}
+
 func (f FileInfo) FileName() string {
 	return f.Name
 }
@@ -174,6 +187,8 @@ func (f FileInfo) IsEquivalentOptional(other FileInfo, modTimeWindow time.Durati
 //  - deleted flag
 //  - invalid flag
 //  - permissions, unless they are ignored
+//  - extended attributes, unless they are unknown on either side
+//  - ownership, unless it is unknown on either side
 // A file is not "equivalent", if it has different
 //  - modification time (difference bigger than modTimeWindow)
 //  - size
@@ -201,6 +216,14 @@ func (f FileInfo) isEquivalent(other FileInfo, modTimeWindow time.Duration, igno
 		return false
 	}
 
+	if f.Xattrs != nil && other.Xattrs != nil && !XattrsEqual(f.Xattrs.Entries, other.Xattrs.Entries) {
+		return false
+	}
+
+	if f.Ownership != nil && other.Ownership != nil && *f.Ownership != *other.Ownership {
+		return false
+	}
+
 	switch f.Type {
 	case FileInfoTypeFile:
 		return f.Size == other.Size && ModTimeEqual(f.ModTime(), other.ModTime(), modTimeWindow) && (ignoreBlocks || BlocksEqual(f.Blocks, other.Blocks))
@@ -252,6 +275,22 @@ func BlocksEqual(a, b []BlockInfo) bool {
 	return true
 }
 
+// XattrsEqual returns whether two lists of extended attributes, sorted by
+// name, are the same.
+func XattrsEqual(a, b []Xattr) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i].Name != b[i].Name || !bytes.Equal(a[i].Value, b[i].Value) {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (f *FileInfo) SetMustRescan(by ShortID) {
 	f.LocalFlags = FlagLocalMustRescan
 	f.ModifiedBy = by
@@ -37,3 +37,50 @@ func (c *Compression) UnmarshalText(bs []byte) error {
 	*c = compressionUnmarshal[string(bs)]
 	return nil
 }
+
+var messageCompressionMarshal = map[MessageCompression]string{
+	MessageCompressionLZ4:  "lz4",
+	MessageCompressionZstd: "zstd",
+}
+
+var messageCompressionUnmarshal = map[string]MessageCompression{
+	"lz4":  MessageCompressionLZ4,
+	"zstd": MessageCompressionZstd,
+}
+
+func (c MessageCompression) GoString() string {
+	return fmt.Sprintf("%q", c.String())
+}
+
+func (c MessageCompression) MarshalText() ([]byte, error) {
+	return []byte(messageCompressionMarshal[c]), nil
+}
+
+// UnmarshalText accepts the names of the algorithms that can be configured,
+// falling back to LZ4 for anything else.
+func (c *MessageCompression) UnmarshalText(bs []byte) error {
+	var ok bool
+	if *c, ok = messageCompressionUnmarshal[string(bs)]; !ok {
+		*c = MessageCompressionLZ4
+	}
+	return nil
+}
+
+// CompressionAlgorithms returns the compression algorithms we can
+// decompress, to be announced in the Hello message.
+func CompressionAlgorithms() []MessageCompression {
+	return []MessageCompression{MessageCompressionLZ4, MessageCompressionZstd}
+}
+
+// NegotiateCompression returns the algorithm to compress messages with,
+// given the preferred one and those the other side announced. LZ4 is used
+// unless the other side supports the preferred algorithm. Devices that
+// don't announce anything are older versions that only know LZ4.
+func NegotiateCompression(preferred MessageCompression, remote []MessageCompression) MessageCompression {
+	for _, alg := range remote {
+		if alg == preferred && alg != MessageCompressionNone {
+			return alg
+		}
+	}
+	return MessageCompressionLZ4
+}
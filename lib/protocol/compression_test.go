@@ -47,3 +47,60 @@ func TestCompressionMarshal(t *testing.T) {
 		}
 	}
 }
+
+func TestMessageCompressionMarshal(t *testing.T) {
+	uTestcases := []struct {
+		s string
+		c MessageCompression
+	}{
+		{"lz4", MessageCompressionLZ4},
+		{"zstd", MessageCompressionZstd},
+		{"", MessageCompressionLZ4},
+		{"whatever", MessageCompressionLZ4},
+	}
+
+	var c MessageCompression
+	for _, tc := range uTestcases {
+		err := c.UnmarshalText([]byte(tc.s))
+		if err != nil {
+			t.Error(err)
+		}
+		if c != tc.c {
+			t.Errorf("%s unmarshalled to %v, not %v", tc.s, c, tc.c)
+		}
+		if tc.s != "lz4" && tc.s != "zstd" {
+			continue
+		}
+		bs, err := tc.c.MarshalText()
+		if err != nil {
+			t.Error(err)
+		}
+		if s := string(bs); s != tc.s {
+			t.Errorf("%v marshalled to %q, not %q", tc.c, s, tc.s)
+		}
+	}
+}
+
+func TestNegotiateCompression(t *testing.T) {
+	testcases := []struct {
+		preferred MessageCompression
+		remote    []MessageCompression
+		res       MessageCompression
+	}{
+		// Both support zstd
+		{MessageCompressionZstd, CompressionAlgorithms(), MessageCompressionZstd},
+		// The other side is an older version
+		{MessageCompressionZstd, nil, MessageCompressionLZ4},
+		{MessageCompressionZstd, []MessageCompression{MessageCompressionLZ4}, MessageCompressionLZ4},
+		// We prefer LZ4, even though the other side has more to offer
+		{MessageCompressionLZ4, CompressionAlgorithms(), MessageCompressionLZ4},
+		// Nonsense from the other side
+		{MessageCompressionNone, []MessageCompression{MessageCompressionNone}, MessageCompressionLZ4},
+	}
+
+	for _, tc := range testcases {
+		if res := NegotiateCompression(tc.preferred, tc.remote); res != tc.res {
+			t.Errorf("negotiated %v for %v and %v, expected %v", res, tc.preferred, tc.remote, tc.res)
+		}
+	}
+}
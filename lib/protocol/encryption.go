@@ -0,0 +1,510 @@
+// Copyright (C) 2019 The Protocol Authors.
+
+package protocol
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Encryption for untrusted devices.
+//
+// Folders shared with a device that has an encryption password set are
+// sent to that device in encrypted form. File names, metadata and block
+// data are encrypted with a key derived from the password and the folder
+// ID, so that the untrusted device can store and forward the data without
+// being able to read it. The untrusted device keeps the folder as
+// "receive encrypted" and treats the encrypted names, blocks and hashes as
+// opaque.
+//
+// - Names are encrypted deterministically, so that the same name always
+//   maps to the same encrypted name, and encoded as base32 split into
+//   directories to keep path components short.
+//
+// - The original FileInfo is encrypted as a whole and carried in the
+//   Encrypted field of the FileInfo sent to the untrusted device. This is
+//   what we decrypt when the untrusted device sends its index back.
+//
+// - Each block is encrypted separately with a random nonce, which makes it
+//   blockOverhead bytes larger. Blocks smaller than minPaddedSize are
+//   padded with random data first, so that small files don't leak their
+//   size.
+//
+// - The block hash is replaced by the deterministic encryption of the real
+//   hash, offset and size of the block. A request from the untrusted device
+//   carries this value, which is how we find the real block to serve.
+
+const (
+	nonceSize           = chacha20poly1305.NonceSizeX
+	tagSize             = 16 // Poly1305 authenticator
+	keySize             = chacha20poly1305.KeySize
+	blockOverhead       = tagSize + nonceSize
+	minPaddedSize       = 1024 // smallest block we'll allow
+	maxPathComponent    = 200  // characters
+	encryptedDirExt     = ".syncthing-enc"
+	encryptedModifiedS  = 1234567890 // all encrypted files have this mtime
+	encryptedHashSuffix = 8 + 4      // real offset and size, after the real hash
+)
+
+var (
+	errEncryptedTooShort = errors.New("encrypted data too short")
+	errEncryptedNonce    = errors.New("encrypted data has incorrect synthetic nonce")
+	errNotEncryptedName  = errors.New("not an encrypted file name")
+	errEncryptedNoInfo   = errors.New("no encrypted file info")
+)
+
+// The base32 alphabet is chosen to be case insensitive safe and sort in
+// the same order as the raw data.
+var base32Hex = base32.HexEncoding.WithPadding(base32.NoPadding)
+
+var (
+	folderKeysMut sync.Mutex
+	folderKeys    = make(map[string]*[keySize]byte) // folder ID + password -> key
+)
+
+// KeyFromPassword returns the folder key for the given folder ID and
+// password. The result is cached, as the derivation is deliberately slow.
+func KeyFromPassword(folderID, password string) *[keySize]byte {
+	cacheKey := folderID + "\x00" + password
+
+	folderKeysMut.Lock()
+	defer folderKeysMut.Unlock()
+	if key, ok := folderKeys[cacheKey]; ok {
+		return key
+	}
+
+	bs, err := scrypt.Key([]byte(password), []byte("syncthing"+folderID), 32768, 8, 1, keySize)
+	if err != nil {
+		panic("key derivation: " + err.Error())
+	}
+	var key [keySize]byte
+	copy(key[:], bs)
+	folderKeys[cacheKey] = &key
+	return &key
+}
+
+// fileKey returns the key used to encrypt the blocks and hashes of the
+// given file.
+func fileKey(filename string, folderKey *[keySize]byte) *[keySize]byte {
+	kdf := hkdf.New(sha256.New, folderKey[:], []byte("syncthing"), []byte(filename))
+	var key [keySize]byte
+	if _, err := io.ReadFull(kdf, key[:]); err != nil {
+		panic("key derivation: " + err.Error())
+	}
+	return &key
+}
+
+// encryptedModel wraps a Model, decrypting the index and requests coming
+// from an untrusted device.
+type encryptedModel struct {
+	Model
+	folderKeys map[string]*[keySize]byte // folder ID -> key
+}
+
+func (e encryptedModel) Index(deviceID DeviceID, folder string, files []FileInfo) {
+	if folderKey, ok := e.folderKeys[folder]; ok {
+		files = decryptFileInfos(deviceID, folder, files, folderKey)
+	}
+	e.Model.Index(deviceID, folder, files)
+}
+
+func (e encryptedModel) IndexUpdate(deviceID DeviceID, folder string, files []FileInfo) {
+	if folderKey, ok := e.folderKeys[folder]; ok {
+		files = decryptFileInfos(deviceID, folder, files, folderKey)
+	}
+	e.Model.IndexUpdate(deviceID, folder, files)
+}
+
+func (e encryptedModel) Request(deviceID DeviceID, folder, name string, size int32, offset int64, hash []byte, weakHash uint32, fromTemporary bool) (RequestResponse, error) {
+	folderKey, ok := e.folderKeys[folder]
+	if !ok {
+		return e.Model.Request(deviceID, folder, name, size, offset, hash, weakHash, fromTemporary)
+	}
+
+	// Figure out the real file name, offset, size and hash from the
+	// encrypted values.
+
+	realName, err := decryptName(name, folderKey)
+	if err != nil {
+		l.Debugf("encrypted request from %v for %q: %v", deviceID, name, err)
+		return nil, ErrNoSuchFile
+	}
+	key := fileKey(realName, folderKey)
+	realHash, realOffset, realSize, err := decryptBlockHash(hash, key)
+	if err != nil {
+		l.Debugf("encrypted request from %v for %q: %v", deviceID, realName, err)
+		return nil, ErrInvalid
+	}
+	if paddedSize(int(realSize))+blockOverhead != int(size) {
+		l.Debugf("encrypted request from %v for %q: unexpected size %d", deviceID, realName, size)
+		return nil, ErrInvalid
+	}
+
+	resp, err := e.Model.Request(deviceID, folder, realName, realSize, realOffset, realHash, 0, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pad and encrypt the response data.
+	data := make([]byte, paddedSize(int(realSize)))
+	n := copy(data, resp.Data())
+	resp.Close()
+	if _, err := rand.Read(data[n:]); err != nil {
+		return nil, ErrGeneric
+	}
+	return newRawResponse(encryptBytes(data, key)), nil
+}
+
+func (e encryptedModel) DownloadProgress(deviceID DeviceID, folder string, updates []FileDownloadProgressUpdate) {
+	if _, ok := e.folderKeys[folder]; ok {
+		// Temporary indexes of encrypted folders are not supported.
+		return
+	}
+	e.Model.DownloadProgress(deviceID, folder, updates)
+}
+
+// encryptedConnection wraps a Connection, encrypting the index sent to and
+// decrypting the blocks requested from an untrusted device.
+type encryptedConnection struct {
+	Connection
+	folderKeys map[string]*[keySize]byte // folder ID -> key
+}
+
+func (e encryptedConnection) Index(folder string, files []FileInfo) error {
+	if folderKey, ok := e.folderKeys[folder]; ok {
+		files = encryptFileInfos(files, folderKey)
+	}
+	return e.Connection.Index(folder, files)
+}
+
+func (e encryptedConnection) IndexUpdate(folder string, files []FileInfo) error {
+	if folderKey, ok := e.folderKeys[folder]; ok {
+		files = encryptFileInfos(files, folderKey)
+	}
+	return e.Connection.IndexUpdate(folder, files)
+}
+
+func (e encryptedConnection) Request(folder string, name string, blockNo int, offset int64, size int, hash []byte, weakHash uint32, fromTemporary bool) ([]byte, error) {
+	folderKey, ok := e.folderKeys[folder]
+	if !ok {
+		return e.Connection.Request(folder, name, blockNo, offset, size, hash, weakHash, fromTemporary)
+	}
+
+	// Translate the request into one for the encrypted block. The
+	// untrusted device can't verify the data, so we don't send a hash.
+	encName := encryptName(name, folderKey)
+	encOffset := offset + int64(blockNo*blockOverhead)
+	encSize := paddedSize(size) + blockOverhead
+
+	bs, err := e.Connection.Request(folder, encName, blockNo, encOffset, encSize, nil, 0, false)
+	if err != nil {
+		return nil, err
+	}
+
+	bs, err = decryptBytes(bs, fileKey(name, folderKey))
+	if err != nil {
+		return nil, err
+	}
+	if len(bs) < size {
+		return nil, errEncryptedTooShort
+	}
+	return bs[:size], nil
+}
+
+func (e encryptedConnection) DownloadProgress(folder string, updates []FileDownloadProgressUpdate) {
+	if _, ok := e.folderKeys[folder]; ok {
+		// The progress updates refer to plaintext names and blocks.
+		return
+	}
+	e.Connection.DownloadProgress(folder, updates)
+}
+
+// rawResponse is a RequestResponse for data that is not backed by a
+// buffer from the pool.
+type rawResponse struct {
+	data   []byte
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newRawResponse(data []byte) *rawResponse {
+	return &rawResponse{data: data, closed: make(chan struct{})}
+}
+
+func (r *rawResponse) Data() []byte {
+	return r.data
+}
+
+func (r *rawResponse) Close() {
+	r.once.Do(func() {
+		close(r.closed)
+	})
+}
+
+func (r *rawResponse) Wait() {
+	<-r.closed
+}
+
+func encryptFileInfos(files []FileInfo, folderKey *[keySize]byte) []FileInfo {
+	encs := make([]FileInfo, len(files))
+	for i, fi := range files {
+		encs[i] = encryptFileInfo(fi, folderKey)
+	}
+	return encs
+}
+
+// encryptFileInfo returns the encrypted version of the FileInfo, as sent
+// to an untrusted device.
+func encryptFileInfo(fi FileInfo, folderKey *[keySize]byte) FileInfo {
+	fi.LocalFlags = 0
+	bs, err := fi.Marshal()
+	if err != nil {
+		panic("impossible serialization mishap: " + err.Error())
+	}
+	encryptedFI := encryptBytes(bs, folderKey)
+
+	key := fileKey(fi.Name, folderKey)
+
+	var size int64
+	var blocks []BlockInfo
+	if fi.Type == FileInfoTypeFile && !fi.Deleted && !fi.IsInvalid() {
+		blocks = make([]BlockInfo, len(fi.Blocks))
+		for i, b := range fi.Blocks {
+			encSize := paddedSize(int(b.Size)) + blockOverhead
+			blocks[i] = BlockInfo{
+				Offset: b.Offset + int64(i*blockOverhead),
+				Size:   int32(encSize),
+				Hash:   encryptBlockHash(b, key),
+			}
+			size += int64(encSize)
+		}
+	}
+
+	// Directories stay directories. Everything else looks like a file;
+	// symlinks are not transferred to the untrusted device and become
+	// invalid files.
+	typ := FileInfoTypeFile
+	if fi.Type == FileInfoTypeDirectory {
+		typ = FileInfoTypeDirectory
+	}
+	invalid := fi.RawInvalid || fi.IsSymlink()
+	if invalid {
+		blocks = nil
+		size = 0
+	}
+
+	blockSize := int32(0)
+	if len(blocks) > 0 {
+		blockSize = int32(fi.BlockSize() + blockOverhead)
+	}
+
+	return FileInfo{
+		Name:          encryptName(fi.Name, folderKey),
+		Type:          typ,
+		Size:          size,
+		Permissions:   0644,
+		ModifiedS:     encryptedModifiedS,
+		Deleted:       fi.Deleted,
+		RawInvalid:    invalid,
+		NoPermissions: true,
+		Version:       fi.Version,
+		Sequence:      fi.Sequence,
+		RawBlockSize:  blockSize,
+		Chunking:      fi.Chunking,
+		Blocks:        blocks,
+		Encrypted:     encryptedFI,
+	}
+}
+
+// decryptFileInfos returns the decrypted versions of the files sent by an
+// untrusted device. Files that cannot be decrypted are dropped, as they
+// are not something we sent in the first place.
+func decryptFileInfos(deviceID DeviceID, folder string, files []FileInfo, folderKey *[keySize]byte) []FileInfo {
+	decs := files[:0]
+	for _, fi := range files {
+		dec, err := decryptFileInfo(fi, folderKey)
+		if err != nil {
+			l.Debugf("encrypted index from %v for folder %q: dropping %q: %v", deviceID, folder, fi.Name, err)
+			continue
+		}
+		decs = append(decs, dec)
+	}
+	return decs
+}
+
+func decryptFileInfo(fi FileInfo, folderKey *[keySize]byte) (FileInfo, error) {
+	if len(fi.Encrypted) == 0 {
+		return FileInfo{}, errEncryptedNoInfo
+	}
+	bs, err := decryptBytes(fi.Encrypted, folderKey)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	var dec FileInfo
+	if err := dec.Unmarshal(bs); err != nil {
+		return FileInfo{}, err
+	}
+	if name, err := decryptName(fi.Name, folderKey); err != nil {
+		return FileInfo{}, err
+	} else if name != dec.Name {
+		return FileInfo{}, fmt.Errorf("encrypted name %q does not match contents", name)
+	}
+
+	// The sequence number is the untrusted device's, and it may consider
+	// the file invalid even though we didn't.
+	dec.Sequence = fi.Sequence
+	dec.RawInvalid = dec.RawInvalid || fi.RawInvalid
+	dec.LocalFlags = 0
+	return dec, nil
+}
+
+// paddedSize returns the size of a block after padding.
+func paddedSize(size int) int {
+	if size < minPaddedSize {
+		return minPaddedSize
+	}
+	return size
+}
+
+func encryptBlockHash(b BlockInfo, key *[keySize]byte) []byte {
+	bs := make([]byte, len(b.Hash)+encryptedHashSuffix)
+	n := copy(bs, b.Hash)
+	binary.BigEndian.PutUint64(bs[n:], uint64(b.Offset))
+	binary.BigEndian.PutUint32(bs[n+8:], uint32(b.Size))
+	return encryptDeterministic(bs, key)
+}
+
+func decryptBlockHash(hash []byte, key *[keySize]byte) ([]byte, int64, int32, error) {
+	bs, err := decryptDeterministic(hash, key)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if len(bs) < encryptedHashSuffix {
+		return nil, 0, 0, errEncryptedTooShort
+	}
+	n := len(bs) - encryptedHashSuffix
+	offset := int64(binary.BigEndian.Uint64(bs[n:]))
+	size := int32(binary.BigEndian.Uint32(bs[n+8:]))
+	if offset < 0 || size < 0 {
+		return nil, 0, 0, ErrInvalid
+	}
+	return bs[:n], offset, size, nil
+}
+
+// encryptName returns the encrypted name, base32 encoded and split into
+// path components, like "A.syncthing-enc/BC/DEFGHIJ...".
+func encryptName(name string, folderKey *[keySize]byte) string {
+	enc := encryptDeterministic([]byte(name), folderKey)
+	return slashify(base32Hex.EncodeToString(enc))
+}
+
+func decryptName(name string, folderKey *[keySize]byte) (string, error) {
+	name, err := deslashify(name)
+	if err != nil {
+		return "", err
+	}
+	bs, err := base32Hex.DecodeString(name)
+	if err != nil {
+		return "", err
+	}
+	dec, err := decryptDeterministic(bs, folderKey)
+	if err != nil {
+		return "", err
+	}
+	return string(dec), nil
+}
+
+// encryptBytes encrypts the data with a random nonce, which is prepended
+// to the result.
+func encryptBytes(data []byte, key *[keySize]byte) []byte {
+	nonce := make([]byte, nonceSize, nonceSize+len(data)+tagSize)
+	if _, err := rand.Read(nonce); err != nil {
+		panic("catastrophic randomness failure: " + err.Error())
+	}
+	return newAEAD(key).Seal(nonce, nonce, data, nil)
+}
+
+func decryptBytes(data []byte, key *[keySize]byte) ([]byte, error) {
+	if len(data) < blockOverhead {
+		return nil, errEncryptedTooShort
+	}
+	return newAEAD(key).Open(nil, data[:nonceSize], data[nonceSize:], nil)
+}
+
+// encryptDeterministic encrypts the data using a synthetic nonce derived
+// from the data itself, so that the same data always encrypts to the same
+// result.
+func encryptDeterministic(data []byte, key *[keySize]byte) []byte {
+	nonce := syntheticNonce(data, key)
+	out := make([]byte, nonceSize, nonceSize+len(data)+tagSize)
+	copy(out, nonce)
+	return newAEAD(key).Seal(out, nonce, data, nil)
+}
+
+func decryptDeterministic(data []byte, key *[keySize]byte) ([]byte, error) {
+	dec, err := decryptBytes(data, key)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(data[:nonceSize], syntheticNonce(dec, key)) {
+		return nil, errEncryptedNonce
+	}
+	return dec, nil
+}
+
+func syntheticNonce(data []byte, key *[keySize]byte) []byte {
+	mac := hmac.New(sha256.New, key[:])
+	mac.Write([]byte("syncthing synthetic nonce"))
+	mac.Write(data)
+	return mac.Sum(nil)[:nonceSize]
+}
+
+func newAEAD(key *[keySize]byte) cipher.AEAD {
+	aead, err := chacha20poly1305.NewX(key[:])
+	if err != nil {
+		panic("cipher failure: " + err.Error())
+	}
+	return aead
+}
+
+// slashify inserts slashes in the encoded name, to keep path components
+// short and to not create enormous directories.
+func slashify(s string) string {
+	// The first character is a directory, with the encrypted extension to
+	// make it obvious what it is.
+	var parts []string
+	parts = append(parts, s[:1]+encryptedDirExt)
+	s = s[1:]
+	// The next two characters are a second level directory.
+	if len(s) > 2 {
+		parts = append(parts, s[:2])
+		s = s[2:]
+	}
+	for len(s) > maxPathComponent {
+		parts = append(parts, s[:maxPathComponent])
+		s = s[maxPathComponent:]
+	}
+	parts = append(parts, s)
+	return strings.Join(parts, "/")
+}
+
+func deslashify(s string) (string, error) {
+	if len(s) == 0 || !strings.HasPrefix(s[1:], encryptedDirExt+"/") {
+		return "", errNotEncryptedName
+	}
+	s = s[:1] + s[1+len(encryptedDirExt):]
+	return strings.Replace(s, "/", "", -1), nil
+}
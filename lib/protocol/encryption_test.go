@@ -0,0 +1,247 @@
+// Copyright (C) 2019 The Protocol Authors.
+
+package protocol
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEnDecryptName(t *testing.T) {
+	key := KeyFromPassword("default", "password")
+	otherKey := KeyFromPassword("other", "password")
+
+	names := []string{
+		"a",
+		"dir/file.txt",
+		"räksmörgås/ünïcödé",
+		strings.Repeat("long name ", 50),
+	}
+	for _, name := range names {
+		enc := encryptName(name, key)
+		if enc == name {
+			t.Errorf("name %q not encrypted", name)
+		}
+		if enc2 := encryptName(name, key); enc2 != enc {
+			t.Errorf("name encryption is not deterministic: %q != %q", enc, enc2)
+		}
+		if encryptName(name, otherKey) == enc {
+			t.Errorf("name %q encrypts the same with different keys", name)
+		}
+		for _, part := range strings.Split(enc, "/") {
+			if len(part) > maxPathComponent {
+				t.Errorf("path component too long: %d", len(part))
+			}
+		}
+		if !strings.HasPrefix(enc[1:], encryptedDirExt+"/") {
+			t.Errorf("unexpected encrypted name structure %q", enc)
+		}
+
+		dec, err := decryptName(enc, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dec != name {
+			t.Errorf("decrypted name %q != %q", dec, name)
+		}
+		if _, err := decryptName(enc, otherKey); err == nil {
+			t.Error("name should not decrypt with the wrong key")
+		}
+	}
+
+	if _, err := decryptName("not/encrypted", key); err == nil {
+		t.Error("plain name should not decrypt")
+	}
+}
+
+func TestEnDecryptBytes(t *testing.T) {
+	key := KeyFromPassword("default", "password")
+	data := []byte("hello world, this is some data")
+
+	enc := encryptBytes(data, key)
+	if len(enc) != len(data)+blockOverhead {
+		t.Errorf("unexpected encrypted length %d", len(enc))
+	}
+	if bytes.Equal(enc, encryptBytes(data, key)) {
+		t.Error("random nonce encryption should not be deterministic")
+	}
+
+	dec, err := decryptBytes(enc, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dec, data) {
+		t.Errorf("decrypted data %q != %q", dec, data)
+	}
+
+	enc[len(enc)-1]++
+	if _, err := decryptBytes(enc, key); err == nil {
+		t.Error("tampered data should not decrypt")
+	}
+	if _, err := decryptBytes(enc[:blockOverhead-1], key); err != errEncryptedTooShort {
+		t.Error("expected too short error, got", err)
+	}
+}
+
+func TestEnDecryptFileInfo(t *testing.T) {
+	key := KeyFromPassword("default", "password")
+
+	blockSize := MinBlockSize
+	fi := FileInfo{
+		Name:         "dir/file",
+		Type:         FileInfoTypeFile,
+		Size:         int64(blockSize) + 100,
+		Permissions:  0755,
+		ModifiedS:    1500000000,
+		Version:      Vector{}.Update(1),
+		Sequence:     42,
+		RawBlockSize: int32(blockSize),
+		Blocks: []BlockInfo{
+			{Offset: 0, Size: int32(blockSize), Hash: []byte{1, 2, 3}},
+			{Offset: int64(blockSize), Size: 100, Hash: []byte{4, 5, 6}},
+		},
+	}
+
+	enc := encryptFileInfo(fi, key)
+	if enc.Name == fi.Name || enc.ModifiedS == fi.ModifiedS || !enc.NoPermissions {
+		t.Error("metadata not hidden")
+	}
+	if !enc.Version.Equal(fi.Version) || enc.Sequence != fi.Sequence {
+		t.Error("version and sequence should be retained")
+	}
+	if enc.BlockSize() != blockSize+blockOverhead {
+		t.Error("unexpected block size", enc.BlockSize())
+	}
+	expBlocks := []BlockInfo{
+		{Offset: 0, Size: int32(blockSize + blockOverhead)},
+		{Offset: int64(blockSize + blockOverhead), Size: minPaddedSize + blockOverhead},
+	}
+	for i, b := range enc.Blocks {
+		if b.Offset != expBlocks[i].Offset || b.Size != expBlocks[i].Size {
+			t.Errorf("unexpected encrypted block %d: %v", i, b)
+		}
+		if bytes.Equal(b.Hash, fi.Blocks[i].Hash) {
+			t.Errorf("block hash %d not encrypted", i)
+		}
+	}
+	if enc.Size != int64(blockSize+minPaddedSize+2*blockOverhead) {
+		t.Error("unexpected encrypted size", enc.Size)
+	}
+
+	// The untrusted device sends it back with its own sequence number
+	enc.Sequence = 7
+	dec, err := decryptFileInfo(enc, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi.Sequence = 7
+	if !reflect.DeepEqual(dec, fi) {
+		t.Errorf("decrypted file info differs:\n%v\n%v", dec, fi)
+	}
+
+	// Symlinks are not sent to the untrusted device
+	sl := FileInfo{Name: "link", Type: FileInfoTypeSymlink, SymlinkTarget: "target"}
+	enc = encryptFileInfo(sl, key)
+	if enc.Type != FileInfoTypeFile || !enc.IsInvalid() || strings.Contains(string(enc.Encrypted), "target") {
+		t.Error("unexpected encrypted symlink", enc)
+	}
+
+	if _, err := decryptFileInfo(FileInfo{Name: "foo"}, key); err != errEncryptedNoInfo {
+		t.Error("expected no info error, got", err)
+	}
+}
+
+func TestEncryptedConnection(t *testing.T) {
+	// The trusted device c0 shares the folder with the untrusted device c1
+	// using a password.
+
+	data := []byte("hello world")
+	hash := sha256.Sum256(data)
+	fi := FileInfo{
+		Name:    "dir/file",
+		Type:    FileInfoTypeFile,
+		Size:    int64(len(data)),
+		Version: Vector{}.Update(1),
+		Blocks:  []BlockInfo{{Size: int32(len(data)), Hash: hash[:]}},
+	}
+
+	m0 := newTestModel()
+	m0.data = data
+	index0 := make(chan []FileInfo, 1)
+	m0.indexFn = func(_ DeviceID, _ string, files []FileInfo) { index0 <- files }
+	m1 := newTestModel()
+	index1 := make(chan []FileInfo, 1)
+	m1.indexFn = func(_ DeviceID, _ string, files []FileInfo) { index1 <- files }
+
+	ar, aw := io.Pipe()
+	br, bw := io.Pipe()
+
+	c0 := NewConnection(c0ID, ar, bw, m0, "c0", CompressNever, MessageCompressionLZ4, map[string]string{"default": "password"})
+	c0.Start()
+	c1 := NewConnection(c1ID, br, aw, m1, "c1", CompressNever, MessageCompressionLZ4, nil)
+	c1.Start()
+	c0.ClusterConfig(ClusterConfig{})
+	c1.ClusterConfig(ClusterConfig{})
+	defer c0.Close(errManual)
+	defer c1.Close(errManual)
+
+	// The untrusted device gets the encrypted index.
+
+	if err := c0.Index("default", []FileInfo{fi}); err != nil {
+		t.Fatal(err)
+	}
+	files := <-index1
+	if len(files) != 1 {
+		t.Fatal("unexpected index", files)
+	}
+	enc := files[0]
+	if enc.Name == fi.Name || len(enc.Encrypted) == 0 || len(enc.Blocks) != 1 {
+		t.Fatal("unexpected encrypted file info", enc)
+	}
+
+	// The untrusted device requests the encrypted block, and the trusted
+	// device serves it from the real file.
+
+	encData, err := c1.Request("default", enc.Name, 0, enc.Blocks[0].Offset, int(enc.Blocks[0].Size), enc.Blocks[0].Hash, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(encData) != minPaddedSize+blockOverhead {
+		t.Error("unexpected encrypted block length", len(encData))
+	}
+	if bytes.Contains(encData, data) {
+		t.Error("block data not encrypted")
+	}
+	if m0.name != fi.Name || m0.size != int32(len(data)) || !bytes.Equal(m0.hash, hash[:]) {
+		t.Errorf("unexpected request to the trusted model: %q %d %x", m0.name, m0.size, m0.hash)
+	}
+
+	// A trusted device requests the block from the untrusted device and
+	// gets the plaintext back.
+
+	m1.data = encData
+	dec, err := c0.Request("default", fi.Name, 0, 0, len(data), hash[:], 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dec, data) {
+		t.Errorf("decrypted block %q != %q", dec, data)
+	}
+	if m1.name != enc.Name || m1.size != int32(minPaddedSize+blockOverhead) || m1.hash != nil {
+		t.Errorf("unexpected request to the untrusted model: %q %d %x", m1.name, m1.size, m1.hash)
+	}
+
+	// The index sent back by the untrusted device is decrypted.
+
+	if err := c1.Index("default", files); err != nil {
+		t.Fatal(err)
+	}
+	files = <-index0
+	if len(files) != 1 || files[0].Name != fi.Name || !reflect.DeepEqual(files[0].Blocks, fi.Blocks) {
+		t.Error("unexpected decrypted index", files)
+	}
+}
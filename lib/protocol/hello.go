@@ -20,9 +20,12 @@ type HelloIntf interface {
 // The HelloResult is the non version specific interpretation of the other
 // side's Hello message.
 type HelloResult struct {
-	DeviceName    string
-	ClientName    string
-	ClientVersion string
+	DeviceName            string
+	ClientName            string
+	ClientVersion         string
+	CompressionAlgorithms []MessageCompression
+	NumConnections        int32
+	Secondary             bool
 }
 
 var (
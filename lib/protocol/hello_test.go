@@ -14,9 +14,10 @@ func TestVersion14Hello(t *testing.T) {
 	// Tests that we can send and receive a version 0.14 hello message.
 
 	expected := Hello{
-		DeviceName:    "test device",
-		ClientName:    "syncthing",
-		ClientVersion: "v0.14.5",
+		DeviceName:            "test device",
+		ClientName:            "syncthing",
+		ClientVersion:         "v0.14.5",
+		CompressionAlgorithms: []MessageCompression{MessageCompressionLZ4, MessageCompressionZstd},
 	}
 	msgBuf, err := expected.Marshal()
 	if err != nil {
@@ -55,6 +56,9 @@ func TestVersion14Hello(t *testing.T) {
 	if res.DeviceName != expected.DeviceName {
 		t.Errorf("incorrect DeviceName %q != expected %q", res.DeviceName, expected.DeviceName)
 	}
+	if len(res.CompressionAlgorithms) != 2 || res.CompressionAlgorithms[1] != MessageCompressionZstd {
+		t.Errorf("incorrect CompressionAlgorithms %v != expected %v", res.CompressionAlgorithms, expected.CompressionAlgorithms)
+	}
 }
 
 func TestOldHelloMsgs(t *testing.T) {
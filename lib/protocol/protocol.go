@@ -14,6 +14,7 @@ import (
 	"time"
 
 	lz4 "github.com/bkaradzic/go-lz4"
+	"github.com/klauspost/compress/zstd"
 )
 
 const (
@@ -159,7 +160,7 @@ type Connection interface {
 	Name() string
 	Index(folder string, files []FileInfo) error
 	IndexUpdate(folder string, files []FileInfo) error
-	Request(folder string, name string, offset int64, size int, hash []byte, weakHash uint32, fromTemporary bool) ([]byte, error)
+	Request(folder string, name string, blockNo int, offset int64, size int, hash []byte, weakHash uint32, fromTemporary bool) ([]byte, error)
 	ClusterConfig(config ClusterConfig)
 	DownloadProgress(folder string, updates []FileDownloadProgressUpdate)
 	Statistics() Statistics
@@ -192,6 +193,7 @@ type rawConnection struct {
 	closeOnce             sync.Once
 	sendCloseOnce         sync.Once
 	compression           Compression
+	compressionAlgorithm  MessageCompression
 }
 
 type asyncResult struct {
@@ -225,14 +227,29 @@ const (
 // Should not be modified in production code, just for testing.
 var CloseTimeout = 10 * time.Second
 
-func NewConnection(deviceID DeviceID, reader io.Reader, writer io.Writer, receiver Model, name string, compress Compression) Connection {
+// NewConnection returns a new connection to the given device. Messages are
+// compressed according to compress, using the given algorithm, which the
+// other side must support (see NegotiateCompression). The passwords map
+// holds the encryption password for each folder that is shared with the
+// device in encrypted form, and may be nil.
+func NewConnection(deviceID DeviceID, reader io.Reader, writer io.Writer, receiver Model, name string, compress Compression, algorithm MessageCompression, passwords map[string]string) Connection {
 	cr := &countingReader{Reader: reader}
 	cw := &countingWriter{Writer: writer}
 
+	receiver = nativeModel{receiver}
+	var keys map[string]*[keySize]byte
+	if len(passwords) > 0 {
+		keys = make(map[string]*[keySize]byte, len(passwords))
+		for folder, password := range passwords {
+			keys[folder] = KeyFromPassword(folder, password)
+		}
+		receiver = encryptedModel{Model: receiver, folderKeys: keys}
+	}
+
 	c := rawConnection{
 		id:                    deviceID,
 		name:                  name,
-		receiver:              nativeModel{receiver},
+		receiver:              receiver,
 		cr:                    cr,
 		cw:                    cw,
 		awaiting:              make(map[int32]chan asyncResult),
@@ -244,8 +261,12 @@ func NewConnection(deviceID DeviceID, reader io.Reader, writer io.Writer, receiv
 		preventSends:          make(chan struct{}),
 		closed:                make(chan struct{}),
 		compression:           compress,
+		compressionAlgorithm:  algorithm,
 	}
 
+	if keys != nil {
+		return wireFormatConnection{encryptedConnection{Connection: &c, folderKeys: keys}}
+	}
 	return wireFormatConnection{&c}
 }
 
@@ -303,7 +324,7 @@ func (c *rawConnection) IndexUpdate(folder string, idx []FileInfo) error {
 }
 
 // Request returns the bytes for the specified block after fetching them from the connected peer.
-func (c *rawConnection) Request(folder string, name string, offset int64, size int, hash []byte, weakHash uint32, fromTemporary bool) ([]byte, error) {
+func (c *rawConnection) Request(folder string, name string, blockNo int, offset int64, size int, hash []byte, weakHash uint32, fromTemporary bool) ([]byte, error) {
 	c.nextIDMut.Lock()
 	id := c.nextID
 	c.nextID++
@@ -514,6 +535,14 @@ func (c *rawConnection) readMessageAfterHeader(hdr Header, fourByteBuf []byte) (
 		}
 		buf = decomp
 
+	case MessageCompressionZstd:
+		decomp, err := zstdDecompress(buf)
+		BufferPool.Put(buf)
+		if err != nil {
+			return nil, fmt.Errorf("decompressing message: %v", err)
+		}
+		buf = decomp
+
 	default:
 		return nil, fmt.Errorf("unknown message compression %d", hdr.Compression)
 	}
@@ -725,14 +754,24 @@ func (c *rawConnection) writeCompressedMessage(msg message) error {
 		return fmt.Errorf("marshalling message: %v", err)
 	}
 
-	compressed, err := c.lz4Compress(buf)
+	var compressed []byte
+	var err error
+	switch c.compressionAlgorithm {
+	case MessageCompressionZstd:
+		compressed = zstdCompress(buf)
+	case MessageCompressionLZ4:
+		compressed, err = c.lz4Compress(buf)
+	default:
+		BufferPool.Put(buf)
+		return fmt.Errorf("unknown compression algorithm %v", c.compressionAlgorithm)
+	}
 	if err != nil {
 		return fmt.Errorf("compressing message: %v", err)
 	}
 
 	hdr := Header{
 		Type:        c.typeOf(msg),
-		Compression: MessageCompressionLZ4,
+		Compression: c.compressionAlgorithm,
 	}
 	hdrSize := hdr.ProtoSize()
 	if hdrSize > 1<<16-1 {
@@ -848,6 +887,13 @@ func (c *rawConnection) newMessage(t MessageType) (message, error) {
 }
 
 func (c *rawConnection) shouldCompressMessage(msg message) bool {
+	switch c.compressionAlgorithm {
+	case MessageCompressionLZ4, MessageCompressionZstd:
+	default:
+		// Nothing was negotiated that we can compress with.
+		return false
+	}
+
 	switch c.compression {
 	case CompressNever:
 		return false
@@ -1001,3 +1047,35 @@ func (c *rawConnection) lz4Decompress(src []byte) ([]byte, error) {
 	}
 	return buf, nil
 }
+
+// The zstd encoder and decoder are safe for concurrent use and shared by all
+// connections. They are created on first use, as they hold on to some
+// goroutines and memory.
+var (
+	zstdEncoder     *zstd.Encoder
+	zstdEncoderOnce sync.Once
+	zstdDecoder     *zstd.Decoder
+	zstdDecoderOnce sync.Once
+)
+
+func zstdCompress(src []byte) []byte {
+	zstdEncoderOnce.Do(func() {
+		var err error
+		zstdEncoder, err = zstd.NewWriter(nil)
+		if err != nil {
+			panic("bug: creating zstd encoder: " + err.Error())
+		}
+	})
+	return zstdEncoder.EncodeAll(src, BufferPool.Get(len(src))[:0])
+}
+
+func zstdDecompress(src []byte) ([]byte, error) {
+	zstdDecoderOnce.Do(func() {
+		var err error
+		zstdDecoder, err = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(MaxMessageLen))
+		if err != nil {
+			panic("bug: creating zstd decoder: " + err.Error())
+		}
+	})
+	return zstdDecoder.DecodeAll(src, nil)
+}
@@ -8,6 +8,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"runtime"
@@ -30,9 +31,9 @@ func TestPing(t *testing.T) {
 	ar, aw := io.Pipe()
 	br, bw := io.Pipe()
 
-	c0 := NewConnection(c0ID, ar, bw, newTestModel(), "name", CompressAlways).(wireFormatConnection).Connection.(*rawConnection)
+	c0 := NewConnection(c0ID, ar, bw, newTestModel(), "name", CompressAlways, MessageCompressionLZ4, nil).(wireFormatConnection).Connection.(*rawConnection)
 	c0.Start()
-	c1 := NewConnection(c1ID, br, aw, newTestModel(), "name", CompressAlways).(wireFormatConnection).Connection.(*rawConnection)
+	c1 := NewConnection(c1ID, br, aw, newTestModel(), "name", CompressAlways, MessageCompressionLZ4, nil).(wireFormatConnection).Connection.(*rawConnection)
 	c1.Start()
 	c0.ClusterConfig(ClusterConfig{})
 	c1.ClusterConfig(ClusterConfig{})
@@ -54,9 +55,9 @@ func TestClose(t *testing.T) {
 	ar, aw := io.Pipe()
 	br, bw := io.Pipe()
 
-	c0 := NewConnection(c0ID, ar, bw, m0, "name", CompressAlways).(wireFormatConnection).Connection.(*rawConnection)
+	c0 := NewConnection(c0ID, ar, bw, m0, "name", CompressAlways, MessageCompressionLZ4, nil).(wireFormatConnection).Connection.(*rawConnection)
 	c0.Start()
-	c1 := NewConnection(c1ID, br, aw, m1, "name", CompressAlways)
+	c1 := NewConnection(c1ID, br, aw, m1, "name", CompressAlways, MessageCompressionLZ4, nil)
 	c1.Start()
 	c0.ClusterConfig(ClusterConfig{})
 	c1.ClusterConfig(ClusterConfig{})
@@ -77,7 +78,7 @@ func TestClose(t *testing.T) {
 	c0.Index("default", nil)
 	c0.Index("default", nil)
 
-	if _, err := c0.Request("default", "foo", 0, 0, nil, 0, false); err == nil {
+	if _, err := c0.Request("default", "foo", 0, 0, 0, nil, 0, false); err == nil {
 		t.Error("Request should return an error")
 	}
 }
@@ -94,7 +95,7 @@ func TestCloseOnBlockingSend(t *testing.T) {
 
 	m := newTestModel()
 
-	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.BlockingRW{}, m, "name", CompressAlways).(wireFormatConnection).Connection.(*rawConnection)
+	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.BlockingRW{}, m, "name", CompressAlways, MessageCompressionLZ4, nil).(wireFormatConnection).Connection.(*rawConnection)
 	c.Start()
 
 	wg := sync.WaitGroup{}
@@ -144,9 +145,9 @@ func TestCloseRace(t *testing.T) {
 	ar, aw := io.Pipe()
 	br, bw := io.Pipe()
 
-	c0 := NewConnection(c0ID, ar, bw, m0, "c0", CompressNever).(wireFormatConnection).Connection.(*rawConnection)
+	c0 := NewConnection(c0ID, ar, bw, m0, "c0", CompressNever, MessageCompressionLZ4, nil).(wireFormatConnection).Connection.(*rawConnection)
 	c0.Start()
-	c1 := NewConnection(c1ID, br, aw, m1, "c1", CompressNever)
+	c1 := NewConnection(c1ID, br, aw, m1, "c1", CompressNever, MessageCompressionLZ4, nil)
 	c1.Start()
 	c0.ClusterConfig(ClusterConfig{})
 	c1.ClusterConfig(ClusterConfig{})
@@ -181,7 +182,7 @@ func TestCloseRace(t *testing.T) {
 func TestClusterConfigFirst(t *testing.T) {
 	m := newTestModel()
 
-	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.NoopRW{}, m, "name", CompressAlways).(wireFormatConnection).Connection.(*rawConnection)
+	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.NoopRW{}, m, "name", CompressAlways, MessageCompressionLZ4, nil).(wireFormatConnection).Connection.(*rawConnection)
 	c.Start()
 
 	select {
@@ -231,7 +232,7 @@ func TestCloseTimeout(t *testing.T) {
 
 	m := newTestModel()
 
-	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.BlockingRW{}, m, "name", CompressAlways).(wireFormatConnection).Connection.(*rawConnection)
+	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.BlockingRW{}, m, "name", CompressAlways, MessageCompressionLZ4, nil).(wireFormatConnection).Connection.(*rawConnection)
 	c.Start()
 
 	done := make(chan struct{})
@@ -434,6 +435,184 @@ func TestLZ4Compression(t *testing.T) {
 	}
 }
 
+func TestZstdCompression(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		dataLen := 150 + rand.Intn(150)
+		data := make([]byte, dataLen)
+		_, err := io.ReadFull(rand.Reader, data[100:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		comp := zstdCompress(data)
+
+		res, err := zstdDecompress(comp)
+		if err != nil {
+			t.Errorf("decompressing %d bytes to %d: %v", len(comp), dataLen, err)
+			continue
+		}
+		if !bytes.Equal(data, res) {
+			t.Error("Incorrect decompressed data")
+		}
+		t.Logf("OK #%d, %d -> %d -> %d", i, dataLen, len(comp), dataLen)
+	}
+}
+
+func TestCompressionAlgorithms(t *testing.T) {
+	// Each side compresses with its own algorithm, the other side must be
+	// able to make sense of it regardless.
+	testCompressionAlgorithms(t, MessageCompressionZstd, MessageCompressionLZ4)
+}
+
+func TestNoCompressionAlgorithm(t *testing.T) {
+	// Without an algorithm we can compress with, messages are sent
+	// uncompressed even when compression is enabled.
+	testCompressionAlgorithms(t, MessageCompressionNone, MessageCompression(42))
+}
+
+func testCompressionAlgorithms(t *testing.T, alg0, alg1 MessageCompression) {
+	t.Helper()
+
+	ar, aw := io.Pipe()
+	br, bw := io.Pipe()
+
+	m0 := newTestModel()
+	m1 := newTestModel()
+	received := make(chan []FileInfo, 2)
+	m0.indexFn = func(_ DeviceID, _ string, fs []FileInfo) { received <- fs }
+	m1.indexFn = m0.indexFn
+
+	c0 := NewConnection(c0ID, ar, bw, m0, "c0", CompressAlways, alg0, nil)
+	c0.Start()
+	defer c0.Close(errManual)
+	c1 := NewConnection(c1ID, br, aw, m1, "c1", CompressAlways, alg1, nil)
+	c1.Start()
+	defer c1.Close(errManual)
+
+	c0.ClusterConfig(ClusterConfig{})
+	c1.ClusterConfig(ClusterConfig{})
+
+	files := testIndex(100)
+	if err := c0.Index("default", files); err != nil {
+		t.Fatal(err)
+	}
+	if err := c1.Index("default", files); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case fs := <-received:
+			if len(fs) != len(files) || fs[99].Name != files[99].Name {
+				t.Error("received a different index")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for index")
+		}
+	}
+}
+
+// testIndex returns an index similar to what a folder with many small files
+// would send.
+func testIndex(n int) []FileInfo {
+	files := make([]FileInfo, n)
+	for i := range files {
+		hash := sha256.Sum256([]byte(rand.String(8)))
+		files[i] = FileInfo{
+			Name:        fmt.Sprintf("some/directory/structure/file-%06d.txt", i),
+			Type:        FileInfoTypeFile,
+			Size:        int64(rand.Intn(MinBlockSize)),
+			Permissions: 0644,
+			ModifiedS:   time.Now().Unix(),
+			ModifiedBy:  c0ID.Short(),
+			Version:     Vector{}.Update(c0ID.Short()),
+			Sequence:    int64(i + 1),
+			Blocks:      []BlockInfo{{Size: MinBlockSize, Hash: hash[:]}},
+		}
+	}
+	return files
+}
+
+func BenchmarkCompressIndexLZ4(b *testing.B) {
+	benchmarkCompressIndex(b, MessageCompressionLZ4)
+}
+
+func BenchmarkCompressIndexZstd(b *testing.B) {
+	benchmarkCompressIndex(b, MessageCompressionZstd)
+}
+
+func BenchmarkDecompressIndexLZ4(b *testing.B) {
+	benchmarkDecompressIndex(b, MessageCompressionLZ4)
+}
+
+func BenchmarkDecompressIndexZstd(b *testing.B) {
+	benchmarkDecompressIndex(b, MessageCompressionZstd)
+}
+
+func benchmarkCompressIndex(b *testing.B, alg MessageCompression) {
+	c := new(rawConnection)
+	data := marshalledTestIndex(b)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+
+	var comp []byte
+	for i := 0; i < b.N; i++ {
+		comp = compressWith(b, c, alg, data)
+		BufferPool.Put(comp)
+	}
+	b.Logf("%v: %d -> %d bytes", alg, len(data), len(comp))
+}
+
+func benchmarkDecompressIndex(b *testing.B, alg MessageCompression) {
+	c := new(rawConnection)
+	data := marshalledTestIndex(b)
+	comp := compressWith(b, c, alg, data)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		// The LZ4 decompression mangles the length prefix in place.
+		src := append([]byte(nil), comp...)
+		var err error
+		switch alg {
+		case MessageCompressionLZ4:
+			_, err = c.lz4Decompress(src)
+		case MessageCompressionZstd:
+			_, err = zstdDecompress(src)
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func marshalledTestIndex(b *testing.B) []byte {
+	idx := &Index{Folder: "default", Files: testIndex(10000)}
+	data, err := idx.Marshal()
+	if err != nil {
+		b.Fatal(err)
+	}
+	return data
+}
+
+func compressWith(b *testing.B, c *rawConnection, alg MessageCompression, data []byte) []byte {
+	switch alg {
+	case MessageCompressionLZ4:
+		comp, err := c.lz4Compress(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+		return comp
+	case MessageCompressionZstd:
+		return zstdCompress(data)
+	}
+	b.Fatal("unknown algorithm", alg)
+	return nil
+}
+
 func TestCheckFilename(t *testing.T) {
 	cases := []struct {
 		name string
@@ -553,6 +732,41 @@ func TestBlockSize(t *testing.T) {
 	}
 }
 
+func TestBlockIndex(t *testing.T) {
+	fixed := FileInfo{
+		RawBlockSize: 128 << KiB,
+		Blocks:       []BlockInfo{{Offset: 0, Size: 128 << KiB}, {Offset: 128 << KiB, Size: 128 << KiB}, {Offset: 256 << KiB, Size: 10}},
+	}
+	cdc := FileInfo{
+		Chunking:     BlockChunkingContentDefined,
+		RawBlockSize: 128 << KiB,
+		Blocks:       []BlockInfo{{Offset: 0, Size: 100}, {Offset: 100, Size: 300 << KiB}, {Offset: 100 + 300<<KiB, Size: 50}},
+	}
+
+	cases := []struct {
+		file   FileInfo
+		offset int64
+		index  int
+	}{
+		{fixed, 0, 0},
+		{fixed, 128<<KiB - 1, 0},
+		{fixed, 128 << KiB, 1},
+		{fixed, 256<<KiB + 5, 2},
+		{cdc, 0, 0},
+		{cdc, 99, 0},
+		{cdc, 100, 1},
+		{cdc, 128 << KiB, 1},
+		{cdc, 100 + 300<<KiB, 2},
+		{cdc, 150 + 300<<KiB, 3},
+	}
+
+	for _, tc := range cases {
+		if idx := tc.file.BlockIndex(tc.offset); idx != tc.index {
+			t.Errorf("BlockIndex(%d) with chunking %v = %d, expected %d", tc.offset, tc.file.Chunking, idx, tc.index)
+		}
+	}
+}
+
 var blockSize int
 
 func BenchmarkBlockSize(b *testing.B) {
@@ -744,6 +958,40 @@ func TestIsEquivalent(t *testing.T) {
 			b:  FileInfo{Type: FileInfoTypeFile, SymlinkTarget: "b"},
 			eq: true,
 		},
+
+		// Extended attributes are checked when known on both sides
+		{
+			a:  FileInfo{Xattrs: &Xattrs{Entries: []Xattr{{Name: "user.a", Value: []byte("a")}}}},
+			b:  FileInfo{Xattrs: &Xattrs{Entries: []Xattr{{Name: "user.a", Value: []byte("b")}}}},
+			eq: false,
+		},
+		{
+			a:  FileInfo{Xattrs: &Xattrs{Entries: []Xattr{{Name: "user.a", Value: []byte("a")}}}},
+			b:  FileInfo{Xattrs: &Xattrs{}},
+			eq: false,
+		},
+		{
+			a:  FileInfo{Xattrs: &Xattrs{Entries: []Xattr{{Name: "user.a", Value: []byte("a")}}}},
+			b:  FileInfo{},
+			eq: true,
+		},
+
+		// As is ownership
+		{
+			a:  FileInfo{Ownership: &Ownership{OwnerName: "alice", Uid: 1000}},
+			b:  FileInfo{Ownership: &Ownership{OwnerName: "bob", Uid: 1000}},
+			eq: false,
+		},
+		{
+			a:  FileInfo{Ownership: &Ownership{OwnerName: "alice", Uid: 1000}},
+			b:  FileInfo{Ownership: &Ownership{OwnerName: "alice", Uid: 1000}},
+			eq: true,
+		},
+		{
+			a:  FileInfo{Ownership: &Ownership{OwnerName: "alice", Uid: 1000}},
+			b:  FileInfo{},
+			eq: true,
+		},
 	}
 
 	if runtime.GOOS == "windows" {
@@ -796,7 +1044,7 @@ func TestSha256OfEmptyBlock(t *testing.T) {
 func TestClusterConfigAfterClose(t *testing.T) {
 	m := newTestModel()
 
-	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.BlockingRW{}, m, "name", CompressAlways).(wireFormatConnection).Connection.(*rawConnection)
+	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.BlockingRW{}, m, "name", CompressAlways, MessageCompressionLZ4, nil).(wireFormatConnection).Connection.(*rawConnection)
 	c.Start()
 
 	c.internalClose(errManual)
@@ -818,7 +1066,7 @@ func TestDispatcherToCloseDeadlock(t *testing.T) {
 	// Verify that we don't deadlock when calling Close() from within one of
 	// the model callbacks (ClusterConfig).
 	m := newTestModel()
-	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.NoopRW{}, m, "name", CompressAlways).(wireFormatConnection).Connection.(*rawConnection)
+	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.NoopRW{}, m, "name", CompressAlways, MessageCompressionLZ4, nil).(wireFormatConnection).Connection.(*rawConnection)
 	m.ccFn = func(devID DeviceID, cc ClusterConfig) {
 		c.Close(errManual)
 	}
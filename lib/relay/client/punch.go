@@ -0,0 +1,47 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package client
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/syncthing/syncthing/lib/relay/protocol"
+)
+
+// ExchangePunchAddresses sends our addresses to the peer at the other end
+// of a session and returns those it sends us. Both peers call it at the
+// same time, before anything else is sent over the session, when they
+// have agreed to try for a direct connection.
+func ExchangePunchAddresses(conn io.ReadWriter, addresses []string) ([]string, error) {
+	if len(addresses) > 16 {
+		addresses = addresses[:16]
+	}
+
+	// Don't depend on the session being buffered, or we might both be
+	// stuck writing.
+	written := make(chan error, 1)
+	go func() {
+		written <- protocol.WriteMessage(conn, protocol.PunchAddresses{Addresses: addresses})
+	}()
+
+	message, err := protocol.ReadMessage(conn)
+	if err != nil {
+		return nil, err
+	}
+	if err := <-written; err != nil {
+		return nil, err
+	}
+
+	switch msg := message.(type) {
+	case protocol.PunchAddresses:
+		l.Debugln("Received punch addresses", msg.Addresses)
+		return msg.Addresses, nil
+	default:
+		return nil, fmt.Errorf("protocol error: expecting punch addresses got %v", msg)
+	}
+}
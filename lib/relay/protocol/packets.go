@@ -21,6 +21,7 @@ const (
 	messageTypeConnectRequest
 	messageTypeSessionInvitation
 	messageTypeRelayFull
+	messageTypePunchAddresses
 )
 
 type header struct {
@@ -43,6 +44,12 @@ type Response struct {
 	Message string
 }
 
+// PunchAddresses is sent by both peers at the start of a session, when
+// they have agreed to try to replace it with a direct connection.
+type PunchAddresses struct {
+	Addresses []string // max:16
+}
+
 type ConnectRequest struct {
 	ID []byte // max:32
 }
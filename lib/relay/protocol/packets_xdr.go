@@ -316,6 +316,87 @@ func (o *Response) UnmarshalXDRFrom(u *xdr.Unmarshaller) error {
 
 /*
 
+PunchAddresses Structure:
+
+ 0                   1                   2                   3
+ 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+|                      Number of Addresses                      |
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+/                                                               /
+/                                                               /
+\               Addresses (length + padded data)                \
+/                                                               /
+/                                                               /
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+
+
+struct PunchAddresses {
+	string Addresses<16>;
+}
+
+*/
+
+func (o PunchAddresses) XDRSize() int {
+	return 4 + xdr.SizeOfSlice(o.Addresses)
+}
+
+func (o PunchAddresses) MarshalXDR() ([]byte, error) {
+	buf := make([]byte, o.XDRSize())
+	m := &xdr.Marshaller{Data: buf}
+	return buf, o.MarshalXDRInto(m)
+}
+
+func (o PunchAddresses) MustMarshalXDR() []byte {
+	bs, err := o.MarshalXDR()
+	if err != nil {
+		panic(err)
+	}
+	return bs
+}
+
+func (o PunchAddresses) MarshalXDRInto(m *xdr.Marshaller) error {
+	if l := len(o.Addresses); l > 16 {
+		return xdr.ElementSizeExceeded("Addresses", l, 16)
+	}
+	m.MarshalUint32(uint32(len(o.Addresses)))
+	for i := range o.Addresses {
+		m.MarshalString(o.Addresses[i])
+	}
+	return m.Error
+}
+
+func (o *PunchAddresses) UnmarshalXDR(bs []byte) error {
+	u := &xdr.Unmarshaller{Data: bs}
+	return o.UnmarshalXDRFrom(u)
+}
+func (o *PunchAddresses) UnmarshalXDRFrom(u *xdr.Unmarshaller) error {
+	_AddressesSize := int(u.UnmarshalUint32())
+	if _AddressesSize < 0 {
+		return xdr.ElementSizeExceeded("Addresses", _AddressesSize, 16)
+	} else if _AddressesSize == 0 {
+		o.Addresses = nil
+	} else {
+		if _AddressesSize > 16 {
+			return xdr.ElementSizeExceeded("Addresses", _AddressesSize, 16)
+		}
+		if _AddressesSize <= len(o.Addresses) {
+			for i := _AddressesSize; i < len(o.Addresses); i++ {
+				o.Addresses[i] = ""
+			}
+			o.Addresses = o.Addresses[:_AddressesSize]
+		} else {
+			o.Addresses = make([]string, _AddressesSize)
+		}
+		for i := range o.Addresses {
+			o.Addresses[i] = u.UnmarshalString()
+		}
+	}
+	return u.Error
+}
+
+/*
+
 ConnectRequest Structure:
 
  0                   1                   2                   3
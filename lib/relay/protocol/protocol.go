@@ -53,6 +53,9 @@ func WriteMessage(w io.Writer, message interface{}) error {
 	case RelayFull:
 		payload, err = msg.MarshalXDR()
 		header.messageType = messageTypeRelayFull
+	case PunchAddresses:
+		payload, err = msg.MarshalXDR()
+		header.messageType = messageTypePunchAddresses
 	default:
 		err = fmt.Errorf("Unknown message type")
 	}
@@ -126,6 +129,10 @@ func ReadMessage(r io.Reader) (interface{}, error) {
 		var msg RelayFull
 		err := msg.UnmarshalXDR(buf)
 		return msg, err
+	case messageTypePunchAddresses:
+		var msg PunchAddresses
+		err := msg.UnmarshalXDR(buf)
+		return msg, err
 	}
 
 	return nil, fmt.Errorf("Unknown message type")
@@ -15,8 +15,9 @@ import (
 	"github.com/syncthing/syncthing/lib/sync"
 )
 
-// HashFile hashes the files and returns a list of blocks representing the file.
-func HashFile(ctx context.Context, fs fs.Filesystem, path string, blockSize int, counter Counter, useWeakHashes bool) ([]protocol.BlockInfo, error) {
+// HashFile hashes the files and returns a list of blocks representing the
+// file. With content defined chunking, blockSize is the average block size.
+func HashFile(ctx context.Context, fs fs.Filesystem, path string, blockSize int, chunking protocol.BlockChunking, counter Counter, useWeakHashes bool) ([]protocol.BlockInfo, error) {
 	fd, err := fs.Open(path)
 	if err != nil {
 		l.Debugln("open:", err)
@@ -36,7 +37,12 @@ func HashFile(ctx context.Context, fs fs.Filesystem, path string, blockSize int,
 
 	// Hash the file. This may take a while for large files.
 
-	blocks, err := Blocks(ctx, fd, blockSize, size, counter, useWeakHashes)
+	var blocks []protocol.BlockInfo
+	if chunking == protocol.BlockChunkingContentDefined {
+		blocks, err = ContentDefinedBlocks(ctx, fd, blockSize, size, counter, useWeakHashes)
+	} else {
+		blocks, err = Blocks(ctx, fd, blockSize, size, counter, useWeakHashes)
+	}
 	if err != nil {
 		l.Debugln("blocks:", err)
 		return nil, err
@@ -104,7 +110,7 @@ func (ph *parallelHasher) hashFiles(ctx context.Context) {
 				panic("Bug. Asked to hash a directory or a deleted file.")
 			}
 
-			blocks, err := HashFile(ctx, ph.fs, f.Name, f.BlockSize(), ph.counter, true)
+			blocks, err := HashFile(ctx, ph.fs, f.Name, f.BlockSize(), f.Chunking, ph.counter, true)
 			if err != nil {
 				l.Debugln("hash error:", f.Name, err)
 				continue
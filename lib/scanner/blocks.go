@@ -25,38 +25,14 @@ type Counter interface {
 
 // Blocks returns the blockwise hash of the reader.
 func Blocks(ctx context.Context, r io.Reader, blocksize int, sizehint int64, counter Counter, useWeakHashes bool) ([]protocol.BlockInfo, error) {
-	if counter == nil {
-		counter = &noopCounter{}
-	}
-
-	hf := sha256.New()
-	hashLength := hf.Size()
-
-	var weakHf hash.Hash32 = noopHash{}
-	var multiHf io.Writer = hf
-	if useWeakHashes {
-		// Use an actual weak hash function, make the multiHf
-		// write to both hash functions.
-		weakHf = adler32.New()
-		multiHf = io.MultiWriter(hf, weakHf)
-	}
-
-	var blocks []protocol.BlockInfo
-	var hashes, thisHash []byte
-
+	h := newBlockHasher(blocksize, sizehint, counter, useWeakHashes)
 	if sizehint >= 0 {
-		// Allocate contiguous blocks for the BlockInfo structures and their
-		// hashes once and for all, and stick to the specified size.
 		r = io.LimitReader(r, sizehint)
-		numBlocks := int(sizehint / int64(blocksize))
-		blocks = make([]protocol.BlockInfo, 0, numBlocks)
-		hashes = make([]byte, 0, hashLength*numBlocks)
 	}
 
 	// A 32k buffer is used for copying into the hash function.
 	buf := make([]byte, 32<<10)
 
-	var offset int64
 	lr := io.LimitReader(r, int64(blocksize)).(*io.LimitedReader)
 	for {
 		select {
@@ -66,7 +42,7 @@ func Blocks(ctx context.Context, r io.Reader, blocksize int, sizehint int64, cou
 		}
 
 		lr.N = int64(blocksize)
-		n, err := io.CopyBuffer(multiHf, lr, buf)
+		n, err := io.CopyBuffer(h.multiHf, lr, buf)
 		if err != nil {
 			return nil, err
 		}
@@ -75,37 +51,133 @@ func Blocks(ctx context.Context, r io.Reader, blocksize int, sizehint int64, cou
 			break
 		}
 
-		counter.Update(n)
+		h.finishBlock(n)
+	}
 
-		// Carve out a hash-sized chunk of "hashes" to store the hash for this
-		// block.
-		hashes = hf.Sum(hashes)
-		thisHash, hashes = hashes[:hashLength], hashes[hashLength:]
+	return h.result(), nil
+}
 
-		b := protocol.BlockInfo{
-			Size:     int32(n),
-			Offset:   offset,
-			Hash:     thisHash,
-			WeakHash: weakHf.Sum32(),
+// ContentDefinedBlocks returns the hashes of the blocks of the reader, where
+// the block boundaries are determined by the content (see chunker). The
+// blocks are avgsize long on average, and an insertion or deletion only
+// affects the blocks around it.
+func ContentDefinedBlocks(ctx context.Context, r io.Reader, avgsize int, sizehint int64, counter Counter, useWeakHashes bool) ([]protocol.BlockInfo, error) {
+	h := newBlockHasher(avgsize, sizehint, counter, useWeakHashes)
+	if sizehint >= 0 {
+		r = io.LimitReader(r, sizehint)
+	}
+
+	c := newChunker(avgsize)
+	buf := make([]byte, c.max)
+	filled := 0
+	eof := false
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
 		}
 
-		blocks = append(blocks, b)
-		offset += n
+		if !eof && filled < len(buf) {
+			n, err := io.ReadFull(r, buf[filled:])
+			filled += n
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				eof = true
+			} else if err != nil {
+				return nil, err
+			}
+		}
 
-		hf.Reset()
-		weakHf.Reset()
+		if filled == 0 {
+			break
+		}
+
+		n := c.cut(buf[:filled])
+		h.multiHf.Write(buf[:n])
+		h.finishBlock(int64(n))
+
+		filled = copy(buf, buf[n:filled])
+	}
+
+	return h.result(), nil
+}
+
+// A blockHasher accumulates the block list of a file, hashing the data
+// written to multiHf until finishBlock is called.
+type blockHasher struct {
+	hf         hash.Hash
+	weakHf     hash.Hash32
+	multiHf    io.Writer
+	hashLength int
+	counter    Counter
+	blocks     []protocol.BlockInfo
+	hashes     []byte
+	offset     int64
+}
+
+func newBlockHasher(blocksize int, sizehint int64, counter Counter, useWeakHashes bool) *blockHasher {
+	if counter == nil {
+		counter = &noopCounter{}
 	}
 
-	if len(blocks) == 0 {
+	h := &blockHasher{
+		hf:      sha256.New(),
+		weakHf:  noopHash{},
+		counter: counter,
+	}
+	h.hashLength = h.hf.Size()
+	h.multiHf = h.hf
+	if useWeakHashes {
+		// Use an actual weak hash function, make the multiHf
+		// write to both hash functions.
+		h.weakHf = adler32.New()
+		h.multiHf = io.MultiWriter(h.hf, h.weakHf)
+	}
+
+	if sizehint >= 0 {
+		// Allocate contiguous blocks for the BlockInfo structures and their
+		// hashes once and for all, and stick to the specified size.
+		numBlocks := int(sizehint / int64(blocksize))
+		h.blocks = make([]protocol.BlockInfo, 0, numBlocks)
+		h.hashes = make([]byte, 0, h.hashLength*numBlocks)
+	}
+
+	return h
+}
+
+// finishBlock records a block of the given size, which has been written to
+// the hash functions.
+func (h *blockHasher) finishBlock(n int64) {
+	h.counter.Update(n)
+
+	// Carve out a hash-sized chunk of "hashes" to store the hash for this
+	// block.
+	var thisHash []byte
+	h.hashes = h.hf.Sum(h.hashes)
+	thisHash, h.hashes = h.hashes[:h.hashLength], h.hashes[h.hashLength:]
+
+	h.blocks = append(h.blocks, protocol.BlockInfo{
+		Size:     int32(n),
+		Offset:   h.offset,
+		Hash:     thisHash,
+		WeakHash: h.weakHf.Sum32(),
+	})
+	h.offset += n
+
+	h.hf.Reset()
+	h.weakHf.Reset()
+}
+
+func (h *blockHasher) result() []protocol.BlockInfo {
+	if len(h.blocks) == 0 {
 		// Empty file
-		blocks = append(blocks, protocol.BlockInfo{
+		return []protocol.BlockInfo{{
 			Offset: 0,
 			Size:   0,
 			Hash:   SHA256OfNothing,
-		})
+		}}
 	}
-
-	return blocks, nil
+	return h.blocks
 }
 
 func Validate(buf, hash []byte, weakHash uint32) bool {
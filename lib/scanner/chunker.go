@@ -0,0 +1,91 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package scanner
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/bits"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// The chunker finds content defined block boundaries using the FastCDC
+// algorithm: a gear hash is rolled over the data and a block ends where the
+// hash has enough zero bits. Before the average size is reached more zero
+// bits are required than after it, which keeps the block sizes close to the
+// average. Blocks are never smaller than a quarter of the average or larger
+// than four times it (or MaxBlockSize).
+//
+// The boundaries depend only on the data and the average size, so the gear
+// table and masks must never change; doing so would make every device see
+// different blocks for the same data.
+type chunker struct {
+	min, avg, max int
+	maskS, maskL  uint64
+}
+
+var gearTable [256]uint64
+
+func init() {
+	for i := range gearTable {
+		sum := sha256.Sum256([]byte{byte(i)})
+		gearTable[i] = binary.BigEndian.Uint64(sum[:])
+	}
+}
+
+func newChunker(avg int) chunker {
+	max := 4 * avg
+	if max > protocol.MaxBlockSize {
+		max = protocol.MaxBlockSize
+	}
+	if max < avg {
+		max = avg
+	}
+	// The gear hash mixes the most recent bytes into the lowest bits only,
+	// so the masks cover the highest ones.
+	avgBits := uint(bits.Len(uint(avg)) - 1)
+	return chunker{
+		min:   avg / 4,
+		avg:   avg,
+		max:   max,
+		maskS: ^uint64(0) << (64 - (avgBits + 2)),
+		maskL: ^uint64(0) << (64 - (avgBits - 2)),
+	}
+}
+
+// cut returns the length of the block at the start of buf, which must hold
+// at least max bytes unless the end of the data has been reached.
+func (c chunker) cut(buf []byte) int {
+	n := len(buf)
+	if n <= c.min {
+		return n
+	}
+	if n > c.max {
+		n = c.max
+	}
+	normal := c.avg
+	if normal > n {
+		normal = n
+	}
+
+	var h uint64
+	i := c.min
+	for ; i < normal; i++ {
+		h = h<<1 + gearTable[buf[i]]
+		if h&c.maskS == 0 {
+			return i + 1
+		}
+	}
+	for ; i < n; i++ {
+		h = h<<1 + gearTable[buf[i]]
+		if h&c.maskL == 0 {
+			return i + 1
+		}
+	}
+	return n
+}
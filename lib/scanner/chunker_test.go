@@ -0,0 +1,106 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"math/rand"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestContentDefinedBlocks(t *testing.T) {
+	const avg = 16 << 10
+	data := make([]byte, 4<<20)
+	rand.New(rand.NewSource(42)).Read(data)
+
+	blocks, err := ContentDefinedBlocks(context.TODO(), bytes.NewReader(data), avg, int64(len(data)), nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := newChunker(avg)
+	var offset int64
+	for i, b := range blocks {
+		if b.Offset != offset {
+			t.Fatalf("block %d at offset %d, expected %d", i, b.Offset, offset)
+		}
+		if int(b.Size) > c.max || (int(b.Size) < c.min && i != len(blocks)-1) {
+			t.Errorf("block %d has size %d, outside [%d, %d]", i, b.Size, c.min, c.max)
+		}
+		sum := sha256.Sum256(data[b.Offset : b.Offset+int64(b.Size)])
+		if !bytes.Equal(sum[:], b.Hash) {
+			t.Errorf("block %d has wrong hash", i)
+		}
+		offset += int64(b.Size)
+	}
+	if offset != int64(len(data)) {
+		t.Fatalf("blocks cover %d bytes, expected %d", offset, len(data))
+	}
+
+	// The average should be in the right neighbourhood.
+	if n := len(blocks); n < len(data)/avg/2 || n > len(data)/avg*2 {
+		t.Errorf("%d blocks for %d bytes with average %d", n, len(data), avg)
+	}
+}
+
+func TestContentDefinedBlocksInsertion(t *testing.T) {
+	const avg = 16 << 10
+	data := make([]byte, 4<<20)
+	rand.New(rand.NewSource(42)).Read(data)
+
+	// Insert a few bytes near the start. With fixed size blocks this
+	// would change every block; here only the first one or two should.
+	modified := make([]byte, 0, len(data)+3)
+	modified = append(modified, data[:1000]...)
+	modified = append(modified, "foo"...)
+	modified = append(modified, data[1000:]...)
+
+	orig, err := ContentDefinedBlocks(context.TODO(), bytes.NewReader(data), avg, -1, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed, err := ContentDefinedBlocks(context.TODO(), bytes.NewReader(modified), avg, -1, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	have := make(map[string]struct{}, len(orig))
+	for _, b := range orig {
+		have[string(b.Hash)] = struct{}{}
+	}
+	differing := 0
+	for _, b := range changed {
+		if _, ok := have[string(b.Hash)]; !ok {
+			differing++
+		}
+	}
+	if differing == 0 || differing > 2 {
+		t.Errorf("%d of %d blocks differ after insertion, expected 1 or 2", differing, len(changed))
+	}
+}
+
+func TestContentDefinedBlocksSmall(t *testing.T) {
+	blocks, err := ContentDefinedBlocks(context.TODO(), bytes.NewReader(nil), protocol.MinBlockSize, 0, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 1 || blocks[0].Size != 0 {
+		t.Errorf("unexpected blocks for empty data: %v", blocks)
+	}
+
+	blocks, err = ContentDefinedBlocks(context.TODO(), bytes.NewReader([]byte("contents")), protocol.MinBlockSize, 8, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 1 || blocks[0].Size != 8 || blocks[0].WeakHash != 0x0f3a036f {
+		t.Errorf("unexpected blocks for short data: %v", blocks)
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/syncthing/syncthing/lib/events"
 	"github.com/syncthing/syncthing/lib/fs"
 	"github.com/syncthing/syncthing/lib/ignore"
+	"github.com/syncthing/syncthing/lib/osutil"
 	"github.com/syncthing/syncthing/lib/protocol"
 	"golang.org/x/text/unicode/norm"
 )
@@ -56,6 +57,16 @@ type Config struct {
 	LocalFlags uint32
 	// Modification time is to be considered unchanged if the difference is lower.
 	ModTimeWindow time.Duration
+	// If ContentDefinedChunking is true, new and changed files are split
+	// into blocks at content defined boundaries instead of fixed offsets.
+	ContentDefinedChunking bool
+	// If ScanXattrs is true, the extended attributes of files and
+	// directories are read, and changes to them detected. Otherwise they
+	// are left unknown.
+	ScanXattrs bool
+	// If ScanOwnership is true, the owner and group of items are read, and
+	// changes to them detected. Otherwise they are left unknown.
+	ScanOwnership bool
 }
 
 type CurrentFiler interface {
@@ -345,10 +356,15 @@ func (w *walker) walkRegular(ctx context.Context, relPath string, info fs.FileIn
 	f, _ := CreateFileInfo(info, relPath, nil)
 	f = w.updateFileInfo(f, curFile)
 	f.NoPermissions = w.IgnorePerms
+	f.Xattrs = w.xattrs(relPath)
+	f.Ownership = w.ownership(info)
 	f.RawBlockSize = int32(blockSize)
+	if w.ContentDefinedChunking {
+		f.Chunking = protocol.BlockChunkingContentDefined
+	}
 
 	if hasCurFile {
-		if curFile.IsEquivalentOptional(f, w.ModTimeWindow, w.IgnorePerms, true, w.LocalFlags) {
+		if w.unchanged(curFile, f) {
 			return nil
 		}
 		if curFile.ShouldConflict() {
@@ -379,9 +395,11 @@ func (w *walker) walkDir(ctx context.Context, relPath string, info fs.FileInfo,
 	f, _ := CreateFileInfo(info, relPath, nil)
 	f = w.updateFileInfo(f, curFile)
 	f.NoPermissions = w.IgnorePerms
+	f.Xattrs = w.xattrs(relPath)
+	f.Ownership = w.ownership(info)
 
 	if hasCurFile {
-		if curFile.IsEquivalentOptional(f, w.ModTimeWindow, w.IgnorePerms, true, w.LocalFlags) {
+		if w.unchanged(curFile, f) {
 			return nil
 		}
 		if curFile.ShouldConflict() {
@@ -405,6 +423,50 @@ func (w *walker) walkDir(ctx context.Context, relPath string, info fs.FileInfo,
 	return nil
 }
 
+// xattrs returns the extended attributes of the item, or nil when they
+// aren't scanned or can't be read.
+func (w *walker) xattrs(relPath string) *protocol.Xattrs {
+	if !w.ScanXattrs {
+		return nil
+	}
+	attrs, err := w.Filesystem.GetXattr(relPath)
+	if err != nil {
+		l.Debugln("xattrs:", relPath, err)
+		return nil
+	}
+	attrs = fs.LimitXattrs(attrs)
+	xattrs := &protocol.Xattrs{Entries: make([]protocol.Xattr, len(attrs))}
+	for i, attr := range attrs {
+		xattrs.Entries[i] = protocol.Xattr{Name: attr.Name, Value: attr.Value}
+	}
+	return xattrs
+}
+
+// ownership returns the owner and group of the item, or nil when they
+// aren't scanned or the platform has none.
+func (w *walker) ownership(info fs.FileInfo) *protocol.Ownership {
+	if !w.ScanOwnership || info.Owner() < 0 {
+		return nil
+	}
+	return &protocol.Ownership{
+		OwnerName: osutil.UserName(info.Owner()),
+		GroupName: osutil.GroupName(info.Group()),
+		Uid:       int32(info.Owner()),
+		Gid:       int32(info.Group()),
+	}
+}
+
+// unchanged returns whether the scanned item f is the same as the current
+// one. Ownership only just read counts as a change. Extended attributes
+// that aren't known for the current item, such as when it came from a
+// device that doesn't sync them, don't.
+func (w *walker) unchanged(curFile, f protocol.FileInfo) bool {
+	if f.Ownership != nil && curFile.Ownership == nil {
+		return false
+	}
+	return curFile.IsEquivalentOptional(f, w.ModTimeWindow, w.IgnorePerms, true, w.LocalFlags)
+}
+
 // walkSymlink returns nil or an error, if the error is of the nature that
 // it should stop the entire walk.
 func (w *walker) walkSymlink(ctx context.Context, relPath string, info fs.FileInfo, finishedChan chan<- ScanResult) error {
@@ -423,9 +485,10 @@ func (w *walker) walkSymlink(ctx context.Context, relPath string, info fs.FileIn
 	curFile, hasCurFile := w.CurrentFiler.CurrentFile(relPath)
 
 	f = w.updateFileInfo(f, curFile)
+	f.Ownership = w.ownership(info)
 
 	if hasCurFile {
-		if curFile.IsEquivalentOptional(f, w.ModTimeWindow, w.IgnorePerms, true, w.LocalFlags) {
+		if w.unchanged(curFile, f) {
 			return nil
 		}
 		if curFile.ShouldConflict() {
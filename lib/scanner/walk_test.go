@@ -537,7 +537,7 @@ func BenchmarkHashFile(b *testing.B) {
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		if _, err := HashFile(context.TODO(), fs.NewFilesystem(fs.FilesystemTypeBasic, ""), testdataName, protocol.MinBlockSize, nil, true); err != nil {
+		if _, err := HashFile(context.TODO(), fs.NewFilesystem(fs.FilesystemTypeBasic, ""), testdataName, protocol.MinBlockSize, protocol.BlockChunkingFixed, nil, true); err != nil {
 			b.Fatal(err)
 		}
 	}
@@ -755,6 +755,115 @@ func TestNotExistingError(t *testing.T) {
 	}
 }
 
+func TestWalkXattrs(t *testing.T) {
+	ffs := fs.NewFilesystem(fs.FilesystemTypeFake, "/TestWalkXattrs")
+	fd, err := ffs.Create("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd.Close()
+	if err := ffs.SetXattr("foo", []fs.Xattr{{Name: "user.tag", Value: []byte("red")}}); err != nil {
+		t.Fatal(err)
+	}
+
+	walk := func(cfiler CurrentFiler) []protocol.FileInfo {
+		t.Helper()
+		fchan := Walk(context.TODO(), Config{
+			Filesystem:   ffs,
+			Subs:         []string{"foo"},
+			Hashers:      2,
+			CurrentFiler: cfiler,
+			ScanXattrs:   true,
+		})
+		var files []protocol.FileInfo
+		for f := range fchan {
+			if f.Err != nil {
+				t.Fatalf("Error while scanning %v: %v", f.Err, f.Path)
+			}
+			files = append(files, f.File)
+		}
+		return files
+	}
+
+	files := walk(nil)
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 file, got %d", len(files))
+	}
+	file := files[0]
+	if file.Xattrs == nil || len(file.Xattrs.Entries) != 1 || file.Xattrs.Entries[0].Name != "user.tag" || string(file.Xattrs.Entries[0].Value) != "red" {
+		t.Fatalf("Expected the extended attribute to be scanned, got %v", file.Xattrs)
+	}
+
+	if files := walk(fakeCurrentFiler{"foo": file}); len(files) != 0 {
+		t.Error("Expected no change, got", files)
+	}
+
+	// Attributes that weren't known before aren't a change, but ones that
+	// differ are
+
+	unknown := file
+	unknown.Xattrs = nil
+	if files := walk(fakeCurrentFiler{"foo": unknown}); len(files) != 0 {
+		t.Error("Expected unknown attributes not to be a change, got", files)
+	}
+
+	if err := ffs.SetXattr("foo", []fs.Xattr{{Name: "user.tag", Value: []byte("blue")}}); err != nil {
+		t.Fatal(err)
+	}
+	if files := walk(fakeCurrentFiler{"foo": file}); len(files) != 1 {
+		t.Error("Expected changed attributes to be a change")
+	}
+}
+
+func TestWalkOwnership(t *testing.T) {
+	ffs := fs.NewFilesystem(fs.FilesystemTypeFake, "/TestWalkOwnership")
+	if err := ffs.Mkdir("foo", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ffs.Lchown("foo", 1234, 5678); err != nil {
+		t.Fatal(err)
+	}
+
+	walk := func(cfiler CurrentFiler) []protocol.FileInfo {
+		t.Helper()
+		fchan := Walk(context.TODO(), Config{
+			Filesystem:    ffs,
+			Subs:          []string{"foo"},
+			Hashers:       2,
+			CurrentFiler:  cfiler,
+			ScanOwnership: true,
+		})
+		var files []protocol.FileInfo
+		for f := range fchan {
+			if f.Err != nil {
+				t.Fatalf("Error while scanning %v: %v", f.Err, f.Path)
+			}
+			files = append(files, f.File)
+		}
+		return files
+	}
+
+	files := walk(nil)
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 directory, got %d", len(files))
+	}
+	dir := files[0]
+	if dir.Ownership == nil || dir.Ownership.Uid != 1234 || dir.Ownership.Gid != 5678 {
+		t.Fatalf("Expected the owner and group to be scanned, got %v", dir.Ownership)
+	}
+
+	if files := walk(fakeCurrentFiler{"foo": dir}); len(files) != 0 {
+		t.Error("Expected no change, got", files)
+	}
+
+	if err := ffs.Lchown("foo", 1234, 1234); err != nil {
+		t.Fatal(err)
+	}
+	if files := walk(fakeCurrentFiler{"foo": dir}); len(files) != 1 {
+		t.Error("Expected a changed group to be a change")
+	}
+}
+
 // Verify returns nil or an error describing the mismatch between the block
 // list and actual reader contents
 func verify(r io.Reader, blocksize int, blocks []protocol.BlockInfo) error {
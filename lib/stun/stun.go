@@ -304,7 +304,14 @@ func (s *Service) String() string {
 }
 
 func (s *Service) isCurrentNATTypePunchable() bool {
-	return s.natType == NATNone || s.natType == NATPortRestricted || s.natType == NATRestricted || s.natType == NATFull
+	return IsPunchable(s.natType)
+}
+
+// IsPunchable returns whether peers behind a NAT of the given type can be
+// reached by sending them packets while they do the same to us, from the
+// address found by STUN.
+func IsPunchable(natType NATType) bool {
+	return natType == NATNone || natType == NATPortRestricted || natType == NATRestricted || natType == NATFull
 }
 
 func areDifferent(first, second *Host) bool {
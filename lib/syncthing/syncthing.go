@@ -142,9 +142,13 @@ func (a *App) startup() error {
 	osutil.MaximizeOpenFileLimit()
 
 	// Figure out our device ID, set it as the log prefix and log it.
-	a.myID = protocol.NewDeviceID(a.cert.Certificate[0])
+	a.myID = tlsutil.DeviceID(a.cert)
 	l.SetPrefix(fmt.Sprintf("[%s] ", a.myID.String()[:5]))
 	l.Infoln("My ID:", a.myID)
+	if err := connections.VerifyClaimedDeviceID(a.cert, a.cfg.Options()); err != nil {
+		l.Warnln("Device ID:", err)
+		return err
+	}
 
 	// Select SHA256 implementation and report. Affected by the
 	// STHASHING environment variable.
@@ -190,6 +194,7 @@ func (a *App) startup() error {
 
 	protectedFiles := []string{
 		locations.Get(locations.Database),
+		locations.Get(locations.BadgerDB),
 		locations.Get(locations.ConfigFile),
 		locations.Get(locations.CertFile),
 		locations.Get(locations.KeyFile),
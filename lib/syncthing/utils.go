@@ -17,6 +17,7 @@ import (
 
 	"github.com/syncthing/syncthing/lib/config"
 	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/db/backend"
 	"github.com/syncthing/syncthing/lib/fs"
 	"github.com/syncthing/syncthing/lib/locations"
 	"github.com/syncthing/syncthing/lib/protocol"
@@ -24,6 +25,15 @@ import (
 )
 
 func LoadOrGenerateCertificate(certFile, keyFile string) (tls.Certificate, error) {
+	// An interrupted replacement of the certificate must not lead to a new
+	// one being generated.
+	if err := tlsutil.FinishCertificateRotation(
+		locations.Get(locations.CertFile),
+		locations.Get(locations.KeyFile),
+	); err != nil {
+		return tls.Certificate{}, err
+	}
+
 	cert, err := tls.LoadX509KeyPair(
 		locations.Get(locations.CertFile),
 		locations.Get(locations.KeyFile),
@@ -60,7 +70,7 @@ func DefaultConfig(path string, myID protocol.DeviceID, noDefaultFolder bool) (c
 // Otherwise it checks the version, and archives and upgrades the config if
 // necessary or returns an error, if the version isn't compatible.
 func LoadConfigAtStartup(path string, cert tls.Certificate, allowNewerConfig, noDefaultFolder bool) (config.Wrapper, error) {
-	myID := protocol.NewDeviceID(cert.Certificate[0])
+	myID := tlsutil.DeviceID(cert)
 	cfg, err := config.Load(path, myID)
 	if fs.IsNotExist(err) {
 		cfg, err = DefaultConfig(path, myID, noDefaultFolder)
@@ -121,6 +131,30 @@ func copyFile(src, dst string) error {
 	return nil
 }
 
-func OpenGoleveldb(path string) (*db.Lowlevel, error) {
-	return db.Open(path)
+// OpenDatabase opens the database using the named backend, at the location
+// for that backend.
+func OpenDatabase(backendName string) (*db.Lowlevel, error) {
+	var t backend.Type
+	if err := t.UnmarshalText([]byte(backendName)); err != nil {
+		return nil, err
+	}
+	location := DatabaseLocation(t)
+	if t != backend.TypeLevelDB {
+		if _, err := os.Stat(location); os.IsNotExist(err) {
+			if _, err := os.Stat(DatabaseLocation(backend.TypeLevelDB)); err == nil {
+				l.Infof("Creating a new %v database; the existing leveldb database can be converted using stindex -mode convert", t)
+			}
+		}
+	}
+	return db.OpenBackend(t, location)
+}
+
+// DatabaseLocation returns where the database of the given backend is kept.
+// They are kept apart, so that switching backends doesn't destroy the other
+// database.
+func DatabaseLocation(t backend.Type) string {
+	if t == backend.TypeBadger {
+		return locations.Get(locations.BadgerDB)
+	}
+	return locations.Get(locations.Database)
 }
@@ -183,6 +183,10 @@ func (s *verboseService) formatEvent(ev events.Event) string {
 			success = "failed"
 		}
 		return fmt.Sprintf("Login %s for username %s.", success, username)
+
+	case events.FileCorrupted:
+		data := ev.Data.(map[string]interface{})
+		return fmt.Sprintf("File %v in folder %v is corrupted in %d blocks", data["item"], data["folder"], len(data["blocks"].([]int)))
 	}
 
 	return fmt.Sprintf("%s %#v", ev.Type, ev)
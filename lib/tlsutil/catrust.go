@@ -0,0 +1,274 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package tlsutil
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// Devices are normally identified by the hash of their self signed
+// certificate. A certificate issued by a CA may instead carry the device ID
+// in its common name, so that the certificate can be replaced without
+// changing the device ID. Peers only accept that if they trust the CA.
+// Global discovery servers and relays know devices by the hash of their
+// certificate only, so a device with a claimed ID can't use them.
+
+var ErrCertificateRevoked = errors.New("certificate has been revoked")
+
+// DeviceID returns the device ID of our own certificate; see CertificateDeviceID.
+func DeviceID(cert tls.Certificate) protocol.DeviceID {
+	if parsed, err := x509.ParseCertificate(cert.Certificate[0]); err == nil {
+		return CertificateDeviceID(parsed)
+	}
+	return protocol.NewDeviceID(cert.Certificate[0])
+}
+
+// HasClaimedDeviceID returns whether our own certificate claims a device ID,
+// rather than being identified by its hash.
+func HasClaimedDeviceID(cert tls.Certificate) bool {
+	return DeviceID(cert) != protocol.NewDeviceID(cert.Certificate[0])
+}
+
+// CertificateDeviceID returns the device ID claimed by the certificate,
+// if it's issued by a CA, or otherwise the hash of it.
+func CertificateDeviceID(cert *x509.Certificate) protocol.DeviceID {
+	if id, ok := ClaimedDeviceID(cert); ok {
+		return id
+	}
+	return protocol.NewDeviceID(cert.Raw)
+}
+
+// ClaimedDeviceID returns the device ID in the common name of a
+// certificate that isn't self signed, if there is one. Whether the claim
+// is to be believed is for a CATrust to decide.
+func ClaimedDeviceID(cert *x509.Certificate) (protocol.DeviceID, bool) {
+	if bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+		return protocol.EmptyDeviceID, false
+	}
+	id, err := protocol.DeviceIDFromString(cert.Subject.CommonName)
+	if err != nil {
+		return protocol.EmptyDeviceID, false
+	}
+	return id, true
+}
+
+// A CATrust decides whether certificates are issued by one of a set of
+// CAs, and haven't been revoked since.
+type CATrust struct {
+	roots   *x509.CertPool
+	revoked map[string]struct{} // serial numbers, as from big.Int.Text(16)
+}
+
+// LoadCATrust loads the CA certificates in PEM format from caFile and,
+// if revocationFile is given, the serial numbers of revoked certificates
+// from that; see ParseRevocationList.
+func LoadCATrust(caFile, revocationFile string) (*CATrust, error) {
+	bs, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("load CA certificates: %s", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(bs) {
+		return nil, fmt.Errorf("load CA certificates: no certificates in %s", caFile)
+	}
+
+	revoked := make(map[string]struct{})
+	if revocationFile != "" {
+		fd, err := os.Open(revocationFile)
+		if err != nil {
+			return nil, fmt.Errorf("load revocation list: %s", err)
+		}
+		defer fd.Close()
+		serials, err := ParseRevocationList(fd)
+		if err != nil {
+			return nil, fmt.Errorf("load revocation list: %s", err)
+		}
+		for _, serial := range serials {
+			revoked[serial.Text(16)] = struct{}{}
+		}
+	}
+
+	return &CATrust{roots: roots, revoked: revoked}, nil
+}
+
+// ParseRevocationList reads the serial numbers of revoked certificates,
+// one per line, in hex with or without colons between the bytes, as shown
+// by "openssl x509 -serial". Empty lines and those starting with # are
+// ignored.
+func ParseRevocationList(r io.Reader) ([]*big.Int, error) {
+	var serials []*big.Int
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		s := strings.TrimSpace(scanner.Text())
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		s = strings.TrimPrefix(s, "serial=")
+		s = strings.Replace(s, ":", "", -1)
+		serial, ok := new(big.Int).SetString(s, 16)
+		if !ok {
+			return nil, fmt.Errorf("line %d: invalid serial number %q", line, scanner.Text())
+		}
+		serials = append(serials, serial)
+	}
+	return serials, scanner.Err()
+}
+
+// Verify returns nil if the certificate is valid, chains to one of the CAs
+// through the given intermediate certificates, and neither it nor any of
+// the certificates it chains through has been revoked.
+func (t *CATrust) Verify(cert *x509.Certificate, intermediates []*x509.Certificate) error {
+	opts := x509.VerifyOptions{
+		Roots:         t.roots,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	for _, ic := range intermediates {
+		opts.Intermediates.AddCert(ic)
+	}
+	chains, err := cert.Verify(opts)
+	if err != nil {
+		return err
+	}
+	for _, chain := range chains {
+		for _, c := range chain {
+			if _, ok := t.revoked[c.SerialNumber.Text(16)]; ok {
+				return ErrCertificateRevoked
+			}
+		}
+	}
+	return nil
+}
+
+// RotateCertificate replaces the certificate and key in certFile and
+// keyFile with those in newCertFile and newKeyFile, if the new
+// certificate is for the same device ID. The old ones are kept with an
+// ".old" suffix.
+//
+// The new ones are first written next to the current ones with a ".new"
+// suffix, the key before the certificate, which commits to the rotation.
+// They are then renamed into place, the key first. If that is interrupted,
+// the current certificate and key may not match until
+// FinishCertificateRotation completes it.
+func RotateCertificate(certFile, keyFile, newCertFile, newKeyFile string) (protocol.DeviceID, error) {
+	// Anything left over from an interrupted rotation is dealt with first.
+	if err := FinishCertificateRotation(certFile, keyFile); err != nil {
+		return protocol.EmptyDeviceID, err
+	}
+
+	cur, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return protocol.EmptyDeviceID, fmt.Errorf("load certificate: %s", err)
+	}
+	next, err := tls.LoadX509KeyPair(newCertFile, newKeyFile)
+	if err != nil {
+		return protocol.EmptyDeviceID, fmt.Errorf("load new certificate: %s", err)
+	}
+	id := DeviceID(cur)
+	if newID := DeviceID(next); newID != id {
+		return protocol.EmptyDeviceID, fmt.Errorf("new certificate is for device %v, not %v", newID, id)
+	}
+
+	for _, f := range []struct{ from, to string }{
+		{certFile, certFile + ".old"},
+		{keyFile, keyFile + ".old"},
+		{newKeyFile, keyFile + ".new"},
+		{newCertFile, certFile + ".new"},
+	} {
+		if err := copyFileSync(f.from, f.to); err != nil {
+			return protocol.EmptyDeviceID, err
+		}
+	}
+
+	if err := FinishCertificateRotation(certFile, keyFile); err != nil {
+		return protocol.EmptyDeviceID, err
+	}
+	return id, nil
+}
+
+// FinishCertificateRotation completes a rotation of the certificate and key
+// in certFile and keyFile that was committed to but interrupted, or else
+// cleans up after one that wasn't committed to. It is to be called before
+// loading them.
+func FinishCertificateRotation(certFile, keyFile string) error {
+	if _, err := os.Lstat(certFile + ".new"); os.IsNotExist(err) {
+		for _, f := range []string{keyFile + ".new", keyFile + ".new.tmp", certFile + ".new.tmp"} {
+			if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// The key may have been renamed already.
+	if err := os.Rename(keyFile+".new", keyFile); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := syncDir(filepath.Dir(keyFile)); err != nil {
+		return err
+	}
+	if err := os.Rename(certFile+".new", certFile); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(certFile))
+}
+
+// copyFileSync copies the file from one name to another, which appears
+// only once the copy is complete and on disk.
+func copyFileSync(from, to string) error {
+	bs, err := ioutil.ReadFile(from)
+	if err != nil {
+		return err
+	}
+	fd, err := os.OpenFile(to+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := fd.Write(bs); err != nil {
+		fd.Close()
+		return err
+	}
+	if err := fd.Sync(); err != nil {
+		fd.Close()
+		return err
+	}
+	if err := fd.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(to+".tmp", to); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(to))
+}
+
+func syncDir(dir string) error {
+	fd, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	if err := fd.Sync(); err != nil && runtime.GOOS != "windows" {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package tlsutil
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/rand"
+)
+
+func TestCATrust(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syncthing-catrust")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca, caKey := testCertificate(t, "Test CA", nil, nil, 1)
+	otherCA, otherCAKey := testCertificate(t, "Other CA", nil, nil, 2)
+	writeCertificate(t, filepath.Join(dir, "ca.pem"), ca)
+
+	id := protocol.NewDeviceID([]byte("device"))
+	cert, _ := testCertificate(t, id.String(), ca, caKey, 10)
+	revokedCert, _ := testCertificate(t, id.String(), ca, caKey, 11)
+	otherCert, _ := testCertificate(t, id.String(), otherCA, otherCAKey, 12)
+
+	if claimed, ok := ClaimedDeviceID(cert); !ok || claimed != id {
+		t.Errorf("Expected the certificate to claim %v, got %v", id, claimed)
+	}
+	if _, ok := ClaimedDeviceID(ca); ok {
+		t.Error("Expected a self signed certificate not to claim a device ID")
+	}
+	if CertificateDeviceID(ca) != protocol.NewDeviceID(ca.Raw) {
+		t.Error("Expected the device ID of a self signed certificate to be its hash")
+	}
+
+	revocations := "# Revoked certificates\n\n0b\nserial=0C\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "revoked.txt"), []byte(revocations), 0644); err != nil {
+		t.Fatal(err)
+	}
+	trust, err := LoadCATrust(filepath.Join(dir, "ca.pem"), filepath.Join(dir, "revoked.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := trust.Verify(cert, nil); err != nil {
+		t.Error("Expected the certificate to be trusted, got", err)
+	}
+	if err := trust.Verify(revokedCert, nil); err != ErrCertificateRevoked {
+		t.Error("Expected the certificate to be revoked, got", err)
+	}
+	if err := trust.Verify(otherCert, nil); err == nil {
+		t.Error("Expected a certificate from another CA not to be trusted")
+	}
+}
+
+func TestParseRevocationList(t *testing.T) {
+	serials, err := ParseRevocationList(strings.NewReader("01:0A:ff\n  # comment\nserial=2A\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(serials) != 2 || serials[0].Int64() != 0x010aff || serials[1].Int64() != 0x2a {
+		t.Errorf("Unexpected serials %v", serials)
+	}
+
+	if _, err := ParseRevocationList(strings.NewReader("01\nbanana\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Error("Expected an error on the second line, got", err)
+	}
+}
+
+func TestRotateCertificate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syncthing-catrust")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The current certificate is self signed, the new ones claim its
+	// device ID or another one.
+
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	cur, err := NewCertificate(certFile, keyFile, "syncthing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := DeviceID(cur)
+
+	ca, caKey := testCertificate(t, "Test CA", nil, nil, 1)
+	for i, claim := range []protocol.DeviceID{protocol.NewDeviceID([]byte("other")), id} {
+		cert, key := testCertificate(t, claim.String(), ca, caKey, int64(10+i))
+		sub := filepath.Join(dir, claim.String())
+		if err := os.Mkdir(sub, 0700); err != nil {
+			t.Fatal(err)
+		}
+		writeCertificate(t, filepath.Join(sub, "cert.pem"), cert)
+		writeKey(t, filepath.Join(sub, "key.pem"), key)
+	}
+
+	other := protocol.NewDeviceID([]byte("other")).String()
+	if _, err := RotateCertificate(certFile, keyFile, filepath.Join(dir, other, "cert.pem"), filepath.Join(dir, other, "key.pem")); err == nil {
+		t.Fatal("Expected a certificate for another device to be refused")
+	}
+
+	rotated, err := RotateCertificate(certFile, keyFile, filepath.Join(dir, id.String(), "cert.pem"), filepath.Join(dir, id.String(), "key.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated != id {
+		t.Errorf("Device ID changed from %v to %v", id, rotated)
+	}
+	if _, err := os.Stat(certFile + ".old"); err != nil {
+		t.Error("Expected the old certificate to be kept:", err)
+	}
+	if HasClaimedDeviceID(cur) {
+		t.Error("Expected the self signed certificate not to claim its device ID")
+	}
+	next, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !HasClaimedDeviceID(next) {
+		t.Error("Expected the new certificate to claim its device ID")
+	}
+}
+
+func TestFinishCertificateRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syncthing-catrust")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	cur, err := NewCertificate(certFile, keyFile, "syncthing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, caKey := testCertificate(t, "Test CA", nil, nil, 1)
+	cert, key := testCertificate(t, DeviceID(cur).String(), ca, caKey, 10)
+
+	// A rotation interrupted before the new certificate was written leaves
+	// the current one in place.
+
+	writeKey(t, keyFile+".new", key)
+	if err := FinishCertificateRotation(certFile, keyFile); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(loaded.Certificate[0], cur.Certificate[0]) {
+		t.Error("Expected the current certificate to be kept")
+	}
+	if _, err := os.Stat(keyFile + ".new"); !os.IsNotExist(err) {
+		t.Error("Expected the new key to be removed, got", err)
+	}
+
+	// One interrupted after the key was replaced is completed.
+
+	writeKey(t, keyFile, key)
+	writeCertificate(t, certFile+".new", cert)
+	if err := FinishCertificateRotation(certFile, keyFile); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err = tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(loaded.Certificate[0], cert.Raw) {
+		t.Error("Expected the new certificate to be in place")
+	}
+}
+
+// testCertificate returns a certificate with the given common name, signed
+// by the parent, or self signed if that is nil.
+func testCertificate(t *testing.T, commonName string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, serial int64) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func writeCertificate(t *testing.T, path string, cert *x509.Certificate) {
+	t.Helper()
+	bs := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	if err := ioutil.WriteFile(path, bs, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeKey(t *testing.T, path string, key *ecdsa.PrivateKey) {
+	t.Helper()
+	block, err := pemBlockForKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
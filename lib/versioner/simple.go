@@ -70,3 +70,8 @@ func (v Simple) GetVersions() (map[string][]FileVersion, error) {
 func (v Simple) Restore(filepath string, versionTime time.Time) error {
 	return restoreFile(v.versionsFs, v.folderFs, filepath, versionTime, TagFilename)
 }
+
+// Open opens the version of the file from the given time for reading.
+func (v Simple) Open(filePath string, versionTime time.Time) (fs.File, error) {
+	return openVersion(v.versionsFs, filePath, versionTime, TagFilename)
+}
@@ -9,6 +9,7 @@ package versioner
 import (
 	"io/ioutil"
 	"math"
+	"os"
 	"path/filepath"
 	"testing"
 	"time"
@@ -87,3 +88,50 @@ func TestSimpleVersioningVersionCount(t *testing.T) {
 		time.Sleep(time.Second)
 	}
 }
+
+func TestSimpleVersioningOpen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fs := fs.NewFilesystem(fs.FilesystemTypeBasic, dir)
+	v := NewSimple("", fs, nil)
+
+	path := filepath.Join("dir", "test.txt")
+	if err := fs.MkdirAll("dir", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, path), []byte("version one"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Archive(path); err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := v.GetVersions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions[path]) != 1 {
+		t.Fatalf("Expected one version of %s, got %v", path, versions)
+	}
+
+	fd, err := v.(Opener).Open(path, versions[path][0].VersionTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, err := ioutil.ReadAll(fd)
+	fd.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bs) != "version one" {
+		t.Errorf("Unexpected version content %q", bs)
+	}
+
+	if _, err := v.(Opener).Open(path, versions[path][0].VersionTime.Add(time.Hour)); err != errNotFound {
+		t.Error("Expected no version an hour later, got", err)
+	}
+}
@@ -210,29 +210,8 @@ func restoreFile(src, dst fs.Filesystem, filePath string, versionTime time.Time,
 
 	filePath = osutil.NativeFilename(filePath)
 
-	// Try and find a file that has the correct mtime
-	sourceFile := ""
-	sourceMtime := time.Time{}
-	if info, err := src.Lstat(taggedFilePath); err == nil && info.IsRegular() {
-		sourceFile = taggedFilePath
-		sourceMtime = info.ModTime()
-	} else if err == nil {
-		l.Debugln("restore:", taggedFilePath, "not regular")
-	} else {
-		l.Debugln("restore:", taggedFilePath, err.Error())
-	}
-
-	// Check for untagged file
-	if sourceFile == "" {
-		info, err := src.Lstat(filePath)
-		if err == nil && info.IsRegular() && info.ModTime().Truncate(time.Second).Equal(versionTime) {
-			sourceFile = filePath
-			sourceMtime = info.ModTime()
-		}
-
-	}
-
-	if sourceFile == "" {
+	sourceFile, sourceMtime, ok := findVersion(src, filePath, taggedFilePath, versionTime)
+	if !ok {
 		return errNotFound
 	}
 
@@ -250,6 +229,41 @@ func restoreFile(src, dst fs.Filesystem, filePath string, versionTime time.Time,
 	return err
 }
 
+// findVersion returns the name and modification time of the version of the
+// file with the given tag or, failing that, the untagged file if it has the
+// version time as modification time.
+func findVersion(src fs.Filesystem, filePath, taggedFilePath string, versionTime time.Time) (string, time.Time, bool) {
+	// Try and find a file that has the correct mtime
+	if info, err := src.Lstat(taggedFilePath); err == nil && info.IsRegular() {
+		return taggedFilePath, info.ModTime(), true
+	} else if err == nil {
+		l.Debugln("restore:", taggedFilePath, "not regular")
+	} else {
+		l.Debugln("restore:", taggedFilePath, err.Error())
+	}
+
+	// Check for untagged file
+	info, err := src.Lstat(filePath)
+	if err == nil && info.IsRegular() && info.ModTime().Truncate(time.Second).Equal(versionTime) {
+		return filePath, info.ModTime(), true
+	}
+
+	return "", time.Time{}, false
+}
+
+// openVersion opens the version of the file from the given time for reading.
+func openVersion(src fs.Filesystem, filePath string, versionTime time.Time, tagger fileTagger) (fs.File, error) {
+	tag := versionTime.In(time.Local).Truncate(time.Second).Format(TimeFormat)
+	taggedFilePath := tagger(filePath, tag)
+	filePath = osutil.NativeFilename(filePath)
+
+	sourceFile, _, ok := findVersion(src, filePath, taggedFilePath, versionTime)
+	if !ok {
+		return nil, errNotFound
+	}
+	return src.Open(sourceFile)
+}
+
 func fsFromParams(folderFs fs.Filesystem, params map[string]string) (versionsFs fs.Filesystem) {
 	if params["fsType"] == "" && params["fsPath"] == "" {
 		versionsFs = fs.NewFilesystem(folderFs.Type(), filepath.Join(folderFs.URI(), ".stversions"))
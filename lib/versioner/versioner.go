@@ -21,6 +21,12 @@ type Versioner interface {
 	Restore(filePath string, versionTime time.Time) error
 }
 
+// An Opener is a Versioner that keeps versions where they can be read, such
+// as to find out what a file looked like before it was changed.
+type Opener interface {
+	Open(filePath string, versionTime time.Time) (fs.File, error)
+}
+
 type FileVersion struct {
 	VersionTime time.Time `json:"versionTime"`
 	ModTime     time.Time `json:"modTime"`